@@ -34,6 +34,7 @@ const EnvVaultWrapTTL = "VAULT_WRAP_TTL"
 const EnvVaultMaxRetries = "VAULT_MAX_RETRIES"
 const EnvVaultToken = "VAULT_TOKEN"
 const EnvVaultMFA = "VAULT_MFA"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
 
 // WrappingLookupFunc is a function that, given an HTTP verb and a path,
 // returns an optional string duration to be used for response wrapping (e.g.
@@ -285,6 +286,7 @@ type Client struct {
 	addr               *url.URL
 	config             *Config
 	token              string
+	namespace          string
 	headers            http.Header
 	wrappingLookupFunc WrappingLookupFunc
 	mfaCreds           []string
@@ -298,7 +300,8 @@ type Client struct {
 //
 // If the environment variable `VAULT_TOKEN` is present, the token will be
 // automatically added to the client. Otherwise, you must manually call
-// `SetToken()`.
+// `SetToken()`. Likewise, the namespace is taken from `VAULT_NAMESPACE` if
+// it is present.
 func NewClient(c *Config) (*Client, error) {
 	def := DefaultConfig()
 	if def == nil {
@@ -336,6 +339,10 @@ func NewClient(c *Config) (*Client, error) {
 		client.token = token
 	}
 
+	if namespace := os.Getenv(EnvVaultNamespace); namespace != "" {
+		client.namespace = namespace
+	}
+
 	return client, nil
 }
 
@@ -426,6 +433,33 @@ func (c *Client) ClearToken() {
 	c.token = ""
 }
 
+// Namespace returns the namespace requests are made against. It will return
+// the empty string for the root namespace.
+func (c *Client) Namespace() string {
+	c.modifyLock.RLock()
+	defer c.modifyLock.RUnlock()
+
+	return c.namespace
+}
+
+// SetNamespace sets the namespace future requests are made against.
+// Setting this on a client will override the value of the VAULT_NAMESPACE
+// environment variable.
+func (c *Client) SetNamespace(namespace string) {
+	c.modifyLock.Lock()
+	defer c.modifyLock.Unlock()
+
+	c.namespace = namespace
+}
+
+// ClearNamespace makes future requests against the root namespace.
+func (c *Client) ClearNamespace() {
+	c.modifyLock.Lock()
+	defer c.modifyLock.Unlock()
+
+	c.namespace = ""
+}
+
 // SetHeaders sets the headers to be used for future requests.
 func (c *Client) SetHeaders(headers http.Header) {
 	c.modifyLock.Lock()
@@ -492,6 +526,7 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 			Path:   path.Join(c.addr.Path, requestPath),
 		},
 		ClientToken: c.token,
+		Namespace:   c.namespace,
 		Params:      make(map[string][]string),
 	}
 
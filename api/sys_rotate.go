@@ -24,7 +24,81 @@ func (c *Sys) KeyStatus() (*KeyStatus, error) {
 	return result, err
 }
 
+func (c *Sys) RotateReencrypt() error {
+	r := c.c.NewRequest("POST", "/v1/sys/rotate/reencrypt")
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+func (c *Sys) RotatePrune() ([]uint32, error) {
+	r := c.c.NewRequest("POST", "/v1/sys/rotate/prune")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			RemovedTerms []uint32 `json:"removed_terms"`
+		} `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data.RemovedTerms, err
+}
+
+func (c *Sys) RotateConfig() (*RotateConfig, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/rotate/config")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *RotateConfig `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+func (c *Sys) ConfigureRotate(config *RotateConfig) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/rotate/config")
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+type RotateConfig struct {
+	Enabled       bool  `json:"enabled"`
+	MaxOperations int64 `json:"max_operations"`
+	Interval      int64 `json:"interval"`
+}
+
 type KeyStatus struct {
+	Term         int              `json:"term"`
+	InstallTime  time.Time        `json:"install_time"`
+	Encryptions  uint64           `json:"encryptions"`
+	Reencryption *ReencryptStatus `json:"reencryption,omitempty"`
+}
+
+type ReencryptStatus struct {
 	Term        int       `json:"term"`
-	InstallTime time.Time `json:"install_time"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Running     bool      `json:"running"`
+	Complete    bool      `json:"complete"`
+	Scanned     int       `json:"scanned"`
+	Reencrypted int       `json:"reencrypted"`
+	Failed      int       `json:"failed"`
+	Error       string    `json:"error"`
 }
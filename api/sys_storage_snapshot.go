@@ -0,0 +1,29 @@
+package api
+
+import "io"
+
+// StorageSnapshot writes a snapshot of the storage of Vault to w
+func (c *Sys) StorageSnapshot(w io.Writer) error {
+	r := c.c.NewRequest("GET", "/v1/sys/storage/snapshot")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// StorageSnapshotRestore replaces the storage of Vault with the snapshot read
+// from snapshot. Vault is sealed once the snapshot is restored.
+func (c *Sys) StorageSnapshotRestore(snapshot io.Reader) error {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/snapshot-restore")
+	r.Body = snapshot
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+package api
+
+// StorageVerify checks that every storage entry decrypts and, for the entries
+// of a known format, decodes. Nothing is modified.
+func (c *Sys) StorageVerify() (*StorageVerifyReport, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/storage/verify")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *StorageVerifyReport `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+type StorageVerifyReport struct {
+	Scanned int                    `json:"scanned"`
+	Skipped int                    `json:"skipped"`
+	BadKeys []*StorageVerifyBadKey `json:"bad_keys"`
+}
+
+type StorageVerifyBadKey struct {
+	Key   string `json:"key"`
+	Term  uint32 `json:"term"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+
+	oidc "github.com/coreos/go-oidc"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	configPath string = "config"
+	rolePrefix string = "role/"
+)
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type jwtAuthBackend struct {
+	*framework.Backend
+
+	l            sync.RWMutex
+	keySet       oidc.KeySet
+	cachedConfig *jwtConfig
+
+	providerCtx       context.Context
+	providerCtxCancel context.CancelFunc
+}
+
+func backend() *jwtAuthBackend {
+	b := new(jwtAuthBackend)
+	b.providerCtx, b.providerCtxCancel = context.WithCancel(context.Background())
+
+	b.Backend = &framework.Backend{
+		AuthRenew:   b.pathLoginRenew,
+		BackendType: logical.TypeCredential,
+		Invalidate:  b.invalidate,
+		Help:        backendHelp,
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+			SealWrapStorage: []string{
+				"config",
+			},
+		},
+		Paths: []*framework.Path{
+			pathLogin(b),
+			pathRoleList(b),
+			pathRole(b),
+			pathConfig(b),
+		},
+		Clean: b.cleanup,
+	}
+
+	return b
+}
+
+func (b *jwtAuthBackend) cleanup(_ context.Context) {
+	b.l.Lock()
+	if b.providerCtxCancel != nil {
+		b.providerCtxCancel()
+	}
+	b.l.Unlock()
+}
+
+func (b *jwtAuthBackend) invalidate(ctx context.Context, key string) {
+	switch key {
+	case configPath:
+		b.reset()
+	}
+}
+
+// reset clears the cached configuration and key set so they are rebuilt on
+// the next login
+func (b *jwtAuthBackend) reset() {
+	b.l.Lock()
+	b.keySet = nil
+	b.cachedConfig = nil
+	b.l.Unlock()
+}
+
+const (
+	backendHelp = `
+The JWT credential provider allows authentication using JWTs, including OIDC
+ID tokens. Tokens are verified against keys from an OIDC discovery URL, a JWKS
+URL or a set of statically configured public keys, and the claims they
+contain are checked against the constraints of the role used to log in.
+`
+)
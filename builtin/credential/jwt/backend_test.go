@@ -0,0 +1,307 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func getBackend(t *testing.T) (*jwtAuthBackend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.(*jwtAuthBackend), config.StorageView
+}
+
+// testKey returns a freshly generated signing key along with the PEM
+// encoding of its public half
+func testKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signJWT(t *testing.T, key *ecdsa.PrivateKey, keyID string, claims interface{}, privateClaims interface{}) string {
+	signerOpts := &jose.SignerOptions{}
+	if keyID != "" {
+		signerOpts.WithHeader("kid", keyID)
+	}
+
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, signerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := jwt.Signed(sig).Claims(claims).Claims(privateClaims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw
+}
+
+// testOIDCServer serves an OIDC discovery document and the JWKS it points to
+func testOIDCServer(t *testing.T, key *ecdsa.PrivateKey, keyID string) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/certs",
+		})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{
+					Key:       &key.PublicKey,
+					KeyID:     keyID,
+					Algorithm: string(jose.ES256),
+					Use:       "sig",
+				},
+			},
+		})
+	})
+	server = httptest.NewServer(mux)
+
+	return server
+}
+
+func TestConfig_Write(t *testing.T) {
+	b, storage := getBackend(t)
+	_, pubKey := testKey(t)
+
+	tests := map[string]struct {
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		"no key source": {
+			data:    map[string]interface{}{"bound_issuer": "foo"},
+			wantErr: true,
+		},
+		"multiple key sources": {
+			data: map[string]interface{}{
+				"jwks_url":               "https://example.com/certs",
+				"jwt_validation_pubkeys": []string{pubKey},
+			},
+			wantErr: true,
+		},
+		"invalid public key": {
+			data:    map[string]interface{}{"jwt_validation_pubkeys": []string{"not a key"}},
+			wantErr: true,
+		},
+		"invalid jwks CA": {
+			data: map[string]interface{}{
+				"jwks_url":    "https://example.com/certs",
+				"jwks_ca_pem": "not a cert",
+			},
+			wantErr: true,
+		},
+		"valid public keys": {
+			data: map[string]interface{}{
+				"jwt_validation_pubkeys": []string{pubKey},
+				"bound_issuer":           "https://example.com",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      configPath,
+				Storage:   storage,
+				Data:      tt.data,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsError() != tt.wantErr {
+				t.Fatalf("bad: wantErr %t, resp: %#v", tt.wantErr, resp)
+			}
+		})
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      configPath,
+		Storage:   storage,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	if resp.Data["bound_issuer"] != "https://example.com" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestConfig_OIDCDiscovery(t *testing.T) {
+	b, storage := getBackend(t)
+	key, _ := testKey(t)
+	server := testOIDCServer(t, key, "key-1")
+	defer server.Close()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      configPath,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"oidc_discovery_url": server.URL,
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	// A discovery URL that doesn't serve a matching issuer is rejected
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      configPath,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"oidc_discovery_url": server.URL + "/missing",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+}
+
+func TestRole_CreateRead(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/plugin-test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies":        "dev,prod",
+			"ttl":             "1h",
+			"max_ttl":         "2h",
+			"bound_audiences": "vault",
+			"bound_subject":   "testsub",
+			"bound_claims": map[string]interface{}{
+				"team": []interface{}{"a", "b"},
+			},
+			"claim_mappings": map[string]interface{}{
+				"email": "user_email",
+			},
+			"user_claim":   "user",
+			"groups_claim": "groups",
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "role/plugin-test",
+		Storage:   storage,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	if resp.Data["ttl"].(int64) != 3600 || resp.Data["max_ttl"].(int64) != 7200 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["user_claim"] != "user" || resp.Data["groups_claim"] != "groups" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A user claim is required
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/no-user-claim",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies": "dev",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+
+	// At least one bound constraint is required, on creation and on update
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/no-bounds",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"user_claim": "user",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/plugin-test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"bound_audiences": "",
+			"bound_subject":   "",
+			"bound_claims":    map[string]interface{}{},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+
+	// Two claims may not be mapped to the same metadata key
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/dup-mappings",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"user_claim": "user",
+			"claim_mappings": map[string]interface{}{
+				"email": "foo",
+				"name":  "foo",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+}
@@ -0,0 +1,70 @@
+package jwt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+type CLIHandler struct{}
+
+func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, error) {
+	mount, ok := m["mount"]
+	if !ok {
+		mount = "jwt"
+	}
+
+	token, ok := m["jwt"]
+	if !ok || token == "" {
+		return nil, fmt.Errorf("'jwt' must be specified")
+	}
+
+	role, ok := m["role"]
+	if !ok || role == "" {
+		return nil, fmt.Errorf("'role' must be specified")
+	}
+
+	path := fmt.Sprintf("auth/%s/login", mount)
+	secret, err := c.Logical().Write(path, map[string]interface{}{
+		"jwt":  strings.TrimSpace(token),
+		"role": role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("empty response from credential provider")
+	}
+
+	return secret, nil
+}
+
+func (h *CLIHandler) Help() string {
+	help := `
+Usage: vault login -method=jwt [CONFIG K=V...]
+
+  The JWT auth method allows users to authenticate using a JWT, such as an
+  OIDC ID token, signed by a trusted provider.
+
+  Authenticate using a JWT against the "dev" role:
+
+      $ vault login -method=jwt role=dev jwt=eyJhbGciOiJSUzI1NiIs...
+
+Configuration:
+
+  jwt=<string>
+      The signed JWT to authenticate with.
+
+  mount=<string>
+      Path where the JWT credential method is mounted. This is usually
+      provided via the -path flag in the "vault login" command, but it can be
+      specified here as well. If specified here, it takes precedence over the
+      value for -path. The default value is "jwt".
+
+  role=<string>
+      Name of the role to log in against.
+`
+
+	return strings.TrimSpace(help)
+}
@@ -0,0 +1,328 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+
+	oidc "github.com/coreos/go-oidc"
+	"github.com/hashicorp/errwrap"
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func pathConfig(b *jwtAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: `config`,
+		Fields: map[string]*framework.FieldSchema{
+			"oidc_discovery_url": {
+				Type:        framework.TypeString,
+				Description: `OIDC Discovery URL, without any .well-known component (base path). Cannot be used with "jwks_url" or "jwt_validation_pubkeys".`,
+			},
+			"oidc_discovery_ca_pem": {
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the OIDC Discovery URL. If not set, system certificates are used.",
+			},
+			"jwks_url": {
+				Type:        framework.TypeString,
+				Description: `JWKS URL to use to authenticate signatures. Cannot be used with "oidc_discovery_url" or "jwt_validation_pubkeys".`,
+			},
+			"jwks_ca_pem": {
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the JWKS URL. If not set, system certificates are used.",
+			},
+			"jwt_validation_pubkeys": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `A list of PEM-encoded public keys to use to authenticate signatures locally. Cannot be used with "jwks_url" or "oidc_discovery_url".`,
+			},
+			"bound_issuer": {
+				Type:        framework.TypeString,
+				Description: "The value against which to match the 'iss' claim in a JWT. Optional, and only used with \"jwks_url\" or \"jwt_validation_pubkeys\"; with OIDC discovery the issuer is the discovery URL.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    confHelpSyn,
+		HelpDescription: confHelpDesc,
+	}
+}
+
+func (b *jwtAuthBackend) config(ctx context.Context, s logical.Storage) (*jwtConfig, error) {
+	b.l.RLock()
+	if b.cachedConfig != nil {
+		defer b.l.RUnlock()
+		return b.cachedConfig, nil
+	}
+	b.l.RUnlock()
+
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	result := &jwtConfig{}
+	if err := entry.DecodeJSON(result); err != nil {
+		return nil, err
+	}
+
+	for _, v := range result.JWTValidationPubKeys {
+		key, err := parsePublicKeyPEM([]byte(v))
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing public key: {{err}}", err)
+		}
+		result.parsedJWTPubKeys = append(result.parsedJWTPubKeys, key)
+	}
+
+	b.l.Lock()
+	b.cachedConfig = result
+	b.l.Unlock()
+
+	return result, nil
+}
+
+func (b *jwtAuthBackend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"oidc_discovery_url":     config.OIDCDiscoveryURL,
+			"oidc_discovery_ca_pem":  config.OIDCDiscoveryCAPEM,
+			"jwks_url":               config.JWKSURL,
+			"jwks_ca_pem":            config.JWKSCAPEM,
+			"jwt_validation_pubkeys": config.JWTValidationPubKeys,
+			"bound_issuer":           config.BoundIssuer,
+		},
+	}, nil
+}
+
+func (b *jwtAuthBackend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &jwtConfig{
+		OIDCDiscoveryURL:     d.Get("oidc_discovery_url").(string),
+		OIDCDiscoveryCAPEM:   d.Get("oidc_discovery_ca_pem").(string),
+		JWKSURL:              d.Get("jwks_url").(string),
+		JWKSCAPEM:            d.Get("jwks_ca_pem").(string),
+		JWTValidationPubKeys: d.Get("jwt_validation_pubkeys").([]string),
+		BoundIssuer:          d.Get("bound_issuer").(string),
+	}
+
+	// Run checks on values
+	methodCount := 0
+	if config.OIDCDiscoveryURL != "" {
+		methodCount++
+	}
+	if config.JWKSURL != "" {
+		methodCount++
+	}
+	if len(config.JWTValidationPubKeys) != 0 {
+		methodCount++
+	}
+	if methodCount != 1 {
+		return logical.ErrorResponse("exactly one of 'oidc_discovery_url', 'jwks_url' or 'jwt_validation_pubkeys' must be set"), nil
+	}
+
+	switch {
+	case config.OIDCDiscoveryURL != "":
+		if _, err := b.createKeySet(config); err != nil {
+			return logical.ErrorResponse(errwrap.Wrapf("error checking discovery URL: {{err}}", err).Error()), nil
+		}
+
+	case config.JWKSURL != "":
+		if _, err := createCAContext(b.providerCtx, config.JWKSCAPEM); err != nil {
+			return logical.ErrorResponse(errwrap.Wrapf("error checking jwks_ca_pem: {{err}}", err).Error()), nil
+		}
+
+	default:
+		for _, v := range config.JWTValidationPubKeys {
+			if _, err := parsePublicKeyPEM([]byte(v)); err != nil {
+				return logical.ErrorResponse(errwrap.Wrapf("error parsing public key: {{err}}", err).Error()), nil
+			}
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	b.reset()
+
+	return nil, nil
+}
+
+// keySetAndIssuer returns the key set used to verify signatures along with
+// the issuer tokens are expected to have, creating the key set if needed
+func (b *jwtAuthBackend) keySetAndIssuer(config *jwtConfig) (oidc.KeySet, string, error) {
+	issuer := config.BoundIssuer
+	if config.OIDCDiscoveryURL != "" {
+		issuer = config.OIDCDiscoveryURL
+	}
+
+	b.l.RLock()
+	keySet := b.keySet
+	b.l.RUnlock()
+	if keySet != nil {
+		return keySet, issuer, nil
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.keySet != nil {
+		return b.keySet, issuer, nil
+	}
+
+	keySet, err := b.createKeySet(config)
+	if err != nil {
+		return nil, "", err
+	}
+	b.keySet = keySet
+
+	return keySet, issuer, nil
+}
+
+func (b *jwtAuthBackend) createKeySet(config *jwtConfig) (oidc.KeySet, error) {
+	switch {
+	case config.OIDCDiscoveryURL != "":
+		ctx, err := createCAContext(b.providerCtx, config.OIDCDiscoveryCAPEM)
+		if err != nil {
+			return nil, err
+		}
+		provider, err := oidc.NewProvider(ctx, config.OIDCDiscoveryURL)
+		if err != nil {
+			return nil, errwrap.Wrapf("error creating provider with given values: {{err}}", err)
+		}
+
+		var discovery struct {
+			JWKSURL string `json:"jwks_uri"`
+		}
+		if err := provider.Claims(&discovery); err != nil {
+			return nil, errwrap.Wrapf("error reading discovery document: {{err}}", err)
+		}
+		if discovery.JWKSURL == "" {
+			return nil, errors.New("discovery document does not contain a jwks_uri")
+		}
+
+		return oidc.NewRemoteKeySet(ctx, discovery.JWKSURL), nil
+
+	case config.JWKSURL != "":
+		ctx, err := createCAContext(b.providerCtx, config.JWKSCAPEM)
+		if err != nil {
+			return nil, err
+		}
+		return oidc.NewRemoteKeySet(ctx, config.JWKSURL), nil
+
+	case len(config.parsedJWTPubKeys) != 0:
+		return &staticKeySet{keys: config.parsedJWTPubKeys}, nil
+	}
+
+	return nil, errors.New("no key source is configured")
+}
+
+// createCAContext returns a context carrying an HTTP client that trusts the
+// given PEM-encoded CAs, or the system CAs if none are given
+func createCAContext(ctx context.Context, caPEM string) (context.Context, error) {
+	if caPEM == "" {
+		return oidc.ClientContext(ctx, cleanhttp.DefaultClient()), nil
+	}
+
+	certPool := x509.NewCertPool()
+	if ok := certPool.AppendCertsFromPEM([]byte(caPEM)); !ok {
+		return nil, errors.New("could not parse CA PEM value successfully")
+	}
+
+	tr := cleanhttp.DefaultPooledTransport()
+	tr.TLSClientConfig = &tls.Config{
+		RootCAs: certPool,
+	}
+
+	return oidc.ClientContext(ctx, &http.Client{
+		Transport: tr,
+	}), nil
+}
+
+// staticKeySet verifies signatures against a set of configured public keys
+type staticKeySet struct {
+	keys []crypto.PublicKey
+}
+
+func (s *staticKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	jws, err := jose.ParseSigned(jwt)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing token: {{err}}", err)
+	}
+
+	for _, key := range s.keys {
+		if payload, err := jws.Verify(key); err == nil {
+			return payload, nil
+		}
+	}
+
+	return nil, errors.New("no known key successfully validated the token signature")
+}
+
+// parsePublicKeyPEM parses a PEM-encoded public key or certificate
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("data does not contain any valid RSA or ECDSA public keys")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("data does not contain any valid RSA or ECDSA public keys")
+	}
+
+	return cert.PublicKey, nil
+}
+
+type jwtConfig struct {
+	OIDCDiscoveryURL     string   `json:"oidc_discovery_url"`
+	OIDCDiscoveryCAPEM   string   `json:"oidc_discovery_ca_pem"`
+	JWKSURL              string   `json:"jwks_url"`
+	JWKSCAPEM            string   `json:"jwks_ca_pem"`
+	JWTValidationPubKeys []string `json:"jwt_validation_pubkeys"`
+	BoundIssuer          string   `json:"bound_issuer"`
+
+	parsedJWTPubKeys []crypto.PublicKey
+}
+
+const (
+	confHelpSyn = `
+Configures the JWT authentication backend.
+`
+	confHelpDesc = `
+The JWT authentication backend validates JWTs (or OIDC ID tokens) using the
+configured credentials. If using OIDC Discovery, the URL must be provided, along
+with (optionally) the CA cert to use for the connection. If using a JWKS URL,
+the URL must be provided, along with (optionally) the CA cert. If not using
+either, a set of public keys must be provided.
+`
+)
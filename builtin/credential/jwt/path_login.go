@@ -0,0 +1,301 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func pathLogin(b *jwtAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: `login$`,
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "The role to log in against.",
+			},
+			"jwt": {
+				Type:        framework.TypeString,
+				Description: "The signed JWT to validate.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathLogin,
+			logical.AliasLookaheadOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+func (b *jwtAuthBackend) pathLogin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	token := d.Get("jwt").(string)
+	if len(token) == 0 {
+		return logical.ErrorResponse("missing token"), nil
+	}
+
+	roleName := d.Get("role").(string)
+	if len(roleName) == 0 {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q could not be found", roleName)), nil
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("could not load configuration"), nil
+	}
+
+	allClaims, err := b.verifyToken(ctx, config, role, token)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	alias, groupAliases, err := b.createIdentity(allClaims, role)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: alias,
+			},
+		}, nil
+	}
+
+	metadata := map[string]string{
+		"role": roleName,
+	}
+	for claim, key := range role.ClaimMappings {
+		if value, ok := claimString(allClaims[claim]); ok {
+			metadata[key] = value
+		}
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Policies:     role.Policies,
+			DisplayName:  alias.Name,
+			Period:       role.Period,
+			NumUses:      role.NumUses,
+			Alias:        alias,
+			GroupAliases: groupAliases,
+			InternalData: map[string]interface{}{
+				"role": roleName,
+			},
+			Metadata: metadata,
+			LeaseOptions: logical.LeaseOptions{
+				Renewable: true,
+				TTL:       role.TTL,
+				MaxTTL:    role.MaxTTL,
+			},
+		},
+	}, nil
+}
+
+func (b *jwtAuthBackend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := req.Auth.InternalData["role"].(string)
+	if roleName == "" {
+		return nil, errors.New("failed to fetch role_name during renewal")
+	}
+
+	// Ensure that the Role still exists.
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to validate role %s during renewal: {{err}}", roleName), err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %s does not exist during renewal", roleName)
+	}
+
+	// If a policy change is detected on the role, the token can't be renewed
+	if !policyutil.EquivalentPolicies(role.Policies, req.Auth.Policies) {
+		return nil, errors.New("policies on role have changed, cannot renew")
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.TTL = role.TTL
+	resp.Auth.MaxTTL = role.MaxTTL
+	resp.Auth.Period = role.Period
+	return resp, nil
+}
+
+// verifyToken checks the token's signature and its claims against the
+// configuration and the role, returning all of the token's claims
+func (b *jwtAuthBackend) verifyToken(ctx context.Context, config *jwtConfig, role *jwtRole, token string) (map[string]interface{}, error) {
+	keySet, issuer, err := b.keySetAndIssuer(config)
+	if err != nil {
+		return nil, errwrap.Wrapf("error configuring token validator: {{err}}", err)
+	}
+
+	payload, err := keySet.VerifySignature(ctx, token)
+	if err != nil {
+		return nil, errwrap.Wrapf("error validating signature: {{err}}", err)
+	}
+
+	claims := jwt.Claims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errwrap.Wrapf("error parsing claims: {{err}}", err)
+	}
+
+	allClaims := make(map[string]interface{})
+	if err := json.Unmarshal(payload, &allClaims); err != nil {
+		return nil, errwrap.Wrapf("error parsing claims: {{err}}", err)
+	}
+
+	if claims.Expiry == 0 {
+		return nil, errors.New("token is missing the exp claim")
+	}
+
+	expected := jwt.Expected{
+		Issuer:  issuer,
+		Subject: role.BoundSubject,
+		Time:    time.Now(),
+	}
+	if err := claims.Validate(expected); err != nil {
+		return nil, errwrap.Wrapf("error validating claims: {{err}}", err)
+	}
+
+	switch {
+	case len(role.BoundAudiences) > 0:
+		found := false
+		for _, aud := range role.BoundAudiences {
+			if claims.Audience.Contains(aud) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("aud claim does not match any bound audience")
+		}
+
+	case len(claims.Audience) > 0:
+		return nil, errors.New("audience claim found in JWT but no audiences bound to the role")
+	}
+
+	for claim, expectedRaw := range role.BoundClaims {
+		expectedValues, err := boundClaimValues(expectedRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("invalid bound claim %q: {{err}}", claim), err)
+		}
+
+		actualValues, ok := claimStrings(allClaims[claim])
+		if !ok {
+			return nil, fmt.Errorf("claim %q is missing or is not a string or list of strings", claim)
+		}
+
+		found := false
+		for _, actual := range actualValues {
+			if strutil.StrListContains(expectedValues, actual) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("claim %q does not match any associated bound claim values", claim)
+		}
+	}
+
+	return allClaims, nil
+}
+
+// createIdentity builds the entity alias and group aliases from the token's
+// claims
+func (b *jwtAuthBackend) createIdentity(allClaims map[string]interface{}, role *jwtRole) (*logical.Alias, []*logical.Alias, error) {
+	userName, ok := claimString(allClaims[role.UserClaim])
+	if !ok || userName == "" {
+		return nil, nil, fmt.Errorf("claim %q not found in token", role.UserClaim)
+	}
+
+	alias := &logical.Alias{
+		Name: userName,
+	}
+
+	var groupAliases []*logical.Alias
+	if role.GroupsClaim == "" {
+		return alias, groupAliases, nil
+	}
+
+	groupsClaimRaw, ok := allClaims[role.GroupsClaim]
+	if !ok {
+		return nil, nil, fmt.Errorf("%q claim not found in token", role.GroupsClaim)
+	}
+	groups, ok := claimStrings(groupsClaimRaw)
+	if !ok {
+		return nil, nil, fmt.Errorf("%q claim could not be converted to string list", role.GroupsClaim)
+	}
+	for _, group := range groups {
+		groupAliases = append(groupAliases, &logical.Alias{
+			Name: group,
+		})
+	}
+
+	return alias, groupAliases, nil
+}
+
+// claimString returns a scalar claim value as a string
+func claimString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return fmt.Sprintf("%t", v), true
+	case float64:
+		return fmt.Sprintf("%v", v), true
+	}
+
+	return "", false
+}
+
+// claimStrings returns a claim that is a string or a list of strings as a
+// list of strings
+func claimStrings(v interface{}) ([]string, bool) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	}
+
+	return nil, false
+}
+
+const (
+	pathLoginHelpSyn = `
+	Authenticates to Vault using a JWT (or OIDC) token.
+	`
+	pathLoginHelpDesc = `
+Authenticates JWTs. The token's signature is verified using the configured
+keys, its expiration, issuer and audience are checked, and its claims must
+satisfy the bindings of the given role.
+`
+)
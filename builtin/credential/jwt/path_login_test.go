@@ -0,0 +1,324 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func setupLoginBackend(t *testing.T, pubKey string, roleData map[string]interface{}) (*jwtAuthBackend, logical.Storage) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      configPath,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"jwt_validation_pubkeys": []string{pubKey},
+			"bound_issuer":           "https://team-vault.auth0.com/",
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	data := map[string]interface{}{
+		"policies":        "test",
+		"bound_audiences": "https://vault.plugin.auth.jwt.test",
+		"bound_subject":   "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		"user_claim":      "https://vault/user",
+		"groups_claim":    "https://vault/groups",
+		"claim_mappings": map[string]interface{}{
+			"first_name":   "name",
+			"/org/primary": "primary_org",
+		},
+		"ttl":     "1h",
+		"max_ttl": "2h",
+	}
+	for k, v := range roleData {
+		data[k] = v
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/plugin-test",
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	return b, storage
+}
+
+func testClaims() (jwt.Claims, map[string]interface{}) {
+	cl := jwt.Claims{
+		Subject:   "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		Issuer:    "https://team-vault.auth0.com/",
+		NotBefore: jwt.NewNumericDate(time.Now().Add(-5 * time.Second)),
+		Expiry:    jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+		Audience:  jwt.Audience{"https://vault.plugin.auth.jwt.test"},
+	}
+
+	privateCl := map[string]interface{}{
+		"https://vault/user":   "jeff",
+		"https://vault/groups": []string{"foo", "bar"},
+		"first_name":           "jeff2",
+		"/org/primary":         "engineering",
+	}
+
+	return cl, privateCl
+}
+
+func login(t *testing.T, b *jwtAuthBackend, storage logical.Storage, token string) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"role": "plugin-test",
+			"jwt":  token,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil {
+		t.Fatal("nil response")
+	}
+
+	return resp
+}
+
+func TestLogin(t *testing.T) {
+	key, pubKey := testKey(t)
+	b, storage := setupLoginBackend(t, pubKey, nil)
+
+	cl, privateCl := testClaims()
+	resp := login(t, b, storage, signJWT(t, key, "", cl, privateCl))
+	if resp.IsError() {
+		t.Fatalf("got error: %v", resp.Error())
+	}
+
+	auth := resp.Auth
+	switch {
+	case len(auth.Policies) != 1 || auth.Policies[0] != "test":
+		t.Fatalf("bad: %#v", auth.Policies)
+	case auth.Alias.Name != "jeff":
+		t.Fatalf("bad: %#v", auth.Alias)
+	case len(auth.GroupAliases) != 2 || auth.GroupAliases[0].Name != "foo" || auth.GroupAliases[1].Name != "bar":
+		t.Fatalf("bad: %#v", auth.GroupAliases)
+	case auth.Metadata["name"] != "jeff2" || auth.Metadata["primary_org"] != "engineering" || auth.Metadata["role"] != "plugin-test":
+		t.Fatalf("bad: %#v", auth.Metadata)
+	case auth.TTL != time.Hour || auth.MaxTTL != 2*time.Hour:
+		t.Fatalf("bad: ttl %s, max_ttl %s", auth.TTL, auth.MaxTTL)
+	}
+}
+
+func TestLogin_Failures(t *testing.T) {
+	key, pubKey := testKey(t)
+	otherKey, _ := testKey(t)
+
+	tests := map[string]struct {
+		signer   *ecdsa.PrivateKey
+		roleData map[string]interface{}
+		modify   func(*jwt.Claims, map[string]interface{})
+	}{
+		"wrong key": {
+			signer: otherKey,
+		},
+		"expired": {
+			signer: key,
+			modify: func(cl *jwt.Claims, _ map[string]interface{}) {
+				cl.Expiry = jwt.NewNumericDate(time.Now().Add(-5 * time.Minute))
+			},
+		},
+		"missing expiry": {
+			signer: key,
+			modify: func(cl *jwt.Claims, _ map[string]interface{}) {
+				cl.Expiry = 0
+			},
+		},
+		"not yet valid": {
+			signer: key,
+			modify: func(cl *jwt.Claims, _ map[string]interface{}) {
+				cl.NotBefore = jwt.NewNumericDate(time.Now().Add(5 * time.Minute))
+			},
+		},
+		"wrong issuer": {
+			signer: key,
+			modify: func(cl *jwt.Claims, _ map[string]interface{}) {
+				cl.Issuer = "https://example.com/"
+			},
+		},
+		"wrong subject": {
+			signer: key,
+			modify: func(cl *jwt.Claims, _ map[string]interface{}) {
+				cl.Subject = "someone-else"
+			},
+		},
+		"wrong audience": {
+			signer: key,
+			modify: func(cl *jwt.Claims, _ map[string]interface{}) {
+				cl.Audience = jwt.Audience{"https://example.com"}
+			},
+		},
+		"unbound audience": {
+			signer: key,
+			roleData: map[string]interface{}{
+				"bound_audiences": "",
+			},
+		},
+		"missing user claim": {
+			signer: key,
+			modify: func(_ *jwt.Claims, privateCl map[string]interface{}) {
+				delete(privateCl, "https://vault/user")
+			},
+		},
+		"invalid groups claim": {
+			signer: key,
+			modify: func(_ *jwt.Claims, privateCl map[string]interface{}) {
+				privateCl["https://vault/groups"] = 42
+			},
+		},
+		"bound claim mismatch": {
+			signer: key,
+			roleData: map[string]interface{}{
+				"bound_claims": map[string]interface{}{
+					"first_name": []interface{}{"bob", "alice"},
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			b, storage := setupLoginBackend(t, pubKey, tt.roleData)
+
+			cl, privateCl := testClaims()
+			if tt.modify != nil {
+				tt.modify(&cl, privateCl)
+			}
+
+			resp := login(t, b, storage, signJWT(t, tt.signer, "", cl, privateCl))
+			if !resp.IsError() {
+				t.Fatalf("expected error, got: %#v", resp)
+			}
+		})
+	}
+}
+
+func TestLogin_BoundClaims(t *testing.T) {
+	key, pubKey := testKey(t)
+	b, storage := setupLoginBackend(t, pubKey, map[string]interface{}{
+		"bound_claims": map[string]interface{}{
+			"first_name":           []interface{}{"bob", "jeff2"},
+			"https://vault/groups": "bar",
+		},
+	})
+
+	cl, privateCl := testClaims()
+	resp := login(t, b, storage, signJWT(t, key, "", cl, privateCl))
+	if resp.IsError() {
+		t.Fatalf("got error: %v", resp.Error())
+	}
+}
+
+func TestLogin_OIDCDiscovery(t *testing.T) {
+	key, _ := testKey(t)
+	server := testOIDCServer(t, key, "key-1")
+	defer server.Close()
+
+	b, storage := getBackend(t)
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      configPath,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"oidc_discovery_url": server.URL,
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/plugin-test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"bound_audiences": "https://vault.plugin.auth.jwt.test",
+			"user_claim":      "https://vault/user",
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	cl, privateCl := testClaims()
+	cl.Issuer = server.URL
+	resp = login(t, b, storage, signJWT(t, key, "key-1", cl, privateCl))
+	if resp.IsError() {
+		t.Fatalf("got error: %v", resp.Error())
+	}
+	if resp.Auth.Alias.Name != "jeff" {
+		t.Fatalf("bad: %#v", resp.Auth.Alias)
+	}
+
+	// The issuer must match the discovery URL
+	cl.Issuer = "https://team-vault.auth0.com/"
+	resp = login(t, b, storage, signJWT(t, key, "key-1", cl, privateCl))
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+}
+
+func TestLogin_JWKS(t *testing.T) {
+	key, _ := testKey(t)
+	server := testOIDCServer(t, key, "key-1")
+	defer server.Close()
+
+	b, storage := getBackend(t)
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      configPath,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"jwks_url":     server.URL + "/certs",
+			"bound_issuer": "https://team-vault.auth0.com/",
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/plugin-test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"bound_audiences": "https://vault.plugin.auth.jwt.test",
+			"user_claim":      "https://vault/user",
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	cl, privateCl := testClaims()
+	resp = login(t, b, storage, signJWT(t, key, "key-1", cl, privateCl))
+	if resp.IsError() {
+		t.Fatalf("got error: %v", resp.Error())
+	}
+
+	otherKey, _ := testKey(t)
+	resp = login(t, b, storage, signJWT(t, otherKey, "key-1", cl, privateCl))
+	if !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+}
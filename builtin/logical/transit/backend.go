@@ -3,6 +3,7 @@ package transit
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
@@ -24,15 +25,20 @@ func Backend(conf *logical.BackendConfig) *backend {
 			SealWrapStorage: []string{
 				"archive/",
 				"policy/",
+				"import/",
 			},
 		},
 
 		Paths: []*framework.Path{
-			// Rotate/Config needs to come before Keys
+			// Rotate/Config/Import/Trim need to come before Keys
 			// as the handler is greedy
 			b.pathConfig(),
 			b.pathRotate(),
+			b.pathTrim(),
+			b.pathKeyVersions(),
 			b.pathRewrap(),
+			b.pathImport(),
+			b.pathImportVersion(),
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
@@ -46,6 +52,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathVerify(),
 			b.pathBackup(),
 			b.pathRestore(),
+			b.pathWrappingKey(),
 		},
 
 		Secrets:     []*framework.Secret{},
@@ -61,6 +68,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	// wrappingKey is the cached key used to wrap imported key material
+	wrappingKey     *keysutil.Policy
+	wrappingKeyLock sync.Mutex
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
@@ -71,5 +82,9 @@ func (b *backend) invalidate(_ context.Context, key string) {
 	case strings.HasPrefix(key, "policy/"):
 		name := strings.TrimPrefix(key, "policy/")
 		b.lm.InvalidatePolicy(name)
+	case key == wrappingKeyStoragePath:
+		b.wrappingKeyLock.Lock()
+		b.wrappingKey = nil
+		b.wrappingKeyLock.Unlock()
 	}
 }
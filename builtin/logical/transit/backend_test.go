@@ -42,6 +42,7 @@ func createBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
 
 func TestTransit_RSA(t *testing.T) {
 	testTransit_RSA(t, "rsa-2048")
+	testTransit_RSA(t, "rsa-3072")
 	testTransit_RSA(t, "rsa-4096")
 }
 
@@ -96,6 +96,10 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 				return logical.ErrorResponse(
 					fmt.Sprintf("cannot set min decryption version of %d, latest key version is %d", minDecryptionVersion, p.LatestVersion)), nil
 			}
+			if minDecryptionVersion < p.MinAvailableVersion {
+				return logical.ErrorResponse(
+					fmt.Sprintf("cannot set min decryption version of %d, key versions below %d have been trimmed", minDecryptionVersion, p.MinAvailableVersion)), nil
+			}
 			p.MinDecryptionVersion = minDecryptionVersion
 			persistNeeded = true
 		}
@@ -114,6 +118,10 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 				return logical.ErrorResponse(
 					fmt.Sprintf("cannot set min encryption version of %d, latest key version is %d", minEncryptionVersion, p.LatestVersion)), nil
 			}
+			if minEncryptionVersion > 0 && minEncryptionVersion < p.MinAvailableVersion {
+				return logical.ErrorResponse(
+					fmt.Sprintf("cannot set min encryption version of %d, key versions below %d have been trimmed", minEncryptionVersion, p.MinAvailableVersion)), nil
+			}
 			p.MinEncryptionVersion = minEncryptionVersion
 			persistNeeded = true
 		}
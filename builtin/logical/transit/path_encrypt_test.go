@@ -2,6 +2,7 @@ package transit
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -546,3 +547,165 @@ func TestTransit_BatchEncryptionCase12(t *testing.T) {
 		t.Fatalf("expected an error")
 	}
 }
+
+// Test that associated data is authenticated by encrypt, decrypt and rewrap,
+// in both single and batch mode
+func TestTransit_AssociatedData(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+
+	plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA==" // "the quick brown fox"
+	aad := "cmVjb3JkLTE="                       // "record-1"
+	otherAAD := "cmVjb3JkLTI="                  // "record-2"
+
+	for _, keyType := range []string{"aes128-gcm96", "aes256-gcm96", "chacha20-poly1305"} {
+		resp, err := request("keys/"+keyType, map[string]interface{}{
+			"type": keyType,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+
+		resp, err = request("encrypt/"+keyType, map[string]interface{}{
+			"plaintext":       plaintext,
+			"associated_data": aad,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		ciphertext := resp.Data["ciphertext"].(string)
+
+		resp, err = request("decrypt/"+keyType, map[string]interface{}{
+			"ciphertext":      ciphertext,
+			"associated_data": aad,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("bad: plaintext. Expected: %q, Actual: %q", plaintext, resp.Data["plaintext"])
+		}
+
+		// Decryption fails without the associated data or with another one
+		for _, data := range []string{"", otherAAD} {
+			resp, err = request("decrypt/"+keyType, map[string]interface{}{
+				"ciphertext":      ciphertext,
+				"associated_data": data,
+			})
+			if err == nil || resp == nil || !resp.IsError() {
+				t.Fatalf("%s: expected an error decrypting with associated data %q, got resp:%#v", keyType, data, resp)
+			}
+		}
+
+		// Rewrapping keeps the associated data
+		resp, err = request("keys/"+keyType+"/rotate", nil)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		resp, err = request("rewrap/"+keyType, map[string]interface{}{
+			"ciphertext": ciphertext,
+		})
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected an error rewrapping without associated data, got resp:%#v", keyType, resp)
+		}
+		resp, err = request("rewrap/"+keyType, map[string]interface{}{
+			"ciphertext":      ciphertext,
+			"associated_data": aad,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		rewrapped := resp.Data["ciphertext"].(string)
+		if !strings.HasPrefix(rewrapped, "vault:v2:") {
+			t.Fatalf("bad: rewrapped ciphertext: %q", rewrapped)
+		}
+
+		// Each batch item carries its own associated data
+		resp, err = request("decrypt/"+keyType, map[string]interface{}{
+			"batch_input": []interface{}{
+				map[string]interface{}{"ciphertext": rewrapped, "associated_data": aad},
+				map[string]interface{}{"ciphertext": rewrapped, "associated_data": otherAAD},
+				map[string]interface{}{"ciphertext": rewrapped, "associated_data": "not base64"},
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		batchResponseItems := resp.Data["batch_results"].([]BatchResponseItem)
+		if batchResponseItems[0].Error != "" || batchResponseItems[0].Plaintext != plaintext {
+			t.Fatalf("bad: batch result: %#v", batchResponseItems[0])
+		}
+		for _, item := range batchResponseItems[1:] {
+			if item.Error == "" || item.Plaintext != "" {
+				t.Fatalf("expected an error, got batch result: %#v", item)
+			}
+		}
+	}
+
+	resp, err := request("encrypt/aes256-gcm96", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"plaintext": plaintext, "associated_data": aad},
+			map[string]interface{}{"plaintext": plaintext, "associated_data": otherAAD},
+			map[string]interface{}{"plaintext": plaintext},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	encrypted := resp.Data["batch_results"].([]BatchResponseItem)
+
+	resp, err = request("decrypt/aes256-gcm96", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"ciphertext": encrypted[0].Ciphertext, "associated_data": aad},
+			map[string]interface{}{"ciphertext": encrypted[1].Ciphertext, "associated_data": otherAAD},
+			map[string]interface{}{"ciphertext": encrypted[2].Ciphertext},
+			map[string]interface{}{"ciphertext": encrypted[0].Ciphertext, "associated_data": otherAAD},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	decrypted := resp.Data["batch_results"].([]BatchResponseItem)
+	for i, item := range decrypted[:3] {
+		if item.Error != "" || item.Plaintext != plaintext {
+			t.Fatalf("bad: batch result %d: %#v", i, item)
+		}
+	}
+	if decrypted[3].Error == "" {
+		t.Fatalf("expected an error, got batch result: %#v", decrypted[3])
+	}
+
+	// Associated data is only supported by AEAD keys without convergent
+	// encryption
+	resp, err = request("keys/rsa", map[string]interface{}{
+		"type": "rsa-2048",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	resp, err = request("keys/convergent", map[string]interface{}{
+		"derived":               true,
+		"convergent_encryption": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	for _, name := range []string{"rsa", "convergent"} {
+		resp, err = request("encrypt/"+name, map[string]interface{}{
+			"plaintext":       plaintext,
+			"context":         "YWJjZA==",
+			"associated_data": aad,
+		})
+		if err == nil || resp == nil || !strings.Contains(resp.Error().Error(), "associated data") {
+			t.Fatalf("%s: expected an error, got resp:%#v", name, resp)
+		}
+	}
+}
@@ -3,18 +3,29 @@ package transit
 import (
 	"context"
 	"crypto/hmac"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/base64"
 	"fmt"
-	"hash"
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
 )
 
+// HMACBatchResponseItem represents a response item for batch HMAC generation
+type HMACBatchResponseItem struct {
+	// HMAC for the input present in the corresponding batch request item
+	HMAC string `json:"hmac,omitempty" structs:"hmac" mapstructure:"hmac"`
+
+	// Error, if set represents a failure encountered while generating the
+	// HMAC of a corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
 func (b *backend) pathHMAC() *framework.Path {
 	return &framework.Path{
 		Pattern: "hmac/" + framework.GenericNameRegex("name") + framework.OptionalParamRegex("urlalgorithm"),
@@ -67,15 +78,28 @@ to the min_encryption_version configured on the key.`,
 func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	ver := d.Get("key_version").(int)
-	inputB64 := d.Get("input").(string)
 	algorithm := d.Get("urlalgorithm").(string)
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	batchInputRaw := d.Raw["batch_input"]
+	var batchInputItems []SignBatchRequestItem
+	if batchInputRaw != nil {
+		err := mapstructure.Decode(batchInputRaw, &batchInputItems)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse batch input: {{err}}", err)
+		}
+
+		if len(batchInputItems) == 0 {
+			return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+		}
+	} else {
+		batchInputItems = []SignBatchRequestItem{
+			{
+				Input: d.Get("input").(string),
+			},
+		}
 	}
 
 	// Get the policy
@@ -109,120 +133,100 @@ func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *fr
 		return nil, fmt.Errorf("HMAC key value could not be computed")
 	}
 
-	var hf hash.Hash
-	switch algorithm {
-	case "sha2-224":
-		hf = hmac.New(sha256.New224, key)
-	case "sha2-256":
-		hf = hmac.New(sha256.New, key)
-	case "sha2-384":
-		hf = hmac.New(sha512.New384, key)
-	case "sha2-512":
-		hf = hmac.New(sha512.New, key)
-	default:
+	hf := hashFunc(algorithm)
+	if hf == nil {
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
-	hf.Write(input)
-	retBytes := hf.Sum(nil)
 
-	retStr := base64.StdEncoding.EncodeToString(retBytes)
-	retStr = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), retStr)
+	// Process batch request items. If the HMAC of any request item cannot
+	// be generated, respectively mark the error in the response collection
+	// and continue to process other items.
+	batchResponseItems := make([]HMACBatchResponseItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		input, err := base64.StdEncoding.DecodeString(item.Input)
+		if err != nil {
+			batchResponseItems[i].Error = fmt.Sprintf("unable to decode input as base64: %s", err)
+			continue
+		}
 
-	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"hmac": retStr,
-		},
+		h := hmac.New(hf, key)
+		h.Write(input)
+		retStr := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		batchResponseItems[i].HMAC = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), retStr)
 	}
-	return resp, nil
-}
 
-func (b *backend) pathHMACVerify(ctx context.Context, req *logical.Request, d *framework.FieldData, verificationHMAC string) (*logical.Response, error) {
-	name := d.Get("name").(string)
-	inputB64 := d.Get("input").(string)
-	algorithm := d.Get("urlalgorithm").(string)
-	if algorithm == "" {
-		algorithm = d.Get("algorithm").(string)
+	// Generate the response
+	resp := &logical.Response{}
+	if batchInputRaw != nil {
+		resp.Data = map[string]interface{}{
+			"batch_results": batchResponseItems,
+		}
+	} else {
+		if batchResponseItems[0].Error != "" {
+			return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+		}
+		resp.Data = map[string]interface{}{
+			"hmac": batchResponseItems[0].HMAC,
+		}
 	}
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
-	}
+	return resp, nil
+}
 
+// verifyHMAC verifies the HMAC, including the vault header and key version,
+// of the given input. Problems with the HMAC itself are returned as user
+// errors.
+func verifyHMAC(p *keysutil.Policy, algorithm string, input []byte, verificationHMAC string) (bool, error) {
 	// Verify the prefix
 	if !strings.HasPrefix(verificationHMAC, "vault:v") {
-		return logical.ErrorResponse("invalid HMAC to verify: no prefix"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC to verify: no prefix"}
 	}
 
 	splitVerificationHMAC := strings.SplitN(strings.TrimPrefix(verificationHMAC, "vault:v"), ":", 2)
 	if len(splitVerificationHMAC) != 2 {
-		return logical.ErrorResponse("invalid HMAC: wrong number of fields"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC: wrong number of fields"}
 	}
 
 	ver, err := strconv.Atoi(splitVerificationHMAC[0])
 	if err != nil {
-		return logical.ErrorResponse("invalid HMAC: version number could not be decoded"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC: version number could not be decoded"}
 	}
 
 	verBytes, err := base64.StdEncoding.DecodeString(splitVerificationHMAC[1])
 	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode verification HMAC as base64: %s", err)), logical.ErrInvalidRequest
-	}
-
-	// Get the policy
-	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, name)
-	if lock != nil {
-		defer lock.RUnlock()
-	}
-	if err != nil {
-		return nil, err
-	}
-	if p == nil {
-		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: fmt.Sprintf("unable to decode verification HMAC as base64: %s", err)}
 	}
 
 	if ver > p.LatestVersion {
-		return logical.ErrorResponse("invalid HMAC: version is too new"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC: version is too new"}
 	}
 
 	if p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion {
-		return logical.ErrorResponse("cannot verify HMAC: version is too old (disallowed by policy)"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "cannot verify HMAC: version is too old (disallowed by policy)"}
 	}
 
 	key, err := p.HMACKey(ver)
 	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: err.Error()}
 	}
 	if key == nil {
-		return nil, fmt.Errorf("HMAC key value could not be computed")
+		return false, fmt.Errorf("HMAC key value could not be computed")
 	}
 
-	var hf hash.Hash
-	switch algorithm {
-	case "sha2-224":
-		hf = hmac.New(sha256.New224, key)
-	case "sha2-256":
-		hf = hmac.New(sha256.New, key)
-	case "sha2-384":
-		hf = hmac.New(sha512.New384, key)
-	case "sha2-512":
-		hf = hmac.New(sha512.New, key)
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
+	hf := hashFunc(algorithm)
+	if hf == nil {
+		return false, errutil.UserError{Err: fmt.Sprintf("unsupported algorithm %s", algorithm)}
 	}
-	hf.Write(input)
-	retBytes := hf.Sum(nil)
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"valid": hmac.Equal(retBytes, verBytes),
-		},
-	}, nil
+	h := hmac.New(hf, key)
+	h.Write(input)
+	return hmac.Equal(h.Sum(nil), verBytes), nil
 }
 
-const pathHMACHelpSyn = `Generate an HMAC for input data using the named key`
+const pathHMACHelpSyn = `Generate an HMAC for input data or a batch of input
+data using the named key`
 
 const pathHMACHelpDesc = `
-Generates an HMAC sum of the given algorithm and key against the given input data.
+Generates an HMAC sum of the given algorithm and key against the given input
+data, or against each item of a batch of input data.
 `
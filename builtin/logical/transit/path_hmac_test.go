@@ -184,3 +184,78 @@ func TestTransit_HMAC(t *testing.T) {
 		t.Fatalf("expected invalid request error, got %v", err)
 	}
 }
+
+func TestTransit_HMAC_Batch(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	// First create a key
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Now, change the key value to something we control
+	p, lock, err := b.lm.GetPolicyShared(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// We don't care as we're the only one using this
+	lock.RUnlock()
+	keyEntry := p.Keys["1"]
+	keyEntry.HMACKey = []byte("01234567890123456789012345678901")
+	p.Keys["1"] = keyEntry
+	if err = p.Persist(context.Background(), storage); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Path = "hmac/foo/sha2-224"
+	req.Data = map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": "dGhlIHF1aWNrIGJyb3duIGZveA=="},
+			map[string]interface{}{"input": "foobar"},
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	batchResponseItems := resp.Data["batch_results"].([]HMACBatchResponseItem)
+	if len(batchResponseItems) != 2 {
+		t.Fatalf("bad: batch results: %#v", batchResponseItems)
+	}
+	if batchResponseItems[0].HMAC != "vault:v1:3p+ZWVquYDvu2dSTCa65Y3fgoMfIAc6fNaBbtg==" {
+		t.Fatalf("bad: batch result: %#v", batchResponseItems[0])
+	}
+	if batchResponseItems[1].Error == "" || batchResponseItems[1].HMAC != "" {
+		t.Fatalf("expected an error, got batch result: %#v", batchResponseItems[1])
+	}
+
+	// Verify the HMACs in a batch, using the algorithm in the path
+	req.Path = "verify/foo/sha2-224"
+	req.Data = map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "hmac": batchResponseItems[0].HMAC},
+			map[string]interface{}{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "hmac": "vault:v1:UcBvm5VskkukzZHlPgm3p5P/Yr/PV6xpuOGZISya3A4="},
+			map[string]interface{}{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "hmac": "vault:v2:3p+ZWVquYDvu2dSTCa65Y3fgoMfIAc6fNaBbtg=="},
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	verifyResponseItems := resp.Data["batch_results"].([]VerifyBatchResponseItem)
+	if !verifyResponseItems[0].Valid || verifyResponseItems[0].Error != "" {
+		t.Fatalf("bad: batch result: %#v", verifyResponseItems[0])
+	}
+	if verifyResponseItems[1].Valid || verifyResponseItems[1].Error != "" {
+		t.Fatalf("bad: batch result: %#v", verifyResponseItems[1])
+	}
+	if verifyResponseItems[2].Valid || verifyResponseItems[2].Error != "invalid HMAC: version is too new" {
+		t.Fatalf("bad: batch result: %#v", verifyResponseItems[2])
+	}
+}
@@ -0,0 +1,261 @@
+package transit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/helper/kwp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// wrappedEphemeralKeySize is the size of the ephemeral AES key once wrapped
+// with the RSA-4096 wrapping key
+const wrappedEphemeralKeySize = 512
+
+func (b *backend) pathImport() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"type": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "aes256-gcm96",
+				Description: `
+The type of the imported key. Currently, "aes128-gcm96" (symmetric),
+"aes256-gcm96" (symmetric), "chacha20-poly1305" (symmetric), "ecdsa-p256"
+(asymmetric), "ecdsa-p384" (asymmetric), "ecdsa-p521" (asymmetric), 'ed25519'
+(asymmetric), 'rsa-2048' (asymmetric), 'rsa-3072' (asymmetric), 'rsa-4096'
+(asymmetric) are supported.  Defaults to "aes256-gcm96".
+`,
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64-encoded wrapped key material. This is
+the ephemeral AES key wrapped with the wrapping key
+using RSA-OAEP, followed by the key material wrapped
+with the ephemeral key using AES key wrap with
+padding (RFC 5649).`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used for the RSA-OAEP step of
+wrapping the ephemeral key. Valid values are "SHA1",
+"SHA224", "SHA256", "SHA384" and "SHA512". Defaults
+to "SHA256".`,
+			},
+
+			"exportable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables keys to be exportable.
+This allows for all the valid keys
+in the key ring to be exported.`,
+			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking a backup of the named
+key in plaintext format. Once set,
+this cannot be disabled.`,
+			},
+
+			"allow_rotation": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Allows the imported key to be rotated
+within Vault, in which case the new
+versions are generated by Vault.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportWrite,
+		},
+
+		HelpSynopsis:    pathImportHelpSyn,
+		HelpDescription: pathImportHelpDesc,
+	}
+}
+
+func (b *backend) pathImportVersion() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import_version",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64-encoded wrapped key material. This is
+the ephemeral AES key wrapped with the wrapping key
+using RSA-OAEP, followed by the key material wrapped
+with the ephemeral key using AES key wrap with
+padding (RFC 5649).`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used for the RSA-OAEP step of
+wrapping the ephemeral key. Valid values are "SHA1",
+"SHA224", "SHA256", "SHA384" and "SHA512". Defaults
+to "SHA256".`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportVersionWrite,
+		},
+
+		HelpSynopsis:    pathImportVersionHelpSyn,
+		HelpDescription: pathImportVersionHelpDesc,
+	}
+}
+
+func (b *backend) pathImportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	keyType := d.Get("type").(string)
+
+	polReq := keysutil.PolicyRequest{
+		Storage:                  req.Storage,
+		Name:                     name,
+		Exportable:               d.Get("exportable").(bool),
+		AllowPlaintextBackup:     d.Get("allow_plaintext_backup").(bool),
+		AllowImportedKeyRotation: d.Get("allow_rotation").(bool),
+	}
+	var ok bool
+	polReq.KeyType, ok = parseKeyType(keyType)
+	if !ok {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
+	}
+
+	key, resp, err := b.unwrapImportedKey(ctx, req.Storage, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := b.lm.ImportPolicy(ctx, polReq, key); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathImportVersionWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	p, lock, err := b.lm.GetPolicyExclusive(ctx, req.Storage, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+	if !p.Imported {
+		return logical.ErrorResponse("new versions can only be imported into imported keys"), logical.ErrInvalidRequest
+	}
+
+	key, resp, err := b.unwrapImportedKey(ctx, req.Storage, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := p.Import(ctx, req.Storage, key); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to import the key material: %s", err)), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+// unwrapImportedKey returns the key material from the ciphertext and hash
+// function fields of the request. The ciphertext is made of an ephemeral AES
+// key wrapped with the wrapping key using RSA-OAEP, followed by the key
+// material wrapped with the ephemeral key using AES key wrap with padding,
+// as in the CKM_RSA_AES_KEY_WRAP mechanism of PKCS #11.
+func (b *backend) unwrapImportedKey(ctx context.Context, storage logical.Storage, d *framework.FieldData) ([]byte, *logical.Response, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(d.Get("ciphertext").(string))
+	if err != nil {
+		return nil, logical.ErrorResponse("failed to base64-decode ciphertext"), logical.ErrInvalidRequest
+	}
+	if len(ciphertext) <= wrappedEphemeralKeySize {
+		return nil, logical.ErrorResponse("ciphertext is too short to contain the wrapped key material"), logical.ErrInvalidRequest
+	}
+
+	var oaepHash hash.Hash
+	switch hashFunction := d.Get("hash_function").(string); hashFunction {
+	case "SHA1":
+		oaepHash = sha1.New()
+	case "SHA224":
+		oaepHash = sha256.New224()
+	case "SHA256":
+		oaepHash = sha256.New()
+	case "SHA384":
+		oaepHash = sha512.New384()
+	case "SHA512":
+		oaepHash = sha512.New()
+	default:
+		return nil, logical.ErrorResponse(fmt.Sprintf("unsupported hash function %q", hashFunction)), logical.ErrInvalidRequest
+	}
+
+	p, err := b.getWrappingKey(ctx, storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrappingKey, ok := p.Keys[strconv.Itoa(p.LatestVersion)]
+	if !ok || wrappingKey.RSAKey == nil {
+		return nil, nil, fmt.Errorf("wrapping key not found")
+	}
+
+	ephemeralKey, err := rsa.DecryptOAEP(oaepHash, rand.Reader, wrappingKey.RSAKey, ciphertext[:wrappedEphemeralKeySize], nil)
+	if err != nil {
+		return nil, logical.ErrorResponse(errwrap.Wrapf("failed to unwrap the ephemeral key: {{err}}", err).Error()), logical.ErrInvalidRequest
+	}
+
+	key, err := kwp.Unwrap(ephemeralKey, ciphertext[wrappedEphemeralKeySize:])
+	if err != nil {
+		return nil, logical.ErrorResponse(errwrap.Wrapf("failed to unwrap the key material: {{err}}", err).Error()), logical.ErrInvalidRequest
+	}
+
+	return key, nil, nil
+}
+
+const pathImportHelpSyn = `Imports an externally generated key into a new named key`
+
+const pathImportHelpDesc = `
+This path is used to create a new named key from key material generated
+outside of Vault. The key material is never sent in plaintext: it must be
+wrapped with an ephemeral AES key using AES key wrap with padding (RFC 5649),
+and the ephemeral key wrapped with the public key from the "wrapping_key"
+endpoint using RSA-OAEP. AES and ChaCha20 keys are imported as their raw
+bytes, asymmetric keys as private keys in PKCS #8 DER form.
+`
+
+const pathImportVersionHelpSyn = `Imports an externally generated key as a new version of a named key`
+
+const pathImportVersionHelpDesc = `
+This path is used to add a new version to a key that was created through the
+"import" endpoint, from key material generated outside of Vault. The key
+material must be wrapped as described for the "import" endpoint, and must be
+of the type of the key.
+`
@@ -0,0 +1,304 @@
+package transit
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/hashicorp/vault/helper/kwp"
+	"github.com/hashicorp/vault/logical"
+)
+
+// wrapImportKey wraps the given key material the way a client or an HSM
+// exporting the key would, using the backend's wrapping key
+func wrapImportKey(t *testing.T, b *backend, s logical.Storage, key []byte) string {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "wrapping_key",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+
+	block, _ := pem.Decode([]byte(resp.Data["public_key"].(string)))
+	if block == nil {
+		t.Fatal("failed to decode the wrapping key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ephemeralKey := make([]byte, 32)
+	if _, err := rand.Read(ephemeralKey); err != nil {
+		t.Fatal(err)
+	}
+	wrappedEphemeralKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub.(*rsa.PublicKey), ephemeralKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappedKey, err := kwp.Wrap(ephemeralKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(append(wrappedEphemeralKey, wrappedKey...))
+}
+
+// marshalEd25519PrivateKey returns the PKCS #8 form of an Ed25519 key
+func marshalEd25519PrivateKey(t *testing.T, key ed25519.PrivateKey) []byte {
+	seed, err := asn1.Marshal(key[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm: asn1.ObjectIdentifier{1, 3, 101, 112},
+		},
+		PrivateKey: seed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestTransit_Import(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Path:      path,
+			Operation: logical.UpdateOperation,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+	readKey := func(name string) map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "keys/" + name,
+			Operation: logical.ReadOperation,
+			Storage:   s,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("resp: %#v\nerr: %v", resp, err)
+		}
+		return resp.Data
+	}
+
+	// An AES key is usable by both sides after the import
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := request("keys/aes/import", map[string]interface{}{
+		"ciphertext": wrapImportKey(t, b, s, aesKey),
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+	if imported := readKey("aes")["imported_key"].(bool); !imported {
+		t.Fatal("expected the key to be marked as imported")
+	}
+
+	resp, err = request("encrypt/aes", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte(testPlaintext)),
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["ciphertext"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != testPlaintext {
+		t.Fatalf("bad plaintext: %q", plaintext)
+	}
+
+	// Asymmetric keys expose the public key of the imported key
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPub, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec384DER, err := x509.MarshalPKCS8PrivateKey(ec384Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec384Pub, err := x509.MarshalPKIXPublicKey(ec384Key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		keyType   string
+		key       []byte
+		publicKey string
+	}{
+		{"ec", "ecdsa-p256", ecDER, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecPub}))},
+		{"ec384", "ecdsa-p384", ec384DER, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ec384Pub}))},
+		{"ed", "ed25519", marshalEd25519PrivateKey(t, edKey), base64.StdEncoding.EncodeToString(edPub)},
+		{"rsa", "rsa-2048", rsaDER, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPub}))},
+	}
+	for _, tc := range cases {
+		resp, err := request("keys/"+tc.name+"/import", map[string]interface{}{
+			"type":       tc.keyType,
+			"ciphertext": wrapImportKey(t, b, s, tc.key),
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: resp: %#v\nerr: %v", tc.name, resp, err)
+		}
+
+		keys := readKey(tc.name)["keys"].(map[string]map[string]interface{})
+		if pub := keys["1"]["public_key"].(string); pub != tc.publicKey {
+			t.Fatalf("%s: bad public key: %s", tc.name, pub)
+		}
+
+		resp, err = request("sign/"+tc.name, map[string]interface{}{
+			"input": base64.StdEncoding.EncodeToString([]byte(testPlaintext)),
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s: resp: %#v\nerr: %v", tc.name, resp, err)
+		}
+	}
+
+	// The key material must match the type of the key
+	resp, err = request("keys/mismatch/import", map[string]interface{}{
+		"type":       "rsa-4096",
+		"ciphertext": wrapImportKey(t, b, s, rsaDER),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp: %#v", resp)
+	}
+
+	resp, err = request("keys/mismatch/import", map[string]interface{}{
+		"type":       "ecdsa-p521",
+		"ciphertext": wrapImportKey(t, b, s, ec384DER),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp: %#v", resp)
+	}
+
+	// Keys cannot be imported over existing keys
+	resp, err = request("keys/aes/import", map[string]interface{}{
+		"ciphertext": wrapImportKey(t, b, s, aesKey),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp: %#v", resp)
+	}
+
+	// Tampered key material is rejected
+	ciphertext, _ := base64.StdEncoding.DecodeString(wrapImportKey(t, b, s, aesKey))
+	ciphertext[len(ciphertext)-1] ^= 1
+	resp, err = request("keys/tampered/import", map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp: %#v", resp)
+	}
+
+	// Imported keys cannot be rotated unless allowed, but new versions can
+	// be imported
+	resp, err = request("keys/aes/rotate", nil)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp: %#v", resp)
+	}
+	resp, err = request("keys/aes/import_version", map[string]interface{}{
+		"ciphertext": wrapImportKey(t, b, s, aesKey),
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+	if version := readKey("aes")["latest_version"].(int); version != 2 {
+		t.Fatalf("bad latest version: %d", version)
+	}
+
+	resp, err = request("keys/rotatable/import", map[string]interface{}{
+		"ciphertext":     wrapImportKey(t, b, s, aesKey),
+		"allow_rotation": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = request("keys/rotatable/rotate", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+
+	// Versions can only be imported into imported keys
+	resp, err = request("keys/generated", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = request("keys/generated/import_version", map[string]interface{}{
+		"ciphertext": wrapImportKey(t, b, s, aesKey),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp: %#v", resp)
+	}
+}
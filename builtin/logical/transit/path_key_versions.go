@@ -0,0 +1,114 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func (b *backend) pathKeyVersions() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/versions",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathKeyVersionsRead,
+		},
+
+		HelpSynopsis:    pathKeyVersionsHelpSyn,
+		HelpDescription: pathKeyVersionsHelpDesc,
+	}
+}
+
+func (b *backend) pathKeyVersionsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, name)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+
+	// Versions below the min decryption version only live in the archive
+	archive, err := p.LoadArchive(ctx, req.Storage)
+	if err != nil {
+		return nil, errwrap.Wrapf("error loading archived key versions: {{err}}", err)
+	}
+
+	minAvailableVersion := p.MinAvailableVersion
+	if minAvailableVersion < 1 {
+		minAvailableVersion = 1
+	}
+
+	retKeys := map[string]map[string]interface{}{}
+	for ver := minAvailableVersion; ver <= p.LatestVersion; ver++ {
+		entry, ok := p.Keys[strconv.Itoa(ver)]
+		if !ok {
+			if ver >= len(archive.Keys) {
+				return nil, fmt.Errorf("key version %d not found", ver)
+			}
+			entry = archive.Keys[ver]
+		}
+
+		creationTime := entry.CreationTime
+		if creationTime.IsZero() {
+			creationTime = time.Unix(entry.DeprecatedCreationTime, 0)
+		}
+		key := map[string]interface{}{
+			"creation_time": creationTime,
+		}
+
+		switch p.Type {
+		case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521:
+			key["public_key"] = entry.FormattedPublicKey
+
+		case keysutil.KeyType_ED25519:
+			// The public keys of derived keys depend on the context
+			if !p.Derived {
+				key["public_key"] = entry.FormattedPublicKey
+			}
+
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+			key["public_key"], err = rsaPublicKeyPEM(entry.RSAKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		retKeys[strconv.Itoa(ver)] = key
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"min_available_version":  p.MinAvailableVersion,
+			"min_decryption_version": p.MinDecryptionVersion,
+			"latest_version":         p.LatestVersion,
+			"keys":                   retKeys,
+		},
+	}, nil
+}
+
+const pathKeyVersionsHelpSyn = `Returns the metadata of the available versions of a named key`
+
+const pathKeyVersionsHelpDesc = `
+This path is used to list every version of the named key whose key material is
+still stored, including the versions below the minimum decryption version which
+can no longer be used, along with their creation time and, for asymmetric keys,
+their public key. Versions removed through the "trim" endpoint are not listed.
+`
@@ -41,6 +41,10 @@ func (b *backend) pathRotateWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
 
+	if p.Imported && !p.AllowImportedKeyRotation {
+		return logical.ErrorResponse("imported key does not allow rotation within Vault; import a new version instead"), logical.ErrInvalidRequest
+	}
+
 	// Rotate the policy
 	err = p.Rotate(ctx, req.Storage)
 
@@ -8,11 +8,54 @@ import (
 	"fmt"
 	"hash"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
 )
 
+// SignBatchRequestItem represents a request item for batch signing and
+// verification
+type SignBatchRequestItem struct {
+	// Context for key derivation. This is required for derived keys.
+	Context string `json:"context" structs:"context" mapstructure:"context"`
+
+	// Input is the base64-encoded data to sign or verify
+	Input string `json:"input" structs:"input" mapstructure:"input"`
+
+	// Signature to verify
+	Signature string `json:"signature" structs:"signature" mapstructure:"signature"`
+
+	// HMAC to verify
+	HMAC string `json:"hmac" structs:"hmac" mapstructure:"hmac"`
+}
+
+// SignBatchResponseItem represents a response item for batch signing
+type SignBatchResponseItem struct {
+	// Signature for the input present in the corresponding batch request
+	// item
+	Signature string `json:"signature,omitempty" structs:"signature" mapstructure:"signature"`
+
+	// PublicKey of the key used for signing, if the key is derived
+	PublicKey []byte `json:"public_key,omitempty" structs:"public_key" mapstructure:"public_key"`
+
+	// Error, if set represents a failure encountered while signing a
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+// VerifyBatchResponseItem represents a response item for batch verification
+type VerifyBatchResponseItem struct {
+	// Valid indicates whether the signature or HMAC present in the
+	// corresponding batch request item matched the input
+	Valid bool `json:"valid" structs:"valid" mapstructure:"valid"`
+
+	// Error, if set represents a failure encountered while verifying a
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
 func (b *backend) pathSign() *framework.Path {
 	return &framework.Path{
 		Pattern: "sign/" + framework.GenericNameRegex("name") + framework.OptionalParamRegex("urlalgorithm"),
@@ -67,7 +110,7 @@ to the min_encryption_version configured on the key.`,
 
 			"prehashed": &framework.FieldSchema{
 				Type:        framework.TypeBool,
-				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter.`,
+				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048', 'rsa-3072' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter.`,
 			},
 			"signature_algorithm": &framework.FieldSchema{
 				Type: framework.TypeString,
@@ -140,7 +183,7 @@ Defaults to "sha2-256". Not valid for all key types.`,
 
 			"prehashed": &framework.FieldSchema{
 				Type:        framework.TypeBool,
-				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter.`,
+				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048', 'rsa-3072' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter.`,
 			},
 			"signature_algorithm": &framework.FieldSchema{
 				Type: framework.TypeString,
@@ -161,7 +204,6 @@ Options are 'pss' or 'pkcs1v15'. Defaults to 'pss'`,
 func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	ver := d.Get("key_version").(int)
-	inputB64 := d.Get("input").(string)
 	hashAlgorithm := d.Get("urlalgorithm").(string)
 	if hashAlgorithm == "" {
 		hashAlgorithm = d.Get("hash_algorithm").(string)
@@ -172,9 +214,24 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 	prehashed := d.Get("prehashed").(bool)
 	sigAlgorithm := d.Get("signature_algorithm").(string)
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	batchInputRaw := d.Raw["batch_input"]
+	var batchInputItems []SignBatchRequestItem
+	if batchInputRaw != nil {
+		err := mapstructure.Decode(batchInputRaw, &batchInputItems)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse batch input: {{err}}", err)
+		}
+
+		if len(batchInputItems) == 0 {
+			return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+		}
+	} else {
+		batchInputItems = []SignBatchRequestItem{
+			{
+				Input:   d.Get("input").(string),
+				Context: d.Get("context").(string),
+			},
+		}
 	}
 
 	// Get the policy
@@ -193,72 +250,81 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support signing", p.Type)), logical.ErrInvalidRequest
 	}
 
-	contextRaw := d.Get("context").(string)
-	var context []byte
-	if len(contextRaw) != 0 {
-		context, err = base64.StdEncoding.DecodeString(contextRaw)
-		if err != nil {
-			return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
-		}
-	}
-
+	var hf func() hash.Hash
 	if p.Type.HashSignatureInput() && !prehashed {
-		var hf hash.Hash
-		switch hashAlgorithm {
-		case "sha2-224":
-			hf = sha256.New224()
-		case "sha2-256":
-			hf = sha256.New()
-		case "sha2-384":
-			hf = sha512.New384()
-		case "sha2-512":
-			hf = sha512.New()
-		default:
+		hf = hashFunc(hashAlgorithm)
+		if hf == nil {
 			return logical.ErrorResponse(fmt.Sprintf("unsupported hash algorithm %s", hashAlgorithm)), nil
 		}
-		hf.Write(input)
-		input = hf.Sum(nil)
 	}
 
-	sig, err := p.Sign(ver, context, input, hashAlgorithm, sigAlgorithm)
-	if err != nil {
-		return nil, err
-	}
-	if sig == nil {
-		return nil, fmt.Errorf("signature could not be computed")
-	}
+	// Process batch request items. If signing of any request item fails,
+	// respectively mark the error in the response collection and continue
+	// to process other items.
+	batchResponseItems := make([]SignBatchResponseItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		input, err := base64.StdEncoding.DecodeString(item.Input)
+		if err != nil {
+			batchResponseItems[i].Error = fmt.Sprintf("unable to decode input as base64: %s", err)
+			continue
+		}
 
-	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"signature": sig.Signature,
-		},
+		var context []byte
+		if len(item.Context) != 0 {
+			context, err = base64.StdEncoding.DecodeString(item.Context)
+			if err != nil {
+				batchResponseItems[i].Error = "failed to base64-decode context"
+				continue
+			}
+		}
+
+		if hf != nil {
+			h := hf()
+			h.Write(input)
+			input = h.Sum(nil)
+		}
+
+		sig, err := p.Sign(ver, context, input, hashAlgorithm, sigAlgorithm)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				batchResponseItems[i].Error = err.Error()
+				continue
+			default:
+				return nil, err
+			}
+		}
+		if sig == nil {
+			return nil, fmt.Errorf("signature could not be computed for input item %d", i)
+		}
+
+		batchResponseItems[i].Signature = sig.Signature
+		batchResponseItems[i].PublicKey = sig.PublicKey
 	}
 
-	if len(sig.PublicKey) > 0 {
-		resp.Data["public_key"] = sig.PublicKey
+	// Generate the response
+	resp := &logical.Response{}
+	if batchInputRaw != nil {
+		resp.Data = map[string]interface{}{
+			"batch_results": batchResponseItems,
+		}
+	} else {
+		if batchResponseItems[0].Error != "" {
+			return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+		}
+		resp.Data = map[string]interface{}{
+			"signature": batchResponseItems[0].Signature,
+		}
+		if len(batchResponseItems[0].PublicKey) > 0 {
+			resp.Data["public_key"] = batchResponseItems[0].PublicKey
+		}
 	}
 
 	return resp, nil
 }
 
 func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-
-	sig := d.Get("signature").(string)
-	hmac := d.Get("hmac").(string)
-	switch {
-	case sig != "" && hmac != "":
-		return logical.ErrorResponse("provide one of 'signature' or 'hmac'"), logical.ErrInvalidRequest
-
-	case sig == "" && hmac == "":
-		return logical.ErrorResponse("neither a 'signature' nor an 'hmac' were given to verify"), logical.ErrInvalidRequest
-
-	case hmac != "":
-		return b.pathHMACVerify(ctx, req, d, hmac)
-	}
-
 	name := d.Get("name").(string)
-	inputB64 := d.Get("input").(string)
 	hashAlgorithm := d.Get("urlalgorithm").(string)
 	if hashAlgorithm == "" {
 		hashAlgorithm = d.Get("hash_algorithm").(string)
@@ -266,12 +332,46 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 			hashAlgorithm = d.Get("algorithm").(string)
 		}
 	}
+	hmacAlgorithm := d.Get("urlalgorithm").(string)
+	if hmacAlgorithm == "" {
+		hmacAlgorithm = d.Get("algorithm").(string)
+	}
 	prehashed := d.Get("prehashed").(bool)
 	sigAlgorithm := d.Get("signature_algorithm").(string)
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	batchInputRaw := d.Raw["batch_input"]
+	var batchInputItems []SignBatchRequestItem
+	if batchInputRaw != nil {
+		err := mapstructure.Decode(batchInputRaw, &batchInputItems)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse batch input: {{err}}", err)
+		}
+
+		if len(batchInputItems) == 0 {
+			return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+		}
+	} else {
+		batchInputItems = []SignBatchRequestItem{
+			{
+				Input:     d.Get("input").(string),
+				Context:   d.Get("context").(string),
+				Signature: d.Get("signature").(string),
+				HMAC:      d.Get("hmac").(string),
+			},
+		}
+	}
+
+	batchResponseItems := make([]VerifyBatchResponseItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		switch {
+		case item.Signature != "" && item.HMAC != "":
+			batchResponseItems[i].Error = "provide one of 'signature' or 'hmac'"
+		case item.Signature == "" && item.HMAC == "":
+			batchResponseItems[i].Error = "neither a 'signature' nor an 'hmac' were given to verify"
+		}
+	}
+	if batchInputRaw == nil && batchResponseItems[0].Error != "" {
+		return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
 	}
 
 	// Get the policy
@@ -286,65 +386,123 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
-	if !p.Type.SigningSupported() {
-		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support verification", p.Type)), logical.ErrInvalidRequest
+	var hf func() hash.Hash
+	if p.Type.HashSignatureInput() && !prehashed {
+		hf = hashFunc(hashAlgorithm)
 	}
 
-	contextRaw := d.Get("context").(string)
-	var context []byte
-	if len(contextRaw) != 0 {
-		context, err = base64.StdEncoding.DecodeString(contextRaw)
+	// Process batch request items. If verification of any request item
+	// fails, respectively mark the error in the response collection and
+	// continue to process other items.
+	for i, item := range batchInputItems {
+		if batchResponseItems[i].Error != "" {
+			continue
+		}
+
+		input, err := base64.StdEncoding.DecodeString(item.Input)
 		if err != nil {
-			return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+			batchResponseItems[i].Error = fmt.Sprintf("unable to decode input as base64: %s", err)
+			continue
 		}
-	}
 
-	if p.Type.HashSignatureInput() && !prehashed {
-		var hf hash.Hash
-		switch hashAlgorithm {
-		case "sha2-224":
-			hf = sha256.New224()
-		case "sha2-256":
-			hf = sha256.New()
-		case "sha2-384":
-			hf = sha512.New384()
-		case "sha2-512":
-			hf = sha512.New()
-		default:
-			return logical.ErrorResponse(fmt.Sprintf("unsupported hash algorithm %s", hashAlgorithm)), nil
+		if item.HMAC != "" {
+			valid, err := verifyHMAC(p, hmacAlgorithm, input, item.HMAC)
+			if err != nil {
+				switch err.(type) {
+				case errutil.UserError:
+					batchResponseItems[i].Error = err.Error()
+					continue
+				default:
+					return nil, err
+				}
+			}
+			batchResponseItems[i].Valid = valid
+			continue
 		}
-		hf.Write(input)
-		input = hf.Sum(nil)
-	}
 
-	valid, err := p.VerifySignature(context, input, sig, hashAlgorithm, sigAlgorithm)
-	if err != nil {
-		switch err.(type) {
-		case errutil.UserError:
-			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
-		case errutil.InternalError:
-			return nil, err
-		default:
-			return nil, err
+		if !p.Type.SigningSupported() {
+			batchResponseItems[i].Error = fmt.Sprintf("key type %v does not support verification", p.Type)
+			continue
 		}
+
+		var context []byte
+		if len(item.Context) != 0 {
+			context, err = base64.StdEncoding.DecodeString(item.Context)
+			if err != nil {
+				batchResponseItems[i].Error = "failed to base64-decode context"
+				continue
+			}
+		}
+
+		if p.Type.HashSignatureInput() && !prehashed {
+			if hf == nil {
+				batchResponseItems[i].Error = fmt.Sprintf("unsupported hash algorithm %s", hashAlgorithm)
+				continue
+			}
+			h := hf()
+			h.Write(input)
+			input = h.Sum(nil)
+		}
+
+		valid, err := p.VerifySignature(context, input, item.Signature, hashAlgorithm, sigAlgorithm)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				batchResponseItems[i].Error = err.Error()
+				continue
+			default:
+				return nil, err
+			}
+		}
+		batchResponseItems[i].Valid = valid
 	}
 
 	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"valid": valid,
-		},
+	resp := &logical.Response{}
+	if batchInputRaw != nil {
+		resp.Data = map[string]interface{}{
+			"batch_results": batchResponseItems,
+		}
+	} else {
+		if batchResponseItems[0].Error != "" {
+			return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+		}
+		resp.Data = map[string]interface{}{
+			"valid": batchResponseItems[0].Valid,
+		}
 	}
+
 	return resp, nil
 }
 
-const pathSignHelpSyn = `Generate a signature for input data using the named key`
+// hashFunc returns the hash function for the given algorithm name, or nil if
+// the algorithm is not supported
+func hashFunc(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha2-224":
+		return sha256.New224
+	case "sha2-256":
+		return sha256.New
+	case "sha2-384":
+		return sha512.New384
+	case "sha2-512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+const pathSignHelpSyn = `Generate a signature for input data or a batch of input
+data using the named key`
 
 const pathSignHelpDesc = `
-Generates a signature of the input data using the named key and the given hash algorithm.
+Generates a signature of the input data, or of each item of a batch of input
+data, using the named key and the given hash algorithm.
 `
-const pathVerifyHelpSyn = `Verify a signature or HMAC for input data created using the named key`
+const pathVerifyHelpSyn = `Verify a signature or HMAC for input data or a batch of
+input data created using the named key`
 
 const pathVerifyHelpDesc = `
-Verifies a signature or HMAC of the input data using the named key and the given hash algorithm.
+Verifies a signature or HMAC of the input data, or of each item of a batch of
+input data, using the named key and the given hash algorithm.
 `
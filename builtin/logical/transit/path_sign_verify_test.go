@@ -418,3 +418,184 @@ func TestTransit_SignVerify_ED25519(t *testing.T) {
 	verifyRequest(req, false, "bar", sig)
 	verifyRequest(req, true, "bar", v1sig)
 }
+
+func TestTransit_SignVerify_Batch(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"type": "ecdsa-p256",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="  // "the quick brown fox"
+	otherInput := "dGhlIHNsb3cgYnJvd24gZm94" // "the slow brown fox"
+
+	resp = request("sign/foo", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input},
+			map[string]interface{}{"input": otherInput},
+			map[string]interface{}{"input": "not base64"},
+		},
+	})
+	signResults := resp.Data["batch_results"].([]SignBatchResponseItem)
+	if len(signResults) != 3 {
+		t.Fatalf("bad: batch results: %#v", signResults)
+	}
+	for i, item := range signResults[:2] {
+		if item.Error != "" || !strings.HasPrefix(item.Signature, "vault:v1:") {
+			t.Fatalf("bad: batch result %d: %#v", i, item)
+		}
+	}
+	if signResults[2].Error == "" || signResults[2].Signature != "" {
+		t.Fatalf("expected an error, got batch result: %#v", signResults[2])
+	}
+
+	resp = request("hmac/foo", map[string]interface{}{
+		"input": input,
+	})
+	hmac := resp.Data["hmac"].(string)
+
+	resp = request("verify/foo", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input, "signature": signResults[0].Signature},
+			map[string]interface{}{"input": input, "signature": signResults[1].Signature},
+			map[string]interface{}{"input": input, "hmac": hmac},
+			map[string]interface{}{"input": otherInput, "hmac": hmac},
+			map[string]interface{}{"input": input},
+			map[string]interface{}{"input": input, "signature": signResults[0].Signature, "hmac": hmac},
+			map[string]interface{}{"input": input, "signature": "vault:v2:" + strings.TrimPrefix(signResults[0].Signature, "vault:v1:")},
+		},
+	})
+	verifyResults := resp.Data["batch_results"].([]VerifyBatchResponseItem)
+	expected := []VerifyBatchResponseItem{
+		{Valid: true},
+		{Valid: false},
+		{Valid: true},
+		{Valid: false},
+		{Error: "neither a 'signature' nor an 'hmac' were given to verify"},
+		{Error: "provide one of 'signature' or 'hmac'"},
+	}
+	if len(verifyResults) != 7 {
+		t.Fatalf("bad: batch results: %#v", verifyResults)
+	}
+	for i, item := range expected {
+		if verifyResults[i] != item {
+			t.Fatalf("bad: batch result %d: expected %#v, got %#v", i, item, verifyResults[i])
+		}
+	}
+	if verifyResults[6].Error == "" || verifyResults[6].Valid {
+		t.Fatalf("expected an error, got batch result: %#v", verifyResults[6])
+	}
+
+	// Single item errors are still returned as error responses
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sign/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"input": "not base64",
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response, got err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "verify/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"batch_input": []interface{}{},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response, got err:%v resp:%#v", err, resp)
+	}
+}
+
+func TestTransit_SignVerify_BatchDerived(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"type":    "ed25519",
+			"derived": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	batchInput := []interface{}{
+		map[string]interface{}{"input": input, "context": "YWJjZA=="},
+		map[string]interface{}{"input": input, "context": "ZWZnaA=="},
+		map[string]interface{}{"input": input},
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sign/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"batch_input": batchInput,
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	signResults := resp.Data["batch_results"].([]SignBatchResponseItem)
+	if signResults[0].Error != "" || signResults[1].Error != "" {
+		t.Fatalf("bad: batch results: %#v", signResults)
+	}
+	if len(signResults[0].PublicKey) != ed25519.PublicKeySize || string(signResults[0].PublicKey) == string(signResults[1].PublicKey) {
+		t.Fatalf("expected distinct derived public keys, got batch results: %#v", signResults)
+	}
+	if signResults[2].Error == "" {
+		t.Fatalf("expected an error for the missing context, got batch result: %#v", signResults[2])
+	}
+
+	// Verify each signature against the context of the other item
+	batchInput = []interface{}{
+		map[string]interface{}{"input": input, "context": "YWJjZA==", "signature": signResults[0].Signature},
+		map[string]interface{}{"input": input, "context": "YWJjZA==", "signature": signResults[1].Signature},
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "verify/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"batch_input": batchInput,
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	verifyResults := resp.Data["batch_results"].([]VerifyBatchResponseItem)
+	if !verifyResults[0].Valid || verifyResults[1].Valid {
+		t.Fatalf("bad: batch results: %#v", verifyResults)
+	}
+}
@@ -0,0 +1,78 @@
+package transit
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func (b *backend) pathTrim() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/trim",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"min_available_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The minimum available version for the key ring. All
+versions before this version will be permanently deleted.
+This value can at most be equal to 'min_decryption_version'
+and, if set, 'min_encryption_version'. It cannot be
+decreased.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTrimUpdate,
+		},
+
+		HelpSynopsis:    pathTrimHelpSyn,
+		HelpDescription: pathTrimHelpDesc,
+	}
+}
+
+func (b *backend) pathTrimUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	minAvailableVersionRaw, ok := d.GetOk("min_available_version")
+	if !ok {
+		return logical.ErrorResponse("missing min_available_version"), logical.ErrInvalidRequest
+	}
+
+	p, lock, err := b.lm.GetPolicyExclusive(ctx, req.Storage, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+
+	if err := p.Trim(ctx, req.Storage, minAvailableVersionRaw.(int)); err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+const pathTrimHelpSyn = `Trim key versions of a named key`
+
+const pathTrimHelpDesc = `
+This path is used to permanently delete the key material of the versions of the
+named key below the given minimum available version. Only versions which can no
+longer be used for decryption can be trimmed, and trimmed versions cannot be
+brought back, neither by lowering the minimum decryption version nor through
+this endpoint.
+`
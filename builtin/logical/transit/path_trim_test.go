@@ -0,0 +1,123 @@
+package transit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_Trim(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+	expectSuccess := func(resp *logical.Response, err error) *logical.Response {
+		t.Helper()
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+	expectError := func(resp *logical.Response, err error) {
+		t.Helper()
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error, got err:%v resp:%#v", err, resp)
+		}
+	}
+
+	expectSuccess(request(logical.UpdateOperation, "keys/aes", nil))
+	resp := expectSuccess(request(logical.UpdateOperation, "encrypt/aes", map[string]interface{}{
+		"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+	}))
+	ciphertext := resp.Data["ciphertext"].(string)
+	for i := 0; i < 5; i++ {
+		expectSuccess(request(logical.UpdateOperation, "keys/aes/rotate", nil))
+	}
+
+	// Versions usable for decryption cannot be trimmed
+	expectError(request(logical.UpdateOperation, "keys/aes/trim", map[string]interface{}{
+		"min_available_version": 3,
+	}))
+	expectError(request(logical.UpdateOperation, "keys/aes/trim", nil))
+
+	expectSuccess(request(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"min_decryption_version": 4,
+	}))
+
+	// Archived versions are still listed until they are trimmed
+	resp = expectSuccess(request(logical.ReadOperation, "keys/aes/versions", nil))
+	if keys := resp.Data["keys"].(map[string]map[string]interface{}); len(keys) != 6 {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+
+	expectSuccess(request(logical.UpdateOperation, "keys/aes/trim", map[string]interface{}{
+		"min_available_version": 3,
+	}))
+
+	resp = expectSuccess(request(logical.ReadOperation, "keys/aes", nil))
+	if resp.Data["min_available_version"].(int) != 3 {
+		t.Fatalf("bad: min_available_version: %#v", resp.Data["min_available_version"])
+	}
+
+	resp = expectSuccess(request(logical.ReadOperation, "keys/aes/versions", nil))
+	keys := resp.Data["keys"].(map[string]map[string]interface{})
+	if len(keys) != 4 {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+	for _, ver := range []string{"3", "4", "5", "6"} {
+		key, ok := keys[ver]
+		if !ok {
+			t.Fatalf("expected version %s to be listed", ver)
+		}
+		if key["creation_time"].(time.Time).IsZero() {
+			t.Fatalf("bad: version %s: %#v", ver, key)
+		}
+		if _, ok := key["public_key"]; ok {
+			t.Fatalf("expected no public key for symmetric keys: %#v", key)
+		}
+	}
+
+	// Trimmed versions cannot be brought back
+	resp, _ = request(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"min_decryption_version": 2,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got resp:%#v", resp)
+	}
+	expectError(request(logical.UpdateOperation, "keys/aes/trim", map[string]interface{}{
+		"min_available_version": 2,
+	}))
+	expectSuccess(request(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"min_decryption_version": 3,
+	}))
+	expectError(request(logical.UpdateOperation, "decrypt/aes", map[string]interface{}{
+		"ciphertext": ciphertext,
+	}))
+
+	// Asymmetric keys list the public key of each version
+	expectSuccess(request(logical.UpdateOperation, "keys/ec", map[string]interface{}{
+		"type": "ecdsa-p256",
+	}))
+	expectSuccess(request(logical.UpdateOperation, "keys/ec/rotate", nil))
+	resp = expectSuccess(request(logical.ReadOperation, "keys/ec", nil))
+	expected := resp.Data["keys"].(map[string]map[string]interface{})
+
+	resp = expectSuccess(request(logical.ReadOperation, "keys/ec/versions", nil))
+	keys = resp.Data["keys"].(map[string]map[string]interface{})
+	if len(keys) != 2 {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+	for ver, key := range keys {
+		if key["public_key"] == "" || key["public_key"] != expected[ver]["public_key"] {
+			t.Fatalf("bad: version %s: %#v", ver, key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// wrappingKeyName is the name of the RSA key used to wrap imported key
+	// material. It is stored outside of the named keys, so it can neither be
+	// used nor managed through the key endpoints.
+	wrappingKeyName          = "wrapping-key"
+	wrappingKeyStoragePrefix = "import/"
+	wrappingKeyStoragePath   = wrappingKeyStoragePrefix + "policy/" + wrappingKeyName
+)
+
+func (b *backend) pathWrappingKey() *framework.Path {
+	return &framework.Path{
+		Pattern: "wrapping_key",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathWrappingKeyRead,
+		},
+
+		HelpSynopsis:    pathWrappingKeyHelpSyn,
+		HelpDescription: pathWrappingKeyHelpDesc,
+	}
+}
+
+// getWrappingKey returns the wrapping key, generating it on first use. On a
+// performance standby the write fails with logical.ErrReadOnly, which
+// forwards the request to the active node.
+func (b *backend) getWrappingKey(ctx context.Context, storage logical.Storage) (*keysutil.Policy, error) {
+	b.wrappingKeyLock.Lock()
+	defer b.wrappingKeyLock.Unlock()
+
+	if b.wrappingKey != nil {
+		return b.wrappingKey, nil
+	}
+
+	p, err := keysutil.LoadPolicy(ctx, storage, wrappingKeyStoragePath)
+	if err != nil {
+		return nil, errwrap.Wrapf("error loading wrapping key: {{err}}", err)
+	}
+	if p == nil {
+		p = keysutil.NewPolicy(keysutil.PolicyConfig{
+			Name:          wrappingKeyName,
+			Type:          keysutil.KeyType_RSA4096,
+			StoragePrefix: wrappingKeyStoragePrefix,
+		})
+		if err := p.Rotate(ctx, storage); err != nil {
+			return nil, errwrap.Wrapf("error generating wrapping key: {{err}}", err)
+		}
+	}
+
+	b.wrappingKey = p
+	return p, nil
+}
+
+func (b *backend) pathWrappingKeyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	p, err := b.getWrappingKey(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := p.Keys[strconv.Itoa(p.LatestVersion)]
+	if !ok || key.RSAKey == nil {
+		return nil, fmt.Errorf("wrapping key not found")
+	}
+
+	publicKey, err := rsaPublicKeyPEM(key.RSAKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": publicKey,
+		},
+	}, nil
+}
+
+const pathWrappingKeyHelpSyn = `Returns the public key to use for wrapping imported keys`
+
+const pathWrappingKeyHelpDesc = `
+This path is used to retrieve the RSA-4096 public key used to wrap key
+material imported through the "keys/<name>/import" and
+"keys/<name>/import_version" endpoints. The key is generated on first use.
+`
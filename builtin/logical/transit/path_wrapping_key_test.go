@@ -0,0 +1,83 @@
+package transit
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+// readOnlyStorage refuses writes, like the storage of a performance standby
+type readOnlyStorage struct {
+	logical.Storage
+}
+
+func (s readOnlyStorage) Put(context.Context, *logical.StorageEntry) error {
+	return logical.ErrReadOnly
+}
+
+func (s readOnlyStorage) Delete(context.Context, string) error {
+	return logical.ErrReadOnly
+}
+
+func TestTransit_WrappingKey(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	readWrappingKey := func(b *backend, storage logical.Storage) (string, error) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "wrapping_key",
+			Operation: logical.ReadOperation,
+			Storage:   storage,
+		})
+		if err != nil {
+			return "", err
+		}
+		return resp.Data["public_key"].(string), nil
+	}
+
+	// Generating the key on read-only storage must report ErrReadOnly, so
+	// that the request is forwarded to the active node
+	_, err := readWrappingKey(b, readOnlyStorage{s})
+	if err == nil || !errwrap.Contains(err, logical.ErrReadOnly.Error()) {
+		t.Fatalf("expected a read-only error, got %v", err)
+	}
+
+	// Concurrent reads generate a single key
+	var wg sync.WaitGroup
+	keys := make([]string, 10)
+	errs := make([]error, len(keys))
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			keys[i], errs[i] = readWrappingKey(b, s)
+		}(i)
+	}
+	wg.Wait()
+	for i := range keys {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if keys[i] != keys[0] {
+			t.Fatal("expected the same wrapping key")
+		}
+	}
+
+	// The key is kept when the mount is loaded again, and reading it no
+	// longer writes
+	config := logical.TestBackendConfig()
+	config.StorageView = s
+	b2, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := readWrappingKey(b2.(*backend), readOnlyStorage{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != keys[0] {
+		t.Fatal("expected the same wrapping key")
+	}
+}
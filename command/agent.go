@@ -0,0 +1,475 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	"github.com/hashicorp/vault/command/agent/auth/approle"
+	"github.com/hashicorp/vault/command/agent/auth/cert"
+	"github.com/hashicorp/vault/command/agent/auth/tokenfile"
+	"github.com/hashicorp/vault/command/agent/cache"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/command/agent/sink/file"
+	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/command/agent/template"
+	"github.com/hashicorp/vault/command/server"
+	gatedwriter "github.com/hashicorp/vault/helper/gated-writer"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/version"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*AgentCommand)(nil)
+var _ cli.CommandAutocomplete = (*AgentCommand)(nil)
+
+type AgentCommand struct {
+	*BaseCommand
+
+	ShutdownCh chan struct{}
+
+	logWriter io.Writer
+	logGate   *gatedwriter.Writer
+	logger    log.Logger
+
+	cleanupGuard sync.Once
+
+	startedCh chan (struct{}) // for tests
+
+	flagConfigs  []string
+	flagLogLevel string
+
+	flagTestVerifyOnly bool
+}
+
+func (c *AgentCommand) Synopsis() string {
+	return "Start a Vault agent"
+}
+
+func (c *AgentCommand) Help() string {
+	helpText := `
+Usage: vault agent [options]
+
+  This command starts a Vault agent that can perform automatic authentication
+  in certain environments, write the resulting token to one or more sinks,
+  and cache responses to requests made through it.
+
+  Start an agent with a configuration file:
+
+      $ vault agent -config=/etc/vault/config.hcl
+
+  For a full list of examples, please see the documentation.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AgentCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   "config",
+		Target: &c.flagConfigs,
+		Completion: complete.PredictOr(
+			complete.PredictFiles("*.hcl"),
+			complete.PredictFiles("*.json"),
+		),
+		Usage: "Path to a configuration file. This configuration file should " +
+			"contain only agent directives.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "log-level",
+		Target:     &c.flagLogLevel,
+		Default:    "info",
+		EnvVar:     "VAULT_LOG_LEVEL",
+		Completion: complete.PredictSet("trace", "debug", "info", "warn", "err"),
+		Usage: "Log verbosity level. Supported values (in order of detail) are " +
+			"\"trace\", \"debug\", \"info\", \"warn\", and \"err\".",
+	})
+
+	// Internal-only flags to follow.
+	//
+	// Why hello there little source code reader! Welcome to the Vault source
+	// code. The remaining options are intentionally undocumented and come with
+	// no warranty or backwards-compatability promise. Do not use these flags
+	// in production. Do not build automation using these flags. Unless you are
+	// developing against Vault, you should not need any of these flags.
+	f.BoolVar(&BoolVar{
+		Name:    "test-verify-only",
+		Target:  &c.flagTestVerifyOnly,
+		Default: false,
+		Hidden:  true,
+	})
+
+	return set
+}
+
+func (c *AgentCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *AgentCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *AgentCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	// Create a logger. We wrap it in a gated writer so that it doesn't
+	// start logging too early.
+	c.logGate = &gatedwriter.Writer{Writer: os.Stderr}
+	c.logWriter = c.logGate
+	var level log.Level
+	c.flagLogLevel = strings.ToLower(strings.TrimSpace(c.flagLogLevel))
+	switch c.flagLogLevel {
+	case "trace":
+		level = log.Trace
+	case "debug":
+		level = log.Debug
+	case "notice", "info", "":
+		level = log.Info
+	case "warn", "warning":
+		level = log.Warn
+	case "err", "error":
+		level = log.Error
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown log level: %s", c.flagLogLevel))
+		return 1
+	}
+
+	if c.logger == nil {
+		c.logger = logging.NewVaultLoggerWithWriter(c.logWriter, level)
+	}
+
+	// Validation
+	if len(c.flagConfigs) != 1 {
+		c.UI.Error("Must specify exactly one config path using -config")
+		return 1
+	}
+
+	// Load the configuration
+	config, err := config.LoadConfig(c.flagConfigs[0])
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading configuration from %s: %s", c.flagConfigs[0], err))
+		return 1
+	}
+
+	// Ensure at least one config was found.
+	if config == nil {
+		c.UI.Output(wrapAtLength(
+			"No configuration read. Please provide the configuration with the " +
+				"-config flag."))
+		return 1
+	}
+
+	// Values in the vault stanza apply unless they have been given by a flag
+	// or environment variable
+	if config.Vault != nil {
+		flagsSet := make(map[string]bool)
+		f.mainSet.Visit(func(fl *flag.Flag) {
+			flagsSet[fl.Name] = true
+		})
+
+		c.setStringFlag(flagsSet, "address", &c.flagAddress, api.EnvVaultAddress, config.Vault.Address)
+		c.setStringFlag(flagsSet, "ca-cert", &c.flagCACert, api.EnvVaultCACert, config.Vault.CACert)
+		c.setStringFlag(flagsSet, "ca-path", &c.flagCAPath, api.EnvVaultCAPath, config.Vault.CAPath)
+		c.setStringFlag(flagsSet, "client-cert", &c.flagClientCert, api.EnvVaultClientCert, config.Vault.ClientCert)
+		c.setStringFlag(flagsSet, "client-key", &c.flagClientKey, api.EnvVaultClientKey, config.Vault.ClientKey)
+		if !flagsSet["tls-skip-verify"] && os.Getenv(api.EnvVaultInsecure) == "" {
+			c.flagTLSSkipVerify = config.Vault.TLSSkipVerify
+		}
+	}
+
+	infoKeys := make([]string, 0, 10)
+	info := make(map[string]string)
+	info["log level"] = c.flagLogLevel
+	infoKeys = append(infoKeys, "log level")
+
+	infoKeys = append(infoKeys, "version")
+	verInfo := version.GetVersion()
+	info["version"] = verInfo.FullVersionNumber(false)
+	if verInfo.Revision != "" {
+		info["version sha"] = strings.Trim(verInfo.Revision, "'")
+		infoKeys = append(infoKeys, "version sha")
+	}
+	infoKeys = append(infoKeys, "cgo")
+	info["cgo"] = "disabled"
+	if version.CgoEnabled {
+		info["cgo"] = "enabled"
+	}
+
+	// Server configuration output
+	padding := 24
+	sort.Strings(infoKeys)
+	c.UI.Output("==> Vault agent configuration:\n")
+	for _, k := range infoKeys {
+		c.UI.Output(fmt.Sprintf(
+			"%s%s: %s",
+			strings.Repeat(" ", padding-len(k)),
+			strings.Title(k),
+			info[k]))
+	}
+	c.UI.Output("")
+
+	if c.flagTestVerifyOnly {
+		return 0
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf(
+			"Error fetching client: %v",
+			err))
+		return 1
+	}
+
+	// The agent manages its own token, so don't pick one up from the
+	// environment or the token helper
+	client.ClearToken()
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	var sinks []*sink.SinkConfig
+	var method auth.AuthMethod
+	if config.AutoAuth != nil {
+		for _, sc := range config.AutoAuth.Sinks {
+			switch sc.Type {
+			case "file":
+				sinkConfig := &sink.SinkConfig{
+					Logger:  c.logger.Named("sink.file"),
+					Config:  sc.Config,
+					Client:  client,
+					WrapTTL: sc.WrapTTL,
+					DHType:  sc.DHType,
+					DHPath:  sc.DHPath,
+					AAD:     sc.AAD,
+				}
+				s, err := file.NewFileSink(sinkConfig)
+				if err != nil {
+					c.UI.Error(errwrap.Wrapf("Error creating file sink: {{err}}", err).Error())
+					return 1
+				}
+				sinkConfig.Sink = s
+				sinks = append(sinks, sinkConfig)
+			default:
+				c.UI.Error(fmt.Sprintf("Unknown sink type %q", sc.Type))
+				return 1
+			}
+		}
+
+		authConfig := &auth.AuthConfig{
+			Logger:    c.logger.Named(fmt.Sprintf("auth.%s", config.AutoAuth.Method.Type)),
+			MountPath: config.AutoAuth.Method.MountPath,
+			Config:    config.AutoAuth.Method.Config,
+		}
+		switch config.AutoAuth.Method.Type {
+		case "approle":
+			method, err = approle.NewApproleAuthMethod(authConfig)
+		case "cert":
+			method, err = cert.NewCertAuthMethod(authConfig)
+		case "token_file":
+			method, err = tokenfile.NewTokenFileAuthMethod(authConfig)
+		default:
+			c.UI.Error(fmt.Sprintf("Unknown auth method %q", config.AutoAuth.Method.Type))
+			return 1
+		}
+		if err != nil {
+			c.UI.Error(errwrap.Wrapf(fmt.Sprintf("Error creating %s auth method: {{err}}", config.AutoAuth.Method.Type), err).Error())
+			return 1
+		}
+	}
+
+	// Start the caching proxy and its listeners
+	var listeners []net.Listener
+	if config.Cache != nil {
+		cacheLogger := c.logger.Named("cache")
+
+		apiProxy, err := cache.NewAPIProxy(&cache.APIProxyConfig{
+			Client: client,
+			Logger: cacheLogger.Named("apiproxy"),
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating API proxy: %v", err))
+			return 1
+		}
+
+		leaseCache, err := cache.NewLeaseCache(&cache.LeaseCacheConfig{
+			BaseContext: ctx,
+			Proxier:     apiProxy,
+			Logger:      cacheLogger.Named("leasecache"),
+			Client:      client,
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating lease cache: %v", err))
+			return 1
+		}
+
+		var inmemSink sink.SinkReader
+		if config.Cache.UseAutoAuthToken {
+			cacheLogger.Debug("auto-auth token is allowed to be used; configuring inmem sink")
+			inmemSink, err = inmem.New(&sink.SinkConfig{
+				Logger: cacheLogger,
+			})
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error creating inmem sink for cache: %v", err))
+				return 1
+			}
+			sinks = append(sinks, &sink.SinkConfig{
+				Logger: cacheLogger,
+				Sink:   inmemSink,
+			})
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/", cache.ProxyHandler(ctx, cacheLogger, leaseCache, inmemSink))
+
+		for i, lnConfig := range config.Listeners {
+			ln, _, _, err := server.NewListener(lnConfig.Type, lnConfig.Config, c.logWriter, c.UI)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error starting listener: %v", err))
+				return 1
+			}
+			listeners = append(listeners, ln)
+
+			srv := &http.Server{
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+				ReadTimeout:       30 * time.Second,
+				IdleTimeout:       5 * time.Minute,
+				ErrorLog:          cacheLogger.StandardLogger(nil),
+			}
+			go srv.Serve(ln)
+
+			c.UI.Output(fmt.Sprintf("==> Vault agent listener %d started on %s", i+1, ln.Addr()))
+		}
+	}
+
+	closeListeners := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+	defer c.cleanupGuard.Do(closeListeners)
+
+	// Output the header that the agent has started
+	c.UI.Output("==> Vault agent started! Log data will stream in below:\n")
+
+	// Inform any tests that the agent is ready
+	if c.startedCh != nil {
+		close(c.startedCh)
+	}
+
+	// Release the log gate.
+	c.logGate.Flush()
+
+	// Write out the PID to the file now that the agent has started
+	if err := storePidFile(config.PidFile); err != nil {
+		c.UI.Error(fmt.Sprintf("Error storing PID: %s", err))
+		return 1
+	}
+
+	defer func() {
+		if err := removePidFile(config.PidFile); err != nil {
+			c.UI.Error(fmt.Sprintf("Error deleting the PID file: %s", err))
+		}
+	}()
+
+	var ah *auth.AuthHandler
+	var ss *sink.SinkServer
+	var ts *template.Server
+	if method != nil {
+		ah = auth.NewAuthHandler(&auth.AuthHandlerConfig{
+			Logger: c.logger.Named("auth.handler"),
+			Client: client,
+		})
+
+		ss = sink.NewSinkServer(&sink.SinkServerConfig{
+			Logger:        c.logger.Named("sink.server"),
+			Client:        client,
+			ExitAfterAuth: config.ExitAfterAuth,
+		})
+
+		// The template server receives each new token as a sink
+		if len(config.Templates) > 0 {
+			ts = template.NewServer(&template.ServerConfig{
+				Logger:          c.logger.Named("template.server"),
+				Client:          client,
+				ExitAfterRender: config.ExitAfterAuth,
+			})
+			sinks = append(sinks, &sink.SinkConfig{
+				Logger: c.logger.Named("template.server"),
+				Sink:   ts,
+			})
+			go ts.Run(ctx, config.Templates)
+		}
+
+		go ah.Run(ctx, method)
+		go ss.Run(ctx, ah.OutputCh, sinks)
+	}
+
+	// Wait for shutdown, or for the sinks and templates to finish if we are
+	// only meant to authenticate once
+	exitCh := make(chan struct{})
+	if config.ExitAfterAuth {
+		go func() {
+			<-ss.DoneCh
+			if ts != nil {
+				<-ts.DoneCh
+			}
+			close(exitCh)
+		}()
+	}
+
+	select {
+	case <-exitCh:
+		c.logger.Info("sinks finished, exiting")
+	case <-c.ShutdownCh:
+		c.UI.Output("==> Vault agent shutdown triggered")
+	}
+
+	cancelFunc()
+	c.cleanupGuard.Do(closeListeners)
+	if ah != nil {
+		<-ah.DoneCh
+	}
+	if ss != nil {
+		<-ss.DoneCh
+	}
+	if ts != nil {
+		<-ts.DoneCh
+	}
+
+	return 0
+}
+
+// setStringFlag sets the value of the given flag from the agent configuration
+// unless it was already set by a flag or environment variable
+func (c *AgentCommand) setStringFlag(flagsSet map[string]bool, name string, target *string, envVar, value string) {
+	if value == "" || flagsSet[name] || os.Getenv(envVar) != "" {
+		return
+	}
+	*target = value
+}
@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
+	"github.com/hashicorp/vault/command/agent/auth"
+	agentapprole "github.com/hashicorp/vault/command/agent/auth/approle"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/command/agent/sink/file"
+	"github.com/hashicorp/vault/helper/dhutil"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logging"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestAppRoleEndToEnd(t *testing.T) {
+	logger := logging.NewVaultLogger(hclog.Trace)
+	coreConfig := &vault.CoreConfig{
+		Logger: logger,
+		CredentialBackends: map[string]logical.Factory{
+			"approle": credAppRole.Factory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+	client := cluster.Cores[0].Client
+
+	if err := client.Sys().EnableAuthWithOptions("approle", &api.EnableAuthOptions{
+		Type: "approle",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Logical().Write("auth/approle/role/test1", map[string]interface{}{
+		"bind_secret_id": "true",
+		"token_ttl":      "3s",
+		"token_max_ttl":  "10s",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Logical().Write("auth/approle/role/test1/secret-id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretID := resp.Data["secret_id"].(string)
+
+	resp, err = client.Logical().Read("auth/approle/role/test1/role-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleID := resp.Data["role_id"].(string)
+
+	dir, err := ioutil.TempDir("", "auth.approle.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	roleIDPath := filepath.Join(dir, "role-id")
+	secretIDPath := filepath.Join(dir, "secret-id")
+	if err := ioutil.WriteFile(roleIDPath, []byte(roleID+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secretIDPath, []byte(secretID+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote side of the diffie-hellman exchange
+	pub, pri, err := dhutil.GeneratePublicPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubBytes, err := jsonutil.EncodeJSON(&dhutil.PublicKeyInfo{
+		Curve25519PublicKey: pub,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dhPath := filepath.Join(dir, "dh-pub")
+	if err := ioutil.WriteFile(dhPath, pubBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	timer := time.AfterFunc(30*time.Second, func() {
+		cancelFunc()
+	})
+	defer timer.Stop()
+
+	am, err := agentapprole.NewApproleAuthMethod(&auth.AuthConfig{
+		Logger:    logger.Named("auth.approle"),
+		MountPath: "auth/approle",
+		Config: map[string]interface{}{
+			"role_id_file_path":   roleIDPath,
+			"secret_id_file_path": secretIDPath,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ah := auth.NewAuthHandler(&auth.AuthHandlerConfig{
+		Logger: logger.Named("auth.handler"),
+		Client: client,
+	})
+	go ah.Run(ctx, am)
+
+	plainPath := filepath.Join(dir, "token")
+	plainConfig := &sink.SinkConfig{
+		Logger: logger.Named("sink.file"),
+		Config: map[string]interface{}{
+			"path": plainPath,
+		},
+	}
+	plainConfig.Sink, err = file.NewFileSink(plainConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encPath := filepath.Join(dir, "token-enc")
+	encConfig := &sink.SinkConfig{
+		Logger: logger.Named("sink.file"),
+		Config: map[string]interface{}{
+			"path": encPath,
+		},
+		DHType: "curve25519",
+		DHPath: dhPath,
+		AAD:    "foobar",
+	}
+	encConfig.Sink, err = file.NewFileSink(encConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss := sink.NewSinkServer(&sink.SinkServerConfig{
+		Logger: logger.Named("sink.server"),
+		Client: client,
+	})
+	go ss.Run(ctx, ah.OutputCh, []*sink.SinkConfig{plainConfig, encConfig})
+
+	defer func() {
+		cancelFunc()
+		<-ah.DoneCh
+		<-ss.DoneCh
+	}()
+
+	readToken := func(path string) string {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			val, err := ioutil.ReadFile(path)
+			if err == nil && len(val) > 0 {
+				return string(val)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for token at %s", path)
+		return ""
+	}
+
+	token := readToken(plainPath)
+	if err := checkToken(client, token); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret ID file is removed once it has been read
+	if _, err := os.Stat(secretIDPath); !os.IsNotExist(err) {
+		t.Fatalf("expected secret ID file to be removed, got %v", err)
+	}
+
+	// The encrypted token can be recovered by the holder of the private key
+	var envelope dhutil.Envelope
+	if err := jsonutil.DecodeJSON([]byte(readToken(encPath)), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	aesKey, err := dhutil.GenerateSharedKey(pri, envelope.Curve25519PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := dhutil.DecryptAES(aesKey, envelope.EncryptedPayload, envelope.Nonce, []byte(string(envelope.Curve25519PublicKey)+"foobar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != token {
+		t.Fatalf("expected decrypted token %q, got %q", token, string(plaintext))
+	}
+
+	// Once the token reaches its max TTL a new one is fetched using the
+	// cached secret ID
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		newToken := readToken(plainPath)
+		if newToken != token {
+			if err := checkToken(client, newToken); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a new token")
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func checkToken(client *api.Client, token string) error {
+	tokenClient, err := client.Clone()
+	if err != nil {
+		return err
+	}
+	tokenClient.SetToken(token)
+	secret, err := tokenClient.Auth().Token().LookupSelf()
+	if err != nil {
+		return err
+	}
+	if secret.Data["display_name"] != "approle" {
+		return fmt.Errorf("unexpected token lookup data: %#v", secret.Data)
+	}
+	return nil
+}
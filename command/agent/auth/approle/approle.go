@@ -0,0 +1,152 @@
+package approle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+type approleMethod struct {
+	logger    log.Logger
+	mountPath string
+
+	roleIDFilePath                 string
+	secretIDFilePath               string
+	cachedRoleID                   string
+	cachedSecretID                 string
+	removeSecretIDFileAfterReading bool
+}
+
+// NewApproleAuthMethod returns an auth method that logs in using a role ID
+// and secret ID read from files
+func NewApproleAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	a := &approleMethod{
+		logger:                         conf.Logger,
+		mountPath:                      conf.MountPath,
+		removeSecretIDFileAfterReading: true,
+	}
+
+	roleIDFilePathRaw, ok := conf.Config["role_id_file_path"]
+	if !ok {
+		return nil, errors.New("missing 'role_id_file_path' value")
+	}
+	a.roleIDFilePath, ok = roleIDFilePathRaw.(string)
+	if !ok {
+		return nil, errors.New("could not convert 'role_id_file_path' config value to string")
+	}
+	if a.roleIDFilePath == "" {
+		return nil, errors.New("'role_id_file_path' value is empty")
+	}
+
+	secretIDFilePathRaw, ok := conf.Config["secret_id_file_path"]
+	if ok {
+		a.secretIDFilePath, ok = secretIDFilePathRaw.(string)
+		if !ok {
+			return nil, errors.New("could not convert 'secret_id_file_path' config value to string")
+		}
+		if a.secretIDFilePath == "" {
+			return nil, errors.New("'secret_id_file_path' value is empty")
+		}
+
+		removeSecretIDFileAfterReadingRaw, ok := conf.Config["remove_secret_id_file_after_reading"]
+		if ok {
+			removeSecretIDFileAfterReading, err := parseutil.ParseBool(removeSecretIDFileAfterReadingRaw)
+			if err != nil {
+				return nil, errwrap.Wrapf("error parsing 'remove_secret_id_file_after_reading' value: {{err}}", err)
+			}
+			a.removeSecretIDFileAfterReading = removeSecretIDFileAfterReading
+		}
+	}
+
+	return a, nil
+}
+
+func (a *approleMethod) Authenticate(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	a.logger.Trace("beginning authentication")
+
+	roleID, err := readFile(a.roleIDFilePath)
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading role ID file: {{err}}", err)
+	}
+	switch {
+	case roleID != "":
+		a.cachedRoleID = roleID
+	case a.cachedRoleID == "":
+		return nil, errors.New("role ID file empty and no cached role ID available")
+	default:
+		a.logger.Warn("role ID file empty, using cached role ID")
+	}
+
+	data := map[string]interface{}{
+		"role_id": a.cachedRoleID,
+	}
+
+	if a.secretIDFilePath != "" {
+		secretID, err := readFile(a.secretIDFilePath)
+		if err != nil {
+			return nil, errwrap.Wrapf("error reading secret ID file: {{err}}", err)
+		}
+		switch {
+		case secretID != "":
+			a.cachedSecretID = secretID
+			if a.removeSecretIDFileAfterReading {
+				if err := os.Remove(a.secretIDFilePath); err != nil {
+					a.logger.Error("error removing secret ID file after reading", "error", err)
+				}
+			}
+		case a.cachedSecretID == "":
+			return nil, errors.New("secret ID file empty and no cached secret ID available")
+		default:
+			a.logger.Warn("secret ID file empty, using cached secret ID")
+		}
+
+		data["secret_id"] = a.cachedSecretID
+	}
+
+	secret, err := client.Logical().Write(fmt.Sprintf("%s/login", a.mountPath), data)
+	if err != nil {
+		return nil, errwrap.Wrapf("error logging in with approle: {{err}}", err)
+	}
+
+	return secret, nil
+}
+
+func (a *approleMethod) NewCreds() chan struct{} {
+	return nil
+}
+
+func (a *approleMethod) CredSuccess() {
+}
+
+func (a *approleMethod) Shutdown() {
+}
+
+// readFile returns the trimmed contents of the file at the given path, or an
+// empty string if it does not exist
+func readFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+)
+
+// AuthMethod is the interface implemented by the auth methods the agent can
+// use to authenticate
+type AuthMethod interface {
+	// Authenticate performs a login against the given client, which has
+	// no token set, and returns the resulting secret
+	Authenticate(context.Context, *api.Client) (*api.Secret, error)
+
+	// NewCreds returns a channel that is signalled when new credentials are
+	// available and a fresh authentication should take place. It may be nil.
+	NewCreds() chan struct{}
+
+	// CredSuccess is called after the credentials have been used to
+	// successfully authenticate
+	CredSuccess()
+
+	// Shutdown cleans up any resources held by the method
+	Shutdown()
+}
+
+// AuthConfig is the configuration passed to an auth method's constructor
+type AuthConfig struct {
+	Logger    log.Logger
+	MountPath string
+	Config    map[string]interface{}
+}
+
+// AuthHandler is responsible for keeping a token alive and renewed and
+// passing new tokens to the sink server
+type AuthHandler struct {
+	DoneCh   chan struct{}
+	OutputCh chan string
+	logger   log.Logger
+	client   *api.Client
+	random   *rand.Rand
+}
+
+// AuthHandlerConfig is the configuration for NewAuthHandler
+type AuthHandlerConfig struct {
+	Logger log.Logger
+	Client *api.Client
+}
+
+// NewAuthHandler returns a new handler for the given configuration
+func NewAuthHandler(conf *AuthHandlerConfig) *AuthHandler {
+	ah := &AuthHandler{
+		DoneCh: make(chan struct{}),
+		// This is buffered so that if we try to output after the sink server
+		// has been shut down, during agent shutdown, we won't block
+		OutputCh: make(chan string, 1),
+		logger:   conf.Logger,
+		client:   conf.Client,
+		random:   rand.New(rand.NewSource(int64(time.Now().Nanosecond()))),
+	}
+
+	return ah
+}
+
+func backoffOrQuit(ctx context.Context, backoff time.Duration) {
+	select {
+	case <-time.After(backoff):
+	case <-ctx.Done():
+	}
+}
+
+// Run authenticates using the given method and keeps the resulting token
+// renewed until the context is canceled, authenticating again whenever the
+// token can no longer be renewed or the method has new credentials
+func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) {
+	if am == nil {
+		panic("nil auth method")
+	}
+
+	ah.logger.Info("starting auth handler")
+	defer func() {
+		am.Shutdown()
+		close(ah.OutputCh)
+		close(ah.DoneCh)
+		ah.logger.Info("auth handler stopped")
+	}()
+
+	credCh := am.NewCreds()
+	if credCh == nil {
+		credCh = make(chan struct{})
+	}
+
+	var renewer *api.Renewer
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// Create a fresh backoff value
+		backoff := 2*time.Second + time.Duration(ah.random.Int63()%int64(time.Second*2)-int64(time.Second))
+
+		ah.logger.Info("authenticating")
+		// Authenticate with a clean client so that methods are free to set a
+		// token on it
+		clientToUse, err := ah.client.Clone()
+		if err != nil {
+			ah.logger.Error("error cloning client", "error", err, "backoff", backoff.Seconds())
+			backoffOrQuit(ctx, backoff)
+			continue
+		}
+		clientToUse.ClearToken()
+
+		secret, err := am.Authenticate(ctx, clientToUse)
+		if err != nil {
+			ah.logger.Error("error authenticating", "error", err, "backoff", backoff.Seconds())
+			backoffOrQuit(ctx, backoff)
+			continue
+		}
+
+		if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+			ah.logger.Error("authentication returned nil auth info", "backoff", backoff.Seconds())
+			backoffOrQuit(ctx, backoff)
+			continue
+		}
+
+		ah.logger.Info("authentication successful, sending token to sinks")
+		select {
+		case ah.OutputCh <- secret.Auth.ClientToken:
+		case <-ctx.Done():
+			return
+		}
+		am.CredSuccess()
+
+		if renewer != nil {
+			renewer.Stop()
+		}
+
+		// Tokens that cannot be renewed are used until most of their TTL has
+		// elapsed, after which we authenticate again. A token with no TTL is
+		// used until new credentials become available.
+		if !secret.Auth.Renewable {
+			var expiryCh <-chan time.Time
+			if secret.Auth.LeaseDuration > 0 {
+				expiryCh = time.After(time.Duration(secret.Auth.LeaseDuration) * time.Second * 2 / 3)
+			}
+
+			ah.logger.Info("token is not renewable, waiting to re-authenticate")
+			select {
+			case <-ctx.Done():
+			case <-expiryCh:
+			case <-credCh:
+				ah.logger.Info("auth method found new credentials, re-authenticating")
+			}
+			continue
+		}
+
+		renewer, err = ah.client.NewRenewer(&api.RenewerInput{
+			Secret: secret,
+		})
+		if err != nil {
+			ah.logger.Error("error creating renewer, backing off and retrying", "error", err, "backoff", backoff.Seconds())
+			backoffOrQuit(ctx, backoff)
+			continue
+		}
+
+		// Start the renewal process
+		ah.logger.Info("starting renewal process")
+		go renewer.Renew()
+
+	RenewerLoop:
+		for {
+			select {
+			case <-ctx.Done():
+				ah.logger.Info("shutdown triggered, stopping renewer")
+				renewer.Stop()
+				break RenewerLoop
+
+			case err := <-renewer.DoneCh():
+				ah.logger.Info("renewer done channel triggered")
+				if err != nil {
+					ah.logger.Error("error renewing token", "error", err)
+				}
+				break RenewerLoop
+
+			case <-renewer.RenewCh():
+				ah.logger.Info("renewed auth token")
+
+			case <-credCh:
+				ah.logger.Info("auth method found new credentials, re-authenticating")
+				break RenewerLoop
+			}
+		}
+	}
+}
+
+// SecretFromTokenLookup builds an auth secret from the response to a token
+// lookup, for auth methods that are handed an existing token rather than
+// performing a login
+func SecretFromTokenLookup(lookup *api.Secret, token string) (*api.Secret, error) {
+	if lookup == nil || lookup.Data == nil {
+		return nil, errors.New("token lookup returned no data")
+	}
+
+	ttl, err := lookup.TokenTTL()
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing token TTL: {{err}}", err)
+	}
+	renewable, err := lookup.TokenIsRenewable()
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing token renewability: {{err}}", err)
+	}
+	policies, err := lookup.TokenPolicies()
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing token policies: {{err}}", err)
+	}
+	accessor, err := lookup.TokenAccessor()
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing token accessor: {{err}}", err)
+	}
+
+	metadata, err := lookup.TokenMetadata()
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing token metadata: {{err}}", err)
+	}
+
+	return &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   token,
+			Accessor:      accessor,
+			Policies:      policies,
+			Metadata:      metadata,
+			LeaseDuration: int(ttl.Seconds()),
+			Renewable:     renewable,
+		},
+	}, nil
+}
@@ -0,0 +1,68 @@
+package cert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+)
+
+type certMethod struct {
+	logger    log.Logger
+	mountPath string
+	name      string
+}
+
+// NewCertAuthMethod returns an auth method that logs in using the TLS client
+// certificate configured for the agent's connection to Vault
+func NewCertAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+
+	c := &certMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+	}
+
+	if conf.Config != nil {
+		if nameRaw, ok := conf.Config["name"]; ok {
+			c.name, ok = nameRaw.(string)
+			if !ok {
+				return nil, errors.New("could not convert 'name' config value to string")
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *certMethod) Authenticate(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	c.logger.Trace("beginning authentication")
+
+	data := map[string]interface{}{}
+	if c.name != "" {
+		data["name"] = c.name
+	}
+
+	secret, err := client.Logical().Write(fmt.Sprintf("%s/login", c.mountPath), data)
+	if err != nil {
+		return nil, errwrap.Wrapf("error logging in with cert: {{err}}", err)
+	}
+
+	return secret, nil
+}
+
+func (c *certMethod) NewCreds() chan struct{} {
+	return nil
+}
+
+func (c *certMethod) CredSuccess() {
+}
+
+func (c *certMethod) Shutdown() {
+}
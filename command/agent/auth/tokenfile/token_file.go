@@ -0,0 +1,84 @@
+package tokenfile
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+)
+
+type tokenFileMethod struct {
+	logger        log.Logger
+	tokenFilePath string
+	cachedToken   string
+}
+
+// NewTokenFileAuthMethod returns an auth method that uses an existing token
+// read from a file rather than performing a login
+func NewTokenFileAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	a := &tokenFileMethod{
+		logger: conf.Logger,
+	}
+
+	tokenFilePathRaw, ok := conf.Config["token_file_path"]
+	if !ok {
+		return nil, errors.New("missing 'token_file_path' value")
+	}
+	a.tokenFilePath, ok = tokenFilePathRaw.(string)
+	if !ok {
+		return nil, errors.New("could not convert 'token_file_path' config value to string")
+	}
+	if a.tokenFilePath == "" {
+		return nil, errors.New("'token_file_path' value is empty")
+	}
+
+	return a, nil
+}
+
+func (a *tokenFileMethod) Authenticate(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	a.logger.Trace("beginning authentication")
+
+	token, err := ioutil.ReadFile(a.tokenFilePath)
+	if err != nil {
+		if a.cachedToken == "" {
+			return nil, errwrap.Wrapf("error reading token file and no cached token available: {{err}}", err)
+		}
+		a.logger.Warn("error reading token file, using cached token", "error", err)
+	} else if trimmed := strings.TrimSpace(string(token)); trimmed != "" {
+		a.cachedToken = trimmed
+	}
+
+	if a.cachedToken == "" {
+		return nil, errors.New("token file empty and no cached token available")
+	}
+
+	client.SetToken(a.cachedToken)
+	lookup, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, errwrap.Wrapf("error looking up token: {{err}}", err)
+	}
+
+	return auth.SecretFromTokenLookup(lookup, a.cachedToken)
+}
+
+func (a *tokenFileMethod) NewCreds() chan struct{} {
+	return nil
+}
+
+func (a *tokenFileMethod) CredSuccess() {
+}
+
+func (a *tokenFileMethod) Shutdown() {
+}
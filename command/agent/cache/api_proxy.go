@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+
+	"github.com/hashicorp/errwrap"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// APIProxy is an implementation of the proxier interface that is used to
+// forward the request to Vault and get the response.
+type APIProxy struct {
+	client *api.Client
+	logger hclog.Logger
+}
+
+// APIProxyConfig is the configuration for NewAPIProxy
+type APIProxyConfig struct {
+	Client *api.Client
+	Logger hclog.Logger
+}
+
+// NewAPIProxy returns a proxier that forwards requests to the Vault server
+// the given client is configured for
+func NewAPIProxy(config *APIProxyConfig) (Proxier, error) {
+	if config.Client == nil {
+		return nil, errors.New("nil API client")
+	}
+	return &APIProxy{
+		client: config.Client,
+		logger: config.Logger,
+	}, nil
+}
+
+func (ap *APIProxy) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	client, err := ap.client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(req.Token)
+
+	// Any wrapping requested by the caller is carried in the forwarded
+	// headers, so don't let the client add its own
+	client.SetWrappingLookupFunc(func(string, string) string {
+		return ""
+	})
+
+	fwReq := client.NewRequest(req.Request.Method, req.Request.URL.Path)
+	fwReq.Params = req.Request.URL.Query()
+
+	fwReq.Headers = make(map[string][]string, len(req.Request.Header))
+	for k, v := range req.Request.Header {
+		if k == "X-Vault-Token" {
+			continue
+		}
+		fwReq.Headers[k] = v
+	}
+
+	// Set the body as decoded JSON where possible so that it can be replayed
+	// if the request is redirected
+	if len(req.RequestBody) > 0 {
+		var body interface{}
+		if err := jsonutil.DecodeJSON(req.RequestBody, &body); err == nil {
+			if err := fwReq.SetJSONBody(body); err != nil {
+				return nil, err
+			}
+		} else {
+			fwReq.Body = bytes.NewReader(req.RequestBody)
+			fwReq.BodySize = int64(len(req.RequestBody))
+		}
+	}
+
+	// Make the request to Vault and get the response. Error responses from
+	// Vault are passed back to the caller as-is.
+	ap.logger.Info("forwarding request", "path", req.Request.URL.Path, "method", req.Request.Method)
+	resp, err := client.RawRequest(fwReq)
+	if resp == nil {
+		if err == nil {
+			err = errors.New("nil response from Vault")
+		}
+		return nil, errwrap.Wrapf("error forwarding request: {{err}}", err)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading response body: {{err}}", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	return &SendResponse{
+		Response:     resp,
+		ResponseBody: respBody,
+	}, nil
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/helper/logging"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/vault"
+)
+
+func setupClusterAndAgent(t *testing.T, useAutoAuthToken bool) (*vault.TestCluster, *api.Client, sink.SinkReader, func()) {
+	t.Helper()
+
+	logger := logging.NewVaultLogger(hclog.Trace)
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		Logger: logger,
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	apiProxy, err := NewAPIProxy(&APIProxyConfig{
+		Client: client,
+		Logger: logger.Named("cache.apiproxy"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaseCache, err := NewLeaseCache(&LeaseCacheConfig{
+		BaseContext: ctx,
+		Proxier:     apiProxy,
+		Logger:      logger.Named("cache.leasecache"),
+		Client:      client,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inmemSink sink.SinkReader
+	if useAutoAuthToken {
+		inmemSink, err = inmem.New(&sink.SinkConfig{
+			Logger: logger,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", ProxyHandler(ctx, logger.Named("cache.handler"), leaseCache, inmemSink))
+	server := &http.Server{
+		Handler: mux,
+	}
+	go server.Serve(ln)
+
+	agentClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := agentClient.SetAddress("http://" + ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		cancelFunc()
+		ln.Close()
+		cluster.Cleanup()
+	}
+
+	return cluster, agentClient, inmemSink, cleanup
+}
+
+func TestCache_TokenCreate(t *testing.T) {
+	cluster, agentClient, _, cleanup := setupClusterAndAgent(t, false)
+	defer cleanup()
+
+	agentClient.SetToken(cluster.RootToken)
+
+	createToken := func(policies []string) string {
+		t.Helper()
+		secret, err := agentClient.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: policies,
+			TTL:      "1h",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret.Auth.ClientToken
+	}
+
+	// Identical requests are served from the cache
+	token1 := createToken([]string{"default"})
+	token2 := createToken([]string{"default"})
+	if token1 != token2 {
+		t.Fatalf("expected cached token, got %q and %q", token1, token2)
+	}
+
+	// A different request results in a new token
+	token3 := createToken([]string{"default", "other"})
+	if token3 == token1 {
+		t.Fatal("expected a new token for a different request")
+	}
+
+	// Responses without leases are not cached
+	mountsBefore, err := agentClient.Sys().ListMounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.Cores[0].Client.Sys().Mount("kv-test", &api.MountInput{Type: "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	mountsAfter, err := agentClient.Sys().ListMounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mountsAfter) != len(mountsBefore)+1 {
+		t.Fatalf("expected uncached mount listing, got %d then %d mounts", len(mountsBefore), len(mountsAfter))
+	}
+}
+
+func TestCache_Namespaces(t *testing.T) {
+	cluster, agentClient, _, cleanup := setupClusterAndAgent(t, false)
+	defer cleanup()
+
+	client := cluster.Cores[0].Client
+	for _, ns := range []string{"ns1", "ns2"} {
+		if _, err := client.Logical().Write("sys/namespaces/"+ns, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	agentClient.SetToken(cluster.RootToken)
+
+	createToken := func(ns string) string {
+		t.Helper()
+		agentClient.SetNamespace(ns)
+		defer agentClient.ClearNamespace()
+		secret, err := agentClient.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{"default"},
+			TTL:      "1h",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret.Auth.ClientToken
+	}
+
+	// Identical requests against different namespaces must not share a
+	// cache entry
+	token1 := createToken("ns1")
+	token2 := createToken("ns2")
+	if token1 == token2 {
+		t.Fatalf("expected distinct tokens per namespace, got %q twice", token1)
+	}
+
+	// Repeating the request in the same namespace is served from the cache
+	if token := createToken("ns1"); token != token1 {
+		t.Fatalf("expected cached token %q, got %q", token1, token)
+	}
+}
+
+func TestCache_AutoAuthToken(t *testing.T) {
+	cluster, agentClient, inmemSink, cleanup := setupClusterAndAgent(t, true)
+	defer cleanup()
+
+	if err := inmemSink.WriteToken(cluster.RootToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests without a token use the auto-auth token
+	agentClient.ClearToken()
+	secret, err := agentClient.Auth().Token().LookupSelf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["id"] != cluster.RootToken {
+		t.Fatalf("expected auto-auth token to be used, got %#v", secret.Data)
+	}
+
+	// Requests with a token keep their own
+	agentClient.SetToken("invalid")
+	if _, err := agentClient.Auth().Token().LookupSelf(); err == nil {
+		t.Fatal("expected error using invalid token")
+	}
+}
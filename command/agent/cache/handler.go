@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// ProxyHandler returns a handler that passes requests received by the agent
+// to the given proxier. If inmemSink is set, its token is attached to any
+// request that does not carry one of its own.
+func ProxyHandler(ctx context.Context, logger hclog.Logger, proxier Proxier, inmemSink sink.SinkReader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("received request", "path", r.URL.Path, "method", r.Method)
+
+		token := r.Header.Get("X-Vault-Token")
+		if token == "" && inmemSink != nil {
+			logger.Debug("using auto auth token", "path", r.URL.Path, "method", r.Method)
+			token = inmemSink.Token()
+		}
+
+		reqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to read request body: {{err}}", err))
+			return
+		}
+		if r.Body != nil {
+			r.Body.Close()
+		}
+
+		resp, err := proxier.Send(ctx, &SendRequest{
+			Token:       token,
+			Request:     r,
+			RequestBody: reqBody,
+		})
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, errwrap.Wrapf("failed to get the response: {{err}}", err))
+			return
+		}
+
+		copyHeader(w.Header(), resp.Response.Header)
+		w.WriteHeader(resp.Response.StatusCode)
+		w.Write(resp.ResponseBody)
+	})
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, vv := range src {
+		for _, v := range vv {
+			dst.Add(k, v)
+		}
+	}
+}
+
+func respondError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	body, _ := jsonutil.EncodeJSON(map[string][]string{
+		"errors": []string{fmt.Sprintf("%s", err)},
+	})
+	w.Write(body)
+}
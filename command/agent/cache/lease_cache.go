@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// LeaseCache is an implementation of Proxier that handles the caching of
+// responses. It passes the incoming request to an underlying Proxier
+// implementation. Responses that carry a lease or a token are cached and kept
+// renewed until they can no longer be renewed, at which point they are
+// evicted.
+type LeaseCache struct {
+	proxier Proxier
+	logger  hclog.Logger
+	client  *api.Client
+	baseCtx context.Context
+
+	l       sync.RWMutex
+	entries map[string]*cacheEntry
+}
+
+// LeaseCacheConfig is the configuration for initializing a new LeaseCache.
+type LeaseCacheConfig struct {
+	BaseContext context.Context
+	Proxier     Proxier
+	Logger      hclog.Logger
+	Client      *api.Client
+}
+
+// cacheEntry is a cached response along with the means to stop renewing it
+type cacheEntry struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+	cancel     context.CancelFunc
+}
+
+// NewLeaseCache creates a new instance of a LeaseCache.
+func NewLeaseCache(conf *LeaseCacheConfig) (*LeaseCache, error) {
+	if conf == nil {
+		return nil, errors.New("nil configuration provided")
+	}
+	if conf.Proxier == nil || conf.Logger == nil {
+		return nil, errors.New("missing configuration required params")
+	}
+	if conf.Client == nil {
+		return nil, errors.New("nil API client")
+	}
+
+	baseCtx := conf.BaseContext
+	if baseCtx == nil {
+		baseCtx = context.Background()
+	}
+
+	return &LeaseCache{
+		proxier: conf.Proxier,
+		logger:  conf.Logger,
+		client:  conf.Client,
+		baseCtx: baseCtx,
+		entries: make(map[string]*cacheEntry),
+	}, nil
+}
+
+// Send performs a cache lookup on the incoming request. If it's a cache hit,
+// it will return the cached response, otherwise it will delegate to the
+// underlying Proxier and cache the received response if it carries a lease
+// or a token.
+func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	key := computeCacheKey(req)
+
+	c.l.RLock()
+	entry, ok := c.entries[key]
+	c.l.RUnlock()
+	if ok {
+		c.logger.Debug("returning cached response", "path", req.Request.URL.Path)
+		return entry.sendResponse(), nil
+	}
+
+	resp, err := c.proxier.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Response.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	var secret api.Secret
+	if err := jsonutil.DecodeJSON(resp.ResponseBody, &secret); err != nil {
+		// Not every response is a secret, so there is nothing to cache
+		return resp, nil
+	}
+
+	var renewSecret *api.Secret
+	var renewToken string
+	switch {
+	case secret.Auth != nil && secret.Auth.ClientToken != "":
+		renewSecret = &api.Secret{Auth: secret.Auth}
+		renewToken = secret.Auth.ClientToken
+	case secret.LeaseID != "" && secret.LeaseDuration > 0:
+		renewSecret = &api.Secret{
+			LeaseID:       secret.LeaseID,
+			LeaseDuration: secret.LeaseDuration,
+			Renewable:     secret.Renewable,
+		}
+		renewToken = req.Token
+	default:
+		return resp, nil
+	}
+
+	renewCtx, cancel := context.WithCancel(c.baseCtx)
+	entry = &cacheEntry{
+		statusCode: resp.Response.StatusCode,
+		header:     resp.Response.Header,
+		body:       resp.ResponseBody,
+		cancel:     cancel,
+	}
+
+	c.l.Lock()
+	if existing, ok := c.entries[key]; ok {
+		existing.cancel()
+	}
+	c.entries[key] = entry
+	c.l.Unlock()
+
+	c.logger.Debug("caching response", "path", req.Request.URL.Path)
+	go c.renew(renewCtx, key, entry, renewSecret, renewToken, req.Request.Header.Get(consts.NamespaceHeaderName))
+
+	return resp, nil
+}
+
+// renew keeps the lease or token of a cached response alive, evicting the
+// response from the cache once it can no longer be renewed. Renewals are made
+// against the namespace the secret was issued in.
+func (c *LeaseCache) renew(ctx context.Context, key string, entry *cacheEntry, secret *api.Secret, token, namespace string) {
+	defer c.evict(key, entry)
+
+	// Leases and tokens that cannot be renewed are kept until they expire,
+	// or for as long as they live if they have no TTL
+	renewable := secret.Renewable
+	ttl := secret.LeaseDuration
+	if secret.Auth != nil {
+		renewable = secret.Auth.Renewable
+		ttl = secret.Auth.LeaseDuration
+	}
+	if !renewable {
+		var expiryCh <-chan time.Time
+		if ttl > 0 {
+			expiryCh = time.After(time.Duration(ttl) * time.Second)
+		}
+		select {
+		case <-ctx.Done():
+		case <-expiryCh:
+		}
+		return
+	}
+
+	client, err := c.client.Clone()
+	if err != nil {
+		c.logger.Error("error creating client for renewal", "error", err)
+		return
+	}
+	client.SetToken(token)
+	client.SetNamespace(namespace)
+
+	renewer, err := client.NewRenewer(&api.RenewerInput{
+		Secret: secret,
+	})
+	if err != nil {
+		c.logger.Error("error creating renewer", "error", err)
+		return
+	}
+	go renewer.Renew()
+	defer renewer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-renewer.DoneCh():
+			if err != nil {
+				c.logger.Debug("renewal halted, evicting from cache", "error", err)
+			}
+			return
+		case <-renewer.RenewCh():
+			c.logger.Trace("renewed cached secret")
+		}
+	}
+}
+
+func (c *LeaseCache) evict(key string, entry *cacheEntry) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	// The entry may already have been replaced by a newer response
+	if c.entries[key] == entry {
+		delete(c.entries, key)
+	}
+}
+
+func (e *cacheEntry) sendResponse() *SendResponse {
+	header := make(http.Header, len(e.header))
+	for k, v := range e.header {
+		header[k] = v
+	}
+
+	return &SendResponse{
+		Response: &api.Response{
+			Response: &http.Response{
+				StatusCode: e.statusCode,
+				Header:     header,
+				Body:       ioutil.NopCloser(bytes.NewReader(e.body)),
+			},
+		},
+		ResponseBody: e.body,
+	}
+}
+
+// computeCacheKey results in a value that uniquely identifies a request
+// received by the agent. It does so by SHA256 hashing the method, path,
+// query, body, namespace and token of the request.
+func computeCacheKey(req *SendRequest) string {
+	h := sha256.New()
+	h.Write([]byte(req.Request.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Request.URL.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Request.URL.RawQuery))
+	h.Write([]byte{0})
+	h.Write(req.RequestBody)
+	h.Write([]byte{0})
+	h.Write([]byte(req.Request.Header.Get(consts.NamespaceHeaderName)))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Token))
+	return hex.EncodeToString(h.Sum(nil))
+}
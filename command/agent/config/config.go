@@ -0,0 +1,529 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// Config is the configuration for the vault agent.
+type Config struct {
+	AutoAuth      *AutoAuth   `hcl:"-"`
+	ExitAfterAuth bool        `hcl:"exit_after_auth"`
+	PidFile       string      `hcl:"pid_file"`
+	Vault         *Vault      `hcl:"-"`
+	Cache         *Cache      `hcl:"-"`
+	Listeners     []*Listener `hcl:"-"`
+	Templates     []*Template `hcl:"-"`
+}
+
+// Vault contains configuration for connecting to the Vault server
+type Vault struct {
+	Address          string      `hcl:"address"`
+	CACert           string      `hcl:"ca_cert"`
+	CAPath           string      `hcl:"ca_path"`
+	TLSSkipVerify    bool        `hcl:"-"`
+	TLSSkipVerifyRaw interface{} `hcl:"tls_skip_verify"`
+	ClientCert       string      `hcl:"client_cert"`
+	ClientKey        string      `hcl:"client_key"`
+}
+
+// AutoAuth is the configured authentication method and sinks
+type AutoAuth struct {
+	Method *Method `hcl:"-"`
+	Sinks  []*Sink `hcl:"-"`
+}
+
+// Method represents the configuration for the authentication backend
+type Method struct {
+	Type      string
+	MountPath string                 `hcl:"mount_path"`
+	Config    map[string]interface{} `hcl:"config"`
+}
+
+// Sink defines a location to write the authenticated token
+type Sink struct {
+	Type       string
+	WrapTTLRaw interface{}            `hcl:"wrap_ttl"`
+	WrapTTL    time.Duration          `hcl:"-"`
+	DHType     string                 `hcl:"dh_type"`
+	DHPath     string                 `hcl:"dh_path"`
+	AAD        string                 `hcl:"aad"`
+	AADEnvVar  string                 `hcl:"aad_env_var"`
+	Config     map[string]interface{} `hcl:"config"`
+}
+
+// Cache contains the configuration for the response caching proxy
+type Cache struct {
+	UseAutoAuthToken bool `hcl:"use_auto_auth_token"`
+}
+
+// Template is a template rendered using the auto-auth token
+type Template struct {
+	Source            string        `hcl:"source"`
+	Contents          string        `hcl:"contents"`
+	Destination       string        `hcl:"destination"`
+	Command           string        `hcl:"command"`
+	CommandTimeoutRaw interface{}   `hcl:"command_timeout"`
+	CommandTimeout    time.Duration `hcl:"-"`
+	PermsRaw          interface{}   `hcl:"perms"`
+	Perms             os.FileMode   `hcl:"-"`
+}
+
+// Listener is a listener the caching proxy serves requests on
+type Listener struct {
+	Type   string
+	Config map[string]interface{}
+}
+
+// LoadConfig loads the configuration at the given path
+func LoadConfig(path string) (*Config, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return nil, fmt.Errorf("location is a directory, not a file")
+	}
+
+	// Read the file
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(string(d))
+}
+
+// ParseConfig parses the given configuration string
+func ParseConfig(d string) (*Config, error) {
+	// Parse!
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start building the result
+	var result Config
+	if err := hcl.DecodeObject(&result, obj); err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	valid := []string{
+		"auto_auth",
+		"exit_after_auth",
+		"pid_file",
+		"vault",
+		"cache",
+		"listener",
+		"template",
+	}
+	if err := checkHCLKeys(list, valid); err != nil {
+		return nil, err
+	}
+
+	if err := parseVault(&result, list); err != nil {
+		return nil, errwrap.Wrapf("error parsing 'vault': {{err}}", err)
+	}
+
+	if err := parseCache(&result, list); err != nil {
+		return nil, errwrap.Wrapf("error parsing 'cache': {{err}}", err)
+	}
+
+	if err := parseAutoAuth(&result, list); err != nil {
+		return nil, errwrap.Wrapf("error parsing 'auto_auth': {{err}}", err)
+	}
+
+	if err := parseListeners(&result, list); err != nil {
+		return nil, errwrap.Wrapf("error parsing 'listener': {{err}}", err)
+	}
+
+	if err := parseTemplates(&result, list); err != nil {
+		return nil, errwrap.Wrapf("error parsing 'template': {{err}}", err)
+	}
+
+	switch {
+	case result.AutoAuth == nil && result.Cache == nil:
+		return nil, errors.New("at least one of 'auto_auth' or 'cache' must be configured")
+	case result.Cache != nil && len(result.Listeners) == 0:
+		return nil, errors.New("at least one 'listener' must be configured when 'cache' is enabled")
+	case result.Cache == nil && len(result.Listeners) > 0:
+		return nil, errors.New("'listener' can only be used when 'cache' is enabled")
+	case result.Cache != nil && result.Cache.UseAutoAuthToken && result.AutoAuth == nil:
+		return nil, errors.New("'use_auto_auth_token' requires 'auto_auth' to be configured")
+	case result.ExitAfterAuth && result.AutoAuth == nil:
+		return nil, errors.New("'exit_after_auth' requires 'auto_auth' to be configured")
+	case len(result.Templates) > 0 && result.AutoAuth == nil:
+		return nil, errors.New("'template' requires 'auto_auth' to be configured")
+	case result.AutoAuth != nil && len(result.AutoAuth.Sinks) == 0 && len(result.Templates) == 0 &&
+		(result.Cache == nil || !result.Cache.UseAutoAuthToken):
+		return nil, errors.New("'auto_auth' requires at least one 'sink' unless its token is used by the cache or a template")
+	}
+
+	return &result, nil
+}
+
+func parseVault(result *Config, list *ast.ObjectList) error {
+	name := "vault"
+
+	vaultList := list.Filter(name)
+	if len(vaultList.Items) == 0 {
+		return nil
+	}
+	if len(vaultList.Items) > 1 {
+		return fmt.Errorf("one and only one %q block is required", name)
+	}
+
+	item := vaultList.Items[0]
+
+	valid := []string{
+		"address",
+		"ca_cert",
+		"ca_path",
+		"tls_skip_verify",
+		"client_cert",
+		"client_key",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var v Vault
+	if err := hcl.DecodeObject(&v, item.Val); err != nil {
+		return err
+	}
+
+	if v.TLSSkipVerifyRaw != nil {
+		var err error
+		if v.TLSSkipVerify, err = parseutil.ParseBool(v.TLSSkipVerifyRaw); err != nil {
+			return err
+		}
+	}
+
+	result.Vault = &v
+	return nil
+}
+
+func parseAutoAuth(result *Config, list *ast.ObjectList) error {
+	name := "auto_auth"
+
+	autoAuthList := list.Filter(name)
+	if len(autoAuthList.Items) == 0 {
+		return nil
+	}
+	if len(autoAuthList.Items) > 1 {
+		return fmt.Errorf("one and only one %q block is required", name)
+	}
+
+	// Get our item
+	item := autoAuthList.Items[0]
+
+	valid := []string{
+		"method",
+		"sink",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var a AutoAuth
+	if err := hcl.DecodeObject(&a, item.Val); err != nil {
+		return err
+	}
+
+	result.AutoAuth = &a
+
+	subs, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("could not parse %q as an object", name)
+	}
+	subList := subs.List
+
+	if err := parseMethod(result, subList); err != nil {
+		return errwrap.Wrapf("error parsing 'method': {{err}}", err)
+	}
+
+	if err := parseSinks(result, subList); err != nil {
+		return errwrap.Wrapf("error parsing 'sink' stanzas: {{err}}", err)
+	}
+
+	return nil
+}
+
+func parseMethod(result *Config, list *ast.ObjectList) error {
+	name := "method"
+
+	methodList := list.Filter(name)
+	if len(methodList.Items) != 1 {
+		return fmt.Errorf("one and only one %q block is required", name)
+	}
+
+	// Get our item
+	item := methodList.Items[0]
+
+	valid := []string{
+		"mount_path",
+		"config",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var m Method
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return err
+	}
+
+	if len(item.Keys) != 1 {
+		return errors.New("method type must be specified")
+	}
+	m.Type = strings.ToLower(item.Keys[0].Token.Value().(string))
+
+	// Default to Vault's default
+	if m.MountPath == "" {
+		m.MountPath = fmt.Sprintf("auth/%s", m.Type)
+	}
+	// Standardize on no trailing slash
+	m.MountPath = strings.TrimSuffix(m.MountPath, "/")
+
+	result.AutoAuth.Method = &m
+	return nil
+}
+
+func parseSinks(result *Config, list *ast.ObjectList) error {
+	name := "sink"
+
+	sinkList := list.Filter(name)
+	if len(sinkList.Items) < 1 {
+		return nil
+	}
+
+	var ts []*Sink
+
+	for _, item := range sinkList.Items {
+		valid := []string{
+			"wrap_ttl",
+			"dh_type",
+			"dh_path",
+			"aad",
+			"aad_env_var",
+			"config",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+		}
+
+		var s Sink
+		if err := hcl.DecodeObject(&s, item.Val); err != nil {
+			return err
+		}
+
+		if len(item.Keys) != 1 {
+			return errors.New("sink type must be specified")
+		}
+		s.Type = strings.ToLower(item.Keys[0].Token.Value().(string))
+
+		if s.WrapTTLRaw != nil {
+			var err error
+			if s.WrapTTL, err = parseutil.ParseDurationSecond(s.WrapTTLRaw); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("sink.%s", s.Type))
+			}
+			s.WrapTTLRaw = nil
+		}
+
+		switch s.DHType {
+		case "":
+		case "curve25519":
+		default:
+			return multierror.Prefix(errors.New("invalid value for 'dh_type'"), fmt.Sprintf("sink.%s", s.Type))
+		}
+
+		if s.AADEnvVar != "" {
+			s.AAD = os.Getenv(s.AADEnvVar)
+			s.AADEnvVar = ""
+		}
+
+		switch {
+		case s.DHPath == "" && s.DHType == "":
+			if s.AAD != "" {
+				return multierror.Prefix(errors.New("specifying AAD data without 'dh_type' does not make sense"), fmt.Sprintf("sink.%s", s.Type))
+			}
+		case s.DHPath != "" && s.DHType != "":
+		default:
+			return multierror.Prefix(errors.New("'dh_type' and 'dh_path' must be specified together"), fmt.Sprintf("sink.%s", s.Type))
+		}
+
+		ts = append(ts, &s)
+	}
+
+	result.AutoAuth.Sinks = ts
+	return nil
+}
+
+func parseCache(result *Config, list *ast.ObjectList) error {
+	name := "cache"
+
+	cacheList := list.Filter(name)
+	if len(cacheList.Items) == 0 {
+		return nil
+	}
+	if len(cacheList.Items) > 1 {
+		return fmt.Errorf("one and only one %q block is required", name)
+	}
+
+	item := cacheList.Items[0]
+
+	valid := []string{
+		"use_auto_auth_token",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var c Cache
+	if err := hcl.DecodeObject(&c, item.Val); err != nil {
+		return err
+	}
+
+	result.Cache = &c
+	return nil
+}
+
+func parseListeners(result *Config, list *ast.ObjectList) error {
+	name := "listener"
+
+	listenerList := list.Filter(name)
+
+	var listeners []*Listener
+	for _, item := range listenerList.Items {
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		if len(item.Keys) != 1 {
+			return errors.New("listener type must be specified")
+		}
+		lnType := strings.ToLower(item.Keys[0].Token.Value().(string))
+
+		switch lnType {
+		case "tcp", "unix":
+		default:
+			return fmt.Errorf("invalid listener type %q", lnType)
+		}
+
+		listeners = append(listeners, &Listener{
+			Type:   lnType,
+			Config: m,
+		})
+	}
+
+	result.Listeners = listeners
+	return nil
+}
+
+func parseTemplates(result *Config, list *ast.ObjectList) error {
+	name := "template"
+
+	templateList := list.Filter(name)
+
+	var templates []*Template
+	for _, item := range templateList.Items {
+		valid := []string{
+			"source",
+			"contents",
+			"destination",
+			"command",
+			"command_timeout",
+			"perms",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+		}
+
+		var t Template
+		if err := hcl.DecodeObject(&t, item.Val); err != nil {
+			return err
+		}
+
+		switch {
+		case t.Source == "" && t.Contents == "":
+			return errors.New("one of 'source' or 'contents' must be specified")
+		case t.Source != "" && t.Contents != "":
+			return errors.New("only one of 'source' or 'contents' can be specified")
+		case t.Destination == "":
+			return errors.New("'destination' must be specified")
+		}
+
+		t.CommandTimeout = 30 * time.Second
+		if t.CommandTimeoutRaw != nil {
+			var err error
+			if t.CommandTimeout, err = parseutil.ParseDurationSecond(t.CommandTimeoutRaw); err != nil {
+				return errwrap.Wrapf("error parsing 'command_timeout': {{err}}", err)
+			}
+			t.CommandTimeoutRaw = nil
+		}
+
+		t.Perms = 0644
+		if t.PermsRaw != nil {
+			permsStr, ok := t.PermsRaw.(string)
+			if !ok {
+				return errors.New("'perms' must be an octal string")
+			}
+			perms, err := strconv.ParseUint(permsStr, 8, 32)
+			if err != nil {
+				return errwrap.Wrapf("error parsing 'perms': {{err}}", err)
+			}
+			t.Perms = os.FileMode(perms)
+			t.PermsRaw = nil
+		}
+
+		templates = append(templates, &t)
+	}
+
+	result.Templates = templates
+	return nil
+}
+
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf("invalid key %q on line %d", key, item.Assign.Line))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	if err := os.Setenv("TEST_AAD_ENV", "aad"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("TEST_AAD_ENV")
+
+	config, err := LoadConfig("./test-fixtures/config.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		AutoAuth: &AutoAuth{
+			Method: &Method{
+				Type:      "approle",
+				MountPath: "auth/approle-custom",
+				Config: map[string]interface{}{
+					"role_id_file_path":   "/tmp/role-id",
+					"secret_id_file_path": "/tmp/secret-id",
+				},
+			},
+			Sinks: []*Sink{
+				&Sink{
+					Type: "file",
+					Config: map[string]interface{}{
+						"path": "/tmp/file-foo",
+					},
+				},
+				&Sink{
+					Type:    "file",
+					WrapTTL: 5 * time.Minute,
+					DHType:  "curve25519",
+					DHPath:  "/tmp/file-foo-dhpath2",
+					AAD:     "aad",
+					Config: map[string]interface{}{
+						"path": "/tmp/file-bar",
+					},
+				},
+			},
+		},
+		Templates: []*Template{
+			&Template{
+				Source:         "/etc/vault/app.conf.tpl",
+				Destination:    "/etc/app/app.conf",
+				Command:        "systemctl reload app",
+				CommandTimeout: 10 * time.Second,
+				Perms:          0600,
+			},
+			&Template{
+				Contents:       `{{ with secret "secret/foo" }}{{ .Data.bar }}{{ end }}`,
+				Destination:    "/etc/app/bar",
+				CommandTimeout: 30 * time.Second,
+				Perms:          0644,
+			},
+		},
+		PidFile: "./pidfile",
+		Vault: &Vault{
+			Address:          "http://127.0.0.1:8200",
+			TLSSkipVerify:    true,
+			TLSSkipVerifyRaw: "true",
+		},
+	}
+
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("bad config:\n%#v\n%#v", config.AutoAuth, expected.AutoAuth)
+	}
+}
+
+func TestLoadConfigFile_Cache(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-cache.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		AutoAuth: &AutoAuth{
+			Method: &Method{
+				Type:      "aws",
+				MountPath: "auth/aws",
+				Config: map[string]interface{}{
+					"role": "foobar",
+				},
+			},
+		},
+		Cache: &Cache{
+			UseAutoAuthToken: true,
+		},
+		Listeners: []*Listener{
+			&Listener{
+				Type: "tcp",
+				Config: map[string]interface{}{
+					"address":     "127.0.0.1:8300",
+					"tls_disable": true,
+				},
+			},
+			&Listener{
+				Type: "unix",
+				Config: map[string]interface{}{
+					"address":     "/path/to/socket",
+					"tls_disable": true,
+				},
+			},
+		},
+		PidFile: "./pidfile",
+	}
+
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("bad config:\n%#v\n%#v", config, expected)
+	}
+}
+
+func TestLoadConfigFile_Bad(t *testing.T) {
+	cases := map[string]string{
+		"bad-config-dh-no-path.hcl":        "'dh_type' and 'dh_path' must be specified together",
+		"bad-config-listener-no-cache.hcl": "'listener' can only be used when 'cache' is enabled",
+	}
+
+	for file, expected := range cases {
+		_, err := LoadConfig("./test-fixtures/" + file)
+		if err == nil {
+			t.Fatalf("%s: expected error", file)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%s: unexpected error: %v", file, err)
+		}
+	}
+}
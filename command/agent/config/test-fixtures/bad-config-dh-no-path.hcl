@@ -0,0 +1,14 @@
+auto_auth {
+	method "approle" {
+		config = {
+			role_id_file_path = "/tmp/role-id"
+		}
+	}
+
+	sink "file" {
+		dh_type = "curve25519"
+		config = {
+			path = "/tmp/file-foo"
+		}
+	}
+}
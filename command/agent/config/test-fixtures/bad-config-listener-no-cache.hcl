@@ -0,0 +1,17 @@
+auto_auth {
+	method "approle" {
+		config = {
+			role_id_file_path = "/tmp/role-id"
+		}
+	}
+
+	sink "file" {
+		config = {
+			path = "/tmp/file-foo"
+		}
+	}
+}
+
+listener "tcp" {
+	address = "127.0.0.1:8300"
+}
@@ -0,0 +1,11 @@
+auto_auth {
+	method "approle" {
+		config = {
+			role_id_file_path = "/tmp/role-id"
+		}
+	}
+}
+
+template {
+	source = "/tmp/foo.tpl"
+}
@@ -0,0 +1,23 @@
+pid_file = "./pidfile"
+
+auto_auth {
+	method "aws" {
+		config = {
+			role = "foobar"
+		}
+	}
+}
+
+cache {
+	use_auto_auth_token = true
+}
+
+listener "tcp" {
+	address = "127.0.0.1:8300"
+	tls_disable = true
+}
+
+listener "unix" {
+	address = "/path/to/socket"
+	tls_disable = true
+}
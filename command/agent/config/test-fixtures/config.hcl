@@ -0,0 +1,45 @@
+pid_file = "./pidfile"
+
+auto_auth {
+	method "approle" {
+		mount_path = "auth/approle-custom"
+		config = {
+			role_id_file_path = "/tmp/role-id"
+			secret_id_file_path = "/tmp/secret-id"
+		}
+	}
+
+	sink "file" {
+		config = {
+			path = "/tmp/file-foo"
+		}
+	}
+
+	sink "file" {
+		wrap_ttl = "5m"
+		aad_env_var = "TEST_AAD_ENV"
+		dh_type = "curve25519"
+		dh_path = "/tmp/file-foo-dhpath2"
+		config = {
+			path = "/tmp/file-bar"
+		}
+	}
+}
+
+vault {
+	address = "http://127.0.0.1:8200"
+	tls_skip_verify = "true"
+}
+
+template {
+	source = "/etc/vault/app.conf.tpl"
+	destination = "/etc/app/app.conf"
+	command = "systemctl reload app"
+	command_timeout = "10s"
+	perms = "0600"
+}
+
+template {
+	contents = "{{ with secret \"secret/foo\" }}{{ .Data.bar }}{{ end }}"
+	destination = "/etc/app/bar"
+}
@@ -0,0 +1,39 @@
+package inmem
+
+import (
+	"errors"
+	"sync/atomic"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/sink"
+)
+
+// inmemSink retains the auto-auth token in memory so that it can be attached
+// to requests passing through the agent's cache
+type inmemSink struct {
+	logger log.Logger
+	token  atomic.Value
+}
+
+// New creates a new in-memory sink
+func New(conf *sink.SinkConfig) (sink.SinkReader, error) {
+	if conf.Logger == nil {
+		return nil, errors.New("nil logger provided")
+	}
+
+	s := &inmemSink{
+		logger: conf.Logger,
+	}
+	s.token.Store("")
+
+	return s, nil
+}
+
+func (s *inmemSink) WriteToken(token string) error {
+	s.token.Store(token)
+	return nil
+}
+
+func (s *inmemSink) Token() string {
+	return s.token.Load().(string)
+}
@@ -0,0 +1,257 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/dhutil"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// Sink is a location the agent writes tokens to
+type Sink interface {
+	WriteToken(string) error
+}
+
+// SinkConfig wraps a sink along with the options controlling how tokens are
+// transformed before being written to it
+type SinkConfig struct {
+	Sink
+	Logger  log.Logger
+	Config  map[string]interface{}
+	Client  *api.Client
+	WrapTTL time.Duration
+	DHType  string
+	DHPath  string
+	AAD     string
+
+	cachedRemotePubKey []byte
+	cachedPubKey       []byte
+	cachedPriKey       []byte
+}
+
+// SinkServer writes tokens received from the auth handler to the configured
+// sinks
+type SinkServer struct {
+	DoneCh        chan struct{}
+	logger        log.Logger
+	client        *api.Client
+	random        *rand.Rand
+	exitAfterAuth bool
+	remaining     *int32
+}
+
+// SinkServerConfig is the configuration for NewSinkServer
+type SinkServerConfig struct {
+	Logger        log.Logger
+	Client        *api.Client
+	ExitAfterAuth bool
+}
+
+// NewSinkServer returns a new sink server
+func NewSinkServer(conf *SinkServerConfig) *SinkServer {
+	ss := &SinkServer{
+		DoneCh:        make(chan struct{}),
+		logger:        conf.Logger,
+		client:        conf.Client,
+		random:        rand.New(rand.NewSource(int64(time.Now().Nanosecond()))),
+		exitAfterAuth: conf.ExitAfterAuth,
+		remaining:     new(int32),
+	}
+
+	return ss
+}
+
+// Run writes each token received on incoming to all of the given sinks,
+// retrying failed writes with backoff, until the context is canceled or the
+// incoming channel is closed. If the server was configured to exit after
+// auth it returns once every sink has been written once.
+func (ss *SinkServer) Run(ctx context.Context, incoming chan string, sinks []*SinkConfig) {
+	if incoming == nil {
+		panic("incoming channel is nil")
+	}
+
+	ss.logger.Info("starting sink server")
+	defer func() {
+		ss.logger.Info("sink server stopped")
+		close(ss.DoneCh)
+	}()
+
+	latestToken := new(string)
+	sinkCh := make(chan func() error, len(sinks))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case token, ok := <-incoming:
+			if !ok {
+				return
+			}
+
+			if token != *latestToken {
+				// Drop any pending writes for the previous token
+			Drain:
+				for {
+					select {
+					case <-sinkCh:
+						atomic.AddInt32(ss.remaining, -1)
+					default:
+						break Drain
+					}
+				}
+
+				*latestToken = token
+
+				sinkFuncs := make([]func() error, 0, len(sinks))
+				for _, s := range sinks {
+					sc := s
+					sinkFuncs = append(sinkFuncs, func() error {
+						if *latestToken != token {
+							return nil
+						}
+
+						out := token
+						var err error
+						if sc.WrapTTL != 0 {
+							if out, err = sc.wrapToken(ss.client, sc.WrapTTL, out); err != nil {
+								return err
+							}
+						}
+
+						if sc.DHType != "" {
+							if out, err = sc.encryptToken(out); err != nil {
+								return err
+							}
+						}
+
+						return sc.WriteToken(out)
+					})
+				}
+
+				for _, f := range sinkFuncs {
+					atomic.AddInt32(ss.remaining, 1)
+					sinkCh <- f
+				}
+			}
+
+		case sinkFunc := <-sinkCh:
+			atomic.AddInt32(ss.remaining, -1)
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if err := sinkFunc(); err != nil {
+				backoff := 2*time.Second + time.Duration(ss.random.Int63()%int64(time.Second*2)-int64(time.Second))
+				ss.logger.Error("error returned by sink function, retrying", "error", err, "backoff", backoff.String())
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+					atomic.AddInt32(ss.remaining, 1)
+					sinkCh <- sinkFunc
+				}
+			} else if atomic.LoadInt32(ss.remaining) == 0 && ss.exitAfterAuth {
+				return
+			}
+		}
+	}
+}
+
+func (s *SinkConfig) encryptToken(token string) (string, error) {
+	if s.DHType != "curve25519" {
+		return "", errors.New("unsupported diffie-hellman type")
+	}
+
+	fileBytes, err := ioutil.ReadFile(s.DHPath)
+	if err != nil {
+		return "", errwrap.Wrapf("error reading file for dh parameters: {{err}}", err)
+	}
+	theirPubKey := new(dhutil.PublicKeyInfo)
+	if err := jsonutil.DecodeJSON(fileBytes, theirPubKey); err != nil {
+		return "", errwrap.Wrapf("error decoding public key: {{err}}", err)
+	}
+	if len(theirPubKey.Curve25519PublicKey) == 0 {
+		return "", errors.New("public key is nil")
+	}
+	// If the remote public key has changed, generate a fresh key pair so
+	// that a shared key is never reused across remote keys
+	if string(theirPubKey.Curve25519PublicKey) != string(s.cachedRemotePubKey) {
+		s.cachedRemotePubKey = theirPubKey.Curve25519PublicKey
+		s.cachedPubKey, s.cachedPriKey, err = dhutil.GeneratePublicPrivateKey()
+		if err != nil {
+			return "", errwrap.Wrapf("error generating pub/pri curve25519 keys: {{err}}", err)
+		}
+	}
+	// The local public key is always included in the AAD so that it cannot
+	// be swapped out in transit
+	aadPrefix := string(s.cachedPubKey)
+
+	aesKey, err := dhutil.GenerateSharedKey(s.cachedPriKey, s.cachedRemotePubKey)
+	if err != nil {
+		return "", errwrap.Wrapf("error deriving shared key: {{err}}", err)
+	}
+	if len(aesKey) == 0 {
+		return "", errors.New("derived AES key is empty")
+	}
+
+	resp := &dhutil.Envelope{
+		Curve25519PublicKey: s.cachedPubKey,
+	}
+	resp.EncryptedPayload, resp.Nonce, err = dhutil.EncryptAES(aesKey, []byte(token), []byte(aadPrefix+s.AAD))
+	if err != nil {
+		return "", errwrap.Wrapf("error encrypting with shared key: {{err}}", err)
+	}
+	m, err := jsonutil.EncodeJSON(resp)
+	if err != nil {
+		return "", errwrap.Wrapf("error encoding encrypted payload: {{err}}", err)
+	}
+
+	return string(m), nil
+}
+
+func (s *SinkConfig) wrapToken(client *api.Client, wrapTTL time.Duration, token string) (string, error) {
+	wrapClient, err := client.Clone()
+	if err != nil {
+		return "", errwrap.Wrapf("error deriving client for wrapping, not writing out to sink: {{err}}", err)
+	}
+	wrapClient.SetToken(token)
+	wrapClient.SetWrappingLookupFunc(func(string, string) string {
+		return wrapTTL.String()
+	})
+	secret, err := wrapClient.Logical().Write("sys/wrapping/wrap", map[string]interface{}{
+		"token": token,
+	})
+	if err != nil {
+		return "", errwrap.Wrapf("error wrapping token, not writing out to sink: {{err}}", err)
+	}
+	if secret == nil {
+		return "", errors.New("nil secret returned, not writing out to sink")
+	}
+	if secret.WrapInfo == nil {
+		return "", errors.New("nil wrap info returned, not writing out to sink")
+	}
+
+	m, err := jsonutil.EncodeJSON(secret.WrapInfo)
+	if err != nil {
+		return "", errwrap.Wrapf("error marshaling token, not writing out to sink: {{err}}", err)
+	}
+
+	return string(m), nil
+}
+
+// SinkReader is a sink that the most recently written token can be read back
+// from
+type SinkReader interface {
+	Sink
+	Token() string
+}
@@ -0,0 +1,467 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// Verify Server satisfies the correct interfaces
+var _ sink.Sink = (*Server)(nil)
+
+// Server renders the configured templates using the auto-auth token. It is
+// used as a sink so that it is handed every new token.
+type Server struct {
+	DoneCh          chan struct{}
+	logger          log.Logger
+	client          *api.Client
+	exitAfterRender bool
+	tokenCh         chan string
+	random          *rand.Rand
+}
+
+// ServerConfig is the configuration for NewServer
+type ServerConfig struct {
+	Logger log.Logger
+	Client *api.Client
+
+	// ExitAfterRender causes Run to return once every template has been
+	// rendered successfully for the first time
+	ExitAfterRender bool
+}
+
+// NewServer returns a new template server
+func NewServer(conf *ServerConfig) *Server {
+	return &Server{
+		DoneCh:          make(chan struct{}),
+		logger:          conf.Logger,
+		client:          conf.Client,
+		exitAfterRender: conf.ExitAfterRender,
+		tokenCh:         make(chan string, 1),
+		random:          rand.New(rand.NewSource(int64(time.Now().Nanosecond()))),
+	}
+}
+
+// WriteToken implements the Sink interface. The latest token replaces any
+// that has not yet been picked up.
+func (ts *Server) WriteToken(token string) error {
+	sendLatest(ts.tokenCh, token)
+	return nil
+}
+
+// Run renders the given templates whenever a new token is received or any of
+// the secrets they use are about to expire, until the context is canceled
+func (ts *Server) Run(ctx context.Context, templates []*config.Template) {
+	ts.logger.Info("starting template server")
+	defer func() {
+		ts.logger.Info("template server stopped")
+		close(ts.DoneCh)
+	}()
+
+	// The runners are stopped before waiting on them
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	renderedCh := make(chan struct{}, len(templates))
+	runners := make([]*runner, 0, len(templates))
+	for i, tmpl := range templates {
+		client, err := ts.client.Clone()
+		if err != nil {
+			ts.logger.Error("error creating client for template", "error", err)
+			return
+		}
+		client.ClearToken()
+
+		r := &runner{
+			logger:  ts.logger.With("destination", tmpl.Destination),
+			name:    fmt.Sprintf("template-%d", i),
+			client:  client,
+			tmpl:    tmpl,
+			random:  rand.New(rand.NewSource(ts.random.Int63())),
+			tokenCh: make(chan string, 1),
+			staleCh: make(chan struct{}, 1),
+			deps:    make(map[string]*dependency),
+		}
+		runners = append(runners, r)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.run(runCtx, renderedCh)
+		}()
+	}
+
+	rendered := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case token := <-ts.tokenCh:
+			for _, r := range runners {
+				sendLatest(r.tokenCh, token)
+			}
+
+		case <-renderedCh:
+			rendered++
+			if ts.exitAfterRender && rendered == len(runners) {
+				ts.logger.Info("all templates rendered")
+				return
+			}
+		}
+	}
+}
+
+// sendLatest sends val on ch, which must have a buffer of one, replacing any
+// value that has not yet been received
+func sendLatest(ch chan string, val string) {
+	for {
+		select {
+		case ch <- val:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// dependency is a secret used by a template. It is reused across renders
+// until it is about to expire.
+type dependency struct {
+	secret *api.Secret
+	stale  bool
+	stopCh chan struct{}
+}
+
+// runner renders a single template
+type runner struct {
+	logger  log.Logger
+	name    string
+	client  *api.Client
+	tmpl    *config.Template
+	random  *rand.Rand
+	tokenCh chan string
+	staleCh chan struct{}
+
+	l    sync.Mutex
+	deps map[string]*dependency
+}
+
+func (r *runner) run(ctx context.Context, renderedCh chan<- struct{}) {
+	defer r.stopDependencies(nil)
+
+	var retryCh <-chan time.Time
+	notified := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token := <-r.tokenCh:
+			// Secrets fetched with a previous token may be revoked along
+			// with it, so start afresh
+			r.stopDependencies(nil)
+			r.client.SetToken(token)
+		case <-r.staleCh:
+		case <-retryCh:
+		}
+		retryCh = nil
+
+		if r.client.Token() == "" {
+			continue
+		}
+
+		if err := r.renderAndWrite(ctx); err != nil {
+			backoff := 2*time.Second + time.Duration(r.random.Int63()%int64(time.Second*2)-int64(time.Second))
+			r.logger.Error("error rendering template", "error", err, "backoff", backoff.String())
+			retryCh = time.After(backoff)
+			continue
+		}
+
+		if !notified {
+			notified = true
+			renderedCh <- struct{}{}
+		}
+	}
+}
+
+// renderAndWrite renders the template and, if the result differs from the
+// destination's contents, writes it and runs the configured command
+func (r *runner) renderAndWrite(ctx context.Context) error {
+	contents, err := r.render()
+	if err != nil {
+		return err
+	}
+
+	existing, err := ioutil.ReadFile(r.tmpl.Destination)
+	if err == nil && bytes.Equal(existing, contents) {
+		r.logger.Debug("rendered template unchanged")
+		return nil
+	}
+
+	if err := writeFile(r.tmpl.Destination, contents, r.tmpl.Perms); err != nil {
+		return err
+	}
+	r.logger.Info("rendered template")
+
+	if r.tmpl.Command != "" {
+		r.runCommand(ctx)
+	}
+
+	return nil
+}
+
+// render executes the template, fetching any secrets that are not already
+// held. Secrets that are no longer used by the template are released.
+func (r *runner) render() ([]byte, error) {
+	text := r.tmpl.Contents
+	if r.tmpl.Source != "" {
+		b, err := ioutil.ReadFile(r.tmpl.Source)
+		if err != nil {
+			return nil, errwrap.Wrapf("error reading template source: {{err}}", err)
+		}
+		text = string(b)
+	}
+
+	used := make(map[string]bool)
+	t, err := template.New(r.name).Funcs(template.FuncMap{
+		"secret":  r.secretFunc(used, false),
+		"pkiCert": r.secretFunc(used, true),
+	}).Parse(text)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing template: {{err}}", err)
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, nil)
+	r.stopDependencies(used)
+	if err != nil {
+		return nil, errwrap.Wrapf("error executing template: {{err}}", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// secretFunc returns the template function used to fetch secrets. With no
+// arguments beyond the path the secret is read, otherwise the "key=value"
+// arguments are written to the path. Certificates are always issued with a
+// write and are refreshed based on their expiration rather than a lease.
+func (r *runner) secretFunc(used map[string]bool, cert bool) func(string, ...string) (*api.Secret, error) {
+	return func(path string, args ...string) (*api.Secret, error) {
+		var data map[string]interface{}
+		if cert || len(args) > 0 {
+			data = make(map[string]interface{}, len(args))
+			for _, arg := range args {
+				i := strings.Index(arg, "=")
+				if i == -1 {
+					return nil, fmt.Errorf("invalid argument %q, expected key=value", arg)
+				}
+				data[arg[:i]] = arg[i+1:]
+			}
+		}
+
+		key := fmt.Sprintf("%t|%s|%s", cert, path, strings.Join(args, "|"))
+		used[key] = true
+
+		r.l.Lock()
+		dep, ok := r.deps[key]
+		fresh := ok && !dep.stale
+		r.l.Unlock()
+		if fresh {
+			return dep.secret, nil
+		}
+
+		var secret *api.Secret
+		var err error
+		if data != nil {
+			secret, err = r.client.Logical().Write(path, data)
+		} else {
+			secret, err = r.client.Logical().Read(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			return nil, fmt.Errorf("no secret exists at %s", path)
+		}
+
+		newDep := &dependency{
+			secret: secret,
+			stopCh: make(chan struct{}),
+		}
+
+		r.l.Lock()
+		if ok {
+			close(dep.stopCh)
+		}
+		r.deps[key] = newDep
+		r.l.Unlock()
+
+		go r.watch(newDep, cert)
+
+		return secret, nil
+	}
+}
+
+// watch waits until the dependency needs to be fetched again, keeping its
+// lease renewed for as long as possible, and then triggers a render
+func (r *runner) watch(dep *dependency, cert bool) {
+	secret := dep.secret
+
+	switch {
+	case !cert && secret.Renewable && secret.LeaseID != "":
+		renewer, err := r.client.NewRenewer(&api.RenewerInput{
+			Secret: secret,
+		})
+		if err != nil {
+			r.logger.Error("error creating renewer", "error", err)
+			break
+		}
+		go renewer.Renew()
+		defer renewer.Stop()
+
+	RenewLoop:
+		for {
+			select {
+			case <-dep.stopCh:
+				return
+			case err := <-renewer.DoneCh():
+				if err != nil {
+					r.logger.Warn("error renewing lease", "error", err)
+				}
+				break RenewLoop
+			case <-renewer.RenewCh():
+				r.logger.Trace("renewed lease", "lease_id", secret.LeaseID)
+			}
+		}
+
+	default:
+		ttl := time.Duration(secret.LeaseDuration) * time.Second
+		if cert {
+			ttl = certTTL(secret)
+		}
+		if ttl <= 0 {
+			// Nothing to watch; the secret is held until no longer used
+			<-dep.stopCh
+			return
+		}
+
+		select {
+		case <-dep.stopCh:
+			return
+		case <-time.After(ttl * 2 / 3):
+		}
+	}
+
+	r.l.Lock()
+	dep.stale = true
+	r.l.Unlock()
+
+	select {
+	case r.staleCh <- struct{}{}:
+	default:
+	}
+}
+
+// certTTL returns the remaining validity of an issued certificate, falling
+// back to its lease if it has one
+func certTTL(secret *api.Secret) time.Duration {
+	if secret.Data != nil {
+		if raw, ok := secret.Data["expiration"]; ok {
+			expiration, err := parseutil.ParseInt(raw)
+			if err == nil && expiration > 0 {
+				return time.Until(time.Unix(expiration, 0))
+			}
+		}
+	}
+	return time.Duration(secret.LeaseDuration) * time.Second
+}
+
+// stopDependencies stops watching and forgets every dependency not in keep
+func (r *runner) stopDependencies(keep map[string]bool) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	for key, dep := range r.deps {
+		if keep[key] {
+			continue
+		}
+		close(dep.stopCh)
+		delete(r.deps, key)
+	}
+}
+
+// runCommand runs the template's command, logging any failure
+func (r *runner) runCommand(ctx context.Context) {
+	cmdCtx, cancel := context.WithTimeout(ctx, r.tmpl.CommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(cmdCtx, "cmd", "/C", r.tmpl.Command)
+	} else {
+		cmd = exec.CommandContext(cmdCtx, "/bin/sh", "-c", r.tmpl.Command)
+	}
+
+	r.logger.Info("running template command", "command", r.tmpl.Command)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.logger.Error("error running template command", "command", r.tmpl.Command, "error", err, "output", string(output))
+		return
+	}
+	r.logger.Debug("template command finished", "command", r.tmpl.Command, "output", string(output))
+}
+
+// writeFile atomically replaces the file at path with the given contents
+func writeFile(path string, contents []byte, perms os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errwrap.Wrapf("error creating destination directory: {{err}}", err)
+	}
+
+	u, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(dir, fmt.Sprintf(".%s.tmp.%s", filepath.Base(path), strings.Split(u, "-")[0]))
+
+	if err := ioutil.WriteFile(tmpPath, contents, perms); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error writing rendered template: {{err}}", err)
+	}
+	// Ensure the permissions are as requested regardless of umask
+	if err := os.Chmod(tmpPath, perms); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error replacing destination file: {{err}}", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package template
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/helper/logging"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/vault"
+)
+
+func waitForContents(t *testing.T, path, expected string) {
+	t.Helper()
+
+	deadline := time.Now().Add(15 * time.Second)
+	var contents []byte
+	for time.Now().Before(deadline) {
+		contents, _ = ioutil.ReadFile(path)
+		if string(contents) == expected {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q in %s, last contents %q", expected, path, string(contents))
+}
+
+func TestServer_Render(t *testing.T) {
+	logger := logging.NewVaultLogger(hclog.Trace)
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		Logger: logger,
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+
+	// The short TTL causes the secret to be fetched again, picking up the
+	// change made below
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"bar": "baz",
+		"ttl": "3s",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "agent-template-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sourcePath := filepath.Join(dir, "source.tpl")
+	if err := ioutil.WriteFile(sourcePath, []byte(`{{ with secret "secret/foo" }}{{ .Data.bar }}{{ end }}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	destPath := filepath.Join(dir, "out", "foo")
+	commandPath := filepath.Join(dir, "command-ran")
+	templates := []*config.Template{
+		&config.Template{
+			Source:         sourcePath,
+			Destination:    destPath,
+			Command:        "touch " + commandPath,
+			CommandTimeout: 10 * time.Second,
+			Perms:          0600,
+		},
+	}
+
+	ts := NewServer(&ServerConfig{
+		Logger: logger.Named("template.server"),
+		Client: client,
+	})
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer func() {
+		cancelFunc()
+		<-ts.DoneCh
+	}()
+	go ts.Run(ctx, templates)
+
+	if err := ts.WriteToken(cluster.RootToken); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForContents(t, destPath, "baz")
+
+	fi, err := os.Stat(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("bad file mode %v", fi.Mode().Perm())
+	}
+	if _, err := os.Stat(commandPath); err != nil {
+		t.Fatalf("expected command to have run: %v", err)
+	}
+
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"bar": "qux",
+		"ttl": "3s",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForContents(t, destPath, "qux")
+}
+
+func TestServer_ExitAfterRender(t *testing.T) {
+	logger := logging.NewVaultLogger(hclog.Trace)
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		Logger: logger,
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+
+	dir, err := ioutil.TempDir("", "agent-template-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	destPath := filepath.Join(dir, "policies")
+	templates := []*config.Template{
+		&config.Template{
+			Contents:       `{{ with secret "auth/token/lookup-self" }}{{ range .Data.policies }}{{ . }}{{ end }}{{ end }}`,
+			Destination:    destPath,
+			CommandTimeout: 10 * time.Second,
+			Perms:          0644,
+		},
+	}
+
+	ts := NewServer(&ServerConfig{
+		Logger:          logger.Named("template.server"),
+		Client:          client,
+		ExitAfterRender: true,
+	})
+	go ts.Run(context.Background(), templates)
+
+	if err := ts.WriteToken(cluster.RootToken); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ts.DoneCh:
+	case <-time.After(15 * time.Second):
+		t.Fatal("timed out waiting for template server to exit")
+	}
+
+	contents, err := ioutil.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "root" {
+		t.Fatalf("bad contents: %q", string(contents))
+	}
+}
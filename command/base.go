@@ -32,6 +32,7 @@ type BaseCommand struct {
 	flagsOnce sync.Once
 
 	flagAddress       string
+	flagNamespace     string
 	flagCACert        string
 	flagCAPath        string
 	flagClientCert    string
@@ -113,6 +114,10 @@ func (c *BaseCommand) Client() (*api.Client, error) {
 
 	client.SetMFACreds(c.flagMFA)
 
+	if c.flagNamespace != "" {
+		client.SetNamespace(c.flagNamespace)
+	}
+
 	c.client = client
 
 	return client, nil
@@ -250,6 +255,17 @@ func (c *BaseCommand) flagSet(bit FlagSetBit) *FlagSets {
 					"transmissions to and from the Vault server.",
 			})
 
+			f.StringVar(&StringVar{
+				Name:       "namespace",
+				Target:     &c.flagNamespace,
+				Default:    "",
+				EnvVar:     api.EnvVaultNamespace,
+				Completion: complete.PredictAnything,
+				Usage: "Namespace to make the request against, as a path " +
+					"relative to the root namespace such as \"team-a/\". The " +
+					"namespace is sent in the X-Vault-Namespace header.",
+			})
+
 			f.DurationVar(&DurationVar{
 				Name:       "wrap-ttl",
 				Target:     &c.flagWrapTTL,
@@ -99,6 +99,7 @@ func (b *BaseCommand) PredictVaultAvailableAuths() complete.Predictor {
 		"cert",
 		"gcp",
 		"github",
+		"jwt",
 		"ldap",
 		"okta",
 		"plugin",
@@ -43,6 +43,7 @@ import (
 	credAws "github.com/hashicorp/vault/builtin/credential/aws"
 	credCert "github.com/hashicorp/vault/builtin/credential/cert"
 	credGitHub "github.com/hashicorp/vault/builtin/credential/github"
+	credJWT "github.com/hashicorp/vault/builtin/credential/jwt"
 	credLdap "github.com/hashicorp/vault/builtin/credential/ldap"
 	credOkta "github.com/hashicorp/vault/builtin/credential/okta"
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
@@ -50,6 +51,7 @@ import (
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 
 	physAzure "github.com/hashicorp/vault/physical/azure"
+	physBolt "github.com/hashicorp/vault/physical/bolt"
 	physCassandra "github.com/hashicorp/vault/physical/cassandra"
 	physCockroachDB "github.com/hashicorp/vault/physical/cockroachdb"
 	physConsul "github.com/hashicorp/vault/physical/consul"
@@ -63,6 +65,7 @@ import (
 	physMSSQL "github.com/hashicorp/vault/physical/mssql"
 	physMySQL "github.com/hashicorp/vault/physical/mysql"
 	physPostgreSQL "github.com/hashicorp/vault/physical/postgresql"
+	physRaft "github.com/hashicorp/vault/physical/raft"
 	physS3 "github.com/hashicorp/vault/physical/s3"
 	physSpanner "github.com/hashicorp/vault/physical/spanner"
 	physSwift "github.com/hashicorp/vault/physical/swift"
@@ -101,6 +104,7 @@ var (
 		"cert":       credCert.Factory,
 		"gcp":        credGcp.Factory,
 		"github":     credGitHub.Factory,
+		"jwt":        credJWT.Factory,
 		"kubernetes": credKube.Factory,
 		"ldap":       credLdap.Factory,
 		"okta":       credOkta.Factory,
@@ -131,6 +135,7 @@ var (
 
 	physicalBackends = map[string]physical.Factory{
 		"azure":                  physAzure.NewAzureBackend,
+		"bolt":                   physBolt.NewBoltBackend,
 		"cassandra":              physCassandra.NewCassandraBackend,
 		"cockroachdb":            physCockroachDB.NewCockroachDBBackend,
 		"consul":                 physConsul.NewConsulBackend,
@@ -149,6 +154,7 @@ var (
 		"mssql":                  physMSSQL.NewMSSQLBackend,
 		"mysql":                  physMySQL.NewMySQLBackend,
 		"postgresql":             physPostgreSQL.NewPostgreSQLBackend,
+		"raft":                   physRaft.NewRaftBackend,
 		"s3":                     physS3.NewS3Backend,
 		"spanner":                physSpanner.NewBackend,
 		"swift":                  physSwift.NewSwiftBackend,
@@ -200,6 +206,7 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 		"centrify": &credCentrify.CLIHandler{},
 		"cert":     &credCert.CLIHandler{},
 		"github":   &credGitHub.CLIHandler{},
+		"jwt":      &credJWT.CLIHandler{},
 		"ldap":     &credLdap.CLIHandler{},
 		"okta":     &credOkta.CLIHandler{},
 		"radius": &credUserpass.CLIHandler{
@@ -212,6 +219,16 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 	}
 
 	Commands = map[string]cli.CommandFactory{
+		"agent": func() (cli.Command, error) {
+			return &AgentCommand{
+				BaseCommand: &BaseCommand{
+					UI:          serverCmdUi,
+					tokenHelper: runOpts.TokenHelper,
+					flagAddress: runOpts.Address,
+				},
+				ShutdownCh: MakeShutdownCh(),
+			}, nil
+		},
 		"audit": func() (cli.Command, error) {
 			return &AuditCommand{
 				BaseCommand: &BaseCommand{
@@ -395,6 +412,15 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				},
 			}, nil
 		},
+		"operator migrate": func() (cli.Command, error) {
+			return &OperatorMigrateCommand{
+				BaseCommand: &BaseCommand{
+					UI: ui,
+				},
+				PhysicalBackends: physicalBackends,
+				ShutdownCh:       MakeShutdownCh(),
+			}, nil
+		},
 		"operator rekey": func() (cli.Command, error) {
 			return &OperatorRekeyCommand{
 				BaseCommand: &BaseCommand{
@@ -422,6 +448,51 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				},
 			}, nil
 		},
+		"operator snapshot": func() (cli.Command, error) {
+			return &OperatorSnapshotCommand{
+				BaseCommand: &BaseCommand{
+					UI:          ui,
+					tokenHelper: runOpts.TokenHelper,
+					flagAddress: runOpts.Address,
+				},
+			}, nil
+		},
+		"operator snapshot restore": func() (cli.Command, error) {
+			return &OperatorSnapshotRestoreCommand{
+				BaseCommand: &BaseCommand{
+					UI:          ui,
+					tokenHelper: runOpts.TokenHelper,
+					flagAddress: runOpts.Address,
+				},
+			}, nil
+		},
+		"operator snapshot save": func() (cli.Command, error) {
+			return &OperatorSnapshotSaveCommand{
+				BaseCommand: &BaseCommand{
+					UI:          ui,
+					tokenHelper: runOpts.TokenHelper,
+					flagAddress: runOpts.Address,
+				},
+			}, nil
+		},
+		"operator storage": func() (cli.Command, error) {
+			return &OperatorStorageCommand{
+				BaseCommand: &BaseCommand{
+					UI:          ui,
+					tokenHelper: runOpts.TokenHelper,
+					flagAddress: runOpts.Address,
+				},
+			}, nil
+		},
+		"operator storage verify": func() (cli.Command, error) {
+			return &OperatorStorageVerifyCommand{
+				BaseCommand: &BaseCommand{
+					UI:          ui,
+					tokenHelper: runOpts.TokenHelper,
+					flagAddress: runOpts.Address,
+				},
+			}, nil
+		},
 		"operator step-down": func() (cli.Command, error) {
 			return &OperatorStepDownCommand{
 				BaseCommand: &BaseCommand{
@@ -0,0 +1,393 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorMigrateCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorMigrateCommand)(nil)
+
+type OperatorMigrateCommand struct {
+	*BaseCommand
+
+	PhysicalBackends map[string]physical.Factory
+	ShutdownCh       chan struct{}
+
+	flagConfig   string
+	flagStart    string
+	flagDryRun   bool
+	flagLogLevel string
+
+	logger log.Logger
+}
+
+// migratorConfig is the configuration of a storage migration
+type migratorConfig struct {
+	StorageSource      *server.Storage `hcl:"-"`
+	StorageDestination *server.Storage `hcl:"-"`
+}
+
+func (c *OperatorMigrateCommand) Synopsis() string {
+	return "Migrates Vault data between storage backends"
+}
+
+func (c *OperatorMigrateCommand) Help() string {
+	helpText := `
+Usage: vault operator migrate [options]
+
+  This command starts a storage backend migration process to copy all data
+  from one backend to another. This operates directly on encrypted data and
+  does not require a Vault server, nor any unsealing. Vault must not be
+  running against the source storage while the migration is in progress.
+
+  Start a migration with a configuration file:
+
+      $ vault operator migrate -config=migrate.hcl
+
+  The configuration file holds a "storage_source" and a "storage_destination"
+  stanza, which take the same settings as the "storage" stanza of the server
+  configuration:
+
+      storage_source "file" {
+        path = "/var/lib/vault"
+      }
+
+      storage_destination "consul" {
+        address = "127.0.0.1:8500"
+        path    = "vault"
+      }
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorMigrateCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetNone)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "config",
+		Target:     &c.flagConfig,
+		Completion: complete.PredictOr(complete.PredictFiles("*.hcl"), complete.PredictFiles("*.json")),
+		Usage:      "Path to a configuration file holding the source and destination storage.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:   "start",
+		Target: &c.flagStart,
+		Usage: "Only copy keys lexicographically at or after this value. This " +
+			"can be used to resume an interrupted migration.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:   "dry-run",
+		Target: &c.flagDryRun,
+		Usage:  "Count the keys that would be copied without writing to the destination.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "log-level",
+		Target:     &c.flagLogLevel,
+		Default:    "info",
+		EnvVar:     "VAULT_LOG_LEVEL",
+		Completion: complete.PredictSet("trace", "debug", "info", "warn", "err"),
+		Usage: "Log verbosity level. Supported values (in order of detail) are " +
+			"\"trace\", \"debug\", \"info\", \"warn\", and \"err\".",
+	})
+
+	return set
+}
+
+func (c *OperatorMigrateCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorMigrateCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorMigrateCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagConfig == "" {
+		c.UI.Error("Must specify exactly one config path using -config")
+		return 1
+	}
+
+	var level log.Level
+	switch strings.ToLower(strings.TrimSpace(c.flagLogLevel)) {
+	case "trace":
+		level = log.Trace
+	case "debug":
+		level = log.Debug
+	case "notice", "info", "":
+		level = log.Info
+	case "warn", "warning":
+		level = log.Warn
+	case "err", "error":
+		level = log.Error
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown log level: %s", c.flagLogLevel))
+		return 1
+	}
+	c.logger = logging.NewVaultLoggerWithWriter(os.Stderr, level)
+
+	config, err := loadMigratorConfig(c.flagConfig)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading configuration from %s: %s", c.flagConfig, err))
+		return 1
+	}
+
+	from, err := c.newBackend(config.StorageSource)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing source storage: %s", err))
+		return 2
+	}
+
+	to, err := c.newBackend(config.StorageDestination)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing destination storage: %s", err))
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.ShutdownCh:
+			c.UI.Output("==> Migration shutdown triggered")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	count, err := c.migrate(ctx, from, to)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error migrating storage: %s", err))
+		return 2
+	}
+
+	if c.flagDryRun {
+		c.UI.Output(fmt.Sprintf("Success! %d keys would be migrated.", count))
+		return 0
+	}
+
+	c.UI.Output(fmt.Sprintf("Success! Migrated %d keys.", count))
+	return 0
+}
+
+// newBackend instantiates the storage backend described by the given stanza
+func (c *OperatorMigrateCommand) newBackend(storage *server.Storage) (physical.Backend, error) {
+	factory, ok := c.PhysicalBackends[storage.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage type %s", storage.Type)
+	}
+
+	return factory(storage.Config, c.logger.ResetNamed("storage."+storage.Type))
+}
+
+// migrate copies every entry of the source to the destination, returning
+// the number of entries copied. In dry-run mode the entries are only
+// counted.
+func (c *OperatorMigrateCommand) migrate(ctx context.Context, from, to physical.Backend) (int, error) {
+	// Refuse to copy data while a Vault server may be writing to the source
+	if ha, ok := from.(physical.HABackend); ok && ha.HAEnabled() {
+		lock, err := ha.LockWith(vault.CoreLockPath, "migrate")
+		if err != nil {
+			return 0, errwrap.Wrapf("error creating source lock: {{err}}", err)
+		}
+		held, _, err := lock.Value()
+		if err != nil {
+			return 0, errwrap.Wrapf("error checking source lock: {{err}}", err)
+		}
+		if held {
+			return 0, fmt.Errorf("the source storage is in use by a Vault server, which must be shut down before migrating")
+		}
+
+		// Hold the lock for the whole copy, so that no Vault server can
+		// become active on the source in the meantime
+		lostCh, err := lock.Lock(ctx.Done())
+		if err != nil {
+			return 0, errwrap.Wrapf("error acquiring source lock: {{err}}", err)
+		}
+		if lostCh == nil {
+			return 0, fmt.Errorf("migration canceled before acquiring the source lock")
+		}
+		defer func() {
+			if err := lock.Unlock(); err != nil {
+				c.logger.Error("error releasing source lock", "error", err)
+			}
+		}()
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-lostCh:
+				c.logger.Error("lost the source lock, stopping migration")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	var count int
+	err := dfsScan(ctx, from, func(ctx context.Context, key string) error {
+		if key < c.flagStart || key == vault.CoreLockPath {
+			return nil
+		}
+
+		count++
+		if c.flagDryRun {
+			return nil
+		}
+
+		entry, err := from.Get(ctx, key)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("error reading entry %q: {{err}}", key), err)
+		}
+		// The entry may have been removed since it was listed
+		if entry == nil {
+			count--
+			return nil
+		}
+
+		if err := to.Put(ctx, entry); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("error writing entry %q: {{err}}", key), err)
+		}
+
+		c.logger.Info("copied key", "path", key)
+		return nil
+	})
+
+	return count, err
+}
+
+// dfsScan walks the storage depth-first, calling cb for every key. Keys are
+// visited in lexicographic order, which allows resuming from a given key.
+func dfsScan(ctx context.Context, source physical.Backend, cb func(ctx context.Context, key string) error) error {
+	dfs := []string{""}
+
+	for l := len(dfs); l > 0; l = len(dfs) {
+		key := dfs[l-1]
+		dfs = dfs[:l-1]
+
+		if key != "" && !strings.HasSuffix(key, "/") {
+			if err := cb(ctx, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		children, err := source.List(ctx, key)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("error listing %q: {{err}}", key), err)
+		}
+
+		// Push in reverse order so that the smallest child is visited first
+		sort.Sort(sort.Reverse(sort.StringSlice(children)))
+		for _, child := range children {
+			dfs = append(dfs, key+child)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+
+	return nil
+}
+
+// loadMigratorConfig loads the migration configuration from the given file
+func loadMigratorConfig(path string) (*migratorConfig, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMigratorConfig(string(d))
+}
+
+func parseMigratorConfig(d string) (*migratorConfig, error) {
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	var result migratorConfig
+	for _, name := range []string{"storage_source", "storage_destination"} {
+		o := list.Filter(name)
+		if len(o.Items) != 1 {
+			return nil, fmt.Errorf("exactly one %q block is required", name)
+		}
+
+		storage, err := parseMigratorStorage(o.Items[0], name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "storage_source":
+			result.StorageSource = storage
+		case "storage_destination":
+			result.StorageDestination = storage
+		}
+	}
+
+	return &result, nil
+}
+
+func parseMigratorStorage(item *ast.ObjectItem, name string) (*server.Storage, error) {
+	if len(item.Keys) == 0 {
+		return nil, fmt.Errorf("%q block must specify a storage type", name)
+	}
+	key := item.Keys[0].Token.Value().(string)
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return nil, multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+	}
+
+	return &server.Storage{
+		Type:   strings.ToLower(key),
+		Config: m,
+	}, nil
+}
@@ -0,0 +1,354 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/physical"
+	physFile "github.com/hashicorp/vault/physical/file"
+	physInmem "github.com/hashicorp/vault/physical/inmem"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorMigrateCommand(tb testing.TB) (*cli.MockUi, *OperatorMigrateCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorMigrateCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+		PhysicalBackends: map[string]physical.Factory{
+			"file":  physFile.NewFileBackend,
+			"inmem": physInmem.NewInmem,
+		},
+		logger: logging.NewVaultLogger(log.Trace),
+	}
+}
+
+var testMigrateKeys = []string{
+	"core/keyring",
+	"core/master",
+	"logical/abc-def",
+	"logical/abc/foo",
+	"logical/abc/foo/bar",
+	"sys/token/id/abc",
+}
+
+func testMigrateBackend(t *testing.T) physical.Backend {
+	t.Helper()
+
+	b, err := physInmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range testMigrateKeys {
+		if err := b.Put(context.Background(), &physical.Entry{Key: key, Value: []byte(key)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b
+}
+
+func testMigrateKeysIn(t *testing.T, b physical.Backend) []string {
+	t.Helper()
+
+	var keys []string
+	err := dfsScan(context.Background(), b, func(ctx context.Context, key string) error {
+		entry, err := b.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if string(entry.Value) != key {
+			return fmt.Errorf("bad value for %q: %q", key, entry.Value)
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestOperatorMigrateCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"too_many_args",
+			[]string{"foo"},
+			"Too many arguments",
+			1,
+		},
+		{
+			"no_config",
+			[]string{},
+			"Must specify exactly one config path",
+			1,
+		},
+		{
+			"missing_config",
+			[]string{"-config", "/nope/not/real"},
+			"Error loading configuration",
+			1,
+		},
+	}
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				ui, cmd := testOperatorMigrateCommand(t)
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "vault-migrate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		from, err := physFile.NewFileBackend(map[string]string{"path": filepath.Join(dir, "from")}, logging.NewVaultLogger(log.Trace))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range testMigrateKeys {
+			if err := from.Put(context.Background(), &physical.Entry{Key: key, Value: []byte(key)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		configPath := filepath.Join(dir, "migrate.hcl")
+		config := fmt.Sprintf(`
+storage_source "file" {
+	path = %q
+}
+
+storage_destination "file" {
+	path = %q
+}
+`, filepath.Join(dir, "from"), filepath.Join(dir, "to"))
+		if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testOperatorMigrateCommand(t)
+		code := cmd.Run([]string{"-config", configPath})
+		if code != 0 {
+			t.Fatalf("expected 0 to be %d: %s", code, ui.ErrorWriter.String())
+		}
+		expected := fmt.Sprintf("Success! Migrated %d keys.", len(testMigrateKeys))
+		if !strings.Contains(ui.OutputWriter.String(), expected) {
+			t.Errorf("expected %q to contain %q", ui.OutputWriter.String(), expected)
+		}
+
+		to, err := physFile.NewFileBackend(map[string]string{"path": filepath.Join(dir, "to")}, logging.NewVaultLogger(log.Trace))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := testMigrateKeysIn(t, to); !reflect.DeepEqual(keys, testMigrateKeys) {
+			t.Fatalf("bad: %v", keys)
+		}
+	})
+}
+
+func TestOperatorMigrateCommand_Migrate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+
+		from := testMigrateBackend(t)
+		to, _ := physInmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+
+		_, cmd := testOperatorMigrateCommand(t)
+		count, err := cmd.migrate(context.Background(), from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(testMigrateKeys) {
+			t.Fatalf("bad: %d", count)
+		}
+		if keys := testMigrateKeysIn(t, to); !reflect.DeepEqual(keys, testMigrateKeys) {
+			t.Fatalf("bad: %v", keys)
+		}
+	})
+
+	t.Run("start", func(t *testing.T) {
+		t.Parallel()
+
+		from := testMigrateBackend(t)
+		to, _ := physInmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+
+		_, cmd := testOperatorMigrateCommand(t)
+		cmd.flagStart = "logical/abc/foo"
+		count, err := cmd.migrate(context.Background(), from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatalf("bad: %d", count)
+		}
+		if keys := testMigrateKeysIn(t, to); !reflect.DeepEqual(keys, testMigrateKeys[3:]) {
+			t.Fatalf("bad: %v", keys)
+		}
+	})
+
+	t.Run("dry_run", func(t *testing.T) {
+		t.Parallel()
+
+		from := testMigrateBackend(t)
+		to, _ := physInmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+
+		_, cmd := testOperatorMigrateCommand(t)
+		cmd.flagDryRun = true
+		count, err := cmd.migrate(context.Background(), from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(testMigrateKeys) {
+			t.Fatalf("bad: %d", count)
+		}
+		if keys := testMigrateKeysIn(t, to); len(keys) != 0 {
+			t.Fatalf("bad: %v", keys)
+		}
+	})
+
+	t.Run("ha_lock", func(t *testing.T) {
+		t.Parallel()
+
+		from, _ := physInmem.NewInmemHA(nil, logging.NewVaultLogger(log.Trace))
+		for _, key := range testMigrateKeys {
+			if err := from.Put(context.Background(), &physical.Entry{Key: key, Value: []byte(key)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		lock, err := from.(physical.HABackend).LockWith(vault.CoreLockPath, "active")
+		if err != nil {
+			t.Fatal(err)
+		}
+		inmem, _ := physInmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+		to := &lockCheckingBackend{Backend: inmem, lock: lock}
+
+		_, cmd := testOperatorMigrateCommand(t)
+		if _, err := cmd.migrate(context.Background(), from, to); err != nil {
+			t.Fatal(err)
+		}
+		if to.puts != len(testMigrateKeys) || to.unlocked != 0 {
+			t.Fatalf("expected the source lock to be held for all %d writes, got %d writes with %d unlocked", len(testMigrateKeys), to.puts, to.unlocked)
+		}
+
+		// The lock is released once the migration is done
+		held, _, err := lock.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if held {
+			t.Fatal("expected the source lock to be released")
+		}
+	})
+
+	t.Run("ha_lock_held", func(t *testing.T) {
+		t.Parallel()
+
+		from, _ := physInmem.NewInmemHA(nil, logging.NewVaultLogger(log.Trace))
+		to, _ := physInmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+
+		lock, err := from.(physical.HABackend).LockWith(vault.CoreLockPath, "active")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := lock.Lock(nil); err != nil {
+			t.Fatal(err)
+		}
+		defer lock.Unlock()
+
+		_, cmd := testOperatorMigrateCommand(t)
+		if _, err := cmd.migrate(context.Background(), from, to); err == nil || !strings.Contains(err.Error(), "in use by a Vault server") {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
+
+func TestOperatorMigrateCommand_Config(t *testing.T) {
+	t.Parallel()
+
+	config, err := parseMigratorConfig(`
+storage_source "file" {
+	path = "/var/lib/vault"
+}
+
+storage_destination "Consul" {
+	address = "127.0.0.1:8500"
+	path    = "vault"
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StorageSource.Type != "file" || config.StorageSource.Config["path"] != "/var/lib/vault" {
+		t.Fatalf("bad: %#v", config.StorageSource)
+	}
+	if config.StorageDestination.Type != "consul" || config.StorageDestination.Config["address"] != "127.0.0.1:8500" {
+		t.Fatalf("bad: %#v", config.StorageDestination)
+	}
+
+	if _, err := parseMigratorConfig(`storage_source "file" {}`); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// lockCheckingBackend records whether the given lock is held whenever an
+// entry is written
+type lockCheckingBackend struct {
+	physical.Backend
+	lock     physical.Lock
+	puts     int
+	unlocked int
+}
+
+func (b *lockCheckingBackend) Put(ctx context.Context, entry *physical.Entry) error {
+	held, _, err := b.lock.Value()
+	if err != nil {
+		return err
+	}
+	b.puts++
+	if !held {
+		b.unlocked++
+	}
+	return b.Backend.Put(ctx, entry)
+}
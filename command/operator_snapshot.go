@@ -0,0 +1,43 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+var _ cli.Command = (*OperatorSnapshotCommand)(nil)
+
+type OperatorSnapshotCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorSnapshotCommand) Synopsis() string {
+	return "Save and restore snapshots of the storage"
+}
+
+func (c *OperatorSnapshotCommand) Help() string {
+	helpText := `
+Usage: vault operator snapshot <subcommand> [options] [args]
+
+  This command groups subcommands for saving and restoring snapshots of the
+  data Vault keeps in its storage backend. Snapshots work with every storage
+  backend, so a snapshot taken from one backend can be restored into another.
+
+  Save a snapshot:
+
+      $ vault operator snapshot save vault.snap
+
+  Restore a snapshot:
+
+      $ vault operator snapshot restore vault.snap
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSnapshotCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorSnapshotRestoreCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorSnapshotRestoreCommand)(nil)
+
+type OperatorSnapshotRestoreCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorSnapshotRestoreCommand) Synopsis() string {
+	return "Restores a snapshot of the storage"
+}
+
+func (c *OperatorSnapshotRestoreCommand) Help() string {
+	helpText := `
+Usage: vault operator snapshot restore [options] PATH
+
+  Replaces every entry in the storage backend of the Vault server with those
+  of the snapshot at PATH, which is verified before anything is replaced.
+  Vault is sealed once the snapshot is restored, and must be unsealed with
+  the unseal keys it had when the snapshot was taken. Other nodes of an HA
+  cluster have to be restarted. This requires a root token.
+
+  Restore the snapshot in vault.snap:
+
+      $ vault operator snapshot restore vault.snap
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSnapshotRestoreCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetHTTP)
+}
+
+func (c *OperatorSnapshotRestoreCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *OperatorSnapshotRestoreCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorSnapshotRestoreCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	path := strings.TrimSpace(args[0])
+
+	file, err := os.Open(path)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error opening snapshot file: %s", err))
+		return 2
+	}
+	defer file.Close()
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	if err := client.Sys().StorageSnapshotRestore(file); err != nil {
+		c.UI.Error(fmt.Sprintf("Error restoring snapshot: %s", err))
+		return 2
+	}
+
+	c.UI.Output("Success! Restored snapshot. Vault is sealed and must be unsealed " +
+		"with the unseal keys of the snapshot.")
+	return 0
+}
@@ -0,0 +1,106 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorSnapshotSaveCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorSnapshotSaveCommand)(nil)
+
+type OperatorSnapshotSaveCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorSnapshotSaveCommand) Synopsis() string {
+	return "Saves a snapshot of the storage"
+}
+
+func (c *OperatorSnapshotSaveCommand) Help() string {
+	helpText := `
+Usage: vault operator snapshot save [options] PATH
+
+  Saves a snapshot of every entry in the storage backend of the Vault server
+  to the file at PATH. The entries remain encrypted, so restoring the
+  snapshot requires the unseal keys Vault had when it was taken. This
+  requires a root token.
+
+  Save a snapshot to vault.snap:
+
+      $ vault operator snapshot save vault.snap
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSnapshotSaveCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetHTTP)
+}
+
+func (c *OperatorSnapshotSaveCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *OperatorSnapshotSaveCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorSnapshotSaveCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	path := strings.TrimSpace(args[0])
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	// Write to a temporary file first so that an existing snapshot is only
+	// replaced by a complete one
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error creating snapshot file: %s", err))
+		return 2
+	}
+
+	err = client.Sys().StorageSnapshot(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		c.UI.Error(fmt.Sprintf("Error saving snapshot: %s", err))
+		return 2
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		c.UI.Error(fmt.Sprintf("Error saving snapshot: %s", err))
+		return 2
+	}
+
+	c.UI.Output(fmt.Sprintf("Success! Saved snapshot to: %s", path))
+	return 0
+}
@@ -0,0 +1,211 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorSnapshotSaveCommand(tb testing.TB) (*cli.MockUi, *OperatorSnapshotSaveCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorSnapshotSaveCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func testOperatorSnapshotRestoreCommand(tb testing.TB) (*cli.MockUi, *OperatorSnapshotRestoreCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorSnapshotRestoreCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestOperatorSnapshotCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			nil,
+			"Not enough arguments",
+			1,
+		},
+		{
+			"too_many_args",
+			[]string{"foo", "bar"},
+			"Too many arguments",
+			1,
+		},
+	}
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				ui, cmd := testOperatorSnapshotSaveCommand(t)
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+
+				ui, restoreCmd := testOperatorSnapshotRestoreCommand(t)
+				code = restoreCmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+				combined = ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("save_restore", func(t *testing.T) {
+		t.Parallel()
+
+		client, keys, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		dir, err := ioutil.TempDir("", "vault-snapshot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "vault.snap")
+
+		if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+			"value": "bar",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testOperatorSnapshotSaveCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{path})
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+		expected := "Success! Saved snapshot to: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		if _, err := client.Logical().Delete("secret/foo"); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, restoreCmd := testOperatorSnapshotRestoreCommand(t)
+		restoreCmd.client = client
+
+		code = restoreCmd.Run([]string{path})
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+		expected = "Success! Restored snapshot."
+		combined = ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		status, err := client.Sys().SealStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !status.Sealed {
+			t.Fatal("expected to be sealed")
+		}
+
+		for _, key := range keys {
+			if _, err := client.Sys().Unseal(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Wait for the node to become active again
+		for i := 0; ; i++ {
+			leader, err := client.Sys().Leader()
+			if err == nil && (!leader.HAEnabled || leader.IsSelf) {
+				break
+			}
+			if i == 100 {
+				t.Fatal("node did not become active")
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		secret, err := client.Logical().Read("secret/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.Data["value"] != "bar" {
+			t.Fatalf("bad: %#v", secret)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		dir, err := ioutil.TempDir("", "vault-snapshot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		ui, cmd := testOperatorSnapshotSaveCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{filepath.Join(dir, "vault.snap")})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error saving snapshot: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testOperatorSnapshotSaveCommand(t)
+		assertNoTabs(t, cmd)
+
+		_, restoreCmd := testOperatorSnapshotRestoreCommand(t)
+		assertNoTabs(t, restoreCmd)
+	})
+}
@@ -0,0 +1,38 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+var _ cli.Command = (*OperatorStorageCommand)(nil)
+
+type OperatorStorageCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorStorageCommand) Synopsis() string {
+	return "Inspect the storage backend"
+}
+
+func (c *OperatorStorageCommand) Help() string {
+	helpText := `
+Usage: vault operator storage <subcommand> [options] [args]
+
+  This command groups subcommands for inspecting the data Vault keeps in its
+  storage backend.
+
+  Verify that every storage entry decrypts:
+
+      $ vault operator storage verify
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorStorageCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
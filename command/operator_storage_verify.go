@@ -0,0 +1,106 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorStorageVerifyCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorStorageVerifyCommand)(nil)
+
+type OperatorStorageVerifyCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorStorageVerifyCommand) Synopsis() string {
+	return "Verifies that the storage entries decrypt"
+}
+
+func (c *OperatorStorageVerifyCommand) Help() string {
+	helpText := `
+Usage: vault operator storage verify [options]
+
+  Reads every entry in the storage backend of the Vault server and checks
+  that it decrypts with the keyring. The mount tables, tokens and leases are
+  also checked to decode. The entries that fail are listed along with the key
+  term they claim to be encrypted under. Nothing is modified. This requires a
+  root token.
+
+  The command exits with status 2 if any entry fails verification.
+
+  Verify the storage:
+
+      $ vault operator storage verify
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorStorageVerifyCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+}
+
+func (c *OperatorStorageVerifyCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorStorageVerifyCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorStorageVerifyCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	report, err := client.Sys().StorageVerify()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error verifying storage: %s", err))
+		return 2
+	}
+
+	code := 0
+	if len(report.BadKeys) > 0 {
+		code = 2
+	}
+
+	if Format(c.UI) != "table" {
+		if ret := OutputData(c.UI, report); ret != 0 {
+			return ret
+		}
+		return code
+	}
+
+	if len(report.BadKeys) == 0 {
+		c.UI.Output(fmt.Sprintf("Success! Verified %d storage entries (%d not encrypted "+
+			"by the barrier).", report.Scanned, report.Skipped))
+		return 0
+	}
+
+	out := []string{"Key | Term | Error"}
+	for _, bad := range report.BadKeys {
+		out = append(out, fmt.Sprintf("%s | %d | %s", bad.Key, bad.Term, bad.Error))
+	}
+	c.UI.Error(fmt.Sprintf("Found %d bad storage entries out of %d:", len(report.BadKeys), report.Scanned))
+	c.UI.Output(tableOutput(out, nil))
+	return code
+}
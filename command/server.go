@@ -36,9 +36,11 @@ import (
 	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/helper/gated-writer"
 	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/reload"
+	"github.com/hashicorp/vault/helper/salt"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
@@ -396,7 +398,8 @@ func (c *ServerCommand) Run(args []string) int {
 				"in a Docker container, provide the IPC_LOCK cap to the container."))
 	}
 
-	if err := c.setupTelemetry(config); err != nil {
+	metricsHelper, err := c.setupTelemetry(config)
+	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
@@ -418,13 +421,32 @@ func (c *ServerCommand) Run(args []string) int {
 	info["log level"] = c.flagLogLevel
 	infoKeys = append(infoKeys, "log level")
 
-	var seal vault.Seal = vault.NewDefaultSeal()
+	seal, sealConfigError := configureSeal(config, &infoKeys, info, c.logger.ResetNamed("seal"))
+	if sealConfigError != nil {
+		c.UI.Error(fmt.Sprintf("Error configuring seal: %s", sealConfigError))
+		return 1
+	}
+
+	// Determine the seal the existing data may still be protected by, in case
+	// a seal migration is needed. A disabled seal is being migrated away
+	// from, in favor of Shamir; an enabled autoseal may be replacing Shamir.
+	var migrationSeal vault.Seal
+	switch {
+	case config.Seal != nil && config.Seal.Disabled:
+		migrationSeal = seal
+		seal = vault.NewDefaultSeal()
+		info["seal type"] += " (disabled)"
+	case seal != nil && seal.StoredKeysSupported():
+		migrationSeal = vault.NewDefaultSeal()
+	}
 
 	// Ensure that the seal finalizer is called, even if using verify-only
 	defer func() {
-		if seal != nil {
-			err = seal.Finalize(context.Background())
-			if err != nil {
+		for _, s := range []vault.Seal{seal, migrationSeal} {
+			if s == nil {
+				continue
+			}
+			if err := s.Finalize(context.Background()); err != nil {
 				c.UI.Error(fmt.Sprintf("Error finalizing seals: %v", err))
 			}
 		}
@@ -453,6 +475,8 @@ func (c *ServerCommand) Run(args []string) int {
 		PluginDirectory:    config.PluginDirectory,
 		EnableUI:           config.EnableUI,
 		EnableRaw:          config.EnableRawEndpoint,
+		PerformanceStandby: config.PerformanceStandby,
+		MetricsHelper:      metricsHelper,
 	}
 	if c.flagDev {
 		coreConfig.DevToken = c.flagDevRootTokenID
@@ -472,6 +496,24 @@ func (c *ServerCommand) Run(args []string) int {
 		}
 	}
 
+	if config.Storage.EnableMetrics {
+		// Keys are only logged hashed with a salt that lives as long as the
+		// process, so the slow operations on a key can be correlated without
+		// revealing it
+		storageSalt, err := salt.NewSalt(context.Background(), nil, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating storage metrics salt: %s", err))
+			return 1
+		}
+		metricsLogger := c.logger.Named("storage.metrics")
+		threshold := config.Storage.SlowOperationThreshold
+		if _, txnOK := coreConfig.Physical.(physical.Transactional); txnOK {
+			coreConfig.Physical = physical.NewTransactionalMetricsBackend(coreConfig.Physical, threshold, storageSalt.GetIdentifiedHMAC, metricsLogger)
+		} else {
+			coreConfig.Physical = physical.NewMetricsBackend(coreConfig.Physical, threshold, storageSalt.GetIdentifiedHMAC, metricsLogger)
+		}
+	}
+
 	if c.flagDevThreeNode {
 		return c.enableThreeNodeDevCluster(coreConfig, info, infoKeys, c.flagDevListenAddr, os.Getenv("VAULT_DEV_TEMP_DIR"))
 	}
@@ -528,12 +570,14 @@ func (c *ServerCommand) Run(args []string) int {
 		coreConfig.RedirectAddr = envAA
 	}
 
-	// Attempt to detect the redirect address, if possible
+	// Attempt to detect the redirect address, if possible. This uses the
+	// storage backend itself, as the wrappers around it for metrics or
+	// latency injection don't implement detection.
 	var detect physical.RedirectDetect
 	if coreConfig.HAPhysical != nil && coreConfig.HAPhysical.HAEnabled() {
 		detect, ok = coreConfig.HAPhysical.(physical.RedirectDetect)
 	} else {
-		detect, ok = coreConfig.Physical.(physical.RedirectDetect)
+		detect, ok = backend.(physical.RedirectDetect)
 	}
 	if ok && coreConfig.RedirectAddr == "" {
 		redirect, err := c.detectRedirect(detect, config)
@@ -672,6 +716,7 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	// Initialize the listeners
 	c.reloadFuncsLock.Lock()
 	lns := make([]net.Listener, 0, len(config.Listeners))
+	unauthenticatedMetrics := make(map[net.Listener]bool)
 	for i, lnConfig := range config.Listeners {
 		ln, props, reloadFunc, err := server.NewListener(lnConfig.Type, lnConfig.Config, c.logGate, c.UI)
 		if err != nil {
@@ -681,6 +726,15 @@ CLUSTER_SYNTHESIS_COMPLETE:
 
 		lns = append(lns, ln)
 
+		if raw, ok := lnConfig.Config["unauthenticated_metrics_access"]; ok {
+			allowed, err := parseutil.ParseBool(raw)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error parsing unauthenticated_metrics_access for listener of type %s: %s", lnConfig.Type, err))
+				return 1
+			}
+			unauthenticatedMetrics[ln] = allowed
+		}
+
 		if reloadFunc != nil {
 			relSlice := (*c.reloadFuncs)["listener|"+lnConfig.Type]
 			relSlice = append(relSlice, reloadFunc)
@@ -783,6 +837,18 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	core.SetClusterListenerAddrs(clusterAddrs)
 	core.SetClusterHandler(handler)
 
+	// Check whether the storage needs to be migrated to the configured seal
+	if err := core.SetSealsForMigration(context.Background(), migrationSeal); err != nil {
+		c.UI.Error(fmt.Sprintf("Error checking for seal migration: %s", err))
+		return 1
+	}
+	if core.SealMigrationPending() {
+		c.UI.Warn(wrapAtLength("WARNING! A seal migration is pending. Unseal " +
+			"Vault with \"vault operator unseal -migrate\" to migrate to the " +
+			"configured seal."))
+		c.UI.Warn("")
+	}
+
 	err = core.UnsealWithStoredKeys(context.Background())
 	if err != nil {
 		if !errwrap.ContainsType(err, new(vault.NonFatalError)) {
@@ -914,8 +980,12 @@ CLUSTER_SYNTHESIS_COMPLETE:
 
 	// Initialize the HTTP servers
 	for _, ln := range lns {
+		lnHandler := handler
+		if unauthenticatedMetrics[ln] {
+			lnHandler = vaulthttp.UnauthenticatedMetricsHandler(core, handler)
+		}
 		server := &http.Server{
-			Handler: handler,
+			Handler: lnHandler,
 		}
 		go server.Serve(ln)
 	}
@@ -940,13 +1010,13 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	c.logGate.Flush()
 
 	// Write out the PID to the file now that server has successfully started
-	if err := c.storePidFile(config.PidFile); err != nil {
+	if err := storePidFile(config.PidFile); err != nil {
 		c.UI.Error(fmt.Sprintf("Error storing PID: %s", err))
 		return 1
 	}
 
 	defer func() {
-		if err := c.removePidFile(config.PidFile); err != nil {
+		if err := removePidFile(config.PidFile); err != nil {
 			c.UI.Error(fmt.Sprintf("Error deleting the PID file: %s", err))
 		}
 	}()
@@ -1398,8 +1468,9 @@ func (c *ServerCommand) detectRedirect(detect physical.RedirectDetect,
 	return url.String(), nil
 }
 
-// setupTelemetry is used to setup the telemetry sub-systems
-func (c *ServerCommand) setupTelemetry(config *server.Config) error {
+// setupTelemetry is used to setup the telemetry sub-systems and returns the
+// helper used to serve the in-memory metrics
+func (c *ServerCommand) setupTelemetry(config *server.Config) (*metricsutil.MetricsHelper, error) {
 	/* Setup telemetry
 	Aggregate on 10 second intervals for 1 minute. Expose the
 	metrics over stderr when there is a SIGUSR1 received.
@@ -1419,10 +1490,19 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 	// Configure the statsite sink
 	var fanout metrics.FanoutSink
+
+	// Configure the Prometheus sink. Prometheus adds its own instance label,
+	// so the hostname is not prefixed to gauge names.
+	var prometheusSink *metricsutil.PrometheusSink
+	if telConfig.PrometheusRetentionTime != 0 {
+		prometheusSink = metricsutil.NewPrometheusSink(telConfig.PrometheusRetentionTime)
+		fanout = append(fanout, prometheusSink)
+		metricsConf.EnableHostname = false
+	}
 	if telConfig.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(telConfig.StatsiteAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -1431,7 +1511,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	if telConfig.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(telConfig.StatsdAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -1467,7 +1547,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
@@ -1482,7 +1562,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 		sink, err := datadog.NewDogStatsdSink(telConfig.DogStatsDAddr, metricsConf.HostName)
 		if err != nil {
-			return errwrap.Wrapf("failed to start DogStatsD sink: {{err}}", err)
+			return nil, errwrap.Wrapf("failed to start DogStatsD sink: {{err}}", err)
 		}
 		sink.SetTags(tags)
 		fanout = append(fanout, sink)
@@ -1496,7 +1576,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 		metricsConf.EnableHostname = false
 		metrics.NewGlobal(metricsConf, inm)
 	}
-	return nil
+	return metricsutil.NewMetricsHelper(inm, prometheusSink), nil
 }
 
 func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]reload.ReloadFunc, configPath []string) error {
@@ -1538,7 +1618,7 @@ func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]rel
 }
 
 // storePidFile is used to write out our PID to a file if necessary
-func (c *ServerCommand) storePidFile(pidPath string) error {
+func storePidFile(pidPath string) error {
 	// Quit fast if no pidfile
 	if pidPath == "" {
 		return nil
@@ -1561,7 +1641,7 @@ func (c *ServerCommand) storePidFile(pidPath string) error {
 }
 
 // removePidFile is used to cleanup the PID file if necessary
-func (c *ServerCommand) removePidFile(pidPath string) error {
+func removePidFile(pidPath string) error {
 	if pidPath == "" {
 		return nil
 	}
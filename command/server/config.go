@@ -17,6 +17,7 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/physical"
 )
 
 // Config is the configuration for the vault server.
@@ -56,6 +57,9 @@ type Config struct {
 	ClusterAddr          string      `hcl:"cluster_addr"`
 	DisableClustering    bool        `hcl:"-"`
 	DisableClusteringRaw interface{} `hcl:"disable_clustering"`
+
+	PerformanceStandby    bool        `hcl:"-"`
+	PerformanceStandbyRaw interface{} `hcl:"performance_standby"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -110,11 +114,13 @@ func (l *Listener) GoString() string {
 
 // Storage is the underlying storage configuration for the server.
 type Storage struct {
-	Type              string
-	RedirectAddr      string
-	ClusterAddr       string
-	DisableClustering bool
-	Config            map[string]string
+	Type                   string
+	RedirectAddr           string
+	ClusterAddr            string
+	DisableClustering      bool
+	EnableMetrics          bool
+	SlowOperationThreshold time.Duration
+	Config                 map[string]string
 }
 
 func (b *Storage) GoString() string {
@@ -123,7 +129,12 @@ func (b *Storage) GoString() string {
 
 // Seal contains Seal configuration for the server
 type Seal struct {
-	Type   string
+	Type string
+
+	// Disabled indicates that the seal is being migrated away from; it is
+	// only used to unseal the existing data during a seal migration.
+	Disabled bool
+
 	Config map[string]string
 }
 
@@ -138,6 +149,13 @@ type Telemetry struct {
 
 	DisableHostname bool `hcl:"disable_hostname"`
 
+	// PrometheusRetentionTime is how long metrics are kept for Prometheus
+	// output after they were last updated. Prometheus output from
+	// sys/metrics is disabled if this is zero.
+	// Default: 0
+	PrometheusRetentionTime    time.Duration `hcl:"-"`
+	PrometheusRetentionTimeRaw interface{}   `hcl:"prometheus_retention_time"`
+
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
 	// Valid configuration combinations:
@@ -314,6 +332,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.PidFile = c2.PidFile
 	}
 
+	result.PerformanceStandby = c.PerformanceStandby
+	if c2.PerformanceStandby {
+		result.PerformanceStandby = c2.PerformanceStandby
+	}
+
 	return result
 }
 
@@ -395,6 +418,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if result.PerformanceStandbyRaw != nil {
+		if result.PerformanceStandby, err = parseutil.ParseBool(result.PerformanceStandbyRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
 		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
@@ -423,6 +452,7 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"api_addr",
 		"cluster_addr",
 		"disable_clustering",
+		"performance_standby",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
@@ -600,6 +630,26 @@ func parseStorage(result *Config, list *ast.ObjectList, name string) error {
 		delete(m, "disable_clustering")
 	}
 
+	// Pull out the metrics options, which wrap the backend rather than
+	// configuring it
+	var enableMetrics bool
+	if v, ok := m["enable_metrics"]; ok {
+		enableMetrics, err = strconv.ParseBool(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+		}
+		delete(m, "enable_metrics")
+	}
+
+	slowOperationThreshold := physical.DefaultSlowOperationThreshold
+	if v, ok := m["slow_operation_threshold"]; ok {
+		slowOperationThreshold, err = parseutil.ParseDurationSecond(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+		}
+		delete(m, "slow_operation_threshold")
+	}
+
 	// Override with top-level values if they are set
 	if result.APIAddr != "" {
 		redirectAddr = result.APIAddr
@@ -614,11 +664,13 @@ func parseStorage(result *Config, list *ast.ObjectList, name string) error {
 	}
 
 	result.Storage = &Storage{
-		RedirectAddr:      redirectAddr,
-		ClusterAddr:       clusterAddr,
-		DisableClustering: disableClustering,
-		Type:              strings.ToLower(key),
-		Config:            m,
+		RedirectAddr:           redirectAddr,
+		ClusterAddr:            clusterAddr,
+		DisableClustering:      disableClustering,
+		EnableMetrics:          enableMetrics,
+		SlowOperationThreshold: slowOperationThreshold,
+		Type:                   strings.ToLower(key),
+		Config:                 m,
 	}
 	return nil
 }
@@ -728,10 +780,25 @@ func parseSeal(result *Config, list *ast.ObjectList, blockName string) error {
 			"kms_key_id",
 			"max_parallel",
 		}
+	case "transit":
+		valid = []string{
+			"address",
+			"token",
+			"mount_path",
+			"key_name",
+			"disable_renewal",
+			"tls_ca_cert",
+			"tls_client_cert",
+			"tls_client_key",
+			"tls_server_name",
+			"tls_skip_verify",
+		}
 	default:
 		return fmt.Errorf("invalid seal type %q", key)
 	}
 
+	valid = append(valid, "disabled")
+
 	if err := checkHCLKeys(item.Val, valid); err != nil {
 		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
 	}
@@ -741,9 +808,20 @@ func parseSeal(result *Config, list *ast.ObjectList, blockName string) error {
 		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
 	}
 
+	var disabled bool
+	var err error
+	if v, ok := m["disabled"]; ok {
+		disabled, err = strconv.ParseBool(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		}
+		delete(m, "disabled")
+	}
+
 	result.Seal = &Seal{
-		Type:   strings.ToLower(key),
-		Config: m,
+		Type:     strings.ToLower(key),
+		Disabled: disabled,
+		Config:   m,
 	}
 
 	return nil
@@ -775,6 +853,7 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 			"tls_disable_client_certs",
 			"tls_client_ca_file",
 			"token",
+			"unauthenticated_metrics_access",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
@@ -822,6 +901,7 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 		"circonus_broker_select_tag",
 		"disable_hostname",
 		"dogstatsd_addr",
+		"prometheus_retention_time",
 		"dogstatsd_tags",
 		"statsd_address",
 		"statsite_address",
@@ -842,6 +922,15 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 	if err := hcl.DecodeObject(&result.Telemetry, item.Val); err != nil {
 		return multierror.Prefix(err, "telemetry:")
 	}
+
+	if result.Telemetry.PrometheusRetentionTimeRaw != nil {
+		var err error
+		if result.Telemetry.PrometheusRetentionTime, err = parseutil.ParseDurationSecond(result.Telemetry.PrometheusRetentionTimeRaw); err != nil {
+			return multierror.Prefix(err, "telemetry.prometheus_retention_time:")
+		}
+		result.Telemetry.PrometheusRetentionTimeRaw = nil
+	}
+
 	return nil
 }
 
@@ -36,6 +36,7 @@ func TestLoadConfigFile(t *testing.T) {
 			Config: map[string]string{
 				"foo": "bar",
 			},
+			SlowOperationThreshold: time.Second,
 		},
 
 		HAStorage: &Storage{
@@ -103,6 +104,7 @@ func TestLoadConfigFile_topLevel(t *testing.T) {
 			Config: map[string]string{
 				"foo": "bar",
 			},
+			SlowOperationThreshold: time.Second,
 		},
 
 		HAStorage: &Storage{
@@ -121,6 +123,8 @@ func TestLoadConfigFile_topLevel(t *testing.T) {
 			DisableHostname: false,
 			DogStatsDAddr:   "127.0.0.1:7254",
 			DogStatsDTags:   []string{"tag_1:val_1", "tag_2:val_2"},
+
+			PrometheusRetentionTime: 30 * time.Second,
 		},
 
 		DisableCache:    true,
@@ -172,7 +176,8 @@ func TestLoadConfigFile_json(t *testing.T) {
 			Config: map[string]string{
 				"foo": "bar",
 			},
-			DisableClustering: true,
+			DisableClustering:      true,
+			SlowOperationThreshold: time.Second,
 		},
 
 		ClusterCipherSuites: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
@@ -243,7 +248,9 @@ func TestLoadConfigFile_json2(t *testing.T) {
 			Config: map[string]string{
 				"foo": "bar",
 			},
-			DisableClustering: true,
+			DisableClustering:      true,
+			EnableMetrics:          true,
+			SlowOperationThreshold: 250 * time.Millisecond,
 		},
 
 		HAStorage: &Storage{
@@ -308,7 +315,8 @@ func TestLoadConfigDir(t *testing.T) {
 			Config: map[string]string{
 				"foo": "bar",
 			},
-			DisableClustering: true,
+			DisableClustering:      true,
+			SlowOperationThreshold: time.Second,
 		},
 
 		EnableUI: true,
@@ -451,3 +459,47 @@ telemetry {
 		t.Errorf("bad error: %q", err)
 	}
 }
+
+func TestParseSeal_transit(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+seal "transit" {
+	address = "https://vault.example.com:8200"
+	token = "s.token"
+	mount_path = "transit/"
+	key_name = "autounseal"
+	disable_renewal = "true"
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Seal{
+		Type: "transit",
+		Config: map[string]string{
+			"address":         "https://vault.example.com:8200",
+			"token":           "s.token",
+			"mount_path":      "transit/",
+			"key_name":        "autounseal",
+			"disable_renewal": "true",
+		},
+	}
+	if !reflect.DeepEqual(config.Seal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Seal, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+seal "transit" {
+	key_name = "autounseal"
+	bad = "one"
+}
+`), logger)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `seal.transit: invalid key "bad" on line 3`) {
+		t.Errorf("bad error: %q", err)
+	}
+}
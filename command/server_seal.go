@@ -0,0 +1,39 @@
+package command
+
+import (
+	"fmt"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/seal/transit"
+)
+
+// configureSeal creates the seal described by the server configuration. If no
+// seal stanza is present the default Shamir seal is returned.
+func configureSeal(config *server.Config, infoKeys *[]string, info map[string]string, logger log.Logger) (vault.Seal, error) {
+	if config.Seal == nil {
+		return vault.NewDefaultSeal(), nil
+	}
+
+	switch config.Seal.Type {
+	case vault.SealTypeShamir:
+		return vault.NewDefaultSeal(), nil
+
+	case vault.SealTypeTransit:
+		transitSeal := transit.NewSeal(logger)
+		sealInfo, err := transitSeal.SetConfig(config.Seal.Config)
+		if err != nil {
+			return nil, err
+		}
+		*infoKeys = append(*infoKeys, "seal type", "transit address", "transit mount path", "transit key name")
+		info["seal type"] = config.Seal.Type
+		info["transit address"] = sealInfo["address"]
+		info["transit mount path"] = sealInfo["mount_path"]
+		info["transit key name"] = sealInfo["key_name"]
+		return vault.NewAutoSeal(transitSeal), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported seal type %q", config.Seal.Type)
+	}
+}
@@ -6,4 +6,8 @@ const (
 	ExpirationRestoreWorkerCount = 64
 
 	VaultKVCLIClientHeader = "X-Vault-Kv-Client"
+
+	// NamespaceHeaderName is the name of the header selecting the namespace
+	// a request is made against
+	NamespaceHeaderName = "X-Vault-Namespace"
 )
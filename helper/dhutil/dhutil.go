@@ -0,0 +1,106 @@
+package dhutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// PublicKeyInfo is the format in which a public key is written so that the
+// other side of the exchange can read it
+type PublicKeyInfo struct {
+	Curve25519PublicKey []byte `json:"curve25519_public_key"`
+}
+
+// Envelope is the format of a payload encrypted with a shared key. It carries
+// the sender's public key so that the recipient can derive the same key.
+type Envelope struct {
+	Curve25519PublicKey []byte `json:"curve25519_public_key"`
+	Nonce               []byte `json:"nonce"`
+	EncryptedPayload    []byte `json:"encrypted_payload"`
+}
+
+// GeneratePublicPrivateKey generates a new Curve25519 key pair, returning the
+// public and then the private key
+func GeneratePublicPrivateKey() ([]byte, []byte, error) {
+	var scalar, public [32]byte
+
+	if _, err := io.ReadFull(rand.Reader, scalar[:]); err != nil {
+		return nil, nil, err
+	}
+
+	curve25519.ScalarBaseMult(&public, &scalar)
+	return public[:], scalar[:], nil
+}
+
+// GenerateSharedKey uses the private key and the other party's public key to
+// generate the shared secret
+func GenerateSharedKey(ourPrivate, theirPublic []byte) ([]byte, error) {
+	if len(ourPrivate) != 32 {
+		return nil, fmt.Errorf("invalid private key length: %d", len(ourPrivate))
+	}
+	if len(theirPublic) != 32 {
+		return nil, fmt.Errorf("invalid public key length: %d", len(theirPublic))
+	}
+
+	var scalar, pub, secret [32]byte
+	copy(scalar[:], ourPrivate)
+	copy(pub[:], theirPublic)
+
+	curve25519.ScalarMult(&secret, &scalar, &pub)
+
+	return secret[:], nil
+}
+
+// EncryptAES encrypts the plaintext with AES-GCM using the given key,
+// returning the ciphertext and the generated nonce
+func EncryptAES(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext := aesgcm.Seal(nil, nonce, plaintext, aad)
+
+	return ciphertext, nonce, nil
+}
+
+// DecryptAES decrypts the ciphertext with AES-GCM using the given key and
+// nonce
+func DecryptAES(key, ciphertext, nonce, aad []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		return nil, errors.New("empty nonce provided")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
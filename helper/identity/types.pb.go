@@ -68,6 +68,10 @@ type Group struct {
 	// the memberships on the external group --for which a corresponding alias
 	// will be set-- will be managed automatically.
 	Type string `sentinel:"" protobuf:"bytes,12,opt,name=type" json:"type,omitempty"`
+	// NamespaceID is the identifier of the namespace to which this group
+	// belongs to. Do not return this value over the API when reading the
+	// group.
+	NamespaceID string `sentinel:"" protobuf:"bytes,13,opt,name=namespace_id,json=namespaceID" json:"namespace_id,omitempty"`
 }
 
 func (m *Group) Reset()                    { *m = Group{} }
@@ -159,6 +163,13 @@ func (m *Group) GetType() string {
 	return ""
 }
 
+func (m *Group) GetNamespaceID() string {
+	if m != nil {
+		return m.NamespaceID
+	}
+	return ""
+}
+
 // Entity represents an entity that gets persisted and indexed.
 // Entity is fundamentally composed of zero or many aliases.
 type Entity struct {
@@ -200,6 +211,10 @@ type Entity struct {
 	// the entities belonging to a particular bucket during invalidation of the
 	// storage key.
 	BucketKeyHash string `sentinel:"" protobuf:"bytes,9,opt,name=bucket_key_hash,json=bucketKeyHash" json:"bucket_key_hash,omitempty"`
+	// NamespaceID is the identifier of the namespace to which this entity
+	// belongs to. Do not return this value over the API when reading the
+	// entity.
+	NamespaceID string `sentinel:"" protobuf:"bytes,11,opt,name=namespace_id,json=namespaceID" json:"namespace_id,omitempty"`
 }
 
 func (m *Entity) Reset()                    { *m = Entity{} }
@@ -270,6 +285,13 @@ func (m *Entity) GetBucketKeyHash() string {
 	return ""
 }
 
+func (m *Entity) GetNamespaceID() string {
+	if m != nil {
+		return m.NamespaceID
+	}
+	return ""
+}
+
 // Alias represents the alias that gets stored inside of the
 // entity object in storage and also represents in an in-memory index of an
 // alias object.
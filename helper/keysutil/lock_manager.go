@@ -48,6 +48,9 @@ type PolicyRequest struct {
 
 	// Whether to allow plaintext backup
 	AllowPlaintextBackup bool
+
+	// Whether to allow rotating an imported key
+	AllowImportedKeyRotation bool
 }
 
 type LockManager struct {
@@ -293,6 +296,56 @@ func (lm *LockManager) RestorePolicy(ctx context.Context, storage logical.Storag
 	return nil
 }
 
+// ImportPolicy acquires an exclusive lock on the policy name and creates a
+// new policy from the given externally generated key material.
+func (lm *LockManager) ImportPolicy(ctx context.Context, req PolicyRequest, key []byte) error {
+	var p *Policy
+	var err error
+
+	lockType := exclusive
+	lock := lm.policyLock(req.Name, lockType)
+	defer lm.UnlockPolicy(lock, lockType)
+
+	// If the policy is in cache, error out
+	if lm.CacheActive() {
+		lm.cacheMutex.RLock()
+		p = lm.cache[req.Name]
+		if p != nil {
+			lm.cacheMutex.RUnlock()
+			return fmt.Errorf("policy %q already exists", req.Name)
+		}
+		lm.cacheMutex.RUnlock()
+	}
+
+	// If the policy exists in storage, error out
+	p, err = lm.getStoredPolicy(ctx, req.Storage, req.Name)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		return fmt.Errorf("policy %q already exists", req.Name)
+	}
+
+	p = NewPolicy(PolicyConfig{
+		Name:                     req.Name,
+		Type:                     req.KeyType,
+		Exportable:               req.Exportable,
+		AllowPlaintextBackup:     req.AllowPlaintextBackup,
+		Imported:                 true,
+		AllowImportedKeyRotation: req.AllowImportedKeyRotation,
+	})
+
+	err = p.Import(ctx, req.Storage, key)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("failed to import the key material for policy %q: {{err}}", req.Name), err)
+	}
+
+	// Update the cache to contain the imported policy
+	lm.UpdateCache(req.Name, p)
+
+	return nil
+}
+
 func (lm *LockManager) BackupPolicy(ctx context.Context, storage logical.Storage, name string) (string, error) {
 	p, lock, err := lm.GetPolicyExclusive(ctx, storage, name)
 	if lock != nil {
@@ -356,13 +409,13 @@ func (lm *LockManager) getPolicyCommon(ctx context.Context, req PolicyRequest, l
 		}
 
 		switch req.KeyType {
-		case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
 			if req.Convergent && !req.Derived {
 				lm.UnlockPolicy(lock, lockType)
 				return nil, nil, false, fmt.Errorf("convergent encryption requires derivation to be enabled")
 			}
 
-		case KeyType_ECDSA_P256:
+		case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 			if req.Derived || req.Convergent {
 				lm.UnlockPolicy(lock, lockType)
 				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
@@ -374,7 +427,7 @@ func (lm *LockManager) getPolicyCommon(ctx context.Context, req PolicyRequest, l
 				return nil, nil, false, fmt.Errorf("convergent encryption not supported for keys of type %v", req.KeyType)
 			}
 
-		case KeyType_RSA2048, KeyType_RSA4096:
+		case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 			if req.Derived || req.Convergent {
 				lm.UnlockPolicy(lock, lockType)
 				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
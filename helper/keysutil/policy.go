@@ -13,6 +13,7 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/json"
@@ -53,6 +54,10 @@ const (
 	KeyType_RSA2048
 	KeyType_RSA4096
 	KeyType_ChaCha20_Poly1305
+	KeyType_AES128_GCM96
+	KeyType_ECDSA_P384
+	KeyType_ECDSA_P521
+	KeyType_RSA3072
 )
 
 const (
@@ -87,7 +92,7 @@ type KeyType int
 
 func (kt KeyType) EncryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -95,7 +100,7 @@ func (kt KeyType) EncryptionSupported() bool {
 
 func (kt KeyType) DecryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -103,7 +108,7 @@ func (kt KeyType) DecryptionSupported() bool {
 
 func (kt KeyType) SigningSupported() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -111,7 +116,7 @@ func (kt KeyType) SigningSupported() bool {
 
 func (kt KeyType) HashSignatureInput() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -119,7 +124,7 @@ func (kt KeyType) HashSignatureInput() bool {
 
 func (kt KeyType) DerivationSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_ED25519:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_ED25519:
 		return true
 	}
 	return false
@@ -139,11 +144,68 @@ func (kt KeyType) String() string {
 		return "rsa-2048"
 	case KeyType_RSA4096:
 		return "rsa-4096"
+	case KeyType_AES128_GCM96:
+		return "aes128-gcm96"
+	case KeyType_ECDSA_P384:
+		return "ecdsa-p384"
+	case KeyType_ECDSA_P521:
+		return "ecdsa-p521"
+	case KeyType_RSA3072:
+		return "rsa-3072"
 	}
 
 	return "[unknown]"
 }
 
+// isECDSA returns whether the key type is one of the ECDSA key types
+func (kt KeyType) isECDSA() bool {
+	switch kt {
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		return true
+	}
+	return false
+}
+
+// isRSA returns whether the key type is one of the RSA key types
+func (kt KeyType) isRSA() bool {
+	switch kt {
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+		return true
+	}
+	return false
+}
+
+// symmetricKeySize returns the size in bytes of the keys of the symmetric key
+// types
+func (kt KeyType) symmetricKeySize() int {
+	if kt == KeyType_AES128_GCM96 {
+		return 16
+	}
+	return 32
+}
+
+// ellipticCurve returns the curve of the ECDSA key types
+func (kt KeyType) ellipticCurve() elliptic.Curve {
+	switch kt {
+	case KeyType_ECDSA_P384:
+		return elliptic.P384()
+	case KeyType_ECDSA_P521:
+		return elliptic.P521()
+	}
+	return elliptic.P256()
+}
+
+// rsaBitSize returns the size in bits of the modulus of the RSA key types
+func (kt KeyType) rsaBitSize() int {
+	switch kt {
+	case KeyType_RSA3072:
+		return 3072
+	case KeyType_RSA4096:
+		return 4096
+	}
+	return 2048
+}
+
 type KeyData struct {
 	Policy       *Policy       `json:"policy"`
 	ArchivedKeys *archivedKeys `json:"archived_keys"`
@@ -238,6 +300,11 @@ type PolicyConfig struct {
 	// StoragePrefix is used to add a prefix when storing and retrieving the
 	// policy object.
 	StoragePrefix string
+
+	// Whether the key material is imported, and whether the key can be
+	// rotated within Vault after that
+	Imported                 bool
+	AllowImportedKeyRotation bool
 }
 
 // NewPolicy takes a policy config and returns a Policy with those settings.
@@ -248,18 +315,20 @@ func NewPolicy(config PolicyConfig) *Policy {
 	}
 
 	return &Policy{
-		Name:                 config.Name,
-		Type:                 config.Type,
-		Derived:              config.Derived,
-		KDF:                  config.KDF,
-		ConvergentEncryption: config.ConvergentEncryption,
-		ConvergentVersion:    convergentVersion,
-		Exportable:           config.Exportable,
-		DeletionAllowed:      config.DeletionAllowed,
-		AllowPlaintextBackup: config.AllowPlaintextBackup,
-		VersionTemplate:      config.VersionTemplate,
-		StoragePrefix:        config.StoragePrefix,
-		versionPrefixCache:   &sync.Map{},
+		Name:                     config.Name,
+		Type:                     config.Type,
+		Derived:                  config.Derived,
+		KDF:                      config.KDF,
+		ConvergentEncryption:     config.ConvergentEncryption,
+		ConvergentVersion:        convergentVersion,
+		Exportable:               config.Exportable,
+		DeletionAllowed:          config.DeletionAllowed,
+		AllowPlaintextBackup:     config.AllowPlaintextBackup,
+		VersionTemplate:          config.VersionTemplate,
+		StoragePrefix:            config.StoragePrefix,
+		Imported:                 config.Imported,
+		AllowImportedKeyRotation: config.AllowImportedKeyRotation,
+		versionPrefixCache:       &sync.Map{},
 	}
 }
 
@@ -307,6 +376,10 @@ type Policy struct {
 	// The minimum version of the key allowed to be used for encryption
 	MinEncryptionVersion int `json:"min_encryption_version"`
 
+	// The minimum version of the key whose key material is still stored.
+	// Versions below it have been trimmed and cannot be restored.
+	MinAvailableVersion int `json:"min_available_version"`
+
 	// The latest key version in this policy
 	LatestVersion int `json:"latest_version"`
 
@@ -343,6 +416,14 @@ type Policy struct {
 	// policy object.
 	StoragePrefix string `json:"storage_prefix"`
 
+	// Imported indicates that the key material was generated outside of
+	// Vault and imported.
+	Imported bool `json:"imported"`
+
+	// AllowImportedKeyRotation allows rotating an imported key, in which case
+	// the new versions are generated by Vault.
+	AllowImportedKeyRotation bool `json:"allow_imported_key_rotation"`
+
 	// versionPrefixCache stores caches of verison prefix strings and the split
 	// version template.
 	versionPrefixCache *sync.Map
@@ -400,7 +481,8 @@ func (p *Policy) handleArchiving(ctx context.Context, storage logical.Storage) e
 	// that now need to be accessible back here.
 	//
 	// For safety, because there isn't really a good reason to, we never delete
-	// keys from the archive even when we move them back.
+	// keys from the archive even when we move them back. Only Trim removes key
+	// material from the archive.
 
 	// Check if we have the latest minimum version in the current set of keys
 	_, keysContainsMinimum := p.Keys[strconv.Itoa(p.MinDecryptionVersion)]
@@ -663,25 +745,26 @@ func (p *Policy) DeriveKey(context []byte, ver int) ([]byte, error) {
 	case Kdf_hmac_sha256_counter:
 		prf := kdf.HMACSHA256PRF
 		prfLen := kdf.HMACSHA256PRFLen
-		return kdf.CounterMode(prf, prfLen, p.Keys[strconv.Itoa(ver)].Key, context, 256)
+		return kdf.CounterMode(prf, prfLen, p.Keys[strconv.Itoa(ver)].Key, context, uint32(8*p.Type.symmetricKeySize()))
 
 	case Kdf_hkdf_sha256:
+		keySize := p.Type.symmetricKeySize()
 		reader := hkdf.New(sha256.New, p.Keys[strconv.Itoa(ver)].Key, nil, context)
 		derBytes := bytes.NewBuffer(nil)
-		derBytes.Grow(32)
+		derBytes.Grow(keySize)
 		limReader := &io.LimitedReader{
 			R: reader,
-			N: 32,
+			N: int64(keySize),
 		}
 
 		switch p.Type {
-		case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
 			n, err := derBytes.ReadFrom(limReader)
 			if err != nil {
 				return nil, errutil.InternalError{Err: fmt.Sprintf("error reading returned derived bytes: %v", err)}
 			}
-			if n != 32 {
-				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed 32, got %d", n)}
+			if n != int64(keySize) {
+				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed %d, got %d", keySize, n)}
 			}
 			return derBytes.Bytes(), nil
 
@@ -704,10 +787,23 @@ func (p *Policy) DeriveKey(context []byte, ver int) ([]byte, error) {
 }
 
 func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string, error) {
+	return p.EncryptWithAssociatedData(ver, context, nonce, value, nil)
+}
+
+// EncryptWithAssociatedData encrypts the value like Encrypt, additionally
+// authenticating the given associated data. The associated data is not part of
+// the ciphertext and must be given again for decryption. It is only supported
+// by AEAD key types, and not with convergent encryption since the nonce would
+// then be reused with different associated data.
+func (p *Policy) EncryptWithAssociatedData(ver int, context, nonce []byte, value string, associatedData []byte) (string, error) {
 	if !p.Type.EncryptionSupported() {
 		return "", errutil.UserError{Err: fmt.Sprintf("message encryption not supported for key type %v", p.Type)}
 	}
 
+	if err := p.checkAssociatedData(associatedData); err != nil {
+		return "", err
+	}
+
 	// Decode the plaintext value
 	plaintext, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
@@ -728,7 +824,7 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 	var ciphertext []byte
 
 	switch p.Type {
-	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
 		// Derive the key that should be used
 		key, err := p.DeriveKey(context, ver)
 		if err != nil {
@@ -738,7 +834,7 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 		var aead cipher.AEAD
 
 		switch p.Type {
-		case KeyType_AES256_GCM96:
+		case KeyType_AES128_GCM96, KeyType_AES256_GCM96:
 			// Setup the cipher
 			aesCipher, err := aes.NewCipher(key)
 			if err != nil {
@@ -783,14 +879,14 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 		}
 
 		// Encrypt and tag with AEAD
-		ciphertext = aead.Seal(nil, nonce, plaintext, nil)
+		ciphertext = aead.Seal(nil, nonce, plaintext, associatedData)
 
 		// Place the encrypted data after the nonce
 		if !p.ConvergentEncryption || p.ConvergentVersion > 1 {
 			ciphertext = append(nonce, ciphertext...)
 		}
 
-	case KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		key := p.Keys[strconv.Itoa(ver)].RSAKey
 		ciphertext, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, plaintext, nil)
 		if err != nil {
@@ -811,10 +907,20 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 }
 
 func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
+	return p.DecryptWithAssociatedData(context, nonce, value, nil)
+}
+
+// DecryptWithAssociatedData decrypts the value like Decrypt, failing unless
+// the given associated data is the one given for encryption
+func (p *Policy) DecryptWithAssociatedData(context, nonce []byte, value string, associatedData []byte) (string, error) {
 	if !p.Type.DecryptionSupported() {
 		return "", errutil.UserError{Err: fmt.Sprintf("message decryption not supported for key type %v", p.Type)}
 	}
 
+	if err := p.checkAssociatedData(associatedData); err != nil {
+		return "", err
+	}
+
 	tplParts, err := p.getTemplateParts()
 	if err != nil {
 		return "", err
@@ -862,7 +968,7 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 	var plain []byte
 
 	switch p.Type {
-	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
 		key, err := p.DeriveKey(context, ver)
 		if err != nil {
 			return "", err
@@ -871,7 +977,7 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 		var aead cipher.AEAD
 
 		switch p.Type {
-		case KeyType_AES256_GCM96:
+		case KeyType_AES128_GCM96, KeyType_AES256_GCM96:
 			// Setup the cipher
 			aesCipher, err := aes.NewCipher(key)
 			if err != nil {
@@ -909,12 +1015,12 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 		}
 
 		// Verify and Decrypt
-		plain, err = aead.Open(nil, nonce, ciphertext, nil)
+		plain, err = aead.Open(nil, nonce, ciphertext, associatedData)
 		if err != nil {
 			return "", errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
 		}
 
-	case KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		key := p.Keys[strconv.Itoa(ver)].RSAKey
 		plain, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, key, decoded, nil)
 		if err != nil {
@@ -928,6 +1034,26 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 	return base64.StdEncoding.EncodeToString(plain), nil
 }
 
+// checkAssociatedData returns an error if associated data is given but cannot
+// be used with the policy
+func (p *Policy) checkAssociatedData(associatedData []byte) error {
+	if len(associatedData) == 0 {
+		return nil
+	}
+
+	switch p.Type {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+	default:
+		return errutil.UserError{Err: fmt.Sprintf("associated data is not supported for key type %v", p.Type)}
+	}
+
+	if p.ConvergentEncryption {
+		return errutil.UserError{Err: "associated data is not supported with convergent encryption"}
+	}
+
+	return nil
+}
+
 func (p *Policy) HMACKey(version int) ([]byte, error) {
 	switch {
 	case version < 0:
@@ -963,11 +1089,11 @@ func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm, sigAlgorith
 	var pubKey []byte
 	var err error
 	switch p.Type {
-	case KeyType_ECDSA_P256:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 		keyParams := p.Keys[strconv.Itoa(ver)]
 		key := &ecdsa.PrivateKey{
 			PublicKey: ecdsa.PublicKey{
-				Curve: elliptic.P256(),
+				Curve: p.Type.ellipticCurve(),
 				X:     keyParams.EC_X,
 				Y:     keyParams.EC_Y,
 			},
@@ -994,7 +1120,7 @@ func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm, sigAlgorith
 			var err error
 			key, err = p.DeriveKey(context, ver)
 			if err != nil {
-				return nil, errutil.InternalError{Err: fmt.Sprintf("error deriving key: %v", err)}
+				return nil, err
 			}
 			pubKey = key.Public().(ed25519.PublicKey)
 		} else {
@@ -1008,7 +1134,7 @@ func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm, sigAlgorith
 			return nil, err
 		}
 
-	case KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		key := p.Keys[strconv.Itoa(ver)].RSAKey
 
 		var algo crypto.Hash
@@ -1097,7 +1223,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig, hashAlgorithm strin
 	}
 
 	switch p.Type {
-	case KeyType_ECDSA_P256:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 		var ecdsaSig ecdsaSignature
 		rest, err := asn1.Unmarshal(sigBytes, &ecdsaSig)
 		if err != nil {
@@ -1109,7 +1235,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig, hashAlgorithm strin
 
 		keyParams := p.Keys[strconv.Itoa(ver)]
 		key := &ecdsa.PublicKey{
-			Curve: elliptic.P256(),
+			Curve: p.Type.ellipticCurve(),
 			X:     keyParams.EC_X,
 			Y:     keyParams.EC_Y,
 		}
@@ -1124,7 +1250,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig, hashAlgorithm strin
 			var err error
 			key, err = p.DeriveKey(context, ver)
 			if err != nil {
-				return false, errutil.InternalError{Err: fmt.Sprintf("error deriving key: %v", err)}
+				return false, err
 			}
 		} else {
 			key = ed25519.PrivateKey(p.Keys[strconv.Itoa(ver)].Key)
@@ -1132,7 +1258,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig, hashAlgorithm strin
 
 		return ed25519.Verify(key.Public().(ed25519.PublicKey), input, sigBytes), nil
 
-	case KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		key := p.Keys[strconv.Itoa(ver)].RSAKey
 
 		var algo crypto.Hash
@@ -1169,34 +1295,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig, hashAlgorithm strin
 	}
 }
 
-func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) (retErr error) {
-	priorLatestVersion := p.LatestVersion
-	priorMinDecryptionVersion := p.MinDecryptionVersion
-	var priorKeys keyEntryMap
-
-	if p.Keys != nil {
-		priorKeys = keyEntryMap{}
-		for k, v := range p.Keys {
-			priorKeys[k] = v
-		}
-	}
-
-	defer func() {
-		if retErr != nil {
-			p.LatestVersion = priorLatestVersion
-			p.MinDecryptionVersion = priorMinDecryptionVersion
-			p.Keys = priorKeys
-		}
-	}()
-
-	if p.Keys == nil {
-		// This is an initial key rotation when generating a new policy. We
-		// don't need to call migrate here because if we've called getPolicy to
-		// get the policy in the first place it will have been run.
-		p.Keys = keyEntryMap{}
-	}
-
-	p.LatestVersion += 1
+func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) error {
 	now := time.Now()
 	entry := KeyEntry{
 		CreationTime:           now,
@@ -1210,16 +1309,16 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) (retErr er
 	entry.HMACKey = hmacKey
 
 	switch p.Type {
-	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
-		// Generate a 256bit key
-		newKey, err := uuid.GenerateRandomBytes(32)
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		// Generate a 128 or 256bit key
+		newKey, err := uuid.GenerateRandomBytes(p.Type.symmetricKeySize())
 		if err != nil {
 			return err
 		}
 		entry.Key = newKey
 
-	case KeyType_ECDSA_P256:
-		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		privKey, err := ecdsa.GenerateKey(p.Type.ellipticCurve(), rand.Reader)
 		if err != nil {
 			return err
 		}
@@ -1248,18 +1347,124 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) (retErr er
 		entry.Key = pri
 		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pub)
 
-	case KeyType_RSA2048, KeyType_RSA4096:
-		bitSize := 2048
-		if p.Type == KeyType_RSA4096 {
-			bitSize = 4096
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+		entry.RSAKey, err = rsa.GenerateKey(rand.Reader, p.Type.rsaBitSize())
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.addKeyEntry(ctx, storage, entry)
+}
+
+// Import adds the given externally generated key material as the latest
+// version of the key. AES and ChaCha20 keys are the raw bytes of the key;
+// asymmetric keys are private keys in PKCS #8 DER form.
+func (p *Policy) Import(ctx context.Context, storage logical.Storage, key []byte) error {
+	now := time.Now()
+	entry := KeyEntry{
+		CreationTime:           now,
+		DeprecatedCreationTime: now.Unix(),
+	}
+
+	hmacKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return err
+	}
+	entry.HMACKey = hmacKey
+
+	switch p.Type {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		if len(key) != p.Type.symmetricKeySize() {
+			return fmt.Errorf("invalid key size %d bytes for key type %v, expected %d bytes", len(key), p.Type, p.Type.symmetricKeySize())
 		}
+		entry.Key = key
 
-		entry.RSAKey, err = rsa.GenerateKey(rand.Reader, bitSize)
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+		privKey, err := parsePKCS8PrivateKey(key)
 		if err != nil {
-			return err
+			return errwrap.Wrapf("error parsing private key: {{err}}", err)
+		}
+
+		switch privKey := privKey.(type) {
+		case *ecdsa.PrivateKey:
+			if !p.Type.isECDSA() || privKey.Curve != p.Type.ellipticCurve() {
+				return fmt.Errorf("ECDSA key on curve %s does not match key type %v", privKey.Curve.Params().Name, p.Type)
+			}
+			entry.EC_D = privKey.D
+			entry.EC_X = privKey.X
+			entry.EC_Y = privKey.Y
+			derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
+			if err != nil {
+				return errwrap.Wrapf("error marshaling public key: {{err}}", err)
+			}
+			pemBlock := &pem.Block{
+				Type:  "PUBLIC KEY",
+				Bytes: derBytes,
+			}
+			pemBytes := pem.EncodeToMemory(pemBlock)
+			if pemBytes == nil || len(pemBytes) == 0 {
+				return fmt.Errorf("error PEM-encoding public key")
+			}
+			entry.FormattedPublicKey = string(pemBytes)
+
+		case ed25519.PrivateKey:
+			if p.Type != KeyType_ED25519 {
+				return fmt.Errorf("Ed25519 key does not match key type %v", p.Type)
+			}
+			entry.Key = privKey
+			entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(privKey.Public().(ed25519.PublicKey))
+
+		case *rsa.PrivateKey:
+			if !p.Type.isRSA() || privKey.N.BitLen() != p.Type.rsaBitSize() {
+				return fmt.Errorf("%d-bit RSA key does not match key type %v", privKey.N.BitLen(), p.Type)
+			}
+			if err := privKey.Validate(); err != nil {
+				return errwrap.Wrapf("invalid RSA key: {{err}}", err)
+			}
+			privKey.Precompute()
+			entry.RSAKey = privKey
+
+		default:
+			return fmt.Errorf("unsupported private key type %T", privKey)
+		}
+
+	default:
+		return fmt.Errorf("unsupported key type %v", p.Type)
+	}
+
+	return p.addKeyEntry(ctx, storage, entry)
+}
+
+// addKeyEntry stores the given entry as the new latest version of the key
+func (p *Policy) addKeyEntry(ctx context.Context, storage logical.Storage, entry KeyEntry) (retErr error) {
+	priorLatestVersion := p.LatestVersion
+	priorMinDecryptionVersion := p.MinDecryptionVersion
+	var priorKeys keyEntryMap
+
+	if p.Keys != nil {
+		priorKeys = keyEntryMap{}
+		for k, v := range p.Keys {
+			priorKeys[k] = v
 		}
 	}
 
+	defer func() {
+		if retErr != nil {
+			p.LatestVersion = priorLatestVersion
+			p.MinDecryptionVersion = priorMinDecryptionVersion
+			p.Keys = priorKeys
+		}
+	}()
+
+	if p.Keys == nil {
+		// This is an initial key rotation when generating a new policy. We
+		// don't need to call migrate here because if we've called getPolicy to
+		// get the policy in the first place it will have been run.
+		p.Keys = keyEntryMap{}
+	}
+
+	p.LatestVersion += 1
 	p.Keys[strconv.Itoa(p.LatestVersion)] = entry
 
 	// This ensures that with new key creations min decryption version is set
@@ -1272,6 +1477,56 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) (retErr er
 	return p.Persist(ctx, storage)
 }
 
+// Trim permanently deletes the key material of the versions below
+// minAvailableVersion, both from the key ring and from the archive. The
+// versions to delete must already be below the minimum decryption version.
+func (p *Policy) Trim(ctx context.Context, storage logical.Storage, minAvailableVersion int) error {
+	switch {
+	case minAvailableVersion < 1:
+		return errutil.UserError{Err: "minimum available version must be at least 1"}
+	case minAvailableVersion < p.MinAvailableVersion:
+		return errutil.UserError{Err: fmt.Sprintf("minimum available version cannot be decreased; currently at %d", p.MinAvailableVersion)}
+	case minAvailableVersion > p.MinDecryptionVersion:
+		return errutil.UserError{Err: fmt.Sprintf("minimum available version of %d is greater than minimum decryption version %d", minAvailableVersion, p.MinDecryptionVersion)}
+	case p.MinEncryptionVersion > 0 && minAvailableVersion > p.MinEncryptionVersion:
+		return errutil.UserError{Err: fmt.Sprintf("minimum available version of %d is greater than minimum encryption version %d", minAvailableVersion, p.MinEncryptionVersion)}
+	}
+
+	// Make sure every version is in the archive before deleting from it
+	if err := p.Persist(ctx, storage); err != nil {
+		return err
+	}
+
+	// Persist the new floor before removing any key material, so that a
+	// failure part way through never leaves the policy pointing at keys
+	// that no longer exist
+	priorMinAvailableVersion := p.MinAvailableVersion
+	priorKeys := keyEntryMap{}
+	for k, v := range p.Keys {
+		priorKeys[k] = v
+	}
+	for i := 1; i < minAvailableVersion; i++ {
+		delete(p.Keys, strconv.Itoa(i))
+	}
+	p.MinAvailableVersion = minAvailableVersion
+	if err := p.Persist(ctx, storage); err != nil {
+		p.MinAvailableVersion = priorMinAvailableVersion
+		p.Keys = priorKeys
+		return err
+	}
+
+	// The trimmed versions are now unusable; if zeroing them out of the
+	// archive fails, trimming again to the same version finishes the job
+	archive, err := p.LoadArchive(ctx, storage)
+	if err != nil {
+		return err
+	}
+	for i := 1; i < minAvailableVersion && i < len(archive.Keys); i++ {
+		archive.Keys[i] = KeyEntry{}
+	}
+	return p.storeArchive(ctx, storage, archive)
+}
+
 func (p *Policy) MigrateKeyToKeysMap() {
 	now := time.Now()
 	p.Keys = keyEntryMap{
@@ -1368,3 +1623,41 @@ func (p *Policy) getVersionPrefix(ver int) string {
 
 	return prefix
 }
+
+// pkcs8 mirrors the PKCS #8 private key structure, used to recognize the
+// Ed25519 keys which the x509 package cannot parse
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// oidEd25519 is the algorithm identifier of Ed25519 keys, from RFC 8410
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// parsePKCS8PrivateKey parses a PKCS #8 DER encoded RSA, ECDSA or Ed25519
+// private key
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	var privKey pkcs8
+	if _, err := asn1.Unmarshal(der, &privKey); err != nil {
+		return nil, err
+	}
+	if !privKey.Algo.Algorithm.Equal(oidEd25519) {
+		return x509.ParsePKCS8PrivateKey(der)
+	}
+
+	// The private key of an Ed25519 key is the 32 byte seed wrapped in
+	// another octet string
+	var seed []byte
+	if _, err := asn1.Unmarshal(privKey.PrivateKey, &seed); err != nil {
+		return nil, errwrap.Wrapf("invalid Ed25519 private key: {{err}}", err)
+	}
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("invalid Ed25519 seed size %d bytes", len(seed))
+	}
+	_, pri, err := ed25519.GenerateKey(bytes.NewReader(seed))
+	if err != nil {
+		return nil, err
+	}
+	return pri, nil
+}
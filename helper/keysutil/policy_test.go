@@ -306,6 +306,84 @@ func testArchivingCommon(t *testing.T, lm *LockManager) {
 	}
 }
 
+func Test_Trim(t *testing.T) {
+	testTrimCommon(t, NewLockManager(false))
+	testTrimCommon(t, NewLockManager(true))
+}
+
+func testTrimCommon(t *testing.T, lm *LockManager) {
+	ctx := context.Background()
+
+	storage := &logical.InmemStorage{}
+	p, lock, _, err := lm.GetPolicyUpsert(ctx, PolicyRequest{
+		Storage: storage,
+		KeyType: KeyType_AES256_GCM96,
+		Name:    "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || lock == nil {
+		t.Fatal("nil policy or lock")
+	}
+	lock.RUnlock()
+
+	keysArchive := []KeyEntry{KeyEntry{}, p.Keys["1"]}
+	for i := 2; i <= 10; i++ {
+		err = p.Rotate(ctx, storage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keysArchive = append(keysArchive, p.Keys[strconv.Itoa(i)])
+	}
+
+	// Versions still available for decryption cannot be trimmed
+	if err := p.Trim(ctx, storage, 2); err == nil {
+		t.Fatal("expected an error trimming above the min decryption version")
+	}
+
+	p.MinDecryptionVersion = 5
+	p.MinEncryptionVersion = 4
+	if err := p.Trim(ctx, storage, 5); err == nil {
+		t.Fatal("expected an error trimming above the min encryption version")
+	}
+	p.MinEncryptionVersion = 0
+
+	err = p.Trim(ctx, storage, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 4; i++ {
+		keysArchive[i] = KeyEntry{}
+	}
+	checkKeys(t, ctx, p, storage, keysArchive, "trim", 10, 10, 6)
+	if p.MinAvailableVersion != 4 {
+		t.Fatalf("expected min available version 4, found %d", p.MinAvailableVersion)
+	}
+
+	// The trimmed versions are gone from storage too
+	p, err = LoadPolicy(ctx, storage, "policy/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MinAvailableVersion != 4 {
+		t.Fatalf("expected min available version 4, found %d", p.MinAvailableVersion)
+	}
+	checkKeys(t, ctx, p, storage, keysArchive, "load", 10, 10, 6)
+
+	// Trimmed versions cannot be brought back
+	if err := p.Trim(ctx, storage, 3); err == nil {
+		t.Fatal("expected an error decreasing the min available version")
+	}
+
+	p.MinDecryptionVersion = 4
+	err = p.Persist(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKeys(t, ctx, p, storage, keysArchive, "minsub", 10, 10, 7)
+}
+
 func checkKeys(t *testing.T,
 	ctx context.Context,
 	p *Policy,
@@ -576,3 +654,65 @@ func Test_BadArchive(t *testing.T) {
 		t.Fatalf("unexpected key length %d", len(p.Keys))
 	}
 }
+
+func Test_KeySizes(t *testing.T) {
+	ctx := context.Background()
+	lm := NewLockManager(false)
+	storage := &logical.InmemStorage{}
+
+	upsert := func(name string, keyType KeyType, derived bool) *Policy {
+		t.Helper()
+		p, lock, _, err := lm.GetPolicyUpsert(ctx, PolicyRequest{
+			Storage: storage,
+			KeyType: keyType,
+			Name:    name,
+			Derived: derived,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p == nil || lock == nil {
+			t.Fatal("nil policy or lock")
+		}
+		lock.RUnlock()
+		return p
+	}
+
+	p := upsert("aes128", KeyType_AES128_GCM96, false)
+	if n := len(p.Keys["1"].Key); n != 16 {
+		t.Fatalf("bad AES-128 key size: %d", n)
+	}
+
+	p = upsert("aes128-derived", KeyType_AES128_GCM96, true)
+	key, err := p.DeriveKey([]byte("context"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 16 {
+		t.Fatalf("bad derived AES-128 key size: %d", len(key))
+	}
+	ciphertext, err := p.Encrypt(0, []byte("context"), nil, "dGhlIHF1aWNrIGJyb3duIGZveA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := p.Decrypt([]byte("context"), nil, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+		t.Fatalf("bad plaintext: %q", plaintext)
+	}
+
+	for _, keyType := range []KeyType{KeyType_ECDSA_P384, KeyType_ECDSA_P521} {
+		p = upsert(keyType.String(), keyType, false)
+		entry := p.Keys["1"]
+		if !keyType.ellipticCurve().IsOnCurve(entry.EC_X, entry.EC_Y) {
+			t.Fatalf("%v: public key is not on the curve", keyType)
+		}
+	}
+
+	p = upsert("rsa3072", KeyType_RSA3072, false)
+	if n := p.Keys["1"].RSAKey.N.BitLen(); n != 3072 {
+		t.Fatalf("bad RSA-3072 modulus size: %d", n)
+	}
+}
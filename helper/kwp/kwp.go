@@ -0,0 +1,125 @@
+// Package kwp implements the AES Key Wrap with Padding algorithm defined in
+// RFC 5649. It is the symmetric half of the CKM_RSA_AES_KEY_WRAP mechanism
+// that HSMs and cloud key managers use to export key material.
+package kwp
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// maxKeySize caps the size of the keys that can be wrapped
+	maxKeySize = 1 << 16
+)
+
+var (
+	// alternativeIV is the constant prefix of the integrity check value
+	alternativeIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+
+	// ErrInvalidWrappedKey is returned when a wrapped key fails the integrity
+	// check, which is also the case when the wrong wrapping key is used
+	ErrInvalidWrappedKey = errors.New("wrapped key failed the integrity check")
+)
+
+// Wrap wraps the given key with the AES key kek
+func Wrap(kek, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 || len(key) > maxKeySize {
+		return nil, errors.New("invalid size of the key to wrap")
+	}
+
+	// Pad the key to a multiple of the semiblock size
+	n := (len(key) + 7) / 8
+	out := make([]byte, 8*(n+1))
+	copy(out, alternativeIV)
+	binary.BigEndian.PutUint32(out[4:8], uint32(len(key)))
+	copy(out[8:], key)
+
+	// A single semiblock is encrypted along with the integrity check value
+	// as one block
+	if n == 1 {
+		block.Encrypt(out, out)
+		return out, nil
+	}
+
+	var b [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b[:8], out[:8])
+			copy(b[8:], out[8*i:8*i+8])
+			block.Encrypt(b[:], b[:])
+
+			xorCounter(b[:8], uint64(n*j+i))
+			copy(out[:8], b[:8])
+			copy(out[8*i:8*i+8], b[8:])
+		}
+	}
+	return out, nil
+}
+
+// Unwrap unwraps the given wrapped key with the AES key kek, verifying its
+// integrity
+func Unwrap(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 || len(wrapped) > maxKeySize+16 {
+		return nil, errors.New("invalid size of the wrapped key")
+	}
+
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+
+	if n == 1 {
+		block.Decrypt(out, out)
+	} else {
+		var b [16]byte
+		for j := 5; j >= 0; j-- {
+			for i := n; i >= 1; i-- {
+				copy(b[:8], out[:8])
+				xorCounter(b[:8], uint64(n*j+i))
+				copy(b[8:], out[8*i:8*i+8])
+				block.Decrypt(b[:], b[:])
+
+				copy(out[:8], b[:8])
+				copy(out[8*i:8*i+8], b[8:])
+			}
+		}
+	}
+
+	// Verify the integrity check value, the length of the key and that the
+	// padding is all zeros, without revealing which of them failed
+	valid := subtle.ConstantTimeCompare(out[:4], alternativeIV)
+	size := int(binary.BigEndian.Uint32(out[4:8]))
+	valid &= subtle.ConstantTimeLessOrEq(8*(n-1)+1, size)
+	valid &= subtle.ConstantTimeLessOrEq(size, 8*n)
+	if valid != 1 {
+		return nil, ErrInvalidWrappedKey
+	}
+	var padding byte
+	for _, c := range out[8+size:] {
+		padding |= c
+	}
+	if padding != 0 {
+		return nil, ErrInvalidWrappedKey
+	}
+
+	return out[8 : 8+size], nil
+}
+
+// xorCounter XORs the big-endian counter t into the semiblock a
+func xorCounter(a []byte, t uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], t)
+	for i := range a {
+		a[i] ^= buf[i]
+	}
+}
@@ -0,0 +1,70 @@
+package kwp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKWP_RFC5649(t *testing.T) {
+	// Test vectors from section 6 of RFC 5649
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	cases := []struct {
+		key     string
+		wrapped string
+	}{
+		{
+			key:     "c37b7e6492584340bed12207808941155068f738",
+			wrapped: "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			key:     "466f7250617369",
+			wrapped: "afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+
+	for _, tc := range cases {
+		key, _ := hex.DecodeString(tc.key)
+		expected, _ := hex.DecodeString(tc.wrapped)
+
+		wrapped, err := Wrap(kek, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(wrapped, expected) {
+			t.Fatalf("bad wrapped key: %x", wrapped)
+		}
+
+		unwrapped, err := Unwrap(kek, wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Fatalf("bad unwrapped key: %x", unwrapped)
+		}
+	}
+}
+
+func TestKWP_Unwrap_Invalid(t *testing.T) {
+	kek := bytes.Repeat([]byte{1}, 32)
+	key := bytes.Repeat([]byte{2}, 32)
+
+	wrapped, err := Wrap(kek, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append([]byte(nil), wrapped...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := Unwrap(kek, tampered); err != ErrInvalidWrappedKey {
+		t.Fatalf("expected integrity failure, got: %v", err)
+	}
+
+	if _, err := Unwrap(bytes.Repeat([]byte{3}, 32), wrapped); err != ErrInvalidWrappedKey {
+		t.Fatalf("expected integrity failure, got: %v", err)
+	}
+
+	if _, err := Unwrap(kek, wrapped[:len(wrapped)-1]); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,58 @@
+package metricsutil
+
+import (
+	"errors"
+	"io"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+const (
+	// PrometheusMetricFormat is the format requested to get metrics in the
+	// Prometheus text exposition format
+	PrometheusMetricFormat = "prometheus"
+
+	// PrometheusContentType is the content type of the Prometheus text
+	// exposition format
+	PrometheusContentType = "text/plain; version=0.0.4"
+)
+
+// ErrPrometheusDisabled is returned when metrics are requested in the
+// Prometheus format but no retention time has been configured
+var ErrPrometheusDisabled = errors.New("prometheus metrics are not enabled; set prometheus_retention_time in the telemetry configuration")
+
+// MetricsHelper gives access to the metrics retained in memory by the server
+type MetricsHelper struct {
+	inmemSink      *metrics.InmemSink
+	prometheusSink *PrometheusSink
+}
+
+// NewMetricsHelper returns a helper for the given sinks. The Prometheus sink
+// may be nil if Prometheus output is disabled.
+func NewMetricsHelper(inmem *metrics.InmemSink, prometheus *PrometheusSink) *MetricsHelper {
+	return &MetricsHelper{
+		inmemSink:      inmem,
+		prometheusSink: prometheus,
+	}
+}
+
+// PrometheusEnabled returns whether metrics can be output in the Prometheus
+// format
+func (m *MetricsHelper) PrometheusEnabled() bool {
+	return m.prometheusSink != nil
+}
+
+// WritePrometheus writes the retained metrics to w in the Prometheus text
+// exposition format
+func (m *MetricsHelper) WritePrometheus(w io.Writer) error {
+	if m.prometheusSink == nil {
+		return ErrPrometheusDisabled
+	}
+	return m.prometheusSink.WriteMetrics(w)
+}
+
+// Summary returns a summary of the metrics from the most recently completed
+// in-memory interval, suitable for encoding as JSON
+func (m *MetricsHelper) Summary() (interface{}, error) {
+	return m.inmemSink.DisplayMetrics(nil, nil)
+}
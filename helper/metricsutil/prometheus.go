@@ -0,0 +1,206 @@
+package metricsutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// Verify PrometheusSink satisfies the correct interfaces
+var _ metrics.MetricSink = (*PrometheusSink)(nil)
+
+var (
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+const (
+	metricTypeGauge   = "gauge"
+	metricTypeCounter = "counter"
+	metricTypeSummary = "summary"
+)
+
+// series is a single metric name and label combination
+type series struct {
+	name       string
+	labels     []metrics.Label
+	metricType string
+
+	// value holds a gauge's last value or a counter's running total. For
+	// summaries, sum and count hold the totals of all observations.
+	value      float64
+	sum        float64
+	count      uint64
+	lastUpdate time.Time
+}
+
+// PrometheusSink is a metrics sink that keeps running values for every metric
+// so that they can be scraped in the Prometheus text exposition format.
+// Metrics that have not been updated within the retention time are dropped.
+type PrometheusSink struct {
+	retention time.Duration
+
+	l      sync.Mutex
+	series map[string]*series
+}
+
+// NewPrometheusSink returns a sink that retains metrics for the given time
+// after their last update
+func NewPrometheusSink(retention time.Duration) *PrometheusSink {
+	return &PrometheusSink{
+		retention: retention,
+		series:    make(map[string]*series),
+	}
+}
+
+func (p *PrometheusSink) SetGauge(key []string, val float32) {
+	p.SetGaugeWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.update(metricTypeGauge, key, labels, func(s *series) {
+		s.value = float64(val)
+	})
+}
+
+func (p *PrometheusSink) EmitKey(key []string, val float32) {
+}
+
+func (p *PrometheusSink) IncrCounter(key []string, val float32) {
+	p.IncrCounterWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.update(metricTypeCounter, key, labels, func(s *series) {
+		s.value += float64(val)
+	})
+}
+
+func (p *PrometheusSink) AddSample(key []string, val float32) {
+	p.AddSampleWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.update(metricTypeSummary, key, labels, func(s *series) {
+		s.sum += float64(val)
+		s.count++
+	})
+}
+
+func (p *PrometheusSink) update(metricType string, key []string, labels []metrics.Label, f func(*series)) {
+	name := flattenName(key)
+	id := seriesID(metricType, name, labels)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	s, ok := p.series[id]
+	if !ok {
+		s = &series{
+			name:       name,
+			labels:     labels,
+			metricType: metricType,
+		}
+		p.series[id] = s
+	}
+	f(s)
+	s.lastUpdate = time.Now()
+}
+
+// WriteMetrics writes every retained metric to w in the Prometheus text
+// exposition format, dropping any that have expired
+func (p *PrometheusSink) WriteMetrics(w io.Writer) error {
+	p.l.Lock()
+	cutoff := time.Now().Add(-p.retention)
+	byName := make(map[string][]series)
+	for id, s := range p.series {
+		if s.lastUpdate.Before(cutoff) {
+			delete(p.series, id)
+			continue
+		}
+		byName[s.name] = append(byName[s.name], *s)
+	}
+	p.l.Unlock()
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		all := byName[name]
+		sort.Slice(all, func(i, j int) bool {
+			return formatLabels(all[i].labels) < formatLabels(all[j].labels)
+		})
+
+		// A name can only have a single type; the first one seen wins
+		metricType := all[0].metricType
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, name)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, metricType)
+		for _, s := range all {
+			if s.metricType != metricType {
+				continue
+			}
+			labels := formatLabels(s.labels)
+			switch metricType {
+			case metricTypeSummary:
+				fmt.Fprintf(bw, "%s_sum%s %s\n", name, labels, formatValue(s.sum))
+				fmt.Fprintf(bw, "%s_count%s %d\n", name, labels, s.count)
+			default:
+				fmt.Fprintf(bw, "%s%s %s\n", name, labels, formatValue(s.value))
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+func flattenName(key []string) string {
+	return invalidNameChars.ReplaceAllString(strings.Join(key, "_"), "_")
+}
+
+func seriesID(metricType, name string, labels []metrics.Label) string {
+	return metricType + "|" + name + formatLabels(labels)
+}
+
+func formatLabels(labels []metrics.Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	sorted := make([]metrics.Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	parts := make([]string, 0, len(sorted))
+	for _, label := range sorted {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", invalidNameChars.ReplaceAllString(label.Name, "_"), labelValueEscaper.Replace(label.Value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,58 @@
+package metricsutil
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	p := NewPrometheusSink(time.Hour)
+
+	p.SetGauge([]string{"vault", "core", "unsealed"}, 1)
+	p.IncrCounter([]string{"vault", "route", "read"}, 1)
+	p.IncrCounter([]string{"vault", "route", "read"}, 2)
+	p.AddSampleWithLabels([]string{"vault", "barrier.get"}, 2.5, []metrics.Label{{Name: "path", Value: `a"b`}})
+	p.AddSampleWithLabels([]string{"vault", "barrier.get"}, 1.5, []metrics.Label{{Name: "path", Value: `a"b`}})
+
+	var buf bytes.Buffer
+	if err := p.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP vault_barrier_get vault_barrier_get
+# TYPE vault_barrier_get summary
+vault_barrier_get_sum{path="a\"b"} 4
+vault_barrier_get_count{path="a\"b"} 2
+# HELP vault_core_unsealed vault_core_unsealed
+# TYPE vault_core_unsealed gauge
+vault_core_unsealed 1
+# HELP vault_route_read vault_route_read
+# TYPE vault_route_read counter
+vault_route_read 3
+`
+	if buf.String() != expected {
+		t.Fatalf("bad output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestPrometheusSink_Retention(t *testing.T) {
+	p := NewPrometheusSink(50 * time.Millisecond)
+
+	p.SetGauge([]string{"old"}, 1)
+	time.Sleep(100 * time.Millisecond)
+	p.SetGauge([]string{"new"}, 2)
+
+	var buf bytes.Buffer
+	if err := p.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("old")) {
+		t.Fatalf("expected expired metric to be dropped:\n%s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("new 2")) {
+		t.Fatalf("expected retained metric:\n%s", buf.String())
+	}
+}
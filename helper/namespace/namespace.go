@@ -0,0 +1,110 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Namespace represents a namespace within Vault. Namespaces are nested; the
+// path of a namespace includes the paths of all of its parents and always
+// ends in a slash, except for the root namespace whose path is empty.
+type Namespace struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+const (
+	// RootNamespaceID is the ID of the root namespace
+	RootNamespaceID = "root"
+)
+
+var (
+	contextNamespace contextValues = "namespace"
+
+	// ErrNoNamespace is returned when no namespace is stored in a context
+	ErrNoNamespace = errors.New("no namespace")
+
+	// RootNamespace is the namespace that all other namespaces descend from
+	RootNamespace = &Namespace{
+		ID:   RootNamespaceID,
+		Path: "",
+	}
+)
+
+type contextValues string
+
+// HasParent returns whether the given namespace is an ancestor of this one
+func (n *Namespace) HasParent(possibleParent *Namespace) bool {
+	switch {
+	case n.Path == "":
+		return false
+	case possibleParent.Path == "":
+		return true
+	default:
+		return strings.HasPrefix(n.Path, possibleParent.Path) && n.Path != possibleParent.Path
+	}
+}
+
+// Contains returns whether the given namespace is this namespace or one of
+// its descendants
+func (n *Namespace) Contains(ns *Namespace) bool {
+	return n.ID == ns.ID || ns.HasParent(n)
+}
+
+// TrimmedPath returns the given path relative to this namespace
+func (n *Namespace) TrimmedPath(path string) string {
+	return strings.TrimPrefix(path, n.Path)
+}
+
+// ContextWithNamespace returns a copy of the context carrying the namespace
+func ContextWithNamespace(ctx context.Context, ns *Namespace) context.Context {
+	return context.WithValue(ctx, contextNamespace, ns)
+}
+
+// RootContext returns a copy of the context carrying the root namespace. If
+// the context is nil a background context is used.
+func RootContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return ContextWithNamespace(context.Background(), RootNamespace)
+	}
+	return ContextWithNamespace(ctx, RootNamespace)
+}
+
+// FromContext retrieves the namespace from a context, returning
+// ErrNoNamespace if none is present
+func FromContext(ctx context.Context) (*Namespace, error) {
+	if ctx == nil {
+		return nil, errors.New("context was nil")
+	}
+
+	nsRaw := ctx.Value(contextNamespace)
+	if nsRaw == nil {
+		return nil, ErrNoNamespace
+	}
+
+	ns := nsRaw.(*Namespace)
+	if ns == nil {
+		return nil, ErrNoNamespace
+	}
+
+	return ns, nil
+}
+
+// Canonicalize trims any prefix '/' and adds a trailing '/' to the provided
+// namespace path
+func Canonicalize(nsPath string) string {
+	if nsPath == "" {
+		return ""
+	}
+
+	// Canonicalize the path to not have a '/' prefix
+	nsPath = strings.TrimPrefix(nsPath, "/")
+
+	// Canonicalize the path to always having a '/' suffix
+	if !strings.HasSuffix(nsPath, "/") {
+		nsPath += "/"
+	}
+
+	return nsPath
+}
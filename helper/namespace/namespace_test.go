@@ -0,0 +1,76 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNamespace_HasParent(t *testing.T) {
+	ns1 := &Namespace{ID: "a", Path: "ns1/"}
+	ns2 := &Namespace{ID: "b", Path: "ns1/ns2/"}
+	other := &Namespace{ID: "c", Path: "ns10/"}
+
+	tests := []struct {
+		ns     *Namespace
+		parent *Namespace
+		want   bool
+	}{
+		{ns1, RootNamespace, true},
+		{ns2, RootNamespace, true},
+		{ns2, ns1, true},
+		{ns1, ns1, false},
+		{ns1, ns2, false},
+		{other, ns1, false},
+		{RootNamespace, RootNamespace, false},
+		{RootNamespace, ns1, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ns.HasParent(tt.parent); got != tt.want {
+			t.Errorf("%q.HasParent(%q): expected %t, got %t", tt.ns.Path, tt.parent.Path, tt.want, got)
+		}
+	}
+
+	if !ns1.Contains(ns1) || !ns1.Contains(ns2) || ns2.Contains(ns1) || !RootNamespace.Contains(ns2) {
+		t.Fatal("unexpected result from Contains")
+	}
+}
+
+func TestNamespace_Context(t *testing.T) {
+	if _, err := FromContext(context.Background()); err != ErrNoNamespace {
+		t.Fatalf("expected ErrNoNamespace, got %v", err)
+	}
+
+	ns := &Namespace{ID: "a", Path: "ns1/"}
+	got, err := FromContext(ContextWithNamespace(context.Background(), ns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != ns {
+		t.Fatalf("bad: %#v", got)
+	}
+
+	got, err = FromContext(RootContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != RootNamespaceID {
+		t.Fatalf("bad: %#v", got)
+	}
+}
+
+func TestNamespace_Canonicalize(t *testing.T) {
+	tests := map[string]string{
+		"":          "",
+		"ns1":       "ns1/",
+		"/ns1":      "ns1/",
+		"ns1/":      "ns1/",
+		"/ns1/ns2/": "ns1/ns2/",
+	}
+
+	for in, want := range tests {
+		if got := Canonicalize(in); got != want {
+			t.Errorf("Canonicalize(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -22,6 +23,7 @@ import (
 	"github.com/hashicorp/vault/builtin/logical/transit"
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 	"github.com/hashicorp/vault/vault"
 )
 
@@ -577,3 +579,262 @@ func TestHTTP_Forwarding_HelpOperation(t *testing.T) {
 	testHelp(cores[0].Client)
 	testHelp(cores[1].Client)
 }
+
+func TestHTTP_PerfStandby(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		PerformanceStandby: true,
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	standby := cores[1]
+	deadline := time.Now().Add(15 * time.Second)
+	for !standby.Core.PerfStandby() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for performance standby")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	client := standby.Client
+	health, err := client.Sys().Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !health.Standby || !health.PerformanceStandby {
+		t.Fatalf("bad: %#v", health)
+	}
+
+	// Writes are forwarded to the active node
+	_, err = client.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads are served by the standby once the write has been invalidated
+	deadline = time.Now().Add(15 * time.Second)
+	for {
+		secret, err := client.Logical().Read("secret/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret != nil && secret.Data["value"] == "bar" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the written value, got: %#v", secret)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestHTTP_PerfStandby_LeasedSecret(t *testing.T) {
+	// The creds backend issues a credential on every read
+	var issued uint32
+	credsFactory := func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b := &framework.Backend{
+			BackendType: logical.TypeLogical,
+			Paths: []*framework.Path{
+				&framework.Path{
+					Pattern: "creds",
+					Callbacks: map[logical.Operation]framework.OperationFunc{
+						logical.ReadOperation: func(context.Context, *logical.Request, *framework.FieldData) (*logical.Response, error) {
+							n := atomic.AddUint32(&issued, 1)
+							resp := &logical.Response{
+								Data: map[string]interface{}{
+									"username": fmt.Sprintf("user-%d", n),
+								},
+								Secret: &logical.Secret{
+									InternalData: map[string]interface{}{
+										"secret_type": "creds",
+									},
+								},
+							}
+							resp.Secret.TTL = time.Hour
+							return resp, nil
+						},
+					},
+				},
+			},
+			Secrets: []*framework.Secret{
+				&framework.Secret{
+					Type: "creds",
+					Revoke: func(context.Context, *logical.Request, *framework.FieldData) (*logical.Response, error) {
+						return nil, nil
+					},
+				},
+			},
+		}
+		if err := b.Setup(ctx, conf); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		PerformanceStandby: true,
+		LogicalBackends: map[string]logical.Factory{
+			"creds": credsFactory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	if err := cores[0].Client.Sys().Mount("creds", &api.MountInput{
+		Type: "creds",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	standby := cores[1]
+	deadline := time.Now().Add(15 * time.Second)
+	for !standby.Core.PerfStandby() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for performance standby")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Wait for the standby to load the mount; until then the read is not
+	// routed to the backend at all
+	deadline = time.Now().Add(15 * time.Second)
+	for {
+		mounts, err := standby.Client.Sys().ListMounts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := mounts["creds/"]; ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the mount")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The read is served by the active node, which issues the credential
+	// once and registers its lease
+	secret, err := standby.Client.Logical().Read("creds/creds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", secret)
+	}
+	if n := atomic.LoadUint32(&issued); n != 1 {
+		t.Fatalf("expected one credential to be issued, got %d", n)
+	}
+
+	leases, err := cores[0].Client.Logical().List("sys/leases/lookup/creds/creds/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leases == nil || len(leases.Data["keys"].([]interface{})) != 1 {
+		t.Fatalf("expected one lease, got: %#v", leases)
+	}
+}
+
+func TestHTTP_PerfStandby_Writes(t *testing.T) {
+	// Count the requests each core handles, including the forwarded ones
+	var activeRequests uint32
+	var active *vault.Core
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		PerformanceStandby: true,
+		LogicalBackends: map[string]logical.Factory{
+			"transit": transit.Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: func(core *vault.Core) http.Handler {
+			handler := Handler(core)
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if core == active {
+					atomic.AddUint32(&activeRequests, 1)
+				}
+				handler.ServeHTTP(w, r)
+			})
+		},
+	})
+	cores := cluster.Cores
+	active = cores[0].Core
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	vault.TestWaitActive(t, active)
+
+	client := cores[0].Client
+	if err := client.Sys().Mount("transit", &api.MountInput{
+		Type: "transit",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("transit/keys/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	standby := cores[1]
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		secret, err := standby.Client.Logical().Read("transit/keys/foo")
+		if err == nil && secret != nil && standby.Core.PerfStandby() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the key on the performance standby: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Encryption and decryption are served by the standby, although they
+	// are writes
+	atomic.StoreUint32(&activeRequests, 0)
+	plaintext := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	secret, err := standby.Client.Logical().Write("transit/encrypt/foo", map[string]interface{}{
+		"plaintext": plaintext,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = standby.Client.Logical().Write("transit/decrypt/foo", map[string]interface{}{
+		"ciphertext": secret.Data["ciphertext"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["plaintext"] != plaintext {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+	if n := atomic.LoadUint32(&activeRequests); n != 0 {
+		t.Fatalf("expected the active node to handle no requests, got %d", n)
+	}
+
+	// Requests that need a write are forwarded along with their body
+	if _, err := standby.Client.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadUint32(&activeRequests); n != 1 {
+		t.Fatalf("expected the active node to handle the write, got %d requests", n)
+	}
+	secret, err = client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+}
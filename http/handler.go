@@ -1,9 +1,11 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,6 +17,7 @@ import (
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
@@ -79,14 +82,16 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/rekey/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, false)))
 	mux.Handle("/v1/sys/rekey-recovery-key/init", handleRequestForwarding(core, handleSysRekeyInit(core, true)))
 	mux.Handle("/v1/sys/rekey-recovery-key/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, true)))
+	mux.Handle("/v1/sys/storage/snapshot", handleRequestForwarding(core, handleSysStorageSnapshot(core)))
+	mux.Handle("/v1/sys/storage/snapshot-restore", handleRequestForwarding(core, handleSysStorageSnapshotRestore(core)))
 	mux.Handle("/v1/sys/wrapping/lookup", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/rewrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/unwrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	for _, path := range injectDataIntoTopRoutes {
-		mux.Handle(path, handleRequestForwarding(core, handleLogical(core, true, nil)))
+		mux.Handle(path, handlePerfStandbyRequestForwarding(core, handleLogical(core, true, nil)))
 	}
-	mux.Handle("/v1/sys/", handleRequestForwarding(core, handleLogical(core, false, nil)))
-	mux.Handle("/v1/", handleRequestForwarding(core, handleLogical(core, false, nil)))
+	mux.Handle("/v1/sys/", handlePerfStandbyRequestForwarding(core, handleLogical(core, false, nil)))
+	mux.Handle("/v1/", handlePerfStandbyRequestForwarding(core, handleLogical(core, false, nil)))
 	if core.UIEnabled() == true {
 		if uiBuiltIn {
 			mux.Handle("/ui/", http.StripPrefix("/ui/", handleUIHeaders(core, handleUI(http.FileServer(&UIAssetWrapper{FileSystem: assetFS()})))))
@@ -276,33 +281,67 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 		}
 
 		// Attempt forwarding the request. If we cannot forward -- perhaps it's
-		// been disabled on the active node -- this will return with an
-		// ErrCannotForward and we simply fall back
-		statusCode, header, retBytes, err := core.ForwardRequest(r)
-		if err != nil {
-			if err == vault.ErrCannotForward {
-				core.Logger().Debug("handleRequestForwarding: cannot forward (possibly disabled on active node), falling back")
-			} else {
-				core.Logger().Error("handleRequestForwarding: error forwarding request", "error", err)
-			}
-
+		// been disabled on the active node -- we simply fall back
+		if !forwardRequest(core, w, r) {
 			// Fall back to redirection
 			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+// handlePerfStandbyRequestForwarding is like handleRequestForwarding, except
+// that performance standbys serve the requests themselves. Those that turn
+// out to need a write are forwarded by request, so their body is buffered to
+// be sent again.
+func handlePerfStandbyRequestForwarding(core *vault.Core, handler http.Handler) http.Handler {
+	forwardingHandler := handleRequestForwarding(core, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !core.PerfStandby() {
+			forwardingHandler.ServeHTTP(w, r)
 			return
 		}
 
-		if header != nil {
-			for k, v := range header {
-				w.Header()[k] = v
-			}
+		// Limit the maximum number of bytes to MaxRequestSize, as when
+		// parsing the request
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestSize))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to read request body: {{err}}", err))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
 		}
 
-		w.WriteHeader(statusCode)
-		w.Write(retBytes)
-		return
+		handler.ServeHTTP(w, r)
 	})
 }
 
+// forwardRequest forwards the request to the active node and writes out its
+// response. It returns false if the request could not be forwarded, in which
+// case nothing has been written.
+func forwardRequest(core *vault.Core, w http.ResponseWriter, r *http.Request) bool {
+	statusCode, header, retBytes, err := core.ForwardRequest(r)
+	if err != nil {
+		if err == vault.ErrCannotForward {
+			core.Logger().Debug("forwardRequest: cannot forward (possibly disabled on active node), falling back")
+		} else {
+			core.Logger().Error("forwardRequest: error forwarding request", "error", err)
+		}
+		return false
+	}
+
+	if header != nil {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+	}
+
+	w.WriteHeader(statusCode)
+	w.Write(retBytes)
+	return true
+}
+
 // request is a helper to perform a request and properly exit in the
 // case of an error.
 func request(core *vault.Core, w http.ResponseWriter, rawReq *http.Request, r *logical.Request) (*logical.Response, bool) {
@@ -311,6 +350,24 @@ func request(core *vault.Core, w http.ResponseWriter, rawReq *http.Request, r *l
 		respondStandby(core, w, rawReq.URL)
 		return resp, false
 	}
+	if errwrap.Contains(err, logical.ErrReadOnly.Error()) && core.PerfStandby() {
+		// The request needs a write that this performance standby cannot
+		// make, so it has to be served by the active node. The body was
+		// buffered so that it can be sent again.
+		if rawReq.Header.Get(NoRequestForwardingHeaderName) == "" && rawReq.GetBody != nil {
+			body, err := rawReq.GetBody()
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, err)
+				return resp, false
+			}
+			rawReq.Body = body
+			if forwardRequest(core, w, rawReq) {
+				return resp, false
+			}
+		}
+		respondStandby(core, w, rawReq.URL)
+		return resp, false
+	}
 	if respondErrorCommon(w, r, resp, err) {
 		return resp, false
 	}
@@ -361,6 +418,16 @@ func respondStandby(core *vault.Core, w http.ResponseWriter, reqURL *url.URL) {
 	w.WriteHeader(307)
 }
 
+// namespacedPath prefixes the given request path with the namespace selected
+// by the namespace header, if any
+func namespacedPath(r *http.Request, path string) string {
+	ns := r.Header.Get(consts.NamespaceHeaderName)
+	if ns == "" {
+		return path
+	}
+	return namespace.Canonicalize(ns) + path
+}
+
 // requestAuth adds the token to the logical.Request if it exists.
 func requestAuth(core *vault.Core, r *http.Request, req *logical.Request) *logical.Request {
 	// Attach the header value if we have it
@@ -27,6 +27,7 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 	if path == "" {
 		return nil, http.StatusNotFound, nil
 	}
+	path = namespacedPath(r, path)
 
 	// Determine the operation
 	var op logical.Operation
@@ -161,6 +161,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"explicit_max_ttl": json.Number("0"),
 			"expire_time":      nil,
 			"entity_id":        "",
+			"namespace_path":   "",
 		},
 		"warnings":  nilWarnings,
 		"wrap_info": nil,
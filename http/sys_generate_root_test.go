@@ -324,6 +324,7 @@ func TestSysGenerateRoot_Update_OTP(t *testing.T) {
 		"explicit_max_ttl": json.Number("0"),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"namespace_path":   "",
 	}
 
 	resp = testHttpGet(t, newRootToken, addr+"/v1/auth/token/lookup-self")
@@ -419,6 +420,7 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 		"explicit_max_ttl": json.Number("0"),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"namespace_path":   "",
 	}
 
 	resp = testHttpGet(t, newRootToken, addr+"/v1/auth/token/lookup-self")
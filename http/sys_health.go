@@ -75,6 +75,7 @@ func handleSysHealthHead(core *vault.Core, w http.ResponseWriter, r *http.Reques
 func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, error) {
 	// Check if being a standby is allowed for the purpose of a 200 OK
 	_, standbyOK := r.URL.Query()["standbyok"]
+	_, perfStandbyOK := r.URL.Query()["perfstandbyok"]
 
 	uninitCode := http.StatusNotImplemented
 	if code, found, ok := fetchStatusCode(r, "uninitcode"); !ok {
@@ -97,6 +98,13 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		standbyCode = code
 	}
 
+	perfStandbyCode := 473 // unofficial 4xx status code
+	if code, found, ok := fetchStatusCode(r, "performancestandbycode"); !ok {
+		return http.StatusBadRequest, nil, nil
+	} else if found {
+		perfStandbyCode = code
+	}
+
 	activeCode := http.StatusOK
 	if code, found, ok := fetchStatusCode(r, "activecode"); !ok {
 		return http.StatusBadRequest, nil, nil
@@ -116,6 +124,7 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 	// Check system status
 	sealed, _ := core.Sealed()
 	standby, _ := core.Standby()
+	perfStandby := core.PerfStandby()
 	var replicationState consts.ReplicationState
 	if standby {
 		replicationState = core.ActiveNodeReplicationState()
@@ -137,6 +146,10 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		code = sealedCode
 	case replicationState.HasState(consts.ReplicationDRSecondary):
 		code = drSecondaryCode
+	case perfStandby:
+		if !perfStandbyOK {
+			code = perfStandbyCode
+		}
 	case !standbyOK && standby:
 		code = standbyCode
 	}
@@ -160,6 +173,7 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		Initialized:                init,
 		Sealed:                     sealed,
 		Standby:                    standby,
+		PerformanceStandby:         perfStandby,
 		ReplicationPerformanceMode: replicationState.GetPerformanceString(),
 		ReplicationDRMode:          replicationState.GetDRString(),
 		ServerTimeUTC:              time.Now().UTC().Unix(),
@@ -174,6 +188,7 @@ type HealthResponse struct {
 	Initialized                bool   `json:"initialized"`
 	Sealed                     bool   `json:"sealed"`
 	Standby                    bool   `json:"standby"`
+	PerformanceStandby         bool   `json:"performance_standby"`
 	ReplicationPerformanceMode string `json:"replication_performance_mode"`
 	ReplicationDRMode          string `json:"replication_dr_mode"`
 	ServerTimeUTC              int64  `json:"server_time_utc"`
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// UnauthenticatedMetricsHandler wraps the given handler so that requests to
+// sys/metrics are served without requiring a token. All other requests are
+// passed through to the wrapped handler.
+func UnauthenticatedMetricsHandler(core *vault.Core, h http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/sys/metrics", wrapGenericHandler(handleSysMetrics(core)))
+	mux.Handle("/", h)
+	return mux
+}
+
+func handleSysMetrics(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "sys/metrics",
+		}
+		resp, err := core.MetricsResponse(r.URL.Query().Get("format"))
+		if respondErrorCommon(w, req, resp, err) {
+			return
+		}
+
+		respondRaw(w, r, resp)
+	})
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/vault"
+)
+
+func testMetricsCluster(t *testing.T, prometheus bool) *vault.TestCluster {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	var promSink *metricsutil.PrometheusSink
+	if prometheus {
+		promSink = metricsutil.NewPrometheusSink(time.Minute)
+	}
+	fanout := metrics.FanoutSink{inm}
+	if promSink != nil {
+		fanout = append(fanout, promSink)
+	}
+	fanout.IncrCounter([]string{"test", "counter"}, 1)
+
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		MetricsHelper: metricsutil.NewMetricsHelper(inm, promSink),
+	}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	return cluster
+}
+
+func TestSysMetrics(t *testing.T) {
+	cluster := testMetricsCluster(t, true)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	// JSON summary
+	req := client.NewRequest("GET", "/v1/sys/metrics")
+	resp, err := client.RawRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary map[string]interface{}
+	if err := resp.DecodeJSON(&summary); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, ok := summary["Counters"]; !ok {
+		t.Fatalf("expected counters in summary, got %#v", summary)
+	}
+
+	// Prometheus text exposition
+	req = client.NewRequest("GET", "/v1/sys/metrics")
+	req.Params.Set("format", "prometheus")
+	resp, err = client.RawRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != metricsutil.PrometheusContentType {
+		t.Fatalf("bad content type: %q", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "# TYPE test_counter counter") {
+		t.Fatalf("missing counter in output:\n%s", body)
+	}
+
+	// Unknown formats are rejected
+	req = client.NewRequest("GET", "/v1/sys/metrics")
+	req.Params.Set("format", "bogus")
+	if _, err := client.RawRequest(req); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+
+	// Metrics require a token unless the listener allows otherwise
+	req = client.NewRequest("GET", "/v1/sys/metrics")
+	req.ClientToken = ""
+	if _, err := client.RawRequest(req); err == nil {
+		t.Fatal("expected error reading metrics without a token")
+	}
+}
+
+func TestSysMetrics_prometheusDisabled(t *testing.T) {
+	cluster := testMetricsCluster(t, false)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	req := client.NewRequest("GET", "/v1/sys/metrics")
+	req.Params.Set("format", "prometheus")
+	resp, err := client.RawRequest(req)
+	if err == nil {
+		t.Fatal("expected error when prometheus is disabled")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad response: %#v", resp)
+	}
+}
+
+func TestSysMetrics_unauthenticated(t *testing.T) {
+	cluster := testMetricsCluster(t, true)
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	server := httptest.NewServer(UnauthenticatedMetricsHandler(core, Handler(core)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/sys/metrics?format=prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 200)
+
+	// Other paths still require a token
+	resp, err = http.Get(server.URL + "/v1/sys/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 400)
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysNamespaces_Header(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	if _, err := client.Logical().Write("sys/namespaces/ns1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	nsClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsClient.SetToken(client.Token())
+	nsClient.SetNamespace("ns1")
+
+	// Requests made with the namespace header are routed within the namespace
+	if _, err := nsClient.Logical().Write("sys/namespaces/child", nil); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().List("ns1/sys/namespaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || !reflect.DeepEqual(secret.Data["keys"], []interface{}{"child/"}) {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// The header and the path prefix combine for nested namespaces
+	nsClient.SetNamespace("/ns1/child/")
+	if err := nsClient.Sys().Mount("kv", &api.MountInput{Type: "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nsClient.Logical().Write("kv/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Logical().Read("ns1/child/kv/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	nsClient.SetNamespace("ns1")
+	secret, err = nsClient.Logical().Read("child/kv/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+}
@@ -28,9 +28,11 @@ func TestSysRotate(t *testing.T) {
 		"warnings":       nil,
 		"auth":           nil,
 		"data": map[string]interface{}{
-			"term": json.Number("2"),
+			"term":        json.Number("2"),
+			"encryptions": json.Number("1"),
 		},
-		"term": json.Number("2"),
+		"term":        json.Number("2"),
+		"encryptions": json.Number("1"),
 	}
 
 	testResponseStatus(t, resp, 200)
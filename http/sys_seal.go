@@ -124,9 +124,12 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 
 			// Attempt the unseal
 			ctx := context.Background()
-			if core.SealAccess().RecoveryKeySupported() {
+			switch {
+			case req.Migrate:
+				_, err = core.UnsealMigrate(ctx, key)
+			case core.SealAccess().RecoveryKeySupported():
 				_, err = core.UnsealWithRecoveryKeys(ctx, key)
-			} else {
+			default:
 				_, err = core.Unseal(key)
 			}
 			if err != nil {
@@ -136,6 +139,8 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 				case errwrap.Contains(err, vault.ErrBarrierNotInit.Error()):
 				case errwrap.Contains(err, vault.ErrBarrierSealed.Error()):
 				case errwrap.Contains(err, consts.ErrStandby.Error()):
+				case errwrap.Contains(err, vault.ErrSealMigrationPending.Error()):
+				case errwrap.Contains(err, vault.ErrNoSealMigration.Error()):
 				default:
 					respondError(w, http.StatusInternalServerError, err)
 					return
@@ -205,31 +210,36 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 	progress, nonce := core.SecretProgress()
 
 	respondOk(w, &SealStatusResponse{
-		Type:        sealConfig.Type,
-		Sealed:      sealed,
-		T:           sealConfig.SecretThreshold,
-		N:           sealConfig.SecretShares,
-		Progress:    progress,
-		Nonce:       nonce,
-		Version:     version.GetVersion().VersionNumber(),
-		ClusterName: clusterName,
-		ClusterID:   clusterID,
+		Type:         sealConfig.Type,
+		Sealed:       sealed,
+		T:            sealConfig.SecretThreshold,
+		N:            sealConfig.SecretShares,
+		Progress:     progress,
+		Nonce:        nonce,
+		Version:      version.GetVersion().VersionNumber(),
+		ClusterName:  clusterName,
+		ClusterID:    clusterID,
+		RecoverySeal: core.SealAccess().RecoveryKeySupported(),
+		Migration:    core.SealMigrationPending(),
 	})
 }
 
 type SealStatusResponse struct {
-	Type        string `json:"type"`
-	Sealed      bool   `json:"sealed"`
-	T           int    `json:"t"`
-	N           int    `json:"n"`
-	Progress    int    `json:"progress"`
-	Nonce       string `json:"nonce"`
-	Version     string `json:"version"`
-	ClusterName string `json:"cluster_name,omitempty"`
-	ClusterID   string `json:"cluster_id,omitempty"`
+	Type         string `json:"type"`
+	Sealed       bool   `json:"sealed"`
+	T            int    `json:"t"`
+	N            int    `json:"n"`
+	Progress     int    `json:"progress"`
+	Nonce        string `json:"nonce"`
+	Version      string `json:"version"`
+	ClusterName  string `json:"cluster_name,omitempty"`
+	ClusterID    string `json:"cluster_id,omitempty"`
+	RecoverySeal bool   `json:"recovery_seal"`
+	Migration    bool   `json:"migration"`
 }
 
 type UnsealRequest struct {
-	Key   string
-	Reset bool
+	Key     string
+	Reset   bool
+	Migrate bool
 }
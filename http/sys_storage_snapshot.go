@@ -0,0 +1,102 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func handleSysStorageSnapshot(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, statusCode, err := buildLogicalRequest(core, w, r)
+		if err != nil || statusCode != 0 {
+			respondError(w, statusCode, err)
+			return
+		}
+
+		switch req.Operation {
+		case logical.ReadOperation:
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// The headers are only sent once the request has been authorized and
+		// the snapshot starts streaming, so that earlier errors can still be
+		// reported. Later errors cut the stream short, which fails its
+		// checksum.
+		sw := &snapshotResponseWriter{w: w}
+		if err := core.SaveSnapshot(req, sw); err != nil {
+			if sw.wroteHeader {
+				core.Logger().Error("snapshot stream interrupted", "error", err)
+				return
+			}
+			respondSnapshotError(w, err)
+			return
+		}
+	})
+}
+
+func handleSysStorageSnapshotRestore(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+		case "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// The body is the snapshot itself rather than JSON, so the request is
+		// built here instead of with buildLogicalRequest
+		requestID, err := uuid.GenerateUUID()
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err))
+			return
+		}
+		req := requestAuth(core, r, &logical.Request{
+			ID:         requestID,
+			Operation:  logical.UpdateOperation,
+			Path:       "sys/storage/snapshot-restore",
+			Connection: getConnection(r),
+			Headers:    r.Header,
+		})
+
+		if err := core.RestoreSnapshot(req, r.Body); err != nil {
+			respondSnapshotError(w, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+func respondSnapshotError(w http.ResponseWriter, err error) {
+	switch {
+	case errwrap.Contains(err, logical.ErrPermissionDenied.Error()):
+		respondError(w, http.StatusForbidden, err)
+	case errwrap.Contains(err, physical.ErrInvalidSnapshot.Error()):
+		respondError(w, http.StatusBadRequest, err)
+	default:
+		respondError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// snapshotResponseWriter sends the snapshot headers on the first write
+type snapshotResponseWriter struct {
+	w           http.ResponseWriter
+	wroteHeader bool
+}
+
+func (s *snapshotResponseWriter) Write(p []byte) (int, error) {
+	if !s.wroteHeader {
+		s.w.Header().Set("Content-Type", "application/gzip")
+		s.w.WriteHeader(http.StatusOK)
+		s.wroteHeader = true
+	}
+	return s.w.Write(p)
+}
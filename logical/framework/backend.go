@@ -235,6 +235,12 @@ func (b *Backend) Cleanup(ctx context.Context) {
 	}
 }
 
+// GeneratesLeases is the logical.LeaseGenerator implementation. A backend
+// may generate leases if it defines secret types.
+func (b *Backend) GeneratesLeases() bool {
+	return len(b.Secrets) > 0
+}
+
 // InvalidateKey is used to clear caches and reset internal state on key changes
 func (b *Backend) InvalidateKey(ctx context.Context, key string) {
 	if b.Invalidate != nil {
@@ -84,6 +84,12 @@ type Backend interface {
 	Type() BackendType
 }
 
+// LeaseGenerator is implemented by backends that can tell whether they may
+// return secrets that get leases
+type LeaseGenerator interface {
+	GeneratesLeases() bool
+}
+
 // BackendConfig is provided to the factory to initialize the backend
 type BackendConfig struct {
 	// View should not be stored, and should only be used for initialization
@@ -276,4 +276,8 @@ var (
 	// ErrMultiAuthzPending is returned if the the request needs more
 	// authorizations
 	ErrMultiAuthzPending = errors.New("request needs further approval")
+
+	// ErrLeaseCountQuotaExceeded is returned if the request would create a
+	// lease beyond the limit of a lease count quota
+	ErrLeaseCountQuotaExceeded = errors.New("lease count quota exceeded")
 )
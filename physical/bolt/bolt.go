@@ -0,0 +1,383 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	boltdb "github.com/boltdb/bolt"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// openTimeout is how long to wait for the file lock of the database,
+	// which is held by any other process that has it open
+	openTimeout = 1 * time.Second
+
+	// defaultBackupInterval is how often a backup is written when a backup
+	// path is configured without an interval
+	defaultBackupInterval = 1 * time.Hour
+)
+
+// initialMmapSize is the size the database is memory mapped with. Writes
+// that grow the database past the mapped size wait for the read transactions,
+// such as consistent reads, to end, so a large size is mapped where address
+// space is plentiful. On Windows the file would be grown to the mapped size.
+func initialMmapSize() int {
+	if runtime.GOOS == "windows" || strconv.IntSize < 64 {
+		return 0
+	}
+	return 1 << 30
+}
+
+var (
+	dataBucketName = []byte("data")
+
+	// errReadOnlyView is returned when writing to the view of a consistent
+	// read
+	errReadOnlyView = errors.New("cannot write to a consistent read view")
+)
+
+// Verify BoltBackend satisfies the correct interfaces
+var _ physical.Backend = (*BoltBackend)(nil)
+var _ physical.Transactional = (*BoltBackend)(nil)
+var _ physical.ConsistentReader = (*BoltBackend)(nil)
+
+// BoltBackend is a physical backend that stores all the data in a single
+// BoltDB file. Every operation is an ACID transaction on that file.
+type BoltBackend struct {
+	path       string
+	db         *boltdb.DB
+	logger     log.Logger
+	permitPool *physical.PermitPool
+
+	// stopCh and doneCh stop the periodic backups, if they are enabled
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewBoltBackend constructs a BoltBackend using the given file, which is
+// created if it does not exist.
+func NewBoltBackend(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+	path, ok := conf["path"]
+	if !ok || path == "" {
+		return nil, fmt.Errorf("'path' must be set")
+	}
+
+	noSync := false
+	if noSyncStr, ok := conf["no_sync"]; ok {
+		var err error
+		noSync, err = strconv.ParseBool(noSyncStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing no_sync parameter: {{err}}", err)
+		}
+	}
+
+	maxParStr, ok := conf["max_parallel"]
+	var maxParInt int
+	if ok {
+		var err error
+		maxParInt, err = strconv.Atoi(maxParStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing max_parallel parameter: {{err}}", err)
+		}
+		if logger.IsDebug() {
+			logger.Debug("max_parallel set", "max_parallel", maxParInt)
+		}
+	} else {
+		maxParInt = physical.DefaultParallelOperations
+	}
+
+	backupPath := conf["backup_path"]
+	backupInterval := defaultBackupInterval
+	if backupIntervalStr, ok := conf["backup_interval"]; ok {
+		if backupPath == "" {
+			return nil, fmt.Errorf("'backup_interval' requires 'backup_path' to be set")
+		}
+		var err error
+		backupInterval, err = time.ParseDuration(backupIntervalStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing backup_interval parameter: {{err}}", err)
+		}
+		if backupInterval <= 0 {
+			return nil, fmt.Errorf("'backup_interval' must be positive")
+		}
+	}
+	if backupPath != "" {
+		if filepath.Clean(backupPath) == filepath.Clean(path) {
+			return nil, fmt.Errorf("'backup_path' must differ from 'path'")
+		}
+		if err := os.MkdirAll(filepath.Dir(backupPath), 0700); err != nil {
+			return nil, errwrap.Wrapf("failed to create bolt backup directory: {{err}}", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, errwrap.Wrapf("failed to create bolt directory: {{err}}", err)
+	}
+
+	db, err := boltdb.Open(path, 0600, &boltdb.Options{
+		Timeout:         openTimeout,
+		InitialMmapSize: initialMmapSize(),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to open bolt database: {{err}}", err)
+	}
+	db.NoSync = noSync
+
+	err = db.Update(func(tx *boltdb.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(dataBucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errwrap.Wrapf("failed to create bolt bucket: {{err}}", err)
+	}
+
+	b := &BoltBackend{
+		path:       path,
+		db:         db,
+		logger:     logger,
+		permitPool: physical.NewPermitPool(maxParInt),
+	}
+
+	if backupPath != "" {
+		b.stopCh = make(chan struct{})
+		b.doneCh = make(chan struct{})
+		go b.runBackups(backupPath, backupInterval)
+		if logger.IsDebug() {
+			logger.Debug("backups enabled", "backup_path", backupPath, "backup_interval", backupInterval)
+		}
+	}
+
+	return b, nil
+}
+
+// Close stops the periodic backups and closes the underlying database file
+func (b *BoltBackend) Close() error {
+	if b.stopCh != nil {
+		close(b.stopCh)
+		<-b.doneCh
+	}
+	return b.db.Close()
+}
+
+// runBackups writes a backup to the given path every interval until the
+// backend is closed
+func (b *BoltBackend) runBackups(path string, interval time.Duration) {
+	defer close(b.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+			if err := b.Backup(context.Background(), path); err != nil {
+				b.logger.Error("periodic backup failed", "error", err)
+			}
+		}
+	}
+}
+
+// Put is used to insert or update an entry
+func (b *BoltBackend) Put(ctx context.Context, entry *physical.Entry) error {
+	defer metrics.MeasureSince([]string{"bolt", "put"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.db.Update(func(tx *boltdb.Tx) error {
+		return tx.Bucket(dataBucketName).Put([]byte(entry.Key), entry.Value)
+	})
+}
+
+// Get is used to fetch an entry
+func (b *BoltBackend) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	defer metrics.MeasureSince([]string{"bolt", "get"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	var entry *physical.Entry
+	err := b.db.View(func(tx *boltdb.Tx) error {
+		entry = getTx(tx, key)
+		return nil
+	})
+	return entry, err
+}
+
+// Delete is used to permanently delete an entry
+func (b *BoltBackend) Delete(ctx context.Context, key string) error {
+	defer metrics.MeasureSince([]string{"bolt", "delete"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.db.Update(func(tx *boltdb.Tx) error {
+		return tx.Bucket(dataBucketName).Delete([]byte(key))
+	})
+}
+
+// List is used to list all the keys under a given prefix, up to the next
+// prefix.
+func (b *BoltBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"bolt", "list"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	var keys []string
+	err := b.db.View(func(tx *boltdb.Tx) error {
+		keys = listTx(tx, prefix)
+		return nil
+	})
+	return keys, err
+}
+
+// Transaction applies all the given operations in a single BoltDB
+// transaction, so either all or none of them take effect.
+func (b *BoltBackend) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	defer metrics.MeasureSince([]string{"bolt", "transaction"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.db.Update(func(tx *boltdb.Tx) error {
+		bucket := tx.Bucket(dataBucketName)
+		for _, txn := range txns {
+			var err error
+			switch txn.Operation {
+			case physical.PutOperation:
+				err = bucket.Put([]byte(txn.Entry.Key), txn.Entry.Value)
+			case physical.DeleteOperation:
+				err = bucket.Delete([]byte(txn.Entry.Key))
+			default:
+				err = fmt.Errorf("%q is not a supported transaction operation", txn.Operation)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConsistentRead calls fn with a view of the entries within a single read
+// transaction. Writes go on meanwhile, unless they grow the database past
+// its memory mapped size, in which case they wait for the transaction to
+// end.
+func (b *BoltBackend) ConsistentRead(ctx context.Context, fn func(physical.Backend) error) error {
+	return b.db.View(func(tx *boltdb.Tx) error {
+		return fn(&boltReadView{tx: tx})
+	})
+}
+
+// boltReadView is a read-only backend reading within a transaction
+type boltReadView struct {
+	tx *boltdb.Tx
+}
+
+func (v *boltReadView) Put(ctx context.Context, entry *physical.Entry) error {
+	return errReadOnlyView
+}
+
+func (v *boltReadView) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	return getTx(v.tx, key), nil
+}
+
+func (v *boltReadView) Delete(ctx context.Context, key string) error {
+	return errReadOnlyView
+}
+
+func (v *boltReadView) List(ctx context.Context, prefix string) ([]string, error) {
+	return listTx(v.tx, prefix), nil
+}
+
+// getTx returns the entry stored at the given key, or nil if it does not
+// exist
+func getTx(tx *boltdb.Tx, key string) *physical.Entry {
+	val := tx.Bucket(dataBucketName).Get([]byte(key))
+	if val == nil {
+		return nil
+	}
+
+	// The returned slice is only valid for the life of the transaction, so
+	// copy it out
+	value := make([]byte, len(val))
+	copy(value, val)
+	return &physical.Entry{
+		Key:   key,
+		Value: value,
+	}
+}
+
+// listTx returns the keys directly under the given prefix, with any deeper
+// keys collapsed into their first path segment followed by a slash
+func listTx(tx *boltdb.Tx, prefix string) []string {
+	var keys []string
+	c := tx.Bucket(dataBucketName).Cursor()
+
+	prefixBytes := []byte(prefix)
+	for k, _ := c.Seek(prefixBytes); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+		key := strings.TrimPrefix(string(k), prefix)
+		if i := strings.Index(key, "/"); i != -1 {
+			key = key[:i+1]
+		}
+
+		// Keys are walked in order, so any duplicate folder entries are
+		// adjacent
+		if len(keys) > 0 && keys[len(keys)-1] == key {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Backup writes a consistent copy of the database to the given file while
+// the backend remains available. The copy is written to a temporary file
+// first, so an existing backup is only replaced by a complete one.
+func (b *BoltBackend) Backup(ctx context.Context, path string) error {
+	defer metrics.MeasureSince([]string{"bolt", "backup"}, time.Now())
+
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return errwrap.Wrapf("failed to create backup file: {{err}}", err)
+	}
+
+	err = b.db.View(func(tx *boltdb.Tx) error {
+		_, err := tx.WriteTo(file)
+		return err
+	})
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("failed to write backup: {{err}}", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("failed to move backup into place: {{err}}", err)
+	}
+
+	b.logger.Info("wrote backup", "path", path)
+	return nil
+}
@@ -0,0 +1,269 @@
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/physical"
+)
+
+func getBolt(t testing.TB) (*BoltBackend, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "vault-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logging.NewVaultLogger(log.Debug)
+
+	b, err := NewBoltBackend(map[string]string{
+		"path": filepath.Join(dir, "vault.db"),
+	}, logger)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return b.(*BoltBackend), dir
+}
+
+func TestBoltBackend(t *testing.T) {
+	b, dir := getBolt(t)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	physical.ExerciseBackend(t, b)
+	physical.ExerciseBackend_ListPrefix(t, b)
+}
+
+func TestBoltBackend_Transaction(t *testing.T) {
+	b, dir := getBolt(t)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	physical.ExerciseTransactionalBackend(t, b)
+}
+
+func TestBoltBackend_TransactionRollback(t *testing.T) {
+	b, dir := getBolt(t)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unsupported operation fails the whole transaction
+	txns := []*physical.TxnEntry{
+		&physical.TxnEntry{
+			Operation: physical.DeleteOperation,
+			Entry:     &physical.Entry{Key: "foo"},
+		},
+		&physical.TxnEntry{
+			Operation: physical.ListOperation,
+			Entry:     &physical.Entry{Key: "baz"},
+		},
+	}
+	if err := b.Transaction(context.Background(), txns); err == nil {
+		t.Fatal("expected error")
+	}
+
+	entry, err := b.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "bar" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestBoltBackend_ConsistentRead(t *testing.T) {
+	b, dir := getBolt(t)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes are not held back by the read, nor seen by it
+	err := b.ConsistentRead(context.Background(), func(view physical.Backend) error {
+		doneCh := make(chan error)
+		go func() {
+			doneCh <- b.Put(context.Background(), &physical.Entry{Key: "foo/zip", Value: []byte("zap")})
+		}()
+		select {
+		case err := <-doneCh:
+			if err != nil {
+				return err
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("write blocked by the consistent read")
+		}
+
+		keys, err := view.List(context.Background(), "foo/")
+		if err != nil {
+			return err
+		}
+		if len(keys) != 1 || keys[0] != "bar" {
+			t.Fatalf("bad: %v", keys)
+		}
+		entry, err := view.Get(context.Background(), "foo/zip")
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			t.Fatalf("bad: %#v", entry)
+		}
+		if err := view.Put(context.Background(), &physical.Entry{Key: "foo/bar"}); err == nil {
+			t.Fatal("expected the view to be read-only")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := b.Get(context.Background(), "foo/zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "zap" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestBoltBackend_Backup(t *testing.T) {
+	b, dir := getBolt(t)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := b.Backup(context.Background(), backupPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes after the backup are not part of it
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo/zip", Value: []byte("zap")}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := NewBoltBackend(map[string]string{
+		"path": backupPath,
+	}, logging.NewVaultLogger(log.Debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.(*BoltBackend).Close()
+
+	keys, err := backup.List(context.Background(), "foo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "bar" {
+		t.Fatalf("bad: %v", keys)
+	}
+	entry, err := backup.Get(context.Background(), "foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "baz" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestBoltBackend_PeriodicBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger := logging.NewVaultLogger(log.Debug)
+	path := filepath.Join(dir, "vault.db")
+	b, err := NewBoltBackend(map[string]string{
+		"path": path,
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+	b.(*BoltBackend).Close()
+
+	backupPath := filepath.Join(dir, "backups", "vault.db")
+	b, err = NewBoltBackend(map[string]string{
+		"path":            path,
+		"backup_path":     backupPath,
+		"backup_interval": "10ms",
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := os.Stat(backupPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no backup written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Closing the backend stops the backups
+	if err := b.(*BoltBackend).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := NewBoltBackend(map[string]string{
+		"path": backupPath,
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.(*BoltBackend).Close()
+
+	entry, err := backup.Get(context.Background(), "foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "baz" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestBoltBackend_BackupConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "vault.db")
+	for name, conf := range map[string]map[string]string{
+		"interval without path": {"backup_interval": "1h"},
+		"invalid interval":      {"backup_path": filepath.Join(dir, "backup.db"), "backup_interval": "soon"},
+		"negative interval":     {"backup_path": filepath.Join(dir, "backup.db"), "backup_interval": "-1h"},
+		"same path":             {"backup_path": path},
+	} {
+		conf["path"] = path
+		if b, err := NewBoltBackend(conf, logging.NewVaultLogger(log.Debug)); err == nil {
+			b.(*BoltBackend).Close()
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -89,6 +89,16 @@ func (c *Cache) Purge(ctx context.Context) {
 	c.lru.Purge()
 }
 
+// Invalidate is used to remove a single key from the cache, when it is known
+// to have been modified without going through the cache
+func (c *Cache) Invalidate(ctx context.Context, key string) {
+	lock := locksutil.LockForKey(c.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.lru.Remove(key)
+}
+
 func (c *Cache) Put(ctx context.Context, entry *Entry) error {
 	if atomic.LoadUint32(c.enabled) == 0 {
 		return c.backend.Put(ctx, entry)
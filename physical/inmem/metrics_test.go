@@ -0,0 +1,79 @@
+package inmem
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/physical"
+)
+
+func TestMetricsBackend(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := physical.NewMetricsBackend(inm, 0, nil, logger)
+	physical.ExerciseBackend(t, metrics)
+	physical.ExerciseBackend_ListPrefix(t, metrics)
+
+	if metrics.Underlying() != inm {
+		t.Fatal("expected the underlying backend to be returned")
+	}
+}
+
+func TestTransactionalMetricsBackend(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewTransactionalInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := physical.NewTransactionalMetricsBackend(inm, 0, nil, logger)
+	physical.ExerciseBackend(t, metrics)
+
+	physical.ExerciseTransactionalBackend(t, metrics)
+}
+
+func TestMetricsBackend_SlowOperation(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(&log.LoggerOptions{
+		Output: &buf,
+		Level:  log.Warn,
+	})
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latent := physical.NewLatencyInjector(inm, 20*time.Millisecond, 1, logger)
+	hashKey := func(key string) string {
+		return "hashed:" + strings.ToUpper(key)
+	}
+	metrics := physical.NewMetricsBackend(latent, 10*time.Millisecond, hashKey, logger)
+
+	err = metrics.Put(context.Background(), &physical.Entry{
+		Key:   "logical/secret",
+		Value: []byte("bar"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "slow storage operation") {
+		t.Fatalf("expected slow operation to be logged: %s", out)
+	}
+	if !strings.Contains(out, "hashed:LOGICAL/SECRET") || !strings.Contains(out, "prefix=logical/") {
+		t.Fatalf("expected hashed key and prefix to be logged: %s", out)
+	}
+	if strings.Contains(out, "logical/secret") {
+		t.Fatalf("expected the key not to be logged: %s", out)
+	}
+}
@@ -0,0 +1,141 @@
+package physical
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	// DefaultSlowOperationThreshold is used if no threshold is specified for
+	// logging slow operations
+	DefaultSlowOperationThreshold = time.Second
+)
+
+// metricsPrefixes are the key prefixes given their own label below the top
+// level
+var metricsPrefixes = []string{
+	"sys/expire/",
+}
+
+// MetricsBackend is used to measure the latency of the underlying physical
+// requests, labelled by the top-level prefix of the key, and to log the
+// requests slower than a threshold
+type MetricsBackend struct {
+	backend       Backend
+	slowThreshold time.Duration
+	hashKey       func(string) string
+	logger        log.Logger
+}
+
+// TransactionalMetricsBackend is the transactional version of the metrics
+// backend
+type TransactionalMetricsBackend struct {
+	*MetricsBackend
+	Transactional
+}
+
+// Verify MetricsBackend satisfies the correct interfaces
+var _ Backend = (*MetricsBackend)(nil)
+var _ Transactional = (*TransactionalMetricsBackend)(nil)
+
+// NewMetricsBackend returns a wrapped physical backend emitting metrics for
+// every request. Requests taking longer than slowThreshold are logged, unless
+// it is zero. Keys are logged through hashKey so that they cannot reveal
+// anything about the data; if it is nil, they are not logged at all.
+func NewMetricsBackend(b Backend, slowThreshold time.Duration, hashKey func(string) string, logger log.Logger) *MetricsBackend {
+	logger.Debug("creating metrics backend", "slow_threshold", slowThreshold)
+
+	return &MetricsBackend{
+		backend:       b,
+		slowThreshold: slowThreshold,
+		hashKey:       hashKey,
+		logger:        logger,
+	}
+}
+
+// NewTransactionalMetricsBackend creates a new transactional MetricsBackend
+func NewTransactionalMetricsBackend(b Backend, slowThreshold time.Duration, hashKey func(string) string, logger log.Logger) *TransactionalMetricsBackend {
+	return &TransactionalMetricsBackend{
+		MetricsBackend: NewMetricsBackend(b, slowThreshold, hashKey, logger),
+		Transactional:  b.(Transactional),
+	}
+}
+
+// Underlying returns the wrapped backend
+func (m *MetricsBackend) Underlying() Backend {
+	return m.backend
+}
+
+// metricsPrefix returns the label used for the metrics of a key
+func metricsPrefix(key string) string {
+	for _, prefix := range metricsPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return prefix
+		}
+	}
+	if i := strings.Index(key, "/"); i != -1 {
+		return key[:i+1]
+	}
+	return "/"
+}
+
+// measure records the duration of a request on the given key, and logs it if
+// it is slow
+func (m *MetricsBackend) measure(op, key string, start time.Time) {
+	prefix := metricsPrefix(key)
+	metrics.MeasureSinceWithLabels([]string{"physical", op}, start, []metrics.Label{
+		{Name: "prefix", Value: prefix},
+	})
+
+	elapsed := time.Since(start)
+	if m.slowThreshold <= 0 || elapsed < m.slowThreshold {
+		return
+	}
+
+	args := []interface{}{"operation", op, "prefix", prefix, "duration", elapsed}
+	if m.hashKey != nil {
+		args = append(args, "key", m.hashKey(key))
+	}
+	m.logger.Warn("slow storage operation", args...)
+}
+
+// Put is a measured put request
+func (m *MetricsBackend) Put(ctx context.Context, entry *Entry) error {
+	defer m.measure("put", entry.Key, time.Now())
+	return m.backend.Put(ctx, entry)
+}
+
+// Get is a measured get request
+func (m *MetricsBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	defer m.measure("get", key, time.Now())
+	return m.backend.Get(ctx, key)
+}
+
+// Delete is a measured delete request
+func (m *MetricsBackend) Delete(ctx context.Context, key string) error {
+	defer m.measure("delete", key, time.Now())
+	return m.backend.Delete(ctx, key)
+}
+
+// List is a measured list request
+func (m *MetricsBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	defer m.measure("list", prefix, time.Now())
+	return m.backend.List(ctx, prefix)
+}
+
+// Transaction is a measured transaction request. A transaction may span
+// several prefixes, so it is not labelled and its keys are not logged.
+func (m *TransactionalMetricsBackend) Transaction(ctx context.Context, txns []*TxnEntry) error {
+	start := time.Now()
+	err := m.Transactional.Transaction(ctx, txns)
+
+	metrics.MeasureSince([]string{"physical", "transaction"}, start)
+	if elapsed := time.Since(start); m.slowThreshold > 0 && elapsed >= m.slowThreshold {
+		m.logger.Warn("slow storage operation", "operation", "transaction", "entries", len(txns), "duration", elapsed)
+	}
+	return err
+}
@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
@@ -18,25 +19,67 @@ import (
 	"github.com/armon/go-metrics"
 	mysql "github.com/go-sql-driver/mysql"
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/physical"
 )
 
 // Verify MySQLBackend satisfies the correct interfaces
 var _ physical.Backend = (*MySQLBackend)(nil)
+var _ physical.HABackend = (*MySQLBackend)(nil)
+var _ physical.Lock = (*MySQLHALock)(nil)
 
 // Unreserved tls key
 // Reserved values are "true", "false", "skip-verify"
 const mysqlTLSKey = "default"
 
+const (
+	// MySQLLockTTL is the default lock TTL
+	MySQLLockTTL = 15 * time.Second
+
+	// MySQLLockRenewInterval is the default interval at which a held
+	// lock is renewed
+	MySQLLockRenewInterval = 5 * time.Second
+
+	// MySQLLockRetryInterval is the amount of time to wait
+	// if a lock fails before trying again.
+	MySQLLockRetryInterval = time.Second
+
+	// MySQLWatchRetryMax is the number of times to re-try a
+	// failed watch before signaling that leadership is lost.
+	MySQLWatchRetryMax = 5
+
+	// MySQLWatchRetryInterval is the amount of time to wait
+	// between watch checks.
+	MySQLWatchRetryInterval = 5 * time.Second
+)
+
 // MySQLBackend is a physical backend that stores data
 // within MySQL database.
 type MySQLBackend struct {
-	dbTable    string
-	client     *sql.DB
-	statements map[string]*sql.Stmt
-	logger     log.Logger
-	permitPool *physical.PermitPool
+	dbTable     string
+	dbLockTable string
+	client      *sql.DB
+	statements  map[string]*sql.Stmt
+	logger      log.Logger
+	permitPool  *physical.PermitPool
+	haEnabled   bool
+}
+
+// MySQLHALock implements a lock using a row of the lock table of a MySQL
+// backend. The row is only held while it is renewed before its TTL runs
+// out.
+type MySQLHALock struct {
+	backend    *MySQLBackend
+	value, key string
+	identity   string
+	held       bool
+	lock       sync.Mutex
+	stopCh     chan struct{}
+	// Allow modifying the Lock durations for ease of unit testing.
+	renewInterval      time.Duration
+	ttl                time.Duration
+	watchRetryInterval time.Duration
 }
 
 // NewMySQLBackend constructs a MySQL backend using the given API client and
@@ -71,6 +114,14 @@ func NewMySQLBackend(conf map[string]string, logger log.Logger) (physical.Backen
 	}
 	dbTable := database + "." + table
 
+	lockTable, ok := conf["ha_table"]
+	if !ok {
+		lockTable = "vault_lock"
+	}
+	dbLockTable := database + "." + lockTable
+
+	haEnabled, _ := strconv.ParseBool(conf["ha_enabled"])
+
 	maxIdleConnStr, ok := conf["max_idle_connections"]
 	var maxIdleConnInt int
 	if ok {
@@ -167,13 +218,25 @@ func NewMySQLBackend(conf map[string]string, logger log.Logger) (physical.Backen
 		}
 	}
 
+	// Create the required lock table if it doesn't exists.
+	if haEnabled {
+		create_query := "CREATE TABLE IF NOT EXISTS " + dbLockTable +
+			" (ha_key varbinary(512) NOT NULL, ha_identity varchar(36) NOT NULL," +
+			" ha_value varbinary(512), valid_until timestamp(6) NOT NULL, PRIMARY KEY (ha_key))"
+		if _, err := db.Exec(create_query); err != nil {
+			return nil, errwrap.Wrapf("failed to create mysql lock table: {{err}}", err)
+		}
+	}
+
 	// Setup the backend.
 	m := &MySQLBackend{
-		dbTable:    dbTable,
-		client:     db,
-		statements: make(map[string]*sql.Stmt),
-		logger:     logger,
-		permitPool: physical.NewPermitPool(maxParInt),
+		dbTable:     dbTable,
+		dbLockTable: dbLockTable,
+		client:      db,
+		statements:  make(map[string]*sql.Stmt),
+		logger:      logger,
+		permitPool:  physical.NewPermitPool(maxParInt),
+		haEnabled:   haEnabled,
 	}
 
 	// Prepare all the statements required
@@ -184,6 +247,21 @@ func NewMySQLBackend(conf map[string]string, logger log.Logger) (physical.Backen
 		"delete": "DELETE FROM " + dbTable + " WHERE vault_key = ?",
 		"list":   "SELECT vault_key FROM " + dbTable + " WHERE vault_key LIKE ?",
 	}
+	if haEnabled {
+		// The lock row is only taken over once it has expired, or renewed
+		// by the identity holding it. MySQL evaluates the assignments from
+		// left to right, so valid_until has to be updated last.
+		takeOver := "valid_until < NOW(6) OR ha_identity = VALUES(ha_identity)"
+		statements["lock"] = "INSERT INTO " + dbLockTable +
+			" (ha_key, ha_identity, ha_value, valid_until) VALUES (?, ?, ?, NOW(6) + INTERVAL ? MICROSECOND)" +
+			" ON DUPLICATE KEY UPDATE" +
+			" ha_identity = IF(" + takeOver + ", VALUES(ha_identity), ha_identity)," +
+			" ha_value = IF(" + takeOver + ", VALUES(ha_value), ha_value)," +
+			" valid_until = IF(" + takeOver + ", VALUES(valid_until), valid_until)"
+		statements["lock_value"] = "SELECT ha_value FROM " + dbLockTable + " WHERE ha_key = ? AND valid_until > NOW(6)"
+		statements["lock_identity"] = "SELECT ha_identity FROM " + dbLockTable + " WHERE ha_key = ? AND valid_until > NOW(6)"
+		statements["unlock"] = "DELETE FROM " + dbLockTable + " WHERE ha_key = ? AND ha_identity = ?"
+	}
 	for name, query := range statements {
 		if err := m.prepare(name, query); err != nil {
 			return nil, err
@@ -315,3 +393,217 @@ func setupMySQLTLSConfig(tlsCaFile string) error {
 
 	return nil
 }
+
+// LockWith is used for mutual exclusion based on the given key.
+func (m *MySQLBackend) LockWith(key, value string) (physical.Lock, error) {
+	identity, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &MySQLHALock{
+		backend:            m,
+		key:                key,
+		value:              value,
+		identity:           identity,
+		renewInterval:      MySQLLockRenewInterval,
+		ttl:                MySQLLockTTL,
+		watchRetryInterval: MySQLWatchRetryInterval,
+	}, nil
+}
+
+func (m *MySQLBackend) HAEnabled() bool {
+	return m.haEnabled
+}
+
+// Lock tries to acquire the lock by repeatedly trying to write the lock
+// row. It will block until either the stop channel is closed or the lock
+// could be acquired successfully. The returned channel will be closed once
+// the lock row is deleted, taken over or expires.
+func (l *MySQLHALock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+	if !l.backend.haEnabled {
+		return nil, fmt.Errorf("ha_enabled is not set")
+	}
+
+	var (
+		stop    = make(chan struct{})
+		success = make(chan struct{})
+		errors  = make(chan error, 1)
+		leader  = make(chan struct{})
+	)
+	// try to acquire the lock asynchronously
+	go l.tryToLock(stop, success, errors)
+
+	select {
+	case <-success:
+		l.held = true
+		l.stopCh = make(chan struct{})
+		// after acquiring it successfully, we must renew the lock periodically,
+		// and watch the lock in order to close the leader channel
+		// once it is lost.
+		go l.periodicallyRenewLock(l.stopCh, leader)
+		go l.watch(l.stopCh, leader)
+	case err := <-errors:
+		close(stop)
+		return nil, err
+	case <-stopCh:
+		close(stop)
+		return nil, nil
+	}
+
+	return leader, nil
+}
+
+// Unlock releases the lock by deleting the lock row if it is still held by
+// this instance.
+func (l *MySQLHALock) Unlock() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.held {
+		return nil
+	}
+
+	l.held = false
+	close(l.stopCh)
+
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	if _, err := l.backend.statements["unlock"].Exec(l.key, l.identity); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Value checks whether or not the lock is held by any instance of
+// MySQLHALock, including this one, and returns the current value.
+func (l *MySQLHALock) Value() (bool, string, error) {
+	if !l.backend.haEnabled {
+		return false, "", fmt.Errorf("ha_enabled is not set")
+	}
+
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	var value []byte
+	err := l.backend.statements["lock_value"].QueryRow(l.key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return true, string(value), nil
+}
+
+// tryToLock tries to write the lock row every `MySQLLockRetryInterval`
+// until it succeeds. If the operation fails due to an error, it is sent to
+// the errors channel. When the lock could be acquired successfully, the
+// success channel is closed.
+func (l *MySQLHALock) tryToLock(stop, success chan struct{}, errors chan error) {
+	ticker := time.NewTicker(MySQLLockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := l.writeItem()
+		switch {
+		case err != nil:
+			errors <- err
+			return
+		case acquired:
+			close(success)
+			return
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *MySQLHALock) periodicallyRenewLock(stopCh, done chan struct{}) {
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := l.writeItem(); err != nil {
+				l.backend.logger.Error("failed to renew lock", "key", l.key, "error", err)
+			}
+		case <-stopCh:
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// writeItem inserts or renews the lock row, returning whether the lock is
+// held by this instance afterwards.
+func (l *MySQLHALock) writeItem() (bool, error) {
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	ttl := int64(l.ttl / time.Microsecond)
+	result, err := l.backend.statements["lock"].Exec(l.key, l.identity, l.value, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	// A row is affected when it is inserted or updated, but not when the
+	// lock is held by another instance and the row is left unchanged
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// watch checks whether the lock row still belongs to this instance and
+// closes the leader channel if not. If an error occurs during the check,
+// watch will retry the operation for `MySQLWatchRetryMax` times and close
+// the leader channel if it can't succeed.
+func (l *MySQLHALock) watch(stopCh, lost chan struct{}) {
+	defer close(lost)
+
+	retries := MySQLWatchRetryMax
+
+	ticker := time.NewTicker(l.watchRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		l.backend.permitPool.Acquire()
+		var identity string
+		err := l.backend.statements["lock_identity"].QueryRow(l.key).Scan(&identity)
+		l.backend.permitPool.Release()
+
+		switch {
+		case err == sql.ErrNoRows:
+			return
+		case err != nil:
+			retries--
+			if retries == 0 {
+				return
+			}
+		case identity != l.identity:
+			return
+		default:
+			retries = MySQLWatchRetryMax
+		}
+	}
+}
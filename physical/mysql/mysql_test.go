@@ -33,14 +33,21 @@ func TestMySQLBackend(t *testing.T) {
 	// Run vault tests
 	logger := logging.NewVaultLogger(log.Debug)
 
-	b, err := NewMySQLBackend(map[string]string{
-		"address":  address,
-		"database": database,
-		"table":    table,
-		"username": username,
-		"password": password,
-	}, logger)
+	conf := map[string]string{
+		"address":    address,
+		"database":   database,
+		"table":      table,
+		"username":   username,
+		"password":   password,
+		"ha_enabled": "true",
+	}
 
+	b, err := NewMySQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+
+	b2, err := NewMySQLBackend(conf, logger)
 	if err != nil {
 		t.Fatalf("Failed to create new backend: %v", err)
 	}
@@ -51,8 +58,13 @@ func TestMySQLBackend(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to drop table: %v", err)
 		}
+		_, err = mysql.client.Exec("DROP TABLE " + mysql.dbLockTable)
+		if err != nil {
+			t.Fatalf("Failed to drop lock table: %v", err)
+		}
 	}()
 
 	physical.ExerciseBackend(t, b)
 	physical.ExerciseBackend_ListPrefix(t, b)
+	physical.ExerciseHABackend(t, b.(physical.HABackend), b2.(physical.HABackend))
 }
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/physical"
 	//log "github.com/hashicorp/go-hclog"
 	log "github.com/hashicorp/go-hclog"
@@ -17,8 +19,31 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	// PostgreSQLLockTTL is the default lock TTL
+	PostgreSQLLockTTL = 15 * time.Second
+
+	// PostgreSQLLockRenewInterval is the default interval at which
+	// a held lock is renewed
+	PostgreSQLLockRenewInterval = 5 * time.Second
+
+	// PostgreSQLLockRetryInterval is the amount of time to wait
+	// if a lock fails before trying again.
+	PostgreSQLLockRetryInterval = time.Second
+
+	// PostgreSQLWatchRetryMax is the number of times to re-try a
+	// failed watch before signaling that leadership is lost.
+	PostgreSQLWatchRetryMax = 5
+
+	// PostgreSQLWatchRetryInterval is the amount of time to wait
+	// between watch checks.
+	PostgreSQLWatchRetryInterval = 5 * time.Second
+)
+
 // Verify PostgreSQLBackend satisfies the correct interfaces
 var _ physical.Backend = (*PostgreSQLBackend)(nil)
+var _ physical.HABackend = (*PostgreSQLBackend)(nil)
+var _ physical.Lock = (*PostgreSQLLock)(nil)
 
 // PostgreSQL Backend is a physical backend that stores data
 // within a PostgreSQL database.
@@ -29,8 +54,32 @@ type PostgreSQLBackend struct {
 	get_query    string
 	delete_query string
 	list_query   string
-	logger       log.Logger
-	permitPool   *physical.PermitPool
+
+	ha_table          string
+	haEnabled         bool
+	ha_lock_query     string
+	ha_value_query    string
+	ha_identity_query string
+	ha_unlock_query   string
+
+	logger     log.Logger
+	permitPool *physical.PermitPool
+}
+
+// PostgreSQLLock implements a lock using a row of the HA table of a
+// PostgreSQL backend. The row is only held while it is renewed before
+// its TTL runs out.
+type PostgreSQLLock struct {
+	backend    *PostgreSQLBackend
+	value, key string
+	identity   string
+	held       bool
+	lock       sync.Mutex
+	stopCh     chan struct{}
+	// Allow modifying the Lock durations for ease of unit testing.
+	renewInterval      time.Duration
+	ttl                time.Duration
+	watchRetryInterval time.Duration
 }
 
 // NewPostgreSQLBackend constructs a PostgreSQL backend using the given
@@ -88,6 +137,18 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 			" UPDATE SET (parent_path, path, key, value) = ($1, $2, $3, $4)"
 	}
 
+	unquoted_ha_table, ok := conf["ha_table"]
+	if !ok {
+		unquoted_ha_table = "vault_ha_locks"
+	}
+	quoted_ha_table := pq.QuoteIdentifier(unquoted_ha_table)
+
+	haEnabled, _ := strconv.ParseBool(conf["ha_enabled"])
+	if haEnabled && upsert_required {
+		db.Close()
+		return nil, fmt.Errorf("ha_enabled requires PostgreSQL 9.5 or later")
+	}
+
 	// Setup the backend.
 	m := &PostgreSQLBackend{
 		table:        quoted_table,
@@ -98,8 +159,19 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		list_query: "SELECT key FROM " + quoted_table + " WHERE path = $1" +
 			"UNION SELECT DISTINCT substring(substr(path, length($1)+1) from '^.*?/') FROM " +
 			quoted_table + " WHERE parent_path LIKE $1 || '%'",
-		logger:     logger,
-		permitPool: physical.NewPermitPool(maxParInt),
+		ha_table:  quoted_ha_table,
+		haEnabled: haEnabled,
+		// The lock row is only taken over once it has expired, or
+		// renewed by the identity holding it
+		ha_lock_query: "INSERT INTO " + quoted_ha_table + " (ha_key, ha_identity, ha_value, valid_until)" +
+			" VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 seconds')" +
+			" ON CONFLICT (ha_key) DO UPDATE SET ha_identity = $2, ha_value = $3, valid_until = NOW() + $4 * INTERVAL '1 seconds'" +
+			" WHERE " + quoted_ha_table + ".valid_until < NOW() OR " + quoted_ha_table + ".ha_identity = $2",
+		ha_value_query:    "SELECT ha_value FROM " + quoted_ha_table + " WHERE ha_key = $1 AND valid_until > NOW()",
+		ha_identity_query: "SELECT ha_identity FROM " + quoted_ha_table + " WHERE ha_key = $1 AND valid_until > NOW()",
+		ha_unlock_query:   "DELETE FROM " + quoted_ha_table + " WHERE ha_key = $1 AND ha_identity = $2",
+		logger:            logger,
+		permitPool:        physical.NewPermitPool(maxParInt),
 	}
 
 	return m, nil
@@ -213,3 +285,214 @@ func (m *PostgreSQLBackend) List(ctx context.Context, prefix string) ([]string,
 
 	return keys, nil
 }
+
+// LockWith is used for mutual exclusion based on the given key.
+func (m *PostgreSQLBackend) LockWith(key, value string) (physical.Lock, error) {
+	identity, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &PostgreSQLLock{
+		backend:            m,
+		key:                key,
+		value:              value,
+		identity:           identity,
+		renewInterval:      PostgreSQLLockRenewInterval,
+		ttl:                PostgreSQLLockTTL,
+		watchRetryInterval: PostgreSQLWatchRetryInterval,
+	}, nil
+}
+
+func (m *PostgreSQLBackend) HAEnabled() bool {
+	return m.haEnabled
+}
+
+// Lock tries to acquire the lock by repeatedly trying to write the lock
+// row. It will block until either the stop channel is closed or the lock
+// could be acquired successfully. The returned channel will be closed once
+// the lock row is deleted, taken over or expires.
+func (l *PostgreSQLLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+	if !l.backend.haEnabled {
+		return nil, fmt.Errorf("ha_enabled is not set")
+	}
+
+	var (
+		stop    = make(chan struct{})
+		success = make(chan struct{})
+		errors  = make(chan error, 1)
+		leader  = make(chan struct{})
+	)
+	// try to acquire the lock asynchronously
+	go l.tryToLock(stop, success, errors)
+
+	select {
+	case <-success:
+		l.held = true
+		l.stopCh = make(chan struct{})
+		// after acquiring it successfully, we must renew the lock periodically,
+		// and watch the lock in order to close the leader channel
+		// once it is lost.
+		go l.periodicallyRenewLock(l.stopCh, leader)
+		go l.watch(l.stopCh, leader)
+	case err := <-errors:
+		close(stop)
+		return nil, err
+	case <-stopCh:
+		close(stop)
+		return nil, nil
+	}
+
+	return leader, nil
+}
+
+// Unlock releases the lock by deleting the lock row if it is still held by
+// this instance.
+func (l *PostgreSQLLock) Unlock() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.held {
+		return nil
+	}
+
+	l.held = false
+	close(l.stopCh)
+
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	if _, err := l.backend.client.Exec(l.backend.ha_unlock_query, l.key, l.identity); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Value checks whether or not the lock is held by any instance of
+// PostgreSQLLock, including this one, and returns the current value.
+func (l *PostgreSQLLock) Value() (bool, string, error) {
+	if !l.backend.haEnabled {
+		return false, "", fmt.Errorf("ha_enabled is not set")
+	}
+
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	var value string
+	err := l.backend.client.QueryRow(l.backend.ha_value_query, l.key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return true, value, nil
+}
+
+// tryToLock tries to write the lock row every `PostgreSQLLockRetryInterval`
+// until it succeeds. If the operation fails due to an error, it is sent to
+// the errors channel. When the lock could be acquired successfully, the
+// success channel is closed.
+func (l *PostgreSQLLock) tryToLock(stop, success chan struct{}, errors chan error) {
+	ticker := time.NewTicker(PostgreSQLLockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := l.writeItem()
+		switch {
+		case err != nil:
+			errors <- err
+			return
+		case acquired:
+			close(success)
+			return
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *PostgreSQLLock) periodicallyRenewLock(stopCh, done chan struct{}) {
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := l.writeItem(); err != nil {
+				l.backend.logger.Error("failed to renew lock", "key", l.key, "error", err)
+			}
+		case <-stopCh:
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// writeItem inserts or renews the lock row, returning whether the lock is
+// held by this instance afterwards.
+func (l *PostgreSQLLock) writeItem() (bool, error) {
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	result, err := l.backend.client.Exec(l.backend.ha_lock_query, l.key, l.identity, l.value, l.ttl.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// watch checks whether the lock row still belongs to this instance and
+// closes the leader channel if not. If an error occurs during the check,
+// watch will retry the operation for `PostgreSQLWatchRetryMax` times and
+// close the leader channel if it can't succeed.
+func (l *PostgreSQLLock) watch(stopCh, lost chan struct{}) {
+	defer close(lost)
+
+	retries := PostgreSQLWatchRetryMax
+
+	ticker := time.NewTicker(l.watchRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		l.backend.permitPool.Acquire()
+		var identity string
+		err := l.backend.client.QueryRow(l.backend.ha_identity_query, l.key).Scan(&identity)
+		l.backend.permitPool.Release()
+
+		switch {
+		case err == sql.ErrNoRows:
+			return
+		case err != nil:
+			retries--
+			if retries == 0 {
+				return
+			}
+		case identity != l.identity:
+			return
+		default:
+			retries = PostgreSQLWatchRetryMax
+		}
+	}
+}
@@ -25,22 +25,49 @@ func TestPostgreSQLBackend(t *testing.T) {
 	// Run vault tests
 	logger := logging.NewVaultLogger(log.Debug)
 
-	b, err := NewPostgreSQLBackend(map[string]string{
+	haTable := os.Getenv("PGHATABLE")
+	if haTable == "" {
+		haTable = "vault_ha_locks"
+	}
+
+	conf := map[string]string{
 		"connection_url": connURL,
 		"table":          table,
-	}, logger)
+		"ha_enabled":     "true",
+		"ha_table":       haTable,
+	}
+
+	b, err := NewPostgreSQLBackend(conf, logger)
 	if err != nil {
 		t.Fatalf("Failed to create new backend: %v", err)
 	}
 
+	b2, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+
+	pg := b.(*PostgreSQLBackend)
+	_, err = pg.client.Exec("CREATE TABLE IF NOT EXISTS " + pg.ha_table +
+		" (ha_key TEXT COLLATE \"C\" NOT NULL, ha_identity TEXT COLLATE \"C\" NOT NULL," +
+		" ha_value TEXT COLLATE \"C\", valid_until TIMESTAMP WITH TIME ZONE NOT NULL," +
+		" CONSTRAINT ha_key PRIMARY KEY (ha_key))")
+	if err != nil {
+		t.Fatalf("Failed to create HA table: %v", err)
+	}
+
 	defer func() {
-		pg := b.(*PostgreSQLBackend)
 		_, err := pg.client.Exec("TRUNCATE TABLE " + pg.table)
 		if err != nil {
 			t.Fatalf("Failed to drop table: %v", err)
 		}
+		_, err = pg.client.Exec("TRUNCATE TABLE " + pg.ha_table)
+		if err != nil {
+			t.Fatalf("Failed to drop HA table: %v", err)
+		}
 	}()
 
 	physical.ExerciseBackend(t, b)
 	physical.ExerciseBackend_ListPrefix(t, b)
+	physical.ExerciseHABackend(t, b.(physical.HABackend), b2.(physical.HABackend))
 }
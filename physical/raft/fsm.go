@@ -0,0 +1,337 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	deleteOp uint32 = 1 << iota
+	putOp
+)
+
+var (
+	dataBucketName = []byte("data")
+
+	// errReadOnlyView is returned when writing to the view of a consistent
+	// read
+	errReadOnlyView = errors.New("cannot write to a consistent read view")
+)
+
+// initialMmapSize is the size the database is memory mapped with. Writes
+// that grow the database past the mapped size wait for the read transactions,
+// such as consistent reads, to end, so a large size is mapped where address
+// space is plentiful. On Windows the file would be grown to the mapped size.
+func initialMmapSize() int {
+	if runtime.GOOS == "windows" || strconv.IntSize < 64 {
+		return 0
+	}
+	return 1 << 30
+}
+
+// Verify FSM satisfies the correct interfaces
+var _ raft.FSM = (*FSM)(nil)
+var _ raft.FSMSnapshot = (*fsmSnapshot)(nil)
+
+// logOperation is a single storage operation carried in a raft log entry.
+type logOperation struct {
+	OpType uint32 `json:"op_type"`
+	Key    string `json:"key"`
+	Value  []byte `json:"value,omitempty"`
+}
+
+// logData is the payload of a raft log entry. All operations in a single
+// entry are applied to the FSM atomically.
+type logData struct {
+	Operations []*logOperation `json:"operations"`
+}
+
+// FSM is the raft finite state machine. It stores the replicated storage
+// entries in a local BoltDB file and is used to serve all reads.
+type FSM struct {
+	// l protects the db pointer, which is swapped out during a restore
+	l      sync.RWMutex
+	path   string
+	logger log.Logger
+	db     *bolt.DB
+}
+
+// NewFSM opens, or creates, the BoltDB file backing the FSM in the given
+// directory.
+func NewFSM(path string, logger log.Logger) (*FSM, error) {
+	f := &FSM{
+		path:   path,
+		logger: logger,
+	}
+
+	if err := f.openDBFile(filepath.Join(path, "vault.db")); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *FSM) openDBFile(dbPath string) error {
+	boltDB, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout:         1 * time.Second,
+		InitialMmapSize: initialMmapSize(),
+	})
+	if err != nil {
+		return errwrap.Wrapf("failed to open fsm database: {{err}}", err)
+	}
+
+	err = boltDB.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(dataBucketName)
+		return err
+	})
+	if err != nil {
+		boltDB.Close()
+		return errwrap.Wrapf("failed to create fsm bucket: {{err}}", err)
+	}
+
+	f.db = boltDB
+	return nil
+}
+
+// Close closes the underlying database.
+func (f *FSM) Close() error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.db.Close()
+}
+
+// Get returns the entry stored at the given key, or nil if it does not exist.
+func (f *FSM) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var entry *physical.Entry
+	err := f.db.View(func(tx *bolt.Tx) error {
+		entry = getTx(tx, key)
+		return nil
+	})
+	return entry, err
+}
+
+// List returns the keys directly under the given prefix, with any deeper
+// keys collapsed into their first path segment followed by a slash.
+func (f *FSM) List(ctx context.Context, prefix string) ([]string, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var keys []string
+	err := f.db.View(func(tx *bolt.Tx) error {
+		keys = listTx(tx, prefix)
+		return nil
+	})
+	return keys, err
+}
+
+// ConsistentRead calls fn with a view of the entries within a single read
+// transaction. Restores, and writes that grow the database past its memory
+// mapped size, wait for it to end.
+func (f *FSM) ConsistentRead(ctx context.Context, fn func(physical.Backend) error) error {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	return f.db.View(func(tx *bolt.Tx) error {
+		return fn(&fsmReadView{tx: tx})
+	})
+}
+
+// fsmReadView is a read-only backend reading within a transaction
+type fsmReadView struct {
+	tx *bolt.Tx
+}
+
+func (v *fsmReadView) Put(ctx context.Context, entry *physical.Entry) error {
+	return errReadOnlyView
+}
+
+func (v *fsmReadView) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	return getTx(v.tx, key), nil
+}
+
+func (v *fsmReadView) Delete(ctx context.Context, key string) error {
+	return errReadOnlyView
+}
+
+func (v *fsmReadView) List(ctx context.Context, prefix string) ([]string, error) {
+	return listTx(v.tx, prefix), nil
+}
+
+// getTx returns the entry stored at the given key, or nil if it does not
+// exist
+func getTx(tx *bolt.Tx, key string) *physical.Entry {
+	val := tx.Bucket(dataBucketName).Get([]byte(key))
+	if val == nil {
+		return nil
+	}
+
+	// The returned slice is only valid for the life of the transaction, so
+	// copy it out
+	value := make([]byte, len(val))
+	copy(value, val)
+	return &physical.Entry{
+		Key:   key,
+		Value: value,
+	}
+}
+
+// listTx returns the keys directly under the given prefix, with any deeper
+// keys collapsed into their first path segment followed by a slash
+func listTx(tx *bolt.Tx, prefix string) []string {
+	var keys []string
+	c := tx.Bucket(dataBucketName).Cursor()
+
+	prefixBytes := []byte(prefix)
+	for k, _ := c.Seek(prefixBytes); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+		key := strings.TrimPrefix(string(k), prefix)
+		if i := strings.Index(key, "/"); i != -1 {
+			key = key[:i+1]
+		}
+
+		// Keys are walked in order, so any duplicate folder entries are
+		// adjacent
+		if len(keys) > 0 && keys[len(keys)-1] == key {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Apply is called by raft once a log entry has been committed. It applies
+// all operations in the entry in a single BoltDB transaction.
+func (f *FSM) Apply(l *raft.Log) interface{} {
+	var command logData
+	if err := jsonutil.DecodeJSON(l.Data, &command); err != nil {
+		f.logger.Error("failed to decode log entry", "index", l.Index, "error", err)
+		return err
+	}
+
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	return f.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(dataBucketName)
+		for _, op := range command.Operations {
+			var err error
+			switch op.OpType {
+			case putOp:
+				err = b.Put([]byte(op.Key), op.Value)
+			case deleteOp:
+				err = b.Delete([]byte(op.Key))
+			default:
+				err = fmt.Errorf("%d is not a supported operation type", op.OpType)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Snapshot returns a point-in-time snapshot of the FSM. The read
+// transaction stays open until the snapshot is released.
+func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	tx, err := f.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fsmSnapshot{tx: tx}, nil
+}
+
+// writeTo writes a consistent copy of the FSM database to the given writer.
+func (f *FSM) writeTo(w io.Writer) error {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	return f.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// Restore replaces the contents of the FSM with the given snapshot, which
+// must be a BoltDB file as written by Snapshot or writeTo.
+func (f *FSM) Restore(r io.ReadCloser) error {
+	defer r.Close()
+
+	dbPath := filepath.Join(f.path, "vault.db")
+	restorePath := dbPath + ".restore"
+
+	file, err := os.OpenFile(restorePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(restorePath)
+		return errwrap.Wrapf("failed to write snapshot: {{err}}", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(restorePath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(restorePath)
+		return err
+	}
+
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if err := f.db.Close(); err != nil {
+		return errwrap.Wrapf("failed to close fsm database: {{err}}", err)
+	}
+	if err := os.Rename(restorePath, dbPath); err != nil {
+		return errwrap.Wrapf("failed to replace fsm database: {{err}}", err)
+	}
+
+	return f.openDBFile(dbPath)
+}
+
+// fsmSnapshot holds an open read transaction for the duration of a raft
+// snapshot.
+type fsmSnapshot struct {
+	tx *bolt.Tx
+}
+
+// Persist writes the snapshot to the given sink.
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := s.tx.WriteTo(sink); err != nil {
+		sink.Cancel()
+		return err
+	}
+
+	return sink.Close()
+}
+
+// Release ends the read transaction.
+func (s *fsmSnapshot) Release() {
+	s.tx.Rollback()
+}
@@ -0,0 +1,719 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// DefaultAddress is the address the raft transport binds to if none is
+	// configured
+	DefaultAddress = "127.0.0.1:8202"
+
+	// snapshotsRetained is the number of raft snapshots kept on disk
+	snapshotsRetained = 2
+
+	// raftLogCacheSize is the number of recent log entries kept in memory
+	raftLogCacheSize = 512
+
+	// applyTimeout is how long to wait for a log entry to be enqueued
+	applyTimeout = 10 * time.Second
+
+	// bootstrapTimeout is how long to wait for a freshly bootstrapped node to
+	// elect itself leader
+	bootstrapTimeout = 30 * time.Second
+)
+
+var (
+	// ErrRaftNotRunning is returned when an operation requires the raft
+	// cluster to be set up but it has not been started
+	ErrRaftNotRunning = errors.New("raft storage is not running")
+)
+
+// Verify RaftBackend satisfies the correct interfaces
+var _ physical.Backend = (*RaftBackend)(nil)
+var _ physical.HABackend = (*RaftBackend)(nil)
+var _ physical.Transactional = (*RaftBackend)(nil)
+var _ physical.ConsistentReader = (*RaftBackend)(nil)
+var _ physical.Lock = (*RaftLock)(nil)
+
+// RaftBackend is a physical backend that replicates its data to the other
+// Vault nodes in the cluster using the raft consensus protocol. Reads are
+// served from the local copy of the data, writes must be performed on the
+// raft leader, which is always the active Vault node.
+type RaftBackend struct {
+	// l protects the raft and transport objects along with the leadership
+	// state, all of which change as the cluster is set up and torn down
+	l sync.RWMutex
+
+	logger     log.Logger
+	permitPool *physical.PermitPool
+
+	fsm         *FSM
+	logStore    raft.LogStore
+	stableStore raft.StableStore
+	snapStore   raft.SnapshotStore
+	boltStore   *raftboltdb.BoltStore
+
+	raft          *raft.Raft
+	raftTransport *raft.NetworkTransport
+	stopCh        chan struct{}
+
+	// isLeader is true while this node is the raft leader. leadershipCh is
+	// closed and replaced every time that changes.
+	isLeader     bool
+	leadershipCh chan struct{}
+
+	dataDir       string
+	localID       string
+	bindAddr      string
+	advertiseAddr net.Addr
+}
+
+// Peer describes a member of the raft cluster
+type Peer struct {
+	ID      string `json:"node_id"`
+	Address string `json:"address"`
+	Leader  bool   `json:"leader"`
+	Voter   bool   `json:"voter"`
+}
+
+// NewRaftBackend constructs a RaftBackend using the given directory to store
+// the raft log and the replicated data
+func NewRaftBackend(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+	path, ok := conf["path"]
+	if !ok {
+		return nil, fmt.Errorf("'path' must be set")
+	}
+
+	raftPath := filepath.Join(path, "raft")
+	if err := os.MkdirAll(raftPath, 0700); err != nil {
+		return nil, errwrap.Wrapf("failed to create raft directory: {{err}}", err)
+	}
+
+	localID, err := nodeID(path, conf["node_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddr, ok := conf["address"]
+	if !ok {
+		bindAddr = DefaultAddress
+	}
+
+	// Without an advertise address the transport advertises the address it
+	// is listening on
+	var advertiseAddr net.Addr
+	if advertise, ok := conf["advertise_address"]; ok {
+		advertiseAddr, err = net.ResolveTCPAddr("tcp", advertise)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to resolve advertise address: {{err}}", err)
+		}
+	}
+
+	fsm, err := NewFSM(path, logger.Named("fsm"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The raft log and stable store share a single BoltDB file
+	boltStore, err := raftboltdb.NewBoltStore(filepath.Join(raftPath, "raft.db"))
+	if err != nil {
+		fsm.Close()
+		return nil, errwrap.Wrapf("failed to open raft log store: {{err}}", err)
+	}
+	logStore, err := raft.NewLogCache(raftLogCacheSize, boltStore)
+	if err != nil {
+		boltStore.Close()
+		fsm.Close()
+		return nil, err
+	}
+
+	snapStore, err := raft.NewFileSnapshotStoreWithLogger(raftPath, snapshotsRetained, logger.Named("snapshot").StandardLogger(&log.StandardLoggerOptions{
+		InferLevels: true,
+	}))
+	if err != nil {
+		boltStore.Close()
+		fsm.Close()
+		return nil, errwrap.Wrapf("failed to create snapshot store: {{err}}", err)
+	}
+
+	b := &RaftBackend{
+		logger:        logger,
+		permitPool:    physical.NewPermitPool(physical.DefaultParallelOperations),
+		fsm:           fsm,
+		logStore:      logStore,
+		stableStore:   boltStore,
+		snapStore:     snapStore,
+		boltStore:     boltStore,
+		leadershipCh:  make(chan struct{}),
+		dataDir:       path,
+		localID:       localID,
+		bindAddr:      bindAddr,
+		advertiseAddr: advertiseAddr,
+	}
+
+	// A node without any raft state has never been part of a cluster. Start
+	// raft right away so that it can either be bootstrapped by initializing
+	// Vault or be joined to an existing cluster. Nodes with existing state
+	// only start raft once Vault is unsealed.
+	hasState, err := raft.HasExistingState(b.logStore, b.stableStore, b.snapStore)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	if !hasState {
+		if err := b.SetupCluster(context.Background()); err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// nodeID returns the configured node ID, or the ID persisted in the data
+// directory, generating and persisting a new one if neither exists
+func nodeID(path, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+
+	idPath := filepath.Join(path, "node-id")
+	id, err := ioutil.ReadFile(idPath)
+	switch {
+	case err == nil:
+		return strings.TrimSpace(string(id)), nil
+	case !os.IsNotExist(err):
+		return "", errwrap.Wrapf("failed to read node id: {{err}}", err)
+	}
+
+	newID, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(idPath, []byte(newID), 0600); err != nil {
+		return "", errwrap.Wrapf("failed to write node id: {{err}}", err)
+	}
+
+	return newID, nil
+}
+
+// NodeID returns the ID of this node in the raft cluster
+func (b *RaftBackend) NodeID() string {
+	return b.localID
+}
+
+// Close tears down the raft cluster and closes the underlying stores
+func (b *RaftBackend) Close() error {
+	if err := b.TeardownCluster(); err != nil {
+		return err
+	}
+	if err := b.boltStore.Close(); err != nil {
+		return err
+	}
+
+	return b.fsm.Close()
+}
+
+// SetupCluster starts the raft protocol on this node. It is a no-op if raft
+// is already running.
+func (b *RaftBackend) SetupCluster(ctx context.Context) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.raft != nil {
+		return nil
+	}
+
+	transport, err := raft.NewTCPTransportWithLogger(b.bindAddr, b.advertiseAddr, 3, 10*time.Second, b.logger.Named("transport").StandardLogger(&log.StandardLoggerOptions{
+		InferLevels: true,
+	}))
+	if err != nil {
+		return errwrap.Wrapf("failed to start raft transport: {{err}}", err)
+	}
+
+	// Keep listening on the same port if raft is restarted after having
+	// been given a random one
+	b.bindAddr = string(transport.LocalAddr())
+
+	notifyCh := make(chan bool, 1)
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(b.localID)
+	raftConfig.Logger = b.logger.Named("raft")
+	raftConfig.NotifyCh = notifyCh
+
+	raftObj, err := raft.NewRaft(raftConfig, b.fsm, b.logStore, b.stableStore, b.snapStore, transport)
+	if err != nil {
+		transport.Close()
+		return errwrap.Wrapf("failed to start raft: {{err}}", err)
+	}
+
+	b.raft = raftObj
+	b.raftTransport = transport
+	b.stopCh = make(chan struct{})
+	go b.monitorLeadership(notifyCh, b.stopCh)
+
+	return nil
+}
+
+// TeardownCluster stops the raft protocol on this node. It is a no-op if raft
+// is not running.
+func (b *RaftBackend) TeardownCluster() error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.raft == nil {
+		return nil
+	}
+
+	close(b.stopCh)
+	if err := b.raft.Shutdown().Error(); err != nil {
+		return errwrap.Wrapf("failed to shut down raft: {{err}}", err)
+	}
+	if err := b.raftTransport.Close(); err != nil {
+		return errwrap.Wrapf("failed to close raft transport: {{err}}", err)
+	}
+
+	b.raft = nil
+	b.raftTransport = nil
+	b.setLeader(false)
+
+	return nil
+}
+
+// Bootstrap makes this node the sole member of a new raft cluster and waits
+// for it to become the leader. Nodes that are already part of a cluster are
+// left as-is.
+func (b *RaftBackend) Bootstrap(ctx context.Context) error {
+	if err := b.SetupCluster(ctx); err != nil {
+		return err
+	}
+
+	hasState, err := raft.HasExistingState(b.logStore, b.stableStore, b.snapStore)
+	if err != nil {
+		return err
+	}
+
+	if !hasState {
+		b.l.RLock()
+		raftObj := b.raft
+		config := raft.Configuration{
+			Servers: []raft.Server{
+				{
+					ID:      raft.ServerID(b.localID),
+					Address: b.raftTransport.LocalAddr(),
+				},
+			},
+		}
+		b.l.RUnlock()
+
+		b.logger.Info("bootstrapping raft cluster", "node_id", b.localID)
+		if err := raftObj.BootstrapCluster(config).Error(); err != nil {
+			return errwrap.Wrapf("failed to bootstrap raft cluster: {{err}}", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, bootstrapTimeout)
+	defer cancel()
+
+	for {
+		b.l.RLock()
+		isLeader := b.isLeader
+		leadershipCh := b.leadershipCh
+		b.l.RUnlock()
+
+		if isLeader {
+			return nil
+		}
+
+		select {
+		case <-leadershipCh:
+		case <-ctx.Done():
+			return errors.New("timed out waiting for raft leadership")
+		}
+	}
+}
+
+// monitorLeadership tracks leadership changes reported by raft
+func (b *RaftBackend) monitorLeadership(notifyCh <-chan bool, stopCh <-chan struct{}) {
+	for {
+		select {
+		case isLeader := <-notifyCh:
+			b.l.Lock()
+			b.setLeader(isLeader)
+			b.l.Unlock()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// setLeader records a leadership change and wakes up anything waiting on it.
+// The lock must be held prior to calling.
+func (b *RaftBackend) setLeader(isLeader bool) {
+	if b.isLeader == isLeader {
+		return
+	}
+
+	b.isLeader = isLeader
+	close(b.leadershipCh)
+	b.leadershipCh = make(chan struct{})
+}
+
+// AddPeer adds a new voting member to the raft cluster. It must be called on
+// the leader.
+func (b *RaftBackend) AddPeer(ctx context.Context, peerID, address string) error {
+	b.l.RLock()
+	raftObj := b.raft
+	b.l.RUnlock()
+	if raftObj == nil {
+		return ErrRaftNotRunning
+	}
+
+	b.logger.Info("adding raft peer", "node_id", peerID, "address", address)
+	return raftObj.AddVoter(raft.ServerID(peerID), raft.ServerAddress(address), 0, 0).Error()
+}
+
+// RemovePeer removes a member from the raft cluster. It must be called on the
+// leader.
+func (b *RaftBackend) RemovePeer(ctx context.Context, peerID string) error {
+	b.l.RLock()
+	raftObj := b.raft
+	b.l.RUnlock()
+	if raftObj == nil {
+		return ErrRaftNotRunning
+	}
+
+	b.logger.Info("removing raft peer", "node_id", peerID)
+	return raftObj.RemoveServer(raft.ServerID(peerID), 0, 0).Error()
+}
+
+// Peers returns the current members of the raft cluster
+func (b *RaftBackend) Peers(ctx context.Context) ([]*Peer, error) {
+	b.l.RLock()
+	raftObj := b.raft
+	b.l.RUnlock()
+	if raftObj == nil {
+		return nil, ErrRaftNotRunning
+	}
+
+	future := raftObj.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+
+	leader := raftObj.Leader()
+	var peers []*Peer
+	for _, server := range future.Configuration().Servers {
+		peers = append(peers, &Peer{
+			ID:      string(server.ID),
+			Address: string(server.Address),
+			Leader:  server.Address == leader,
+			Voter:   server.Suffrage == raft.Voter,
+		})
+	}
+
+	return peers, nil
+}
+
+// Snapshot writes a copy of the replicated data to the given writer
+func (b *RaftBackend) Snapshot(w io.Writer) error {
+	return b.fsm.writeTo(w)
+}
+
+// RestoreSnapshot replaces the replicated data with the contents of a
+// snapshot taken by Snapshot. It must be called on the leader and the new
+// data is replicated to the rest of the cluster.
+func (b *RaftBackend) RestoreSnapshot(ctx context.Context, r io.Reader) error {
+	b.l.RLock()
+	raftObj := b.raft
+	b.l.RUnlock()
+	if raftObj == nil {
+		return ErrRaftNotRunning
+	}
+
+	// The snapshot size must be known up front
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return errwrap.Wrapf("failed to read snapshot: {{err}}", err)
+	}
+
+	meta := &raft.SnapshotMeta{
+		Version: raft.SnapshotVersionMax,
+		Size:    int64(buf.Len()),
+	}
+
+	b.logger.Info("restoring raft snapshot", "size", meta.Size)
+	return raftObj.Restore(meta, &buf, 0)
+}
+
+// applyLog commits the given operations through raft and waits for them to
+// be applied to the local FSM
+func (b *RaftBackend) applyLog(ctx context.Context, command *logData) error {
+	b.l.RLock()
+	raftObj := b.raft
+	b.l.RUnlock()
+	if raftObj == nil {
+		return ErrRaftNotRunning
+	}
+
+	commandBytes, err := jsonutil.EncodeJSON(command)
+	if err != nil {
+		return err
+	}
+
+	applyFuture := raftObj.Apply(commandBytes, applyTimeout)
+	if err := applyFuture.Error(); err != nil {
+		return err
+	}
+	if err, ok := applyFuture.Response().(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+// Put is used to insert or update an entry
+func (b *RaftBackend) Put(ctx context.Context, entry *physical.Entry) error {
+	defer metrics.MeasureSince([]string{"raft", "put"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.applyLog(ctx, &logData{
+		Operations: []*logOperation{
+			{
+				OpType: putOp,
+				Key:    entry.Key,
+				Value:  entry.Value,
+			},
+		},
+	})
+}
+
+// Get is used to fetch an entry
+func (b *RaftBackend) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	defer metrics.MeasureSince([]string{"raft", "get"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.fsm.Get(ctx, key)
+}
+
+// Delete is used to permanently delete an entry
+func (b *RaftBackend) Delete(ctx context.Context, key string) error {
+	defer metrics.MeasureSince([]string{"raft", "delete"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.applyLog(ctx, &logData{
+		Operations: []*logOperation{
+			{
+				OpType: deleteOp,
+				Key:    key,
+			},
+		},
+	})
+}
+
+// ConsistentRead calls fn with a view of the entries as this node has
+// applied them
+func (b *RaftBackend) ConsistentRead(ctx context.Context, fn func(physical.Backend) error) error {
+	return b.fsm.ConsistentRead(ctx, fn)
+}
+
+// List is used to list all the keys under a given prefix, up to the next
+// prefix.
+func (b *RaftBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"raft", "list"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.fsm.List(ctx, prefix)
+}
+
+// Transaction applies all the given operations in a single raft log entry
+func (b *RaftBackend) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	defer metrics.MeasureSince([]string{"raft", "transaction"}, time.Now())
+
+	command := &logData{
+		Operations: make([]*logOperation, 0, len(txns)),
+	}
+	for _, txn := range txns {
+		op := &logOperation{
+			Key: txn.Entry.Key,
+		}
+		switch txn.Operation {
+		case physical.PutOperation:
+			op.OpType = putOp
+			op.Value = txn.Entry.Value
+		case physical.DeleteOperation:
+			op.OpType = deleteOp
+		default:
+			return fmt.Errorf("%q is not a supported transaction operation", txn.Operation)
+		}
+		command.Operations = append(command.Operations, op)
+	}
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.applyLog(ctx, command)
+}
+
+// HAEnabled indicates whether the HA functionality should be exposed.
+// Raft always supports HA.
+func (b *RaftBackend) HAEnabled() bool {
+	return true
+}
+
+// LockWith is used for mutual exclusion based on the given key. The lock is
+// held by whichever node is the raft leader.
+func (b *RaftBackend) LockWith(key, value string) (physical.Lock, error) {
+	return &RaftLock{
+		key:   key,
+		value: value,
+		b:     b,
+	}, nil
+}
+
+// RaftLock implements a lock tied to raft leadership
+type RaftLock struct {
+	key   string
+	value string
+	b     *RaftBackend
+
+	l      sync.Mutex
+	held   bool
+	stopCh chan struct{}
+}
+
+// Lock blocks until this node is the raft leader, then records the lock
+// value so that standbys can find the active node.
+func (l *RaftLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	for {
+		l.b.l.RLock()
+		isLeader := l.b.isLeader
+		leadershipCh := l.b.leadershipCh
+		l.b.l.RUnlock()
+
+		if isLeader {
+			err := l.b.applyLog(context.Background(), &logData{
+				Operations: []*logOperation{
+					{
+						OpType: putOp,
+						Key:    l.key,
+						Value:  []byte(l.value),
+					},
+				},
+			})
+			switch err {
+			case nil:
+				leaderLost := make(chan struct{})
+				l.stopCh = make(chan struct{})
+				l.held = true
+				go l.monitorLeadership(leadershipCh, leaderLost, l.stopCh)
+				return leaderLost, nil
+			case raft.ErrNotLeader, raft.ErrLeadershipLost:
+				// Leadership changed underneath us, wait for the next change
+			default:
+				return nil, err
+			}
+		}
+
+		select {
+		case <-leadershipCh:
+		case <-stopCh:
+			return nil, nil
+		}
+	}
+}
+
+// monitorLeadership closes leaderLost on the first leadership change after
+// the lock was acquired, since leadership can only have been lost
+func (l *RaftLock) monitorLeadership(leadershipCh <-chan struct{}, leaderLost chan struct{}, stopCh <-chan struct{}) {
+	select {
+	case <-leadershipCh:
+		close(leaderLost)
+	case <-stopCh:
+	}
+}
+
+// Unlock releases the lock and, if this node is still the raft leader, hands
+// leadership to another node so that it can take over
+func (l *RaftLock) Unlock() error {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if !l.held {
+		return nil
+	}
+
+	close(l.stopCh)
+	l.held = false
+
+	l.b.l.RLock()
+	raftObj := l.b.raft
+	isLeader := l.b.isLeader
+	l.b.l.RUnlock()
+
+	if raftObj != nil && isLeader {
+		if err := raftObj.LeadershipTransfer().Error(); err != nil {
+			l.b.logger.Warn("failed to transfer raft leadership", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Value returns the value of the lock and whether a raft leader exists to
+// hold it
+func (l *RaftLock) Value() (bool, string, error) {
+	l.b.l.RLock()
+	raftObj := l.b.raft
+	l.b.l.RUnlock()
+
+	if raftObj == nil || raftObj.Leader() == "" {
+		return false, "", nil
+	}
+
+	entry, err := l.b.fsm.Get(context.Background(), l.key)
+	if err != nil {
+		return false, "", err
+	}
+	if entry == nil {
+		return false, "", nil
+	}
+
+	return true, string(entry.Value), nil
+}
@@ -0,0 +1,291 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/physical"
+)
+
+func getRaft(t testing.TB, bootstrap bool) (*RaftBackend, string) {
+	t.Helper()
+
+	raftDir, err := ioutil.TempDir("", "vault-raft-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logging.NewVaultLogger(log.Debug)
+
+	backend, err := NewRaftBackend(map[string]string{
+		"path":    raftDir,
+		"address": "127.0.0.1:0",
+	}, logger)
+	if err != nil {
+		os.RemoveAll(raftDir)
+		t.Fatal(err)
+	}
+	b := backend.(*RaftBackend)
+
+	if bootstrap {
+		if err := b.Bootstrap(context.Background()); err != nil {
+			b.Close()
+			os.RemoveAll(raftDir)
+			t.Fatal(err)
+		}
+	}
+
+	return b, raftDir
+}
+
+func addPeer(t testing.TB, leader, follower *RaftBackend) {
+	t.Helper()
+
+	follower.l.RLock()
+	addr := string(follower.raftTransport.LocalAddr())
+	follower.l.RUnlock()
+
+	if err := leader.AddPeer(context.Background(), follower.NodeID(), addr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitForKey(t testing.TB, b *RaftBackend, key string, value []byte) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		entry, err := b.Get(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value == nil && entry == nil {
+			return
+		}
+		if entry != nil && bytes.Equal(entry.Value, value) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q to replicate", key)
+}
+
+func TestRaft_Backend(t *testing.T) {
+	b, dir := getRaft(t, true)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	physical.ExerciseBackend(t, b)
+	physical.ExerciseBackend_ListPrefix(t, b)
+}
+
+func TestRaft_TransactionalBackend(t *testing.T) {
+	b, dir := getRaft(t, true)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	physical.ExerciseTransactionalBackend(t, b)
+}
+
+func TestRaft_NotRunning(t *testing.T) {
+	b, dir := getRaft(t, true)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.TeardownCluster(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads are served locally while writes require raft
+	entry, err := b.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || !bytes.Equal(entry.Value, []byte("bar")) {
+		t.Fatalf("bad entry: %#v", entry)
+	}
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("baz")}); err != ErrRaftNotRunning {
+		t.Fatalf("expected raft not running error, got %v", err)
+	}
+
+	// Restarting picks up the existing cluster
+	if err := b.SetupCluster(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRaft_Join_RemovePeer(t *testing.T) {
+	b1, dir1 := getRaft(t, true)
+	defer os.RemoveAll(dir1)
+	defer b1.Close()
+
+	b2, dir2 := getRaft(t, false)
+	defer os.RemoveAll(dir2)
+	defer b2.Close()
+
+	addPeer(t, b1, b2)
+
+	if err := b1.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	waitForKey(t, b2, "foo", []byte("bar"))
+
+	peers, err := b1.Peers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(peers))
+	}
+	for _, peer := range peers {
+		if peer.Leader != (peer.ID == b1.NodeID()) {
+			t.Fatalf("bad leader flag for peer %q", peer.ID)
+		}
+	}
+
+	// Writes can only be performed on the leader
+	if err := b2.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("baz")}); err == nil {
+		t.Fatal("expected error writing on a follower")
+	}
+
+	if err := b1.RemovePeer(context.Background(), b2.NodeID()); err != nil {
+		t.Fatal(err)
+	}
+	peers, err = b1.Peers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].ID != b1.NodeID() {
+		t.Fatalf("bad peers after removal: %#v", peers)
+	}
+}
+
+func TestRaft_HABackend(t *testing.T) {
+	b1, dir1 := getRaft(t, true)
+	defer os.RemoveAll(dir1)
+	defer b1.Close()
+
+	b2, dir2 := getRaft(t, false)
+	defer os.RemoveAll(dir2)
+	defer b2.Close()
+
+	addPeer(t, b1, b2)
+
+	lock1, err := b1.LockWith("core/lock", "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderLostCh, err := lock1.Lock(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaderLostCh == nil {
+		t.Fatal("missing leader lost channel")
+	}
+
+	// The follower sees the leader's lock value once it has replicated
+	lock2, err := b2.LockWith("core/lock", "node2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForKey(t, b2, "core/lock", []byte("node1"))
+	held, value, err := lock2.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held || value != "node1" {
+		t.Fatalf("bad lock value: %t %q", held, value)
+	}
+
+	// The follower cannot acquire the lock while the leader holds it
+	stopCh := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() {
+		close(stopCh)
+	})
+	ch, err := lock2.Lock(stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch != nil {
+		t.Fatal("should not have acquired the lock")
+	}
+
+	// Releasing the lock hands leadership to the follower
+	if err := lock1.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	ch, err = lock2.Lock(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch == nil {
+		t.Fatal("should have acquired the lock")
+	}
+	waitForKey(t, b1, "core/lock", []byte("node2"))
+
+	if err := lock2.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRaft_SnapshotRestore(t *testing.T) {
+	b, dir := getRaft(t, true)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	ctx := context.Background()
+	if err := b.Put(ctx, &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot bytes.Buffer
+	if err := b.Snapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Put(ctx, &physical.Entry{Key: "foo", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(ctx, &physical.Entry{Key: "other", Value: []byte("value")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.RestoreSnapshot(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := b.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || !bytes.Equal(entry.Value, []byte("bar")) {
+		t.Fatalf("bad entry after restore: %#v", entry)
+	}
+	entry, err = b.Get(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("expected entry to be removed by restore: %#v", entry)
+	}
+
+	// Writes still work after a restore
+	if err := b.Put(ctx, &physical.Entry{Key: "foo", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+}
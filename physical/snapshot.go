@@ -0,0 +1,308 @@
+package physical
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// Snapshots are gzip compressed streams holding, with every integer in big
+// endian order:
+//
+//   - the snapshotMagic bytes, followed by the uint32 format version
+//   - every entry as a uint32 key length, the key, a uint32 value length and
+//     the value
+//   - a uint32 zero, which is never the length of a valid key
+//   - the SHA-256 checksum of all the preceding bytes
+//
+// The entries are stored exactly as the backend holds them, so a snapshot of
+// one backend can be restored into any other.
+const (
+	snapshotVersion = 1
+
+	// snapshotMaxSize bounds the length of a single key or value, to avoid
+	// large allocations when reading corrupted snapshots
+	snapshotMaxSize = 512 * 1024 * 1024
+)
+
+var snapshotMagic = []byte("VAULTSNP")
+
+// ErrInvalidSnapshot is returned when reading a snapshot that is malformed,
+// truncated or fails its checksum
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ConsistentReader is implemented by backends that can read their entries as
+// they were at a single point in time, such as within a read transaction,
+// without holding back writes
+type ConsistentReader interface {
+	// ConsistentRead calls fn with a read-only view of the entries as they
+	// were when it was called
+	ConsistentRead(ctx context.Context, fn func(Backend) error) error
+}
+
+// WriteSnapshot writes every entry of the backend, other than the excluded
+// keys, to w as a snapshot and returns the number of entries written. If the
+// backend is a ConsistentReader, the snapshot holds the entries as they were
+// at a single point in time. Otherwise the entries are read one at a time,
+// so entries modified while the snapshot is written may or may not be part
+// of it.
+func WriteSnapshot(ctx context.Context, b Backend, w io.Writer, exclude []string) (int, error) {
+	cr, ok := b.(ConsistentReader)
+	if !ok {
+		return writeSnapshot(ctx, b, w, exclude)
+	}
+
+	var count int
+	err := cr.ConsistentRead(ctx, func(view Backend) error {
+		var err error
+		count, err = writeSnapshot(ctx, view, w, exclude)
+		return err
+	})
+	return count, err
+}
+
+func writeSnapshot(ctx context.Context, b Backend, w io.Writer, exclude []string) (int, error) {
+	gz := gzip.NewWriter(w)
+	hash := sha256.New()
+	out := bufio.NewWriter(io.MultiWriter(gz, hash))
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], snapshotVersion)
+	out.Write(snapshotMagic)
+	out.Write(header[:])
+
+	var count int
+	err := scanSnapshotKeys(ctx, b, func(key string) error {
+		if isExcluded(key, exclude) {
+			return nil
+		}
+
+		entry, err := b.Get(ctx, key)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to read entry %q: {{err}}", key), err)
+		}
+		// The entry may have been removed since it was listed
+		if entry == nil {
+			return nil
+		}
+
+		if err := writeSnapshotField(out, []byte(entry.Key)); err != nil {
+			return err
+		}
+		if err := writeSnapshotField(out, entry.Value); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := writeSnapshotField(out, nil); err != nil {
+		return count, err
+	}
+	if err := out.Flush(); err != nil {
+		return count, err
+	}
+	if _, err := gz.Write(hash.Sum(nil)); err != nil {
+		return count, err
+	}
+	if err := gz.Close(); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// VerifySnapshot reads the whole snapshot and checks its checksum
+func VerifySnapshot(r io.Reader) error {
+	return readSnapshot(r, func(*Entry) error {
+		return nil
+	})
+}
+
+// RestoreSnapshot replaces the contents of the backend with the entries of
+// the snapshot and returns the number of entries restored. The excluded keys
+// are neither restored nor removed. The snapshot is verified before the
+// backend is modified.
+func RestoreSnapshot(ctx context.Context, b Backend, r io.ReadSeeker, exclude []string) (int, error) {
+	if err := VerifySnapshot(r); err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// Write the entries of the snapshot first, so that a failure leaves as
+	// much of it in place as possible
+	restored := make(map[string]struct{})
+	err := readSnapshot(r, func(entry *Entry) error {
+		if isExcluded(entry.Key, exclude) {
+			return nil
+		}
+		if err := b.Put(ctx, entry); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to write entry %q: {{err}}", entry.Key), err)
+		}
+		restored[entry.Key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return len(restored), err
+	}
+
+	// Then remove everything else
+	err = scanSnapshotKeys(ctx, b, func(key string) error {
+		if _, ok := restored[key]; ok || isExcluded(key, exclude) {
+			return nil
+		}
+		if err := b.Delete(ctx, key); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to delete entry %q: {{err}}", key), err)
+		}
+		return nil
+	})
+
+	return len(restored), err
+}
+
+// readSnapshot calls fn for every entry of the snapshot. The checksum is only
+// known to match once all the entries have been read.
+func readSnapshot(r io.Reader, fn func(*Entry) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return ErrInvalidSnapshot
+	}
+	defer gz.Close()
+
+	in := &snapshotReader{
+		r:    bufio.NewReader(gz),
+		hash: sha256.New(),
+	}
+
+	header, err := in.read(len(snapshotMagic) + 4)
+	if err != nil || !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) {
+		return ErrInvalidSnapshot
+	}
+	if version := binary.BigEndian.Uint32(header[len(snapshotMagic):]); version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	for {
+		key, err := in.readField()
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			break
+		}
+		value, err := in.readField()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&Entry{Key: string(key), Value: value}); err != nil {
+			return err
+		}
+	}
+
+	sum := in.hash.Sum(nil)
+	checksum := make([]byte, len(sum))
+	if _, err := io.ReadFull(in.r, checksum); err != nil {
+		return ErrInvalidSnapshot
+	}
+	if subtle.ConstantTimeCompare(sum, checksum) != 1 {
+		return ErrInvalidSnapshot
+	}
+
+	// Anything past the checksum means the snapshot is not what was written
+	if _, err := in.r.ReadByte(); err != io.EOF {
+		return ErrInvalidSnapshot
+	}
+
+	return nil
+}
+
+// snapshotReader reads the fields of a snapshot while hashing them
+type snapshotReader struct {
+	r    *bufio.Reader
+	hash hash.Hash
+}
+
+func (s *snapshotReader) read(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(s.r, buf); err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	s.hash.Write(buf)
+	return buf, nil
+}
+
+func (s *snapshotReader) readField() ([]byte, error) {
+	length, err := s.read(4)
+	if err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length)
+	if n > snapshotMaxSize {
+		return nil, ErrInvalidSnapshot
+	}
+	return s.read(int(n))
+}
+
+func writeSnapshotField(w io.Writer, field []byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(field)
+	return err
+}
+
+// scanSnapshotKeys calls fn for every key of the backend
+func scanSnapshotKeys(ctx context.Context, b Backend, fn func(key string) error) error {
+	prefixes := []string{""}
+	for len(prefixes) > 0 {
+		prefix := prefixes[len(prefixes)-1]
+		prefixes = prefixes[:len(prefixes)-1]
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		keys, err := b.List(ctx, prefix)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to list %q: {{err}}", prefix), err)
+		}
+		for _, key := range keys {
+			if strings.HasSuffix(key, "/") {
+				prefixes = append(prefixes, prefix+key)
+				continue
+			}
+			if err := fn(prefix + key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isExcluded(key string, exclude []string) bool {
+	for _, e := range exclude {
+		if key == e {
+			return true
+		}
+	}
+	return false
+}
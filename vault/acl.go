@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
@@ -25,6 +26,10 @@ type ACL struct {
 
 	// root is enabled if the "root" named policy is present.
 	root bool
+
+	// namespace is the namespace the policies belong to. Paths outside of
+	// it are always denied; paths within it are matched relative to it.
+	namespace *namespace.Namespace
 }
 
 type PolicyCheckOpts struct {
@@ -44,6 +49,10 @@ type ACLResults struct {
 	RootPrivs  bool
 	IsRoot     bool
 	MFAMethods []string
+
+	// ControlGroup is set when the matching path requires additional
+	// authorizations before the request can be processed
+	ControlGroup *ControlGroup
 }
 
 // New is used to construct a policy based ACL from a set of policies.
@@ -104,6 +113,7 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.ControlGroup = nil
 				goto INSERT
 
 			default:
@@ -182,6 +192,21 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				}
 			}
 
+			// Control groups are combined so that the factors of every
+			// policy have to be satisfied, using the shortest TTL
+			if pc.Permissions.ControlGroup != nil {
+				if existingPerms.ControlGroup == nil {
+					existingPerms.ControlGroup = &ControlGroup{
+						TTL: pc.Permissions.ControlGroup.TTL,
+					}
+				} else if pc.Permissions.ControlGroup.TTL > 0 &&
+					(existingPerms.ControlGroup.TTL == 0 ||
+						pc.Permissions.ControlGroup.TTL < existingPerms.ControlGroup.TTL) {
+					existingPerms.ControlGroup.TTL = pc.Permissions.ControlGroup.TTL
+				}
+				existingPerms.ControlGroup.Factors = append(existingPerms.ControlGroup.Factors, pc.Permissions.ControlGroup.Factors...)
+			}
+
 		INSERT:
 			tree.Insert(pc.Prefix, existingPerms)
 		}
@@ -189,7 +214,24 @@ func NewACL(policies []*Policy) (*ACL, error) {
 	return a, nil
 }
 
+// namespacedPath returns the given full path relative to the namespace of
+// the ACL, and false if the path lies outside of that namespace
+func (a *ACL) namespacedPath(path string) (string, bool) {
+	if a.namespace == nil || a.namespace.ID == namespace.RootNamespaceID {
+		return path, true
+	}
+	if !strings.HasPrefix(path, a.namespace.Path) {
+		return "", false
+	}
+	return a.namespace.TrimmedPath(path), true
+}
+
 func (a *ACL) Capabilities(path string) (pathCapabilities []string) {
+	path, ok := a.namespacedPath(path)
+	if !ok {
+		return []string{DenyCapability}
+	}
+
 	// Fast-path root
 	if a.root {
 		return []string{RootCapability}
@@ -246,6 +288,11 @@ CHECK:
 func (a *ACL) AllowOperation(req *logical.Request) (ret *ACLResults) {
 	ret = new(ACLResults)
 
+	path, ok := a.namespacedPath(req.Path)
+	if !ok {
+		return
+	}
+
 	// Fast-path root
 	if a.root {
 		ret.Allowed = true
@@ -254,7 +301,6 @@ func (a *ACL) AllowOperation(req *logical.Request) (ret *ACLResults) {
 		return
 	}
 	op := req.Operation
-	path := req.Path
 
 	// Help is always allowed
 	if op == logical.HelpOperation {
@@ -314,6 +360,8 @@ CHECK:
 		return
 	}
 
+	ret.ControlGroup = permissions.ControlGroup
+
 	if permissions.MaxWrappingTTL > 0 {
 		if req.WrapInfo == nil || req.WrapInfo.TTL > permissions.MaxWrappingTTL {
 			return
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
 )
@@ -127,7 +128,7 @@ func (c *Core) disableAudit(ctx context.Context, path string) (bool, error) {
 	defer c.auditLock.Unlock()
 
 	newTable := c.audit.shallowClone()
-	entry := newTable.remove(path)
+	entry := newTable.remove(namespace.RootNamespace, path)
 
 	// Ensure there was a match
 	if entry == nil {
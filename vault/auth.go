@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -55,11 +56,25 @@ func (c *Core) enableCredential(ctx context.Context, entry *MountEntry) error {
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	ns := namespaceFromContext(ctx)
+	entry.NamespaceID = ns.ID
+	entry.namespace = ns
+
+	// Requests are assigned to the namespace holding their path, so a mount
+	// must not lie within a child namespace
+	if c.namespaceByPath(entry.APIPath()).ID != ns.ID {
+		return logical.CodedError(409, fmt.Sprintf("path '%s' is within a child namespace", entry.Path))
+	}
+
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
 	// Look for matching name
 	for _, ent := range c.auth.Entries {
+		if !ent.inNamespace(ns) {
+			continue
+		}
+
 		switch {
 		// Existing is oauth/github/ new is oauth/ or
 		// existing is oauth/ and new is oauth/github/
@@ -75,7 +90,7 @@ func (c *Core) enableCredential(ctx context.Context, entry *MountEntry) error {
 		return fmt.Errorf("token credential backend cannot be instantiated")
 	}
 
-	if conflict := c.router.MountConflict(credentialRoutePrefix + entry.Path); conflict != "" {
+	if conflict := c.router.MountConflict(entry.APIPath()); conflict != "" {
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", conflict))
 	}
 
@@ -140,13 +155,12 @@ func (c *Core) enableCredential(ctx context.Context, entry *MountEntry) error {
 
 	c.auth = newTable
 
-	path := credentialRoutePrefix + entry.Path
-	if err := c.router.Mount(backend, path, entry, view); err != nil {
+	if err := c.router.Mount(backend, entry.APIPath(), entry, view); err != nil {
 		return err
 	}
 
 	if c.logger.IsInfo() {
-		c.logger.Info("enabled credential backend", "path", entry.Path, "type", entry.Type)
+		c.logger.Info("enabled credential backend", "path", entry.APIPath(), "type", entry.Type)
 	}
 	return nil
 }
@@ -165,7 +179,7 @@ func (c *Core) disableCredential(ctx context.Context, path string) error {
 	}
 
 	// Store the view for this backend
-	fullPath := credentialRoutePrefix + path
+	fullPath := namespaceFromContext(ctx).Path + credentialRoutePrefix + path
 	view := c.router.MatchingStorageByAPIPath(fullPath)
 	if view == nil {
 		return fmt.Errorf("no matching backend %q", fullPath)
@@ -228,7 +242,7 @@ func (c *Core) removeCredEntry(ctx context.Context, path string) error {
 
 	// Taint the entry from the auth table
 	newTable := c.auth.shallowClone()
-	entry := newTable.remove(path)
+	entry := newTable.remove(namespaceFromContext(ctx), path)
 	if entry == nil {
 		c.logger.Error("nil entry found removing entry in auth table", "path", path)
 		return logical.CodedError(500, "failed to remove entry in auth table")
@@ -248,7 +262,7 @@ func (c *Core) removeCredEntry(ctx context.Context, path string) error {
 // unmounts and remounts the backend to pick up any changes, such as filtered
 // paths
 func (c *Core) remountCredEntryForce(ctx context.Context, path string) error {
-	fullPath := credentialRoutePrefix + path
+	fullPath := namespaceFromContext(ctx).Path + credentialRoutePrefix + path
 	me := c.router.MatchingMountEntry(fullPath)
 	if me == nil {
 		return fmt.Errorf("cannot find mount for path %q", path)
@@ -273,7 +287,7 @@ func (c *Core) taintCredEntry(ctx context.Context, path string) error {
 	// Taint the entry from the auth table
 	// We do this on the original since setting the taint operates
 	// on the entries which a shallow clone shares anyways
-	entry := c.auth.setTaint(path, true)
+	entry := c.auth.setTaint(namespaceFromContext(ctx), path, true)
 
 	// Ensure there was a match
 	if entry == nil {
@@ -340,6 +354,11 @@ func (c *Core) loadCredentials(ctx context.Context) error {
 
 	// Upgrade to table-scoped entries
 	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == "" {
+			entry.NamespaceID = namespace.RootNamespaceID
+			needPersist = true
+		}
+		entry.namespace = c.namespaceByID(entry.NamespaceID)
 		if entry.Table == "" {
 			entry.Table = c.auth.Type
 			needPersist = true
@@ -460,6 +479,11 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 	for _, entry := range c.auth.Entries {
 		var backend logical.Backend
 
+		if entry.namespace == nil {
+			c.logger.Error("skipping auth entry for missing namespace", "path", entry.Path, "namespace_id", entry.NamespaceID)
+			continue
+		}
+
 		// Create a barrier view using the UUID
 		viewPath := credentialBarrierPrefix + entry.UUID + "/"
 		view := NewBarrierView(c.barrier, viewPath)
@@ -497,7 +521,7 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 
 	ROUTER_MOUNT:
 		// Mount the backend
-		path := credentialRoutePrefix + entry.Path
+		path := entry.APIPath()
 		err = c.router.Mount(backend, path, entry, view)
 		if err != nil {
 			c.logger.Error("failed to mount auth entry", "path", entry.Path, "error", err)
@@ -535,7 +559,7 @@ func (c *Core) teardownCredentials(ctx context.Context) error {
 	if c.auth != nil {
 		authTable := c.auth.shallowClone()
 		for _, e := range authTable.Entries {
-			backend := c.router.MatchingBackend(credentialRoutePrefix + e.Path)
+			backend := c.router.MatchingBackend(e.APIPath())
 			if backend != nil {
 				backend.Cleanup(ctx)
 			}
@@ -602,6 +626,7 @@ func (c *Core) defaultAuthTable() *MountTable {
 	}
 	tokenAuth := &MountEntry{
 		Table:            credentialTableType,
+		NamespaceID:      namespace.RootNamespaceID,
 		Path:             "token/",
 		Type:             "token",
 		Description:      "token based credentials",
@@ -123,6 +123,25 @@ type SecurityBarrier interface {
 	// ActiveKeyInfo is used to inform details about the active key
 	ActiveKeyInfo() (*KeyInfo, error)
 
+	// PersistEncryptions stores the number of encryptions performed under
+	// the active key, which is used to rotate it automatically
+	PersistEncryptions(ctx context.Context) error
+
+	// Reencrypt rewrites the entry at the given key under the active term if
+	// it is encrypted under an older term. It returns the term the entry was
+	// encrypted under, which is zero for entries not encrypted by the keyring.
+	Reencrypt(ctx context.Context, key string) (uint32, error)
+
+	// Verify reads the entry at the given key and checks that it decrypts,
+	// without modifying anything. It returns the term the entry is encrypted
+	// under and its plaintext, which is nil if the entry does not exist.
+	Verify(ctx context.Context, key string) (uint32, []byte, error)
+
+	// RemoveKeys removes the keys of all terms before the given term from
+	// the keyring, returning the removed terms. Nothing may be encrypted
+	// under those terms anymore.
+	RemoveKeys(ctx context.Context, term uint32) ([]uint32, error)
+
 	// Rekey is used to change the master key used to protect the keyring
 	Rekey(context.Context, []byte) error
 
@@ -180,4 +199,5 @@ func (e *Entry) Logical() *logical.StorageEntry {
 type KeyInfo struct {
 	Term        int
 	InstallTime time.Time
+	Encryptions uint64
 }
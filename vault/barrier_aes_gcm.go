@@ -8,8 +8,10 @@ import (
 	"crypto/subtle"
 	"encoding/binary"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -49,6 +51,11 @@ var _ SecurityBarrier = &AESGCMBarrier{}
 // bit. AES-GCM is high performance, and provides both confidentiality
 // and integrity.
 type AESGCMBarrier struct {
+	// unaccountedEncryptions is the number of encryptions under the active
+	// term that have not been added to the count persisted in the keyring.
+	// It is accessed atomically and kept first for alignment.
+	unaccountedEncryptions uint64
+
 	backend physical.Backend
 
 	l      sync.RWMutex
@@ -427,6 +434,7 @@ func (b *AESGCMBarrier) Seal() error {
 	b.keyring.Zeroize(true)
 	b.keyring = nil
 	b.sealed = true
+	atomic.StoreUint64(&b.unaccountedEncryptions, 0)
 	return nil
 }
 
@@ -449,8 +457,9 @@ func (b *AESGCMBarrier) Rotate(ctx context.Context) (uint32, error) {
 	term := b.keyring.ActiveTerm()
 	newTerm := term + 1
 
-	// Add a new encryption key
-	newKeyring, err := b.keyring.AddKey(&Key{
+	// Add a new encryption key, keeping the final encryption count of the
+	// current one
+	newKeyring, err := b.accountEncryptions(b.keyring).AddKey(&Key{
 		Term:    newTerm,
 		Version: 1,
 		Value:   encrypt,
@@ -466,6 +475,7 @@ func (b *AESGCMBarrier) Rotate(ctx context.Context) (uint32, error) {
 
 	// Swap the keyrings
 	b.keyring = newKeyring
+	atomic.StoreUint64(&b.unaccountedEncryptions, 0)
 	return newTerm, nil
 }
 
@@ -572,10 +582,217 @@ func (b *AESGCMBarrier) ActiveKeyInfo() (*KeyInfo, error) {
 	info := &KeyInfo{
 		Term:        int(term),
 		InstallTime: key.InstallTime,
+		Encryptions: key.Encryptions + atomic.LoadUint64(&b.unaccountedEncryptions),
 	}
 	return info, nil
 }
 
+// PersistEncryptions adds the encryptions performed under the active term
+// since the last call to the count stored in the keyring
+func (b *AESGCMBarrier) PersistEncryptions(ctx context.Context) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	if atomic.LoadUint64(&b.unaccountedEncryptions) == 0 {
+		return nil
+	}
+
+	newKeyring := b.accountEncryptions(b.keyring)
+	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		return err
+	}
+
+	b.keyring = newKeyring
+	atomic.StoreUint64(&b.unaccountedEncryptions, 0)
+	return nil
+}
+
+// accountEncryptions returns a copy of the keyring with the unaccounted
+// encryptions added to the count of the active key. The write lock must be
+// held, and the counter reset once the returned keyring is in use.
+func (b *AESGCMBarrier) accountEncryptions(keyring *Keyring) *Keyring {
+	unaccounted := atomic.LoadUint64(&b.unaccountedEncryptions)
+	if unaccounted == 0 {
+		return keyring
+	}
+
+	activeKey := *keyring.ActiveKey()
+	activeKey.Encryptions += unaccounted
+
+	clone := keyring.Clone()
+	clone.keys[activeKey.Term] = &activeKey
+	return clone
+}
+
+// Reencrypt rewrites the entry at the given key under the active term if it
+// is encrypted under an older term. The barrier is locked while the entry is
+// rewritten so that concurrent writes to it are not lost.
+func (b *AESGCMBarrier) Reencrypt(ctx context.Context, key string) (uint32, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return 0, ErrBarrierSealed
+	}
+
+	// The keyring is encrypted by the master key and the upgrade keys must
+	// stay encrypted under the term they upgrade from
+	if key == keyringPath || key == barrierInitPath || strings.HasPrefix(key, keyringUpgradePrefix) {
+		return 0, nil
+	}
+
+	pe, err := b.backend.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if pe == nil || len(pe.Value) < termSize+1 {
+		return 0, nil
+	}
+
+	// Entries written to the physical storage directly, such as the seal
+	// configuration, do not carry a term of the keyring
+	term := binary.BigEndian.Uint32(pe.Value[:4])
+	if b.keyring.TermKey(term) == nil {
+		return 0, nil
+	}
+	switch pe.Value[4] {
+	case AESGCMVersion1, AESGCMVersion2:
+	default:
+		return 0, nil
+	}
+
+	activeTerm := b.keyring.ActiveTerm()
+	if term == activeTerm {
+		return term, nil
+	}
+
+	plain, err := b.decryptKeyring(key, pe.Value)
+	if err != nil {
+		return term, errwrap.Wrapf("decryption failed: {{err}}", err)
+	}
+	defer memzero(plain)
+
+	primary, err := b.aeadForTerm(activeTerm)
+	if err != nil {
+		return term, err
+	}
+
+	pe = &physical.Entry{
+		Key:      key,
+		Value:    b.encrypt(key, activeTerm, primary, plain),
+		SealWrap: pe.SealWrap,
+	}
+	atomic.AddUint64(&b.unaccountedEncryptions, 1)
+	if err := b.backend.Put(ctx, pe); err != nil {
+		return term, err
+	}
+
+	return term, nil
+}
+
+// Verify reads the entry at the given key and checks that it decrypts. The
+// keyring is checked against the master key.
+func (b *AESGCMBarrier) Verify(ctx context.Context, key string) (uint32, []byte, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return 0, nil, ErrBarrierSealed
+	}
+
+	pe, err := b.backend.Get(ctx, key)
+	if err != nil {
+		return 0, nil, err
+	}
+	if pe == nil {
+		return 0, nil, nil
+	}
+
+	// A corrupted entry may be too short to hold the term, version, nonce
+	// and tag, which the decryption does not check
+	if len(pe.Value) < termSize+1 {
+		return 0, nil, fmt.Errorf("entry is too short to be encrypted")
+	}
+	term := binary.BigEndian.Uint32(pe.Value[:termSize])
+
+	var gcm cipher.AEAD
+	if key == keyringPath {
+		gcm, err = b.aeadFromKey(b.keyring.MasterKey())
+	} else {
+		gcm, err = b.aeadForTerm(term)
+	}
+	if err != nil {
+		return term, nil, err
+	}
+	if gcm == nil {
+		return term, nil, fmt.Errorf("no decryption key available for term %d", term)
+	}
+	if len(pe.Value) < termSize+1+gcm.NonceSize()+gcm.Overhead() {
+		return term, nil, fmt.Errorf("entry is too short to be encrypted")
+	}
+
+	var plain []byte
+	if key == keyringPath {
+		plain, err = b.decrypt(key, gcm, pe.Value)
+	} else {
+		plain, err = b.decryptKeyring(key, pe.Value)
+	}
+	if err != nil {
+		return term, nil, errwrap.Wrapf("decryption failed: {{err}}", err)
+	}
+
+	return term, plain, nil
+}
+
+// RemoveKeys removes the keys of all terms before the given term from the
+// keyring and persists it, returning the removed terms.
+func (b *AESGCMBarrier) RemoveKeys(ctx context.Context, term uint32) ([]uint32, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return nil, ErrBarrierSealed
+	}
+
+	if term > b.keyring.ActiveTerm() {
+		return nil, fmt.Errorf("cannot remove the active key")
+	}
+
+	var removed []uint32
+	newKeyring := b.keyring
+	for oldTerm := range b.keyring.keys {
+		if oldTerm >= term {
+			continue
+		}
+
+		var err error
+		newKeyring, err = newKeyring.RemoveKey(oldTerm)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to remove encryption key: {{err}}", err)
+		}
+		removed = append(removed, oldTerm)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	// Persist the new keyring
+	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		return nil, err
+	}
+
+	// Swap the keyrings and drop the cached AEADs of the removed terms
+	b.keyring = newKeyring
+	b.cacheLock.Lock()
+	for _, oldTerm := range removed {
+		delete(b.cache, oldTerm)
+	}
+	b.cacheLock.Unlock()
+
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	return removed, nil
+}
+
 // Rekey is used to change the master key used to protect the keyring
 func (b *AESGCMBarrier) Rekey(ctx context.Context, key []byte) error {
 	b.l.Lock()
@@ -652,6 +869,7 @@ func (b *AESGCMBarrier) Put(ctx context.Context, entry *Entry) error {
 		Value:    b.encrypt(entry.Key, term, primary, entry.Value),
 		SealWrap: entry.SealWrap,
 	}
+	atomic.AddUint64(&b.unaccountedEncryptions, 1)
 	return b.backend.Put(ctx, pe)
 }
 
@@ -864,6 +1082,7 @@ func (b *AESGCMBarrier) Encrypt(ctx context.Context, key string, plaintext []byt
 	}
 
 	ciphertext := b.encrypt(key, term, primary, plaintext)
+	atomic.AddUint64(&b.unaccountedEncryptions, 1)
 	return ciphertext, nil
 }
 
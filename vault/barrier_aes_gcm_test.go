@@ -3,6 +3,7 @@ package vault
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"testing"
 
@@ -58,6 +59,137 @@ func TestAESGCMBarrier_Rotate(t *testing.T) {
 	testBarrier_Rotate(t, b)
 }
 
+func TestAESGCMBarrier_Encryptions(t *testing.T) {
+	_, b, key := mockBarrier(t)
+
+	for i := 0; i < 3; i++ {
+		if err := b.Put(context.Background(), &Entry{Key: "test", Value: []byte("test")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	info, err := b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Encryptions != 3 {
+		t.Fatalf("bad: %d", info.Encryptions)
+	}
+
+	// The count is kept across a reseal once persisted
+	if err := b.PersistEncryptions(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.Seal(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.Unseal(context.Background(), key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Encryptions < 3 {
+		t.Fatalf("bad: %d", info.Encryptions)
+	}
+
+	// A new key starts counting from zero
+	if _, err := b.Rotate(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Encryptions != 0 {
+		t.Fatalf("bad: %d", info.Encryptions)
+	}
+}
+
+func TestAESGCMBarrier_Reencrypt(t *testing.T) {
+	inm, b, _ := mockBarrier(t)
+
+	// Write an entry under the first term
+	if err := b.Put(context.Background(), &Entry{Key: "test", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Entries written directly to the physical storage are left untouched
+	raw := &physical.Entry{Key: "core/raw", Value: []byte(`{"raw":true}`)}
+	if err := inm.Put(context.Background(), raw); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, err := b.Rotate(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	term, err := b.Reencrypt(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if term != 1 {
+		t.Fatalf("bad: %d", term)
+	}
+	pe, _ := inm.Get(context.Background(), "test")
+	if binary.BigEndian.Uint32(pe.Value[:4]) != 2 {
+		t.Fatalf("entry not re-encrypted: %v", pe.Value)
+	}
+
+	term, err = b.Reencrypt(context.Background(), "test")
+	if err != nil || term != 2 {
+		t.Fatalf("bad: %d %v", term, err)
+	}
+
+	term, err = b.Reencrypt(context.Background(), "core/raw")
+	if err != nil || term != 0 {
+		t.Fatalf("bad: %d %v", term, err)
+	}
+	pe, _ = inm.Get(context.Background(), "core/raw")
+	if !bytes.Equal(pe.Value, raw.Value) {
+		t.Fatalf("bad: %s", pe.Value)
+	}
+
+	// The keyring itself is encrypted by the master key
+	term, err = b.Reencrypt(context.Background(), keyringPath)
+	if err != nil || term != 0 {
+		t.Fatalf("bad: %d %v", term, err)
+	}
+
+	if _, err := b.RemoveKeys(context.Background(), 3); err == nil {
+		t.Fatalf("expected error removing the active key")
+	}
+	removed, err := b.RemoveKeys(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != 1 {
+		t.Fatalf("bad: %v", removed)
+	}
+
+	out, err := b.Get(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || string(out.Value) != "test" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The pruned keyring is persisted
+	if err := b.ReloadKeyring(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyring, err := b.Keyring()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keyring.TermKey(1) != nil || keyring.TermKey(2) == nil {
+		t.Fatalf("bad keyring: %#v", keyring)
+	}
+}
+
 func TestAESGCMBarrier_Upgrade(t *testing.T) {
 	inm, err := inmem.NewInmem(nil, logger)
 	if err != nil {
@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// ReencryptStatus describes the progress of a re-encryption of the barrier
+// entries under the active key term
+type ReencryptStatus struct {
+	// Term is the active key term when the re-encryption was started. Once
+	// it has completed without failures, nothing is encrypted under an
+	// older term.
+	Term uint32
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	Running     bool
+	Complete    bool
+	Scanned     int
+	Reencrypted int
+	Failed      int
+	Error       string
+}
+
+// startReencrypt starts re-encrypting every barrier entry written under an
+// older key term in the background. The job is stopped if the node is sealed
+// or steps down.
+func (c *Core) startReencrypt() error {
+	c.reencryptLock.Lock()
+	defer c.reencryptLock.Unlock()
+
+	if c.reencryptStatus != nil && c.reencryptStatus.Running {
+		return fmt.Errorf("re-encryption is already in progress")
+	}
+
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		return err
+	}
+
+	status := &ReencryptStatus{
+		Term:      uint32(info.Term),
+		StartTime: time.Now(),
+		Running:   true,
+	}
+	c.reencryptStatus = status
+
+	go c.runReencrypt(c.activeContext, status)
+
+	return nil
+}
+
+// runReencrypt walks the barrier and rewrites the entries encrypted under an
+// older term than that of the given status, which it keeps up to date
+func (c *Core) runReencrypt(ctx context.Context, status *ReencryptStatus) {
+	c.logger.Info("starting re-encryption of barrier entries")
+
+	err := logical.ScanView(ctx, c.barrier, func(path string) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		entryTerm, err := c.barrier.Reencrypt(ctx, path)
+
+		c.reencryptLock.Lock()
+		defer c.reencryptLock.Unlock()
+
+		status.Scanned++
+		switch {
+		case err != nil:
+			c.logger.Error("failed to re-encrypt barrier entry", "path", path, "error", err)
+			status.Failed++
+		case entryTerm != 0 && entryTerm < status.Term:
+			status.Reencrypted++
+		}
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	c.reencryptLock.Lock()
+	defer c.reencryptLock.Unlock()
+
+	status.Running = false
+	status.EndTime = time.Now()
+	if err != nil {
+		status.Error = err.Error()
+		c.logger.Error("re-encryption of barrier entries failed", "error", err)
+		return
+	}
+
+	status.Complete = true
+	c.logger.Info("completed re-encryption of barrier entries", "scanned", status.Scanned, "reencrypted", status.Reencrypted, "failed", status.Failed)
+}
+
+// ReencryptStatus returns the status of the last re-encryption of the
+// barrier entries, or nil if none has been started since unsealing
+func (c *Core) ReencryptStatus() *ReencryptStatus {
+	c.reencryptLock.Lock()
+	defer c.reencryptLock.Unlock()
+
+	if c.reencryptStatus == nil {
+		return nil
+	}
+
+	status := *c.reencryptStatus
+	return &status
+}
+
+// pruneKeyring removes the keys of the terms that are no longer referenced
+// by any barrier entry, which is only known after a re-encryption has
+// completed without failures
+func (c *Core) pruneKeyring(ctx context.Context) ([]uint32, error) {
+	c.reencryptLock.Lock()
+	defer c.reencryptLock.Unlock()
+
+	status := c.reencryptStatus
+	switch {
+	case status == nil || status.Running:
+		return nil, fmt.Errorf("a completed re-encryption is required to prune the keyring")
+	case !status.Complete:
+		return nil, fmt.Errorf("the last re-encryption did not complete: %s", status.Error)
+	case status.Failed > 0:
+		return nil, fmt.Errorf("the last re-encryption failed to re-encrypt %d entries", status.Failed)
+	}
+
+	return c.barrier.RemoveKeys(ctx, status.Term)
+}
@@ -0,0 +1,204 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// keyRotationConfigPath is the location of the automatic key rotation
+	// configuration within the config sub-view of the system barrier view
+	keyRotationConfigPath = "rotate"
+
+	// defaultKeyRotationMaxOperations is the number of encryptions after
+	// which the barrier key is rotated by default. It keeps the probability
+	// of a nonce collision within the bounds recommended for AES-GCM with
+	// random nonces, which is 2^32 encryptions, with some margin.
+	defaultKeyRotationMaxOperations = 3865470566
+
+	// minimumKeyRotationInterval is the shortest allowed interval between
+	// automatic key rotations
+	minimumKeyRotationInterval = 24 * time.Hour
+)
+
+var (
+	// keyRotationCheckInterval is how often the active node persists the
+	// encryption count and checks whether the key needs to be rotated
+	keyRotationCheckInterval = 30 * time.Second
+)
+
+// KeyRotationConfig holds the settings of the automatic rotation of the
+// barrier key
+type KeyRotationConfig struct {
+	Disabled      bool          `json:"disabled"`
+	MaxOperations int64         `json:"max_operations"`
+	Interval      time.Duration `json:"interval"`
+}
+
+// loadKeyRotationConfig returns the automatic key rotation configuration,
+// which holds the defaults if none has been stored
+func (c *Core) loadKeyRotationConfig(ctx context.Context) (*KeyRotationConfig, error) {
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := view.Get(ctx, keyRotationConfigPath)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read key rotation config: {{err}}", err)
+	}
+
+	config := &KeyRotationConfig{
+		MaxOperations: defaultKeyRotationMaxOperations,
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, errwrap.Wrapf("failed to decode key rotation config: {{err}}", err)
+	}
+
+	return config, nil
+}
+
+// rotateBarrierKey installs a new barrier key and sets up the upgrade path
+// for the standby instances
+func (c *Core) rotateBarrierKey(ctx context.Context) (uint32, error) {
+	// Rotate to the new term
+	newTerm, err := c.barrier.Rotate(ctx)
+	if err != nil {
+		c.logger.Error("failed to create new encryption key", "error", err)
+		return 0, err
+	}
+	c.logger.Info("installed new encryption key", "term", newTerm)
+
+	// In HA mode, we need to an upgrade path for the standby instances
+	if c.ha != nil {
+		// Create the upgrade path to the new term
+		if err := c.barrier.CreateUpgrade(ctx, newTerm); err != nil {
+			c.logger.Error("failed to create new upgrade", "term", newTerm, "error", err)
+		}
+
+		// Schedule the destroy of the upgrade path
+		time.AfterFunc(keyRotateGracePeriod, func() {
+			if err := c.barrier.DestroyUpgrade(ctx, newTerm); err != nil {
+				c.logger.Error("failed to destroy upgrade", "term", newTerm, "error", err)
+			}
+		})
+	}
+
+	// Write to the canary path, which will force a synchronous truing during
+	// replication
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   coreKeyringCanaryPath,
+		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
+	}); err != nil {
+		c.logger.Error("error saving keyring canary", "error", err)
+		return newTerm, errwrap.Wrapf("failed to save keyring canary: {{err}}", err)
+	}
+
+	return newTerm, nil
+}
+
+// runKeyRotationCheck periodically checks whether the barrier key has to be
+// rotated until the given context is canceled
+func (c *Core) runKeyRotationCheck(ctx context.Context) {
+	ticker := time.NewTicker(keyRotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.checkKeyRotation(ctx); err != nil {
+				c.logger.Error("failed to check for automatic key rotation", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkKeyRotation persists the number of encryptions under the active key
+// and rotates it if it has been used for too many encryptions or for longer
+// than the configured interval
+func (c *Core) checkKeyRotation(ctx context.Context) error {
+	if c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	if err := c.barrier.PersistEncryptions(ctx); err != nil {
+		return errwrap.Wrapf("failed to persist encryption count: {{err}}", err)
+	}
+
+	config, err := c.loadKeyRotationConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config.Disabled {
+		return nil
+	}
+
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	switch {
+	case config.MaxOperations > 0 && info.Encryptions >= uint64(config.MaxOperations):
+		reason = "max_operations"
+	case config.Interval > 0 && time.Since(info.InstallTime) >= config.Interval:
+		reason = "interval"
+	default:
+		return nil
+	}
+
+	c.logger.Info("rotating encryption key automatically", "reason", reason, "term", info.Term, "encryptions", info.Encryptions)
+
+	newTerm, err := c.rotateBarrierKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.auditKeyRotation(ctx, newTerm, reason)
+	return nil
+}
+
+// auditKeyRotation records an automatic key rotation in the audit log as if
+// it had been requested through sys/rotate
+func (c *Core) auditKeyRotation(ctx context.Context, term uint32, reason string) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		c.logger.Error("failed to generate audit request ID", "error", err)
+		return
+	}
+
+	logInput := &audit.LogInput{
+		Request: &logical.Request{
+			ID:        id,
+			Operation: logical.UpdateOperation,
+			Path:      "sys/rotate",
+			Data: map[string]interface{}{
+				"reason": reason,
+			},
+		},
+		NonHMACReqDataKeys: []string{"reason"},
+	}
+	if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit automatic key rotation", "error", err)
+		return
+	}
+
+	logInput.Response = &logical.Response{
+		Data: map[string]interface{}{
+			"term": term,
+		},
+	}
+	if err := c.auditBroker.LogResponse(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit automatic key rotation", "error", err)
+	}
+}
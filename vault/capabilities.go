@@ -4,6 +4,7 @@ import (
 	"context"
 	"sort"
 
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -29,9 +30,15 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 		return []string{DenyCapability}, nil
 	}
 
+	tokenNS := c.namespaceByID(te.namespaceID())
+	if tokenNS == nil {
+		return []string{DenyCapability}, nil
+	}
+	tokenCtx := namespace.ContextWithNamespace(ctx, tokenNS)
+
 	var policies []*Policy
 	for _, tePolicy := range te.Policies {
-		policy, err := c.policyStore.GetPolicy(ctx, tePolicy, PolicyTypeToken)
+		policy, err := c.policyStore.GetPolicy(tokenCtx, tePolicy, PolicyTypeToken)
 		if err != nil {
 			return nil, err
 		}
@@ -44,7 +51,7 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 	}
 
 	for _, item := range derivedPolicies {
-		policy, err := c.policyStore.GetPolicy(ctx, item, PolicyTypeToken)
+		policy, err := c.policyStore.GetPolicy(tokenCtx, item, PolicyTypeToken)
 		if err != nil {
 			return nil, err
 		}
@@ -59,8 +66,10 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 	if err != nil {
 		return nil, err
 	}
+	acl.namespace = tokenNS
 
-	capabilities := acl.Capabilities(path)
+	// The path is relative to the namespace of the request
+	capabilities := acl.Capabilities(namespaceFromContext(ctx).Path + path)
 	sort.Strings(capabilities)
 	return capabilities, nil
 }
@@ -0,0 +1,363 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// controlGroupCubbyholePath is where the pending request of a control
+	// group is stored, in the cubbyhole of its wrapping token
+	controlGroupCubbyholePath = "cubbyhole/control-group"
+
+	// controlGroupConfigPath is the location of the control group
+	// configuration within the config sub-view of the system barrier view
+	controlGroupConfigPath = "control-group"
+
+	// defaultControlGroupTTL is the lifetime of a control group wrapping
+	// token if neither the policy nor the request specify one
+	defaultControlGroupTTL = 24 * time.Hour
+)
+
+var (
+	// contextControlGroupSatisfied marks a request that is replayed after
+	// its control group has been satisfied
+	contextControlGroupSatisfied = controlGroupContextValues("control-group-satisfied")
+
+	// errControlGroupNotFound is returned when an accessor does not belong to
+	// a control group wrapping token
+	errControlGroupNotFound = errors.New("control group request not found")
+)
+
+type controlGroupContextValues string
+
+// ControlGroupConfig holds the settings of control groups
+type ControlGroupConfig struct {
+	MaxTTL time.Duration `json:"max_ttl"`
+}
+
+// controlGroupRequest is a request held back until the authorizations
+// required by its control group have been given
+type controlGroupRequest struct {
+	ID             string                       `json:"id"`
+	Operation      logical.Operation            `json:"operation"`
+	Path           string                       `json:"path"`
+	Data           map[string]interface{}       `json:"data"`
+	ClientToken    string                       `json:"client_token"`
+	EntityID       string                       `json:"entity_id"`
+	Factors        []*controlGroupFactor        `json:"factors"`
+	Authorizations []*controlGroupAuthorization `json:"authorizations"`
+}
+
+// controlGroupFactor is a factor of a control group with its group names
+// resolved at the time of the request
+type controlGroupFactor struct {
+	Name      string   `json:"name"`
+	GroupIDs  []string `json:"group_ids"`
+	Approvals int      `json:"approvals"`
+}
+
+// controlGroupAuthorization records an approval of a control group request
+type controlGroupAuthorization struct {
+	EntityID string    `json:"entity_id"`
+	Time     time.Time `json:"time"`
+}
+
+// controlGroupSatisfied returns whether the request in the context is the
+// replay of a request whose control group has been satisfied
+func controlGroupSatisfied(ctx context.Context) bool {
+	satisfied, _ := ctx.Value(contextControlGroupSatisfied).(bool)
+	return satisfied
+}
+
+// loadControlGroupConfig returns the control group configuration, which is
+// empty if none has been stored
+func (c *Core) loadControlGroupConfig(ctx context.Context) (*ControlGroupConfig, error) {
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := view.Get(ctx, controlGroupConfigPath)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read control group config: {{err}}", err)
+	}
+
+	config := new(ControlGroupConfig)
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, errwrap.Wrapf("failed to decode control group config: {{err}}", err)
+	}
+
+	return config, nil
+}
+
+// controlGroupWrap holds back the request and returns a wrapping token in its
+// place. The request is processed when the token is unwrapped once the
+// control group is satisfied.
+func (c *Core) controlGroupWrap(ctx context.Context, req *logical.Request, te *TokenEntry, cg *ControlGroup) (*logical.Response, error) {
+	config, err := c.loadControlGroupConfig(ctx)
+	if err != nil {
+		c.logger.Error("failed to load control group config", "error", err)
+		return nil, ErrInternalError
+	}
+
+	ttl := defaultControlGroupTTL
+	switch {
+	case cg.TTL > 0:
+		ttl = cg.TTL
+	case req.WrapInfo != nil && req.WrapInfo.TTL > 0:
+		ttl = req.WrapInfo.TTL
+	}
+	if config.MaxTTL > 0 && ttl > config.MaxTTL {
+		ttl = config.MaxTTL
+	}
+
+	cgReq := &controlGroupRequest{
+		ID:          req.ID,
+		Operation:   req.Operation,
+		Path:        req.Path,
+		Data:        req.Data,
+		ClientToken: req.ClientToken,
+		EntityID:    te.EntityID,
+	}
+
+	// Group names are resolved within the namespace of the request
+	for _, factor := range cg.Factors {
+		groupIDs := append([]string(nil), factor.Identity.GroupIDs...)
+		for _, name := range factor.Identity.GroupNames {
+			group, err := c.identityStore.MemDBGroupByName(ctx, name, false)
+			if err != nil {
+				c.logger.Error("failed to look up control group factor group", "group_name", name, "error", err)
+				return nil, ErrInternalError
+			}
+			if group != nil {
+				groupIDs = append(groupIDs, group.ID)
+			}
+		}
+
+		cgReq.Factors = append(cgReq.Factors, &controlGroupFactor{
+			Name:      factor.Name,
+			GroupIDs:  strutil.RemoveDuplicates(groupIDs, false),
+			Approvals: factor.Identity.Approvals,
+		})
+	}
+
+	creationTime := time.Now()
+	cgTE := TokenEntry{
+		Path:           req.Path,
+		Policies:       []string{controlGroupPolicyName},
+		CreationTime:   creationTime.Unix(),
+		TTL:            ttl,
+		NumUses:        1,
+		ExplicitMaxTTL: ttl,
+	}
+
+	if err := c.tokenStore.create(ctx, &cgTE); err != nil {
+		c.logger.Error("failed to create control group token", "error", err)
+		return nil, ErrInternalError
+	}
+
+	if err := c.storeControlGroupRequest(ctx, cgTE.ID, cgReq); err != nil {
+		c.tokenStore.Revoke(ctx, cgTE.ID)
+		c.logger.Error("failed to store control group request", "error", err)
+		return nil, ErrInternalError
+	}
+
+	// Store info for lookup
+	cubbyReq := &logical.Request{
+		Operation:   logical.CreateOperation,
+		Path:        "cubbyhole/wrapinfo",
+		ClientToken: cgTE.ID,
+		Data: map[string]interface{}{
+			"creation_ttl":  ttl,
+			"creation_time": creationTime,
+			"creation_path": req.Path,
+		},
+	}
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err == nil && cubbyResp != nil && cubbyResp.IsError() {
+		err = cubbyResp.Error()
+	}
+	if err != nil {
+		c.tokenStore.Revoke(ctx, cgTE.ID)
+		c.logger.Error("failed to store control group wrapping information", "error", err)
+		return nil, ErrInternalError
+	}
+
+	wAuth := &logical.Auth{
+		ClientToken: cgTE.ID,
+		Policies:    []string{controlGroupPolicyName},
+		LeaseOptions: logical.LeaseOptions{
+			TTL:       cgTE.TTL,
+			Renewable: false,
+		},
+	}
+
+	// Register the wrapping token with the expiration manager
+	if err := c.expiration.RegisterAuth(cgTE.Path, wAuth); err != nil {
+		// Revoke since it's not yet being tracked for expiration
+		c.tokenStore.Revoke(ctx, cgTE.ID)
+		c.logger.Error("failed to register control group token lease", "request_path", req.Path, "error", err)
+		return nil, ErrInternalError
+	}
+
+	return &logical.Response{
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			Token:           cgTE.ID,
+			Accessor:        cgTE.Accessor,
+			TTL:             ttl,
+			CreationTime:    creationTime,
+			CreationPath:    req.Path,
+			WrappedEntityID: te.EntityID,
+		},
+	}, nil
+}
+
+// controlGroupRequestByAccessor returns the token entry and the pending
+// request of the control group wrapping token with the given accessor
+func (c *Core) controlGroupRequestByAccessor(ctx context.Context, accessor string) (*TokenEntry, *controlGroupRequest, error) {
+	aEntry, err := c.tokenStore.lookupByAccessor(ctx, accessor, false)
+	if err != nil {
+		if _, ok := err.(*logical.StatusBadRequest); ok {
+			return nil, nil, errControlGroupNotFound
+		}
+		return nil, nil, err
+	}
+	if aEntry.TokenID == "" {
+		return nil, nil, errControlGroupNotFound
+	}
+
+	te, err := c.tokenStore.Lookup(ctx, aEntry.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if te == nil || len(te.Policies) != 1 || te.Policies[0] != controlGroupPolicyName {
+		return nil, nil, errControlGroupNotFound
+	}
+
+	cgReq, err := c.loadControlGroupRequest(ctx, te.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return te, cgReq, nil
+}
+
+// loadControlGroupRequest reads the pending request from the cubbyhole of
+// the given control group wrapping token
+func (c *Core) loadControlGroupRequest(ctx context.Context, token string) (*controlGroupRequest, error) {
+	cubbyReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: token,
+	}
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err != nil {
+		return nil, errwrap.Wrapf("error looking up control group request: {{err}}", err)
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil {
+		return nil, errControlGroupNotFound
+	}
+	if cubbyResp.IsError() {
+		return nil, cubbyResp.Error()
+	}
+
+	raw, ok := cubbyResp.Data["request"].(string)
+	if !ok {
+		return nil, errors.New("could not decode control group request")
+	}
+
+	cgReq := new(controlGroupRequest)
+	if err := jsonutil.DecodeJSON([]byte(raw), cgReq); err != nil {
+		return nil, errwrap.Wrapf("failed to decode control group request: {{err}}", err)
+	}
+
+	return cgReq, nil
+}
+
+// storeControlGroupRequest writes the pending request to the cubbyhole of
+// the given control group wrapping token
+func (c *Core) storeControlGroupRequest(ctx context.Context, token string, cgReq *controlGroupRequest) error {
+	// The request is stored as a string so that the types of its data are
+	// preserved, as with wrapped responses
+	marshaled, err := json.Marshal(cgReq)
+	if err != nil {
+		return errwrap.Wrapf("failed to encode control group request: {{err}}", err)
+	}
+
+	cubbyReq := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: token,
+		Data: map[string]interface{}{
+			"request": string(marshaled),
+		},
+	}
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err != nil {
+		return err
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp.Error()
+	}
+
+	return nil
+}
+
+// controlGroupFactorsForEntity returns the names of the factors of the
+// request that the given entity is able to authorize
+func (c *Core) controlGroupFactorsForEntity(cgReq *controlGroupRequest, entityID string) ([]string, error) {
+	groups, inheritedGroups, err := c.identityStore.groupsByEntityID(entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs := make(map[string]struct{}, len(groups)+len(inheritedGroups))
+	for _, group := range append(groups, inheritedGroups...) {
+		groupIDs[group.ID] = struct{}{}
+	}
+
+	var factors []string
+	for _, factor := range cgReq.Factors {
+		for _, groupID := range factor.GroupIDs {
+			if _, ok := groupIDs[groupID]; ok {
+				factors = append(factors, factor.Name)
+				break
+			}
+		}
+	}
+
+	return factors, nil
+}
+
+// controlGroupApproved returns whether every factor of the request has
+// received the required number of authorizations. Group memberships are
+// evaluated at the time of the check.
+func (c *Core) controlGroupApproved(cgReq *controlGroupRequest) (bool, error) {
+	approvals := make(map[string]int, len(cgReq.Factors))
+	for _, authz := range cgReq.Authorizations {
+		factors, err := c.controlGroupFactorsForEntity(cgReq, authz.EntityID)
+		if err != nil {
+			return false, err
+		}
+		for _, factor := range factors {
+			approvals[factor]++
+		}
+	}
+
+	for _, factor := range cgReq.Factors {
+		if approvals[factor.Name] < factor.Approvals {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_ControlGroup(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["value"] = "bar"
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	// Set up the requester and the approver
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.Data["name"] = "bob"
+	req.ClientToken = root
+	resp := testNamespaceRequest(t, c, req)
+	requesterEntityID := resp.Data["id"].(string)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.Data["name"] = "alice"
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	approverEntityID := resp.Data["id"].(string)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/group")
+	req.Data["name"] = "managers"
+	req.Data["member_entity_ids"] = []string{approverEntityID}
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/requester")
+	req.Data["policy"] = `
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approvals = 1
+			}
+		}
+	}
+}
+path "sys/control-group/authorize" {
+	capabilities = ["update"]
+}
+`
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/approver")
+	req.Data["policy"] = `
+path "sys/control-group/*" {
+	capabilities = ["update"]
+}
+`
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	requester := &TokenEntry{
+		Path:     "auth/token/create",
+		Policies: []string{"default", "requester"},
+		EntityID: requesterEntityID,
+	}
+	if err := c.tokenStore.create(context.Background(), requester); err != nil {
+		t.Fatal(err)
+	}
+	approver := &TokenEntry{
+		Path:     "auth/token/create",
+		Policies: []string{"default", "approver"},
+		EntityID: approverEntityID,
+	}
+	if err := c.tokenStore.create(context.Background(), approver); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request is held back and a wrapping token returned instead
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = requester.ID
+	resp = testNamespaceRequest(t, c, req)
+	if resp.Data != nil || resp.WrapInfo == nil || resp.WrapInfo.Token == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	wrapToken := resp.WrapInfo.Token
+	wrapAccessor := resp.WrapInfo.Accessor
+
+	// The token can't be unwrapped before the request has been authorized
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+	req.ClientToken = wrapToken
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Requesters can't authorize their own request
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+	req.Data["accessor"] = wrapAccessor
+	req.ClientToken = requester.ID
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+	req.Data["accessor"] = wrapAccessor
+	req.ClientToken = approver.ID
+	resp = testNamespaceRequest(t, c, req)
+	if resp.Data["approved"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/request")
+	req.Data["accessor"] = wrapAccessor
+	req.ClientToken = approver.ID
+	resp = testNamespaceRequest(t, c, req)
+	if resp.Data["approved"] != true || resp.Data["request_path"] != "secret/foo" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["request_entity"].(map[string]interface{})["name"] != "bob" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	authorizations := resp.Data["authorizations"].([]map[string]interface{})
+	if len(authorizations) != 1 || authorizations[0]["entity_name"] != "alice" {
+		t.Fatalf("bad: %#v", authorizations)
+	}
+
+	// Unwrapping processes the original request
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+	req.ClientToken = wrapToken
+	resp = testNamespaceRequest(t, c, req)
+	if !strings.Contains(string(resp.Data[logical.HTTPRawBody].([]byte)), `"value":"bar"`) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The token can only be unwrapped once
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+	req.ClientToken = wrapToken
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/armon/go-radix"
 	log "github.com/hashicorp/go-hclog"
 
 	"google.golang.org/grpc"
@@ -29,7 +30,9 @@ import (
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/reload"
 	"github.com/hashicorp/vault/helper/tlsutil"
 	"github.com/hashicorp/vault/logical"
@@ -39,9 +42,9 @@ import (
 )
 
 const (
-	// coreLockPath is the path used to acquire a coordinating lock
+	// CoreLockPath is the path used to acquire a coordinating lock
 	// for a highly-available deploy.
-	coreLockPath = "core/lock"
+	CoreLockPath = "core/lock"
 
 	// The poison pill is used as a check during certain scenarios to indicate
 	// to standby nodes that they should seal
@@ -97,6 +100,14 @@ var (
 	// in an HA setting
 	ErrHANotEnabled = errors.New("Vault is not configured for highly-available mode")
 
+	// ErrSealMigrationPending is returned if a regular unseal is attempted
+	// while a seal migration is pending
+	ErrSealMigrationPending = errors.New("seal migration is pending; unseal with the migrate flag to perform the migration")
+
+	// ErrNoSealMigration is returned if a migration unseal is attempted while
+	// no seal migration is pending
+	ErrNoSealMigration = errors.New("no seal migration is pending")
+
 	// manualStepDownSleepPeriod is how long to sleep after a user-initiated
 	// step down of the active node, to prevent instantly regrabbing the lock.
 	// It's var not const so that tests can manipulate it.
@@ -168,9 +179,22 @@ type Core struct {
 	// physical backend is the un-trusted backend with durable data
 	physical physical.Backend
 
-	// Our Seal, for seal configuration information
+	// underlyingPhysical is the physical backend as configured, without the
+	// cache and seal unwrapping layers
+	underlyingPhysical physical.Backend
+
+	// Our Seal, for seal configuration information. While a seal migration
+	// is pending this is the seal that currently protects the barrier.
 	seal Seal
 
+	// sealMigrationTarget is the seal being migrated to while a seal
+	// migration is pending, and nil otherwise
+	sealMigrationTarget Seal
+
+	// sealMigrationKey holds the key recovered from the key shares provided
+	// during a migration unseal until the migration has been performed
+	sealMigrationKey []byte
+
 	// barrier is the security barrier wrapping the physical backend
 	barrier SecurityBarrier
 
@@ -231,6 +255,16 @@ type Core struct {
 	// change underneath a calling function
 	authLock sync.RWMutex
 
+	// namespacesByPath and namespacesByID are loaded after unseal and hold
+	// the namespaces, which are used to partition mounts, policies and
+	// tokens
+	namespacesByPath *radix.Tree
+	namespacesByID   map[string]*namespace.Namespace
+
+	// namespaceLock is used to ensure that the namespaces do not change
+	// underneath a calling function
+	namespaceLock sync.RWMutex
+
 	// audit is loaded after unseal since it is a protected
 	// configuration
 	audit *MountTable
@@ -257,6 +291,10 @@ type Core struct {
 	// renewal, expiration and revocation
 	expiration *ExpirationManager
 
+	// leaseCountQuotas holds the lease count quota rules enforced by the
+	// expiration manager and the token store
+	leaseCountQuotas *leaseCountQuotaStore
+
 	// rollback manager is used to run rollbacks periodically
 	rollback *RollbackManager
 
@@ -297,6 +335,36 @@ type Core struct {
 	// wrapping information
 	wrappingJWTKey *ecdsa.PrivateKey
 
+	// controlGroupLock serializes updates to the authorizations of control
+	// group requests
+	controlGroupLock sync.Mutex
+
+	// reencryptStatus is the status of the last re-encryption of the barrier
+	// entries since unsealing, protected by reencryptLock
+	reencryptStatus *ReencryptStatus
+	reencryptLock   sync.Mutex
+
+	// storageRestored is set, under the state lock, when a snapshot restore
+	// replaced the storage. The keyring in memory is stale then, so it must
+	// not be persisted when sealing.
+	storageRestored bool
+
+	// perfStandbyEnabled indicates whether standbys serve read-only requests
+	// themselves instead of forwarding every request to the active node
+	perfStandbyEnabled bool
+
+	// perfStandby is set while this standby serves read-only requests,
+	// protected by the stateLock
+	perfStandby bool
+
+	// perfStandbyStorage makes the storage read-only on performance standbys
+	// and records the keys written on the active node
+	perfStandbyStorage *perfStandbyStorage
+
+	// perfStandbyPosition is the point of the invalidation log of the active
+	// node up to which this standby has invalidated its caches
+	perfStandbyPosition *perfStandbyPosition
+
 	//
 	// Cluster information
 	//
@@ -369,6 +437,10 @@ type Core struct {
 	// rawEnabled indicates whether the Raw endpoint is enabled
 	rawEnabled bool
 
+	// metricsHelper gives access to the in-memory metrics served by
+	// sys/metrics
+	metricsHelper *metricsutil.MetricsHelper
+
 	// pluginDirectory is the location vault will look for plugin binaries
 	pluginDirectory string
 
@@ -435,6 +507,14 @@ type CoreConfig struct {
 
 	PluginDirectory string `json:"plugin_directory" structs:"plugin_directory" mapstructure:"plugin_directory"`
 
+	// PerformanceStandby makes the standbys serve read-only requests
+	// themselves, forwarding the others to the active node
+	PerformanceStandby bool `json:"performance_standby" structs:"performance_standby" mapstructure:"performance_standby"`
+
+	// MetricsHelper gives access to the metrics retained in memory so that
+	// they can be served by sys/metrics
+	MetricsHelper *metricsutil.MetricsHelper
+
 	ReloadFuncs     *map[string][]reload.ReloadFunc
 	ReloadFuncsLock *sync.RWMutex
 }
@@ -478,6 +558,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	c := &Core{
 		devToken:                         conf.DevToken,
 		physical:                         conf.Physical,
+		underlyingPhysical:               conf.Physical,
 		redirectAddr:                     conf.RedirectAddr,
 		clusterAddr:                      conf.ClusterAddr,
 		seal:                             conf.Seal,
@@ -494,6 +575,9 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterPeerClusterAddrsCache:     cache.New(3*HeartbeatInterval, time.Second),
 		enableMlock:                      !conf.DisableMlock,
 		rawEnabled:                       conf.EnableRaw,
+		perfStandbyEnabled:               conf.PerformanceStandby,
+		perfStandbyPosition:              new(perfStandbyPosition),
+		metricsHelper:                    conf.MetricsHelper,
 		replicationState:                 new(uint32),
 		rpcServerActive:                  new(uint32),
 		atomicPrimaryClusterAddrs:        new(atomic.Value),
@@ -529,13 +613,18 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 
 	c.sealUnwrapper = NewSealUnwrapper(phys, conf.Logger.Named("sealunwrapper"))
 
+	// Below the cache, so that writes refused on performance standbys are
+	// never cached
+	c.perfStandbyStorage = newPerfStandbyStorage(c.sealUnwrapper)
+	storage := c.perfStandbyStorage.backend()
+
 	var ok bool
 
 	// Wrap the physical backend in a cache layer if enabled
 	if txnOK {
-		c.physical = physical.NewTransactionalCache(c.sealUnwrapper, conf.CacheSize, conf.Logger.ResetNamed("storage.cache"))
+		c.physical = physical.NewTransactionalCache(storage, conf.CacheSize, conf.Logger.ResetNamed("storage.cache"))
 	} else {
-		c.physical = physical.NewCache(c.sealUnwrapper, conf.CacheSize, conf.Logger.Named("storage.cache"))
+		c.physical = physical.NewCache(storage, conf.CacheSize, conf.Logger.Named("storage.cache"))
 	}
 	c.physicalCache = c.physical.(physical.ToggleablePurgemonster)
 
@@ -762,6 +851,12 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 		return nil, nil, nil, logical.ErrPermissionDenied
 	}
 
+	// Tokens whose namespace has been deleted are no longer usable
+	tokenNS := c.namespaceByID(te.namespaceID())
+	if tokenNS == nil {
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+
 	tokenPolicies := te.Policies
 
 	entity, derivedPolicies, err := c.fetchEntityAndDerivedPolicies(te.EntityID)
@@ -771,8 +866,9 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 
 	tokenPolicies = append(tokenPolicies, derivedPolicies...)
 
-	// Construct the corresponding ACL object
-	acl, err := c.policyStore.ACL(c.activeContext, tokenPolicies...)
+	// Construct the corresponding ACL object. The policies are resolved
+	// within the namespace of the token.
+	acl, err := c.policyStore.ACL(namespace.ContextWithNamespace(c.activeContext, tokenNS), tokenPolicies...)
 	if err != nil {
 		c.logger.Error("failed to construct ACL", "error", err)
 		return nil, nil, nil, ErrInternalError
@@ -781,7 +877,10 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 	return acl, te, entity, nil
 }
 
-func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool) (*logical.Auth, *TokenEntry, error) {
+// checkToken validates the token of the request and checks it against the
+// ACLs. If the request is allowed but requires the authorizations of a
+// control group, the control group is returned.
+func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool) (*logical.Auth, *TokenEntry, *ControlGroup, error) {
 	defer metrics.MeasureSince([]string{"core", "check_token"}, time.Now())
 
 	var acl *ACL
@@ -798,7 +897,7 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		// unauth, we just have no information to attach to the request, so
 		// ignore errors...this was best-effort anyways
 		if err != nil && !unauth {
-			return nil, te, err
+			return nil, te, nil, err
 		}
 	}
 
@@ -806,7 +905,7 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 	rootPath := c.router.RootPath(req.Path)
 
 	if rootPath && unauth {
-		return nil, nil, errors.New("cannot access root path in unauthenticated request")
+		return nil, nil, nil, errors.New("cannot access root path in unauthenticated request")
 	}
 
 	// When we receive a write of either type, rather than require clients to
@@ -825,9 +924,9 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		default:
 			c.logger.Error("failed to run existence check", "error", err)
 			if _, ok := err.(errutil.UserError); ok {
-				return nil, nil, err
+				return nil, nil, nil, err
 			} else {
-				return nil, nil, ErrInternalError
+				return nil, nil, nil, ErrInternalError
 			}
 		}
 
@@ -867,14 +966,19 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		RootPrivsRequired: rootPath,
 	})
 	if authResults.Error.ErrorOrNil() != nil {
-		return auth, te, authResults.Error
+		return auth, te, nil, authResults.Error
 	}
 	if !authResults.Allowed {
 		// Return auth for audit logging even if not allowed
-		return auth, te, logical.ErrPermissionDenied
+		return auth, te, nil, logical.ErrPermissionDenied
+	}
+
+	var controlGroup *ControlGroup
+	if authResults.ACLResults != nil {
+		controlGroup = authResults.ACLResults.ControlGroup
 	}
 
-	return auth, te, nil
+	return auth, te, controlGroup, nil
 }
 
 // Sealed checks if the Vault is current sealed
@@ -912,7 +1016,7 @@ func (c *Core) Leader() (isLeader bool, leaderAddr, clusterAddr string, err erro
 	}
 
 	// Initialize a lock
-	lock, err := c.ha.LockWith(coreLockPath, "read")
+	lock, err := c.ha.LockWith(CoreLockPath, "read")
 	if err != nil {
 		return false, "", "", err
 	}
@@ -1064,6 +1168,10 @@ func (c *Core) Unseal(key []byte) (bool, error) {
 		return true, nil
 	}
 
+	if c.sealMigrationTarget != nil {
+		return false, ErrSealMigrationPending
+	}
+
 	masterKey, err := c.unsealPart(ctx, config, key, false)
 	if err != nil {
 		return false, err
@@ -1106,6 +1214,10 @@ func (c *Core) UnsealWithRecoveryKeys(ctx context.Context, key []byte) (bool, er
 		return true, nil
 	}
 
+	if c.sealMigrationTarget != nil {
+		return false, ErrSealMigrationPending
+	}
+
 	masterKey, err := c.unsealPart(ctx, config, key, true)
 	if err != nil {
 		return false, err
@@ -1120,6 +1232,63 @@ func (c *Core) UnsealWithRecoveryKeys(ctx context.Context, key []byte) (bool, er
 // unsealPart takes in a key share, and returns the master key if the threshold
 // is met. If recovery keys are supported, recovery key shares may be provided.
 func (c *Core) unsealPart(ctx context.Context, config *SealConfig, key []byte, useRecoveryKeys bool) ([]byte, error) {
+	recoveredKey, err := c.recoverKeyPart(config, key)
+	if err != nil {
+		return nil, err
+	}
+	if recoveredKey == nil {
+		return nil, nil
+	}
+
+	if c.seal.RecoveryKeySupported() && useRecoveryKeys {
+		// Verify recovery key
+		if err := c.seal.VerifyRecoveryKey(ctx, recoveredKey); err != nil {
+			return nil, err
+		}
+
+		// Get stored keys and shamir combine into single master key. Unsealing with
+		// recovery keys currently does not support: 1) mixed stored and non-stored
+		// keys setup, nor 2) seals that support recovery keys but not stored keys.
+		// If insufficient shares are provided, shamir.Combine will error, and if
+		// no stored keys are found it will return masterKey as nil.
+		return c.storedMasterKey(ctx, c.seal)
+	}
+
+	// If this is not a recovery key-supported seal, then the recovered key is
+	// the master key to be returned.
+	return recoveredKey, nil
+}
+
+// storedMasterKey fetches the stored keys from the given seal and combines
+// them into the master key.
+func (c *Core) storedMasterKey(ctx context.Context, seal Seal) ([]byte, error) {
+	if !seal.StoredKeysSupported() {
+		return nil, nil
+	}
+
+	masterKeyShares, err := seal.GetStoredKeys(ctx)
+	if err != nil {
+		return nil, errwrap.Wrapf("unable to retrieve stored keys: {{err}}", err)
+	}
+
+	switch len(masterKeyShares) {
+	case 0:
+		return nil, nil
+	case 1:
+		return masterKeyShares[0], nil
+	}
+
+	masterKey, err := shamir.Combine(masterKeyShares)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to compute master key: {{err}}", err)
+	}
+	return masterKey, nil
+}
+
+// recoverKeyPart takes in a key share and stores it. Once the threshold of the
+// given configuration is met, the combined key is returned; until then a nil
+// key is returned.
+func (c *Core) recoverKeyPart(config *SealConfig, key []byte) ([]byte, error) {
 	// Check if we already have this piece
 	if c.unlockInfo != nil {
 		for _, existing := range c.unlockInfo.Parts {
@@ -1171,38 +1340,6 @@ func (c *Core) unsealPart(ctx context.Context, config *SealConfig, key []byte, u
 		}
 	}
 
-	if c.seal.RecoveryKeySupported() && useRecoveryKeys {
-		// Verify recovery key
-		if err := c.seal.VerifyRecoveryKey(ctx, recoveredKey); err != nil {
-			return nil, err
-		}
-
-		// Get stored keys and shamir combine into single master key. Unsealing with
-		// recovery keys currently does not support: 1) mixed stored and non-stored
-		// keys setup, nor 2) seals that support recovery keys but not stored keys.
-		// If insufficient shares are provided, shamir.Combine will error, and if
-		// no stored keys are found it will return masterKey as nil.
-		var masterKey []byte
-		if c.seal.StoredKeysSupported() {
-			masterKeyShares, err := c.seal.GetStoredKeys(ctx)
-			if err != nil {
-				return nil, errwrap.Wrapf("unable to retrieve stored keys: {{err}}", err)
-			}
-
-			if len(masterKeyShares) == 1 {
-				return masterKeyShares[0], nil
-			}
-
-			masterKey, err = shamir.Combine(masterKeyShares)
-			if err != nil {
-				return nil, errwrap.Wrapf("failed to compute master key: {{err}}", err)
-			}
-		}
-		return masterKey, nil
-	}
-
-	// If this is not a recovery key-supported seal, then the recovered key is
-	// the master key to be returned.
 	return recoveredKey, nil
 }
 
@@ -1211,6 +1348,15 @@ func (c *Core) unsealPart(ctx context.Context, config *SealConfig, key []byte, u
 func (c *Core) unsealInternal(ctx context.Context, masterKey []byte) (bool, error) {
 	defer memzero(masterKey)
 
+	// Raft storage only runs while unsealed; it must be up before anything
+	// is written
+	if raftBackend := c.raftBackend(); raftBackend != nil {
+		if err := raftBackend.SetupCluster(ctx); err != nil {
+			c.logger.Error("failed to start raft storage", "error", err)
+			return false, err
+		}
+	}
+
 	// Attempt to unlock
 	if err := c.barrier.Unseal(ctx, masterKey); err != nil {
 		return false, err
@@ -1221,6 +1367,15 @@ func (c *Core) unsealInternal(ctx context.Context, masterKey []byte) (bool, erro
 
 	// Do post-unseal setup if HA is not enabled
 	if c.ha == nil {
+		// Finish any pending seal migration before anything else touches
+		// storage
+		if err := c.migrateSeal(ctx); err != nil {
+			c.logger.Error("seal migration failed", "error", err)
+			c.barrier.Seal()
+			c.logger.Warn("vault is sealed")
+			return false, err
+		}
+
 		// We still need to set up cluster info even if it's not part of a
 		// cluster right now. This also populates the cached cluster object.
 		if err := c.setupCluster(ctx); err != nil {
@@ -1564,6 +1719,12 @@ func (c *Core) sealInternal(keepLock bool) error {
 		<-c.standbyDoneCh
 		atomic.StoreUint32(&c.keepHALockOnStepDown, 0)
 		c.logger.Debug("runStandby done")
+
+		if c.perfStandby {
+			if err := c.teardownPerfStandby(); err != nil {
+				c.logger.Error("performance standby teardown failed", "error", err)
+			}
+		}
 	}
 
 	c.logger.Debug("sealing barrier")
@@ -1572,6 +1733,13 @@ func (c *Core) sealInternal(keepLock bool) error {
 		return err
 	}
 
+	if raftBackend := c.raftBackend(); raftBackend != nil {
+		if err := raftBackend.TeardownCluster(); err != nil {
+			c.logger.Error("error stopping raft storage", "error", err)
+			return err
+		}
+	}
+
 	if c.ha != nil {
 		sd, ok := c.ha.(physical.ServiceDiscovery)
 		if ok {
@@ -1638,6 +1806,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupPluginCatalog(); err != nil {
 		return err
 	}
+	if err := c.loadNamespaces(c.activeContext); err != nil {
+		return err
+	}
 	if err := c.loadMounts(c.activeContext); err != nil {
 		return err
 	}
@@ -1656,9 +1827,15 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupCredentials(c.activeContext); err != nil {
 		return err
 	}
+	if err := c.setupNamespaces(c.activeContext); err != nil {
+		return err
+	}
 	if err := c.startRollback(); err != nil {
 		return err
 	}
+	if err := c.setupLeaseCountQuotas(c.activeContext); err != nil {
+		return err
+	}
 	if err := c.setupExpiration(); err != nil {
 		return err
 	}
@@ -1680,8 +1857,15 @@ func (c *Core) postUnseal() (retErr error) {
 			return err
 		}
 	}
+	// Start a new invalidation log, so that the performance standbys
+	// invalidate everything they read while another node was active
+	if err := c.perfStandbyStorage.log.reset(c.ha != nil && c.perfStandbyEnabled); err != nil {
+		return err
+	}
+
 	c.metricsCh = make(chan struct{})
 	go c.emitMetrics(c.metricsCh)
+	go c.runKeyRotationCheck(c.activeContext)
 	c.logger.Info("post-unseal setup complete")
 	return nil
 }
@@ -1698,6 +1882,12 @@ func (c *Core) preSeal() error {
 	c.recoveryRekeyConfig = nil
 	c.recoveryRekeyProgress = nil
 
+	// Forget the re-encryption status; a running job is stopped along with
+	// the active context
+	c.reencryptLock.Lock()
+	c.reencryptStatus = nil
+	c.reencryptLock.Unlock()
+
 	if c.metricsCh != nil {
 		close(c.metricsCh)
 		c.metricsCh = nil
@@ -1712,6 +1902,9 @@ func (c *Core) preSeal() error {
 	if err := c.stopExpiration(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
 	}
+	if err := c.teardownLeaseCountQuotas(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down lease count quotas: {{err}}", err))
+	}
 	if err := c.teardownCredentials(c.activeContext); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
 	}
@@ -1724,6 +1917,9 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(c.activeContext); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
 	}
+	if err := c.teardownNamespaces(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down namespaces: {{err}}", err))
+	}
 	if err := enterprisePreSeal(c); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -1735,6 +1931,19 @@ func (c *Core) preSeal() error {
 		c.sealUnwrapper.(*transactionalSealUnwrapper).stopUnwraps()
 	}
 
+	if err := c.perfStandbyStorage.log.reset(false); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	// Save the encryptions performed since the last rotation check, so that
+	// they still count towards rotating the key after an unseal. The active
+	// context may already be canceled.
+	if c.storageRestored {
+		c.storageRestored = false
+	} else if err := c.barrier.PersistEncryptions(context.Background()); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error persisting encryption count: {{err}}", err))
+	}
+
 	// Purge the cache
 	c.physicalCache.SetEnabled(false)
 	c.physicalCache.Purge(c.activeContext)
@@ -1801,13 +2010,18 @@ func (c *Core) runStandby(doneCh, manualStepDownCh, stopCh chan struct{}) {
 			}
 		}
 
+		// Serve read-only requests while waiting for the lock
+		if c.perfStandbyEnabled && !c.setupPerfStandbyOrStop(stopCh) {
+			return
+		}
+
 		// Create a lock
 		uuid, err := uuid.GenerateUUID()
 		if err != nil {
 			c.logger.Error("failed to generate uuid", "error", err)
 			return
 		}
-		lock, err := c.ha.LockWith(coreLockPath, uuid)
+		lock, err := c.ha.LockWith(CoreLockPath, uuid)
 		if err != nil {
 			c.logger.Error("failed to create lock", "error", err)
 			return
@@ -1862,6 +2076,13 @@ func (c *Core) runStandby(doneCh, manualStepDownCh, stopCh chan struct{}) {
 			return
 		}
 
+		// Stop serving read-only requests before becoming active
+		if c.perfStandby {
+			if err := c.teardownPerfStandby(); err != nil {
+				c.logger.Error("performance standby teardown failed", "error", err)
+			}
+		}
+
 		// Store the lock so that we can manually clear it later if needed
 		c.heldHALock = lock
 
@@ -1877,6 +2098,17 @@ func (c *Core) runStandby(doneCh, manualStepDownCh, stopCh chan struct{}) {
 				c.seal.SetRecoveryConfig(ctx, nil)
 			}
 
+			// Now that we hold the lock, finish any pending seal migration
+			if err := c.migrateSeal(ctx); err != nil {
+				c.logger.Error("seal migration failed", "error", err)
+				go c.Shutdown()
+				c.heldHALock = nil
+				lock.Unlock()
+				c.stateLock.Unlock()
+				metrics.MeasureSince([]string{"core", "leadership_setup_failed"}, activeTime)
+				return
+			}
+
 			if err := c.performKeyUpgrades(ctx); err != nil {
 				// We call this in a goroutine so that we can give up the
 				// statelock and have this shut us down; sealInternal has a
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -251,6 +252,37 @@ func TestCore_SealUnseal(t *testing.T) {
 	}
 }
 
+// The encryptions performed under the active key are kept across a seal
+func TestCore_SealUnseal_Encryptions(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	for i := 0; i < 10; i++ {
+		if err := c.barrier.Put(context.Background(), &Entry{Key: fmt.Sprintf("foo%d", i), Value: []byte("bar")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, key); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	after, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Term != before.Term || after.Encryptions < before.Encryptions {
+		t.Fatalf("expected at least %d encryptions under term %d, got %d under term %d", before.Encryptions, before.Term, after.Encryptions, after.Term)
+	}
+}
+
 // Attempt to shutdown after unseal
 func TestCore_Shutdown(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
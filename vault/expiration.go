@@ -80,6 +80,16 @@ type ExpirationManager struct {
 	leaseCheckCounter uint32
 
 	logLeaseExpirations bool
+
+	// leaseCounts holds the number of leases of every mount, keyed by the
+	// path of the mount, for enforcing the lease count quotas. The leases
+	// that exist when Vault is unsealed are counted as they are restored.
+	// countedLeases holds the IDs of the counted leases, so that a lease is
+	// counted at most once.
+	leaseCounts     map[string]int64
+	countedLeases   map[string]struct{}
+	leaseCountsLock sync.RWMutex
+	quotas          *leaseCountQuotaStore
 }
 
 // NewExpirationManager creates a new ExpirationManager that is backed
@@ -104,6 +114,10 @@ func NewExpirationManager(c *Core, view *BarrierView, logger log.Logger) *Expira
 		leaseCheckCounter: 0,
 
 		logLeaseExpirations: os.Getenv("VAULT_SKIP_LOGGING_LEASE_EXPIRATIONS") == "",
+
+		leaseCounts:   make(map[string]int64),
+		countedLeases: make(map[string]struct{}),
+		quotas:        c.leaseCountQuotas,
 	}
 
 	if exp.logger == nil {
@@ -513,6 +527,7 @@ func (m *ExpirationManager) revokeCommon(leaseID string, force, skipToken bool)
 	if err := m.deleteEntry(leaseID); err != nil {
 		return err
 	}
+	m.releaseLeaseCount(leaseID)
 
 	// Delete the secondary index, but only if it's a leased secret (not auth)
 	if le.Secret != nil {
@@ -587,7 +602,13 @@ func (m *ExpirationManager) RevokeByToken(te *TokenEntry) error {
 		// we're already revoking the token, so we just want to clean up the lease.
 		// This avoids spurious revocations later in the log when the timer runs
 		// out, and eases up resource usage.
-		return m.revokeCommon(tokenLeaseID, false, true)
+		if err := m.revokeCommon(tokenLeaseID, false, true); err != nil {
+			return err
+		}
+
+		// A token revoked before its lease was registered still holds the
+		// lease count reserved when it was created
+		m.releaseLeaseCount(tokenLeaseID)
 	}
 
 	return nil
@@ -836,6 +857,7 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 
 	leaseID := path.Join(req.Path, leaseUUID)
 
+	var counted bool
 	defer func() {
 		// If there is an error we want to rollback as much as possible (note
 		// that errors here are ignored to do as much cleanup as we can). We
@@ -856,9 +878,20 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 			if err := m.removeIndexByToken(req.ClientToken, leaseID); err != nil {
 				retErr = multierror.Append(retErr, errwrap.Wrapf("an additional error was encountered removing lease indexes associated with the newly-generated secret: {{err}}", err))
 			}
+
+			if counted {
+				m.releaseLeaseCount(leaseID)
+			}
 		}
 	}()
 
+	// Count the lease, unless that would exceed a lease count quota
+	if err := m.acquireLeaseCount(leaseID, true); err != nil {
+		return "", err
+	}
+	counted = true
+	m.markRestored(leaseID)
+
 	le := leaseEntry{
 		LeaseID:     leaseID,
 		ClientToken: req.ClientToken,
@@ -915,11 +948,16 @@ func (m *ExpirationManager) RegisterAuth(source string, auth *logical.Auth) erro
 		ExpireTime:  auth.ExpirationTime(),
 	}
 
+	m.markRestored(le.LeaseID)
+
 	// Encode the entry
 	if err := m.persistEntry(&le); err != nil {
 		return err
 	}
 
+	// The lease was counted, and the quotas enforced, when the token was
+	// created
+
 	// Setup revocation timer
 	m.updatePending(&le, auth.LeaseTotal())
 	return nil
@@ -1080,7 +1118,14 @@ func (m *ExpirationManager) renewAuthEntry(req *logical.Request, le *leaseEntry,
 	auth := *le.Auth
 	auth.IssueTime = le.IssueTime
 	auth.Increment = increment
-	if strings.HasPrefix(le.Path, "auth/token/") {
+
+	// Tokens issued within a namespace are routed to the token store beneath
+	// the namespace path, so the mount is checked rather than the path alone
+	tokenStoreLease := strings.HasPrefix(le.Path, "auth/token/")
+	if entry := m.router.MatchingMountEntry(le.Path); entry != nil && entry.Type == "token" {
+		tokenStoreLease = true
+	}
+	if tokenStoreLease {
 		auth.ClientToken = le.ClientToken
 	} else {
 		auth.ClientToken = ""
@@ -1138,8 +1183,10 @@ func (m *ExpirationManager) loadEntryInternal(leaseID string, restoreMode bool,
 		}
 
 		// Update the cache of restored leases, either synchronously or through
-		// the lazy loaded restore process
-		m.restoreLoaded.Store(le.LeaseID, struct{}{})
+		// the lazy loaded restore process, counting the lease the first time
+		if _, loaded := m.restoreLoaded.LoadOrStore(le.LeaseID, struct{}{}); !loaded {
+			m.acquireLeaseCount(le.LeaseID, false)
+		}
 
 		// Setup revocation timer
 		m.updatePending(le, le.ExpireTime.Sub(time.Now()))
@@ -1267,6 +1314,96 @@ func (m *ExpirationManager) lookupByToken(token string) ([]string, error) {
 	return leaseIDs, nil
 }
 
+// markRestored records a lease created while the leases are being restored,
+// so that the restore does not load and count it a second time. It must be
+// called before the lease is persisted.
+func (m *ExpirationManager) markRestored(leaseID string) {
+	if !m.inRestoreMode() {
+		return
+	}
+
+	m.restoreModeLock.RLock()
+	defer m.restoreModeLock.RUnlock()
+	if m.inRestoreMode() {
+		m.restoreLoaded.Store(leaseID, struct{}{})
+	}
+}
+
+// leaseCountKey returns the key a lease is counted under, which is the path
+// of its mount
+func (m *ExpirationManager) leaseCountKey(leaseID string) string {
+	return m.router.MatchingMount(leaseID)
+}
+
+// acquireLeaseCount counts a new lease. If enforce is set, it fails instead
+// if the lease would exceed one of the lease count quotas that apply to it.
+// Leases that are already counted are left as they are.
+func (m *ExpirationManager) acquireLeaseCount(leaseID string, enforce bool) error {
+	key := m.leaseCountKey(leaseID)
+
+	m.leaseCountsLock.Lock()
+	defer m.leaseCountsLock.Unlock()
+
+	if _, ok := m.countedLeases[leaseID]; ok {
+		return nil
+	}
+	if enforce {
+		if err := m.checkLeaseCountQuotaLocked(leaseID); err != nil {
+			return err
+		}
+	}
+	m.countedLeases[leaseID] = struct{}{}
+	m.leaseCounts[key]++
+	return nil
+}
+
+// releaseLeaseCount stops counting a deleted lease, if it is counted
+func (m *ExpirationManager) releaseLeaseCount(leaseID string) {
+	key := m.leaseCountKey(leaseID)
+
+	m.leaseCountsLock.Lock()
+	defer m.leaseCountsLock.Unlock()
+
+	if _, ok := m.countedLeases[leaseID]; !ok {
+		return
+	}
+	delete(m.countedLeases, leaseID)
+
+	if m.leaseCounts[key] <= 1 {
+		delete(m.leaseCounts, key)
+		return
+	}
+	m.leaseCounts[key]--
+}
+
+func (m *ExpirationManager) checkLeaseCountQuotaLocked(leaseID string) error {
+	for _, rule := range m.quotas.matching(leaseID) {
+		if m.leaseCountLocked(rule.Path) >= rule.MaxLeases {
+			return errwrap.Wrapf(fmt.Sprintf("quota %q allows at most %d leases under %q: {{err}}", rule.Name, rule.MaxLeases, rule.Path), logical.ErrLeaseCountQuotaExceeded)
+		}
+	}
+	return nil
+}
+
+// leaseCount returns the number of leases of the mounts under the given
+// path
+func (m *ExpirationManager) leaseCount(prefix string) int64 {
+	m.leaseCountsLock.RLock()
+	defer m.leaseCountsLock.RUnlock()
+
+	return m.leaseCountLocked(prefix)
+}
+
+func (m *ExpirationManager) leaseCountLocked(prefix string) int64 {
+	var count int64
+	for key, n := range m.leaseCounts {
+		if strings.HasPrefix(key, prefix) {
+			count += n
+		}
+	}
+	return count
+}
+
 // emitMetrics is invoked periodically to emit statistics
 func (m *ExpirationManager) emitMetrics() {
 	m.pendingLock.RLock()
@@ -145,7 +145,7 @@ func (i *IdentityStore) pathLookupEntityUpdate() framework.OperationFunc {
 			}
 
 		case name != "":
-			entity, err = i.MemDBEntityByName(name, false)
+			entity, err = i.MemDBEntityByName(ctx, name, false)
 			if err != nil {
 				return nil, err
 			}
@@ -181,7 +181,7 @@ func (i *IdentityStore) pathLookupEntityUpdate() framework.OperationFunc {
 			}
 		}
 
-		if entity == nil {
+		if entity == nil || entity.NamespaceID != namespaceFromContext(ctx).ID {
 			return nil, nil
 		}
 
@@ -256,7 +256,7 @@ func (i *IdentityStore) pathLookupGroupUpdate() framework.OperationFunc {
 				return nil, err
 			}
 		case name != "":
-			group, err = i.MemDBGroupByName(name, false)
+			group, err = i.MemDBGroupByName(ctx, name, false)
 			if err != nil {
 				return nil, err
 			}
@@ -291,7 +291,7 @@ func (i *IdentityStore) pathLookupGroupUpdate() framework.OperationFunc {
 			}
 		}
 
-		if group == nil {
+		if group == nil || group.NamespaceID != namespaceFromContext(ctx).ID {
 			return nil, nil
 		}
 
@@ -61,8 +61,15 @@ func NewIdentityStore(ctx context.Context, core *Core, config *logical.BackendCo
 			groupPaths(iStore),
 			lookupPaths(iStore),
 			upgradePaths(iStore),
+			oidcPaths(iStore),
 		),
-		Invalidate: iStore.Invalidate,
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				oidcWellKnownPrefix + "*",
+			},
+		},
+		Invalidate:   iStore.Invalidate,
+		PeriodicFunc: iStore.oidcPeriodicFunc,
 	}
 
 	err = iStore.Setup(ctx, config)
@@ -312,7 +319,7 @@ func (i *IdentityStore) entityByAliasFactorsInTxn(txn *memdb.Txn, mountAccessor,
 
 // CreateOrFetchEntity creates a new entity. This is used by core to
 // associate each login attempt by an alias to a unified entity in Vault.
-func (i *IdentityStore) CreateOrFetchEntity(alias *logical.Alias) (*identity.Entity, error) {
+func (i *IdentityStore) CreateOrFetchEntity(ctx context.Context, alias *logical.Alias) (*identity.Entity, error) {
 	var entity *identity.Entity
 	var err error
 
@@ -359,7 +366,7 @@ func (i *IdentityStore) CreateOrFetchEntity(alias *logical.Alias) (*identity.Ent
 
 	entity = &identity.Entity{}
 
-	err = i.sanitizeEntity(entity)
+	err = i.sanitizeEntity(ctx, entity)
 	if err != nil {
 		return nil, err
 	}
@@ -162,7 +162,7 @@ func (i *IdentityStore) pathAliasRegister() framework.OperationFunc {
 			return i.pathAliasIDUpdate()(ctx, req, d)
 		}
 
-		return i.handleAliasUpdateCommon(req, d, nil)
+		return i.handleAliasUpdateCommon(ctx, req, d, nil)
 	}
 }
 
@@ -185,12 +185,12 @@ func (i *IdentityStore) pathAliasIDUpdate() framework.OperationFunc {
 			return logical.ErrorResponse("invalid alias id"), nil
 		}
 
-		return i.handleAliasUpdateCommon(req, d, alias)
+		return i.handleAliasUpdateCommon(ctx, req, d, alias)
 	}
 }
 
 // handleAliasUpdateCommon is used to update an alias
-func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framework.FieldData, alias *identity.Alias) (*logical.Response, error) {
+func (i *IdentityStore) handleAliasUpdateCommon(ctx context.Context, req *logical.Request, d *framework.FieldData, alias *identity.Alias) (*logical.Response, error) {
 	var err error
 	var newAlias bool
 	var entity *identity.Entity
@@ -203,6 +203,8 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 		newAlias = true
 	}
 
+	ns := namespaceFromContext(ctx)
+
 	// Get entity id
 	canonicalID := d.Get("entity_id").(string)
 	if canonicalID == "" {
@@ -214,7 +216,7 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 		if err != nil {
 			return nil, err
 		}
-		if entity == nil {
+		if entity == nil || entity.NamespaceID != ns.ID {
 			return logical.ErrorResponse("invalid entity ID"), nil
 		}
 	}
@@ -231,7 +233,7 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 	}
 
 	mountValidationResp := i.core.router.validateMountByAccessor(mountAccessor)
-	if mountValidationResp == nil {
+	if mountValidationResp == nil || mountValidationResp.NamespaceID != ns.ID {
 		return logical.ErrorResponse(fmt.Sprintf("invalid mount accessor %q", mountAccessor)), nil
 	}
 
@@ -307,7 +309,7 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 	// ID creation and other validations; This is more useful for new entities
 	// and may not perform anything for the existing entities. Placing the
 	// check here to make the flow common for both new and existing entities.
-	err = i.sanitizeEntity(entity)
+	err = i.sanitizeEntity(ctx, entity)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +141,9 @@ func (i *IdentityStore) pathEntityMergeID() framework.OperationFunc {
 			return nil, err
 		}
 
-		if toEntityForLocking == nil {
+		ns := namespaceFromContext(ctx)
+
+		if toEntityForLocking == nil || toEntityForLocking.NamespaceID != ns.ID {
 			return logical.ErrorResponse("entity id to merge to is invalid"), nil
 		}
 
@@ -179,7 +181,7 @@ func (i *IdentityStore) pathEntityMergeID() framework.OperationFunc {
 				return nil, err
 			}
 
-			if lockFromEntity == nil {
+			if lockFromEntity == nil || lockFromEntity.NamespaceID != ns.ID {
 				return logical.ErrorResponse("entity id to merge from is invalid"), nil
 			}
 
@@ -310,7 +312,7 @@ func (i *IdentityStore) pathEntityRegister() framework.OperationFunc {
 			return i.pathEntityIDUpdate()(ctx, req, d)
 		}
 
-		return i.handleEntityUpdateCommon(req, d, nil)
+		return i.handleEntityUpdateCommon(ctx, req, d, nil)
 	}
 }
 
@@ -328,16 +330,16 @@ func (i *IdentityStore) pathEntityIDUpdate() framework.OperationFunc {
 		if err != nil {
 			return nil, err
 		}
-		if entity == nil {
+		if entity == nil || entity.NamespaceID != namespaceFromContext(ctx).ID {
 			return nil, fmt.Errorf("invalid entity id")
 		}
 
-		return i.handleEntityUpdateCommon(req, d, entity)
+		return i.handleEntityUpdateCommon(ctx, req, d, entity)
 	}
 }
 
 // handleEntityUpdateCommon is used to update an entity
-func (i *IdentityStore) handleEntityUpdateCommon(req *logical.Request, d *framework.FieldData, entity *identity.Entity) (*logical.Response, error) {
+func (i *IdentityStore) handleEntityUpdateCommon(ctx context.Context, req *logical.Request, d *framework.FieldData, entity *identity.Entity) (*logical.Response, error) {
 	var err error
 	var newEntity bool
 
@@ -357,7 +359,7 @@ func (i *IdentityStore) handleEntityUpdateCommon(req *logical.Request, d *framew
 	// Get the name
 	entityName := d.Get("name").(string)
 	if entityName != "" {
-		entityByName, err := i.MemDBEntityByName(entityName, false)
+		entityByName, err := i.MemDBEntityByName(ctx, entityName, false)
 		if err != nil {
 			return nil, err
 		}
@@ -377,7 +379,7 @@ func (i *IdentityStore) handleEntityUpdateCommon(req *logical.Request, d *framew
 		entity.Metadata = metadata.(map[string]string)
 	}
 	// ID creation and some validations
-	err = i.sanitizeEntity(entity)
+	err = i.sanitizeEntity(ctx, entity)
 	if err != nil {
 		return nil, err
 	}
@@ -419,7 +421,7 @@ func (i *IdentityStore) pathEntityIDRead() framework.OperationFunc {
 		if err != nil {
 			return nil, err
 		}
-		if entity == nil {
+		if entity == nil || entity.NamespaceID != namespaceFromContext(ctx).ID {
 			return nil, nil
 		}
 
@@ -434,6 +436,7 @@ func (i *IdentityStore) handleEntityReadCommon(entity *identity.Entity) (*logica
 	respData["metadata"] = entity.Metadata
 	respData["merged_entity_ids"] = entity.MergedEntityIDs
 	respData["policies"] = entity.Policies
+	respData["namespace_id"] = entity.NamespaceID
 
 	// Convert protobuf timestamp into RFC3339 format
 	respData["creation_time"] = ptypes.TimestampString(entity.CreationTime)
@@ -493,6 +496,14 @@ func (i *IdentityStore) pathEntityIDDelete() framework.OperationFunc {
 			return logical.ErrorResponse("missing entity id"), nil
 		}
 
+		entity, err := i.MemDBEntityByID(entityID, false)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil || entity.NamespaceID != namespaceFromContext(ctx).ID {
+			return nil, nil
+		}
+
 		return nil, i.deleteEntity(entityID)
 	}
 }
@@ -507,13 +518,19 @@ func (i *IdentityStore) pathEntityIDList() framework.OperationFunc {
 			return nil, errwrap.Wrapf("failed to fetch iterator for entities in memdb: {{err}}", err)
 		}
 
+		ns := namespaceFromContext(ctx)
+
 		var entityIDs []string
 		for {
 			raw := iter.Next()
 			if raw == nil {
 				break
 			}
-			entityIDs = append(entityIDs, raw.(*identity.Entity).ID)
+			entity := raw.(*identity.Entity)
+			if entity.NamespaceID != ns.ID {
+				continue
+			}
+			entityIDs = append(entityIDs, entity.ID)
 		}
 
 		return logical.ListResponse(entityIDs), nil
@@ -92,7 +92,7 @@ func (i *IdentityStore) pathGroupAliasRegister() framework.OperationFunc {
 		i.groupLock.Lock()
 		defer i.groupLock.Unlock()
 
-		return i.handleGroupAliasUpdateCommon(req, d, nil)
+		return i.handleGroupAliasUpdateCommon(ctx, req, d, nil)
 	}
 }
 
@@ -114,11 +114,11 @@ func (i *IdentityStore) pathGroupAliasIDUpdate() framework.OperationFunc {
 			return logical.ErrorResponse("invalid group alias ID"), nil
 		}
 
-		return i.handleGroupAliasUpdateCommon(req, d, groupAlias)
+		return i.handleGroupAliasUpdateCommon(ctx, req, d, groupAlias)
 	}
 }
 
-func (i *IdentityStore) handleGroupAliasUpdateCommon(req *logical.Request, d *framework.FieldData, groupAlias *identity.Alias) (*logical.Response, error) {
+func (i *IdentityStore) handleGroupAliasUpdateCommon(ctx context.Context, req *logical.Request, d *framework.FieldData, groupAlias *identity.Alias) (*logical.Response, error) {
 	var err error
 	var newGroupAlias bool
 	var group *identity.Group
@@ -128,13 +128,15 @@ func (i *IdentityStore) handleGroupAliasUpdateCommon(req *logical.Request, d *fr
 		newGroupAlias = true
 	}
 
+	ns := namespaceFromContext(ctx)
+
 	groupID := d.Get("canonical_id").(string)
 	if groupID != "" {
 		group, err = i.MemDBGroupByID(groupID, true)
 		if err != nil {
 			return nil, err
 		}
-		if group == nil {
+		if group == nil || group.NamespaceID != ns.ID {
 			return logical.ErrorResponse("invalid group ID"), nil
 		}
 		if group.Type != groupTypeExternal {
@@ -154,7 +156,7 @@ func (i *IdentityStore) handleGroupAliasUpdateCommon(req *logical.Request, d *fr
 	}
 
 	mountValidationResp := i.core.router.validateMountByAccessor(mountAccessor)
-	if mountValidationResp == nil {
+	if mountValidationResp == nil || mountValidationResp.NamespaceID != ns.ID {
 		return logical.ErrorResponse(fmt.Sprintf("invalid mount accessor %q", mountAccessor)), nil
 	}
 
@@ -209,7 +211,7 @@ func (i *IdentityStore) handleGroupAliasUpdateCommon(req *logical.Request, d *fr
 	group.Alias.MountType = mountValidationResp.MountType
 	group.Alias.MountAccessor = mountValidationResp.MountAccessor
 
-	err = i.sanitizeAndUpsertGroup(group, nil)
+	err = i.sanitizeAndUpsertGroup(ctx, group, nil)
 	if err != nil {
 		return nil, err
 	}
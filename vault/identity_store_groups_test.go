@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -145,7 +146,7 @@ func TestIdentityStore_MemDBGroupIndexes(t *testing.T) {
 	var fetchedGroup *identity.Group
 
 	// Fetch group given the name
-	fetchedGroup, err = i.MemDBGroupByName("testgroupname", false)
+	fetchedGroup, err = i.MemDBGroupByName(context.Background(), "testgroupname", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -300,6 +301,7 @@ func TestIdentityStore_GroupsCreateUpdate(t *testing.T) {
 	expectedData["last_update_time"] = resp.Data["last_update_time"]
 	expectedData["modify_index"] = resp.Data["modify_index"]
 	expectedData["alias"] = resp.Data["alias"]
+	expectedData["namespace_id"] = namespace.RootNamespaceID
 
 	if !reflect.DeepEqual(expectedData, resp.Data) {
 		t.Fatalf("bad: group data;\nexpected: %#v\n actual: %#v\n", expectedData, resp.Data)
@@ -420,6 +422,7 @@ func TestIdentityStore_GroupsCRUD_ByID(t *testing.T) {
 	expectedData["last_update_time"] = resp.Data["last_update_time"]
 	expectedData["modify_index"] = resp.Data["modify_index"]
 	expectedData["alias"] = resp.Data["alias"]
+	expectedData["namespace_id"] = namespace.RootNamespaceID
 
 	if !reflect.DeepEqual(expectedData, resp.Data) {
 		t.Fatalf("bad: group data;\nexpected: %#v\n actual: %#v\n", expectedData, resp.Data)
@@ -0,0 +1,1171 @@
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	oidcConfigStorageKey  = "oidc_config"
+	oidcKeyStoragePrefix  = "oidc_keys/"
+	oidcRoleStoragePrefix = "oidc_roles/"
+	oidcPublicKeysPrefix  = "oidc_public_keys/"
+	oidcDefaultTTL        = 24 * time.Hour
+	oidcDefaultRotation   = 24 * time.Hour
+	oidcDefaultVerifyTTL  = 24 * time.Hour
+	oidcDefaultAlgorithm  = "RS256"
+	oidcWellKnownPrefix   = "oidc/.well-known/"
+)
+
+// oidcSupportedAlgs are the signing algorithms supported for OIDC keys
+var oidcSupportedAlgs = []string{
+	string(jose.RS256),
+	string(jose.RS384),
+	string(jose.RS512),
+	string(jose.ES256),
+	string(jose.ES384),
+	string(jose.ES512),
+}
+
+// oidcReservedClaims are populated by Vault and can not be set by a role's
+// template
+var oidcReservedClaims = []string{
+	"iss",
+	"sub",
+	"aud",
+	"exp",
+	"iat",
+	"nbf",
+}
+
+type oidcConfig struct {
+	Issuer string `json:"issuer"`
+}
+
+// namedKey is a signing key used to sign tokens for one or more roles. The
+// key ring tracks the IDs of the current key and of previous keys that are
+// still published for verification.
+type namedKey struct {
+	Name             string           `json:"name"`
+	Algorithm        string           `json:"signing_algorithm"`
+	VerificationTTL  time.Duration    `json:"verification_ttl"`
+	RotationPeriod   time.Duration    `json:"rotation_period"`
+	AllowedClientIDs []string         `json:"allowed_client_ids"`
+	KeyRing          []*expireableKey `json:"key_ring"`
+	SigningKey       *jose.JSONWebKey `json:"signing_key"`
+	NextRotation     time.Time        `json:"next_rotation"`
+}
+
+type expireableKey struct {
+	KeyID    string    `json:"key_id"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+type oidcRole struct {
+	Name     string        `json:"name"`
+	Key      string        `json:"key"`
+	Template string        `json:"template"`
+	TokenTTL time.Duration `json:"token_ttl"`
+	ClientID string        `json:"client_id"`
+}
+
+type oidcDiscovery struct {
+	Issuer        string   `json:"issuer"`
+	Keys          string   `json:"jwks_uri"`
+	ResponseTypes []string `json:"response_types_supported"`
+	Subjects      []string `json:"subject_types_supported"`
+	IDTokenAlgs   []string `json:"id_token_signing_alg_values_supported"`
+}
+
+func oidcPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "oidc/config/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"issuer": {
+					Type:        framework.TypeString,
+					Description: "Issuer URL to be used in the iss claim of the token. If not set, Vault's api_addr will be used.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathOIDCReadConfig,
+				logical.UpdateOperation: i.pathOIDCUpdateConfig,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-config"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-config"][1]),
+		},
+		{
+			Pattern: "oidc/key/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key.",
+				},
+				"rotation_period": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often to generate a new signing key.",
+					Default:     int(oidcDefaultRotation.Seconds()),
+				},
+				"verification_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Controls how long the public portion of a key will be available for verification after being rotated.",
+					Default:     int(oidcDefaultVerifyTTL.Seconds()),
+				},
+				"algorithm": {
+					Type:        framework.TypeString,
+					Description: "Signing algorithm to use. One of RS256, RS384, RS512, ES256, ES384 or ES512.",
+					Default:     oidcDefaultAlgorithm,
+				},
+				"allowed_client_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Comma separated list of role client IDs allowed to use this key for signing. If '*', all roles are allowed.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: i.pathOIDCCreateUpdateKey,
+				logical.UpdateOperation: i.pathOIDCCreateUpdateKey,
+				logical.ReadOperation:   i.pathOIDCReadKey,
+				logical.DeleteOperation: i.pathOIDCDeleteKey,
+			},
+			ExistenceCheck: i.pathOIDCKeyExistenceCheck,
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-key"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-key"][1]),
+		},
+		{
+			Pattern: "oidc/key/" + framework.GenericNameRegex("name") + "/rotate/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key.",
+				},
+				"verification_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Controls how long the public portion of the rotated key will be available for verification. Defaults to the key's verification_ttl.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathOIDCRotateKey,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-key-rotate"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-key-rotate"][1]),
+		},
+		{
+			Pattern: "oidc/key/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathOIDCListKeys,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-key-list"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-key-list"][1]),
+		},
+		{
+			Pattern: "oidc/role/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"key": {
+					Type:        framework.TypeString,
+					Description: "The OIDC key used to sign tokens issued for this role.",
+				},
+				"template": {
+					Type:        framework.TypeString,
+					Description: "The JSON template of additional claims to populate in tokens. Values may reference the calling entity, e.g. {{identity.entity.name}}.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "TTL of the tokens generated against the role.",
+					Default:     int(oidcDefaultTTL.Seconds()),
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: i.pathOIDCCreateUpdateRole,
+				logical.UpdateOperation: i.pathOIDCCreateUpdateRole,
+				logical.ReadOperation:   i.pathOIDCReadRole,
+				logical.DeleteOperation: i.pathOIDCDeleteRole,
+			},
+			ExistenceCheck: i.pathOIDCRoleExistenceCheck,
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-role"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-role"][1]),
+		},
+		{
+			Pattern: "oidc/role/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathOIDCListRoles,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-role-list"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-role-list"][1]),
+		},
+		{
+			Pattern: "oidc/token/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathOIDCGenerateToken,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-token"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-token"][1]),
+		},
+		{
+			Pattern: oidcWellKnownPrefix + "openid-configuration",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathOIDCDiscovery,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-discovery"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-discovery"][1]),
+		},
+		{
+			Pattern: oidcWellKnownPrefix + "keys",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathOIDCReadPublicKeys,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-keys"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-keys"][1]),
+		},
+	}
+}
+
+func (i *IdentityStore) pathOIDCReadConfig(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := i.getOIDCConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer": config.Issuer,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCUpdateConfig(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	config, err := i.getOIDCConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if issuerRaw, ok := d.GetOk("issuer"); ok {
+		config.Issuer = strings.TrimSuffix(issuerRaw.(string), "/")
+	}
+
+	entry, err := logical.StorageEntryJSON(oidcConfigStorageKey, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.view.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) getOIDCConfig(ctx context.Context) (*oidcConfig, error) {
+	entry, err := i.view.Get(ctx, oidcConfigStorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var config oidcConfig
+	if entry != nil {
+		if err := entry.DecodeJSON(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+// oidcIssuer returns the configured issuer, falling back to an issuer based
+// on the cluster's API address
+func (i *IdentityStore) oidcIssuer(ctx context.Context) (string, error) {
+	config, err := i.getOIDCConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	issuer := config.Issuer
+	if issuer == "" {
+		issuer = strings.TrimSuffix(i.core.redirectAddr, "/")
+	}
+
+	return issuer + "/v1/identity/oidc", nil
+}
+
+func (i *IdentityStore) pathOIDCKeyExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	key, err := i.getOIDCKey(ctx, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+
+	return key != nil, nil
+}
+
+func (i *IdentityStore) pathOIDCCreateUpdateKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	key, err := i.getOIDCKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		if req.Operation == logical.UpdateOperation {
+			return logical.ErrorResponse(fmt.Sprintf("no key named %q", name)), logical.ErrInvalidRequest
+		}
+		key = &namedKey{
+			Name: name,
+		}
+	}
+
+	if rotationPeriodRaw, ok := d.GetOk("rotation_period"); ok {
+		key.RotationPeriod = time.Duration(rotationPeriodRaw.(int)) * time.Second
+	} else if req.Operation == logical.CreateOperation {
+		key.RotationPeriod = time.Duration(d.Get("rotation_period").(int)) * time.Second
+	}
+	if key.RotationPeriod < time.Minute {
+		return logical.ErrorResponse("rotation_period must be at least one minute"), logical.ErrInvalidRequest
+	}
+
+	if verificationTTLRaw, ok := d.GetOk("verification_ttl"); ok {
+		key.VerificationTTL = time.Duration(verificationTTLRaw.(int)) * time.Second
+	} else if req.Operation == logical.CreateOperation {
+		key.VerificationTTL = time.Duration(d.Get("verification_ttl").(int)) * time.Second
+	}
+
+	if allowedClientIDsRaw, ok := d.GetOk("allowed_client_ids"); ok {
+		key.AllowedClientIDs = allowedClientIDsRaw.([]string)
+	}
+
+	prevAlgorithm := key.Algorithm
+	if algorithmRaw, ok := d.GetOk("algorithm"); ok {
+		key.Algorithm = algorithmRaw.(string)
+	} else if req.Operation == logical.CreateOperation {
+		key.Algorithm = d.Get("algorithm").(string)
+	}
+	if !strutil.StrListContains(oidcSupportedAlgs, key.Algorithm) {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %q", key.Algorithm)), logical.ErrInvalidRequest
+	}
+
+	// Make sure all roles using this key can still be verified for the
+	// lifetime of their tokens
+	roles, err := i.oidcRolesByKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.TokenTTL > key.VerificationTTL {
+			return logical.ErrorResponse(fmt.Sprintf("verification_ttl cannot be shorter than the ttl of role %q", role.Name)), logical.ErrInvalidRequest
+		}
+	}
+
+	// Generate a new signing key when the key is created or its algorithm
+	// changes
+	if key.SigningKey == nil || key.Algorithm != prevAlgorithm {
+		if err := i.rotateOIDCKey(ctx, key, key.VerificationTTL); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if err := i.putOIDCKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCReadKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	key, err := i.getOIDCKey(ctx, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"rotation_period":    int64(key.RotationPeriod.Seconds()),
+			"verification_ttl":   int64(key.VerificationTTL.Seconds()),
+			"algorithm":          key.Algorithm,
+			"allowed_client_ids": key.AllowedClientIDs,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCDeleteKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	roles, err := i.oidcRolesByKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) > 0 {
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			names = append(names, role.Name)
+		}
+		return logical.ErrorResponse(fmt.Sprintf("unable to delete key %q because it is currently referenced by these roles: %s", name, strings.Join(names, ", "))), logical.ErrInvalidRequest
+	}
+
+	key, err := i.getOIDCKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	for _, k := range key.KeyRing {
+		if err := i.view.Delete(ctx, oidcPublicKeysPrefix+k.KeyID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.view.Delete(ctx, oidcKeyStoragePrefix+name); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCListKeys(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keys, err := i.view.List(ctx, oidcKeyStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) pathOIDCRotateKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	key, err := i.getOIDCKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no key named %q", name)), logical.ErrInvalidRequest
+	}
+
+	verificationTTL := key.VerificationTTL
+	if verificationTTLRaw, ok := d.GetOk("verification_ttl"); ok {
+		verificationTTL = time.Duration(verificationTTLRaw.(int)) * time.Second
+	}
+
+	if err := i.rotateOIDCKey(ctx, key, verificationTTL); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) getOIDCKey(ctx context.Context, name string) (*namedKey, error) {
+	entry, err := i.view.Get(ctx, oidcKeyStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var key namedKey
+	if err := entry.DecodeJSON(&key); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (i *IdentityStore) putOIDCKey(ctx context.Context, key *namedKey) error {
+	entry, err := logical.StorageEntryJSON(oidcKeyStoragePrefix+key.Name, key)
+	if err != nil {
+		return err
+	}
+
+	return i.view.Put(ctx, entry)
+}
+
+// rotateOIDCKey generates a new signing key for the named key and publishes
+// its public portion. The previous signing key remains published for
+// verificationTTL. The caller must hold the OIDC lock.
+func (i *IdentityStore) rotateOIDCKey(ctx context.Context, key *namedKey, verificationTTL time.Duration) error {
+	signingKey, err := generateOIDCSigningKey(key.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	publicKey := jose.JSONWebKey{
+		Key:       signingKey.Key.(crypto.Signer).Public(),
+		KeyID:     signingKey.KeyID,
+		Algorithm: signingKey.Algorithm,
+		Use:       signingKey.Use,
+	}
+	entry, err := logical.StorageEntryJSON(oidcPublicKeysPrefix+publicKey.KeyID, publicKey)
+	if err != nil {
+		return err
+	}
+	if err := i.view.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if key.SigningKey != nil {
+		for _, k := range key.KeyRing {
+			if k.KeyID == key.SigningKey.KeyID {
+				k.ExpireAt = now.Add(verificationTTL)
+			}
+		}
+	}
+
+	key.SigningKey = signingKey
+	key.KeyRing = append(key.KeyRing, &expireableKey{KeyID: signingKey.KeyID})
+	key.NextRotation = now.Add(key.RotationPeriod)
+
+	return i.putOIDCKey(ctx, key)
+}
+
+func generateOIDCSigningKey(algorithm string) (*jose.JSONWebKey, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch jose.SignatureAlgorithm(algorithm) {
+	case jose.RS256, jose.RS384, jose.RS512:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jose.ES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jose.ES384:
+		signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case jose.ES512:
+		signer, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate signing key: {{err}}", err)
+	}
+
+	keyID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &jose.JSONWebKey{
+		Key:       signer,
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		Use:       "sig",
+	}, nil
+}
+
+// oidcPeriodicFunc rotates keys that are due for rotation and removes public
+// keys whose verification period has passed
+func (i *IdentityStore) oidcPeriodicFunc(ctx context.Context, req *logical.Request) error {
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	names, err := i.view.List(ctx, oidcKeyStoragePrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		key, err := i.getOIDCKey(ctx, name)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			continue
+		}
+
+		if now.After(key.NextRotation) {
+			i.logger.Debug("rotating OIDC key", "name", key.Name)
+			if err := i.rotateOIDCKey(ctx, key, key.VerificationTTL); err != nil {
+				return errwrap.Wrapf(fmt.Sprintf("failed to rotate OIDC key %q: {{err}}", key.Name), err)
+			}
+		}
+
+		keyRing := make([]*expireableKey, 0, len(key.KeyRing))
+		for _, k := range key.KeyRing {
+			if !k.ExpireAt.IsZero() && now.After(k.ExpireAt) {
+				if err := i.view.Delete(ctx, oidcPublicKeysPrefix+k.KeyID); err != nil {
+					return err
+				}
+				continue
+			}
+			keyRing = append(keyRing, k)
+		}
+		if len(keyRing) != len(key.KeyRing) {
+			key.KeyRing = keyRing
+			if err := i.putOIDCKey(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i *IdentityStore) pathOIDCRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := i.getOIDCRole(ctx, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+
+	return role != nil, nil
+}
+
+func (i *IdentityStore) pathOIDCCreateUpdateRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	role, err := i.getOIDCRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		if req.Operation == logical.UpdateOperation {
+			return logical.ErrorResponse(fmt.Sprintf("no role named %q", name)), logical.ErrInvalidRequest
+		}
+		role = &oidcRole{
+			Name: name,
+		}
+	}
+
+	if keyRaw, ok := d.GetOk("key"); ok {
+		role.Key = keyRaw.(string)
+	}
+	if role.Key == "" {
+		return logical.ErrorResponse("the key parameter is required"), logical.ErrInvalidRequest
+	}
+
+	if templateRaw, ok := d.GetOk("template"); ok {
+		role.Template = templateRaw.(string)
+	}
+	if _, err := populateOIDCTemplate(role.Template, &identity.Entity{}, nil); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid template: %s", err)), logical.ErrInvalidRequest
+	}
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TokenTTL = time.Duration(ttlRaw.(int)) * time.Second
+	} else if req.Operation == logical.CreateOperation {
+		role.TokenTTL = time.Duration(d.Get("ttl").(int)) * time.Second
+	}
+
+	key, err := i.getOIDCKey(ctx, role.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key %q does not exist", role.Key)), logical.ErrInvalidRequest
+	}
+	if role.TokenTTL > key.VerificationTTL {
+		return logical.ErrorResponse("a role's ttl cannot be longer than the verification_ttl of the key it references"), logical.ErrInvalidRequest
+	}
+
+	if role.ClientID == "" {
+		role.ClientID, err = uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(oidcRoleStoragePrefix+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.view.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCReadRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := i.getOIDCRole(ctx, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"key":       role.Key,
+			"template":  role.Template,
+			"ttl":       int64(role.TokenTTL.Seconds()),
+			"client_id": role.ClientID,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCDeleteRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	if err := i.view.Delete(ctx, oidcRoleStoragePrefix+d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCListRoles(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := i.view.List(ctx, oidcRoleStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(roles), nil
+}
+
+func (i *IdentityStore) getOIDCRole(ctx context.Context, name string) (*oidcRole, error) {
+	entry, err := i.view.Get(ctx, oidcRoleStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role oidcRole
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// oidcRolesByKey returns the roles that sign their tokens with the given key
+func (i *IdentityStore) oidcRolesByKey(ctx context.Context, keyName string) ([]*oidcRole, error) {
+	names, err := i.view.List(ctx, oidcRoleStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []*oidcRole
+	for _, name := range names {
+		role, err := i.getOIDCRole(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.Key == keyName {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
+
+func (i *IdentityStore) pathOIDCGenerateToken(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	if req.EntityID == "" {
+		return logical.ErrorResponse("no entity associated with the request's token"), logical.ErrInvalidRequest
+	}
+
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	role, err := i.getOIDCRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no role named %q", name)), logical.ErrInvalidRequest
+	}
+
+	key, err := i.getOIDCKey(ctx, role.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.SigningKey == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key %q does not exist", role.Key)), logical.ErrInvalidRequest
+	}
+	if !strutil.StrListContains(key.AllowedClientIDs, "*") && !strutil.StrListContains(key.AllowedClientIDs, role.ClientID) {
+		return logical.ErrorResponse("the key this role references is not allowed to sign tokens for the role's client_id"), logical.ErrInvalidRequest
+	}
+
+	entity, err := i.MemDBEntityByID(req.EntityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity associated with the request's token does not exist"), logical.ErrInvalidRequest
+	}
+
+	directGroups, inheritedGroups, err := i.groupsByEntityID(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := populateOIDCTemplate(role.Template, entity, append(directGroups, inheritedGroups...))
+	if err != nil {
+		return nil, errwrap.Wrapf("error populating template: {{err}}", err)
+	}
+
+	issuer, err := i.oidcIssuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims["iss"] = issuer
+	claims["sub"] = entity.ID
+	claims["aud"] = role.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(role.TokenTTL).Unix()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(key.Algorithm),
+		Key:       key.SigningKey,
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, errwrap.Wrapf("error creating signer: {{err}}", err)
+	}
+
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return nil, errwrap.Wrapf("error signing token: {{err}}", err)
+	}
+
+	token, err := signature.CompactSerialize()
+	if err != nil {
+		return nil, errwrap.Wrapf("error serializing token: {{err}}", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":     token,
+			"client_id": role.ClientID,
+			"ttl":       int64(role.TokenTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCDiscovery(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	issuer, err := i.oidcIssuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	disc := oidcDiscovery{
+		Issuer:        issuer,
+		Keys:          issuer + "/.well-known/keys",
+		ResponseTypes: []string{"id_token"},
+		Subjects:      []string{"public"},
+		IDTokenAlgs:   oidcSupportedAlgs,
+	}
+
+	data, err := json.Marshal(disc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     data,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCReadPublicKeys(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keyIDs, err := i.view.List(ctx, oidcPublicKeysPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &jose.JSONWebKeySet{
+		Keys: make([]jose.JSONWebKey, 0, len(keyIDs)),
+	}
+	for _, keyID := range keyIDs {
+		entry, err := i.view.Get(ctx, oidcPublicKeysPrefix+keyID)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var key jose.JSONWebKey
+		if err := entry.DecodeJSON(&key); err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, key)
+	}
+
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     data,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
+
+// populateOIDCTemplate parses the JSON claims template and replaces any
+// identity parameters with values from the given entity and groups. A string
+// value consisting solely of a parameter takes the parameter's type, so group
+// lists become JSON arrays; parameters embedded in longer strings are
+// substituted as strings. Claims whose value resolves to nothing are omitted.
+func populateOIDCTemplate(template string, entity *identity.Entity, groups []*identity.Group) (map[string]interface{}, error) {
+	claims := make(map[string]interface{})
+	if strings.TrimSpace(template) == "" {
+		return claims, nil
+	}
+
+	if err := json.Unmarshal([]byte(template), &claims); err != nil {
+		return nil, errwrap.Wrapf("template is not a valid JSON object: {{err}}", err)
+	}
+
+	for _, reserved := range oidcReservedClaims {
+		if _, ok := claims[reserved]; ok {
+			return nil, fmt.Errorf("top level key %q is reserved", reserved)
+		}
+	}
+
+	for k, v := range claims {
+		populated, ok, err := populateOIDCTemplateValue(v, entity, groups)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = populated
+	}
+
+	return claims, nil
+}
+
+func populateOIDCTemplateValue(v interface{}, entity *identity.Entity, groups []*identity.Group) (interface{}, bool, error) {
+	switch v := v.(type) {
+	case string:
+		return populateOIDCTemplateString(v, entity, groups)
+
+	case map[string]interface{}:
+		for k, elem := range v {
+			populated, ok, err := populateOIDCTemplateValue(elem, entity, groups)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				delete(v, k)
+				continue
+			}
+			v[k] = populated
+		}
+		return v, true, nil
+
+	case []interface{}:
+		ret := make([]interface{}, 0, len(v))
+		for _, elem := range v {
+			populated, ok, err := populateOIDCTemplateValue(elem, entity, groups)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				ret = append(ret, populated)
+			}
+		}
+		return ret, true, nil
+
+	default:
+		return v, true, nil
+	}
+}
+
+func populateOIDCTemplateString(s string, entity *identity.Entity, groups []*identity.Group) (interface{}, bool, error) {
+	// A value that is only a parameter keeps the parameter's type
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+		return oidcTemplateParameter(strings.TrimSpace(trimmed[2:len(trimmed)-2]), entity, groups)
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start == -1 {
+			out.WriteString(s)
+			break
+		}
+		end := strings.Index(s[start:], "}}")
+		if end == -1 {
+			return nil, false, fmt.Errorf("unclosed parameter in %q", s)
+		}
+		end += start
+
+		out.WriteString(s[:start])
+		val, ok, err := oidcTemplateParameter(strings.TrimSpace(s[start+2:end]), entity, groups)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			switch val := val.(type) {
+			case string:
+				out.WriteString(val)
+			default:
+				return nil, false, fmt.Errorf("parameter %q can not be embedded in a string", strings.TrimSpace(s[start+2:end]))
+			}
+		}
+		s = s[end+2:]
+	}
+
+	return out.String(), true, nil
+}
+
+// oidcTemplateParameter resolves a single identity parameter. The returned
+// bool is false when the parameter is valid but has no value for the entity.
+func oidcTemplateParameter(param string, entity *identity.Entity, groups []*identity.Group) (interface{}, bool, error) {
+	const prefix = "identity.entity."
+	if !strings.HasPrefix(param, prefix) {
+		return nil, false, fmt.Errorf("unknown parameter %q", param)
+	}
+	field := strings.TrimPrefix(param, prefix)
+
+	switch {
+	case field == "id":
+		return entity.ID, entity.ID != "", nil
+
+	case field == "name":
+		return entity.Name, entity.Name != "", nil
+
+	case strings.HasPrefix(field, "metadata."):
+		val, ok := entity.Metadata[strings.TrimPrefix(field, "metadata.")]
+		return val, ok, nil
+
+	case strings.HasPrefix(field, "aliases.") && strings.HasSuffix(field, ".name"):
+		accessor := strings.TrimSuffix(strings.TrimPrefix(field, "aliases."), ".name")
+		if accessor == "" {
+			return nil, false, fmt.Errorf("missing mount accessor in parameter %q", param)
+		}
+		for _, alias := range entity.Aliases {
+			if alias.MountAccessor == accessor {
+				return alias.Name, true, nil
+			}
+		}
+		return nil, false, nil
+
+	case field == "groups.ids":
+		ids := make([]string, 0, len(groups))
+		for _, group := range groups {
+			ids = append(ids, group.ID)
+		}
+		return ids, true, nil
+
+	case field == "groups.names":
+		names := make([]string, 0, len(groups))
+		for _, group := range groups {
+			names = append(names, group.Name)
+		}
+		return names, true, nil
+	}
+
+	return nil, false, fmt.Errorf("unknown parameter %q", param)
+}
+
+var oidcHelp = map[string][2]string{
+	"oidc-config": {
+		"OIDC configuration",
+		"Update OIDC configuration in the identity backend",
+	},
+	"oidc-key": {
+		"CRUD operations for OIDC keys.",
+		"Create, Read, Update, and Delete OIDC named keys used to sign tokens.",
+	},
+	"oidc-key-rotate": {
+		"Rotate a named OIDC key.",
+		`Generate a new signing key for the named key. The previous public key
+remains available for verification for the key's verification_ttl, or the
+given verification_ttl.`,
+	},
+	"oidc-key-list": {
+		"List OIDC keys",
+		"List all named OIDC keys",
+	},
+	"oidc-role": {
+		"CRUD operations on OIDC Roles",
+		`Create, Read, Update, and Delete OIDC roles. A role ties a signing key to
+a template of claims to add to tokens issued for the role, and is assigned a
+client_id that is used as the audience of those tokens.`,
+	},
+	"oidc-role-list": {
+		"List configured OIDC roles",
+		"List all configured OIDC roles in the identity backend.",
+	},
+	"oidc-token": {
+		"Generate an OIDC token",
+		`Generate an identity token for the entity associated with the request's
+token, signed by the role's key and containing the role's templated claims.`,
+	},
+	"oidc-discovery": {
+		"Query OIDC configurations",
+		"Query this path to retrieve the configured OIDC Issuer and Keys endpoints, response types, subject types, and signing algorithms used by the OIDC backend.",
+	},
+	"oidc-keys": {
+		"Retrieve public keys",
+		"Query this path to retrieve the public portion of keys used to sign OIDC tokens. Clients can use this to validate the authenticity of the OIDC token claims.",
+	},
+}
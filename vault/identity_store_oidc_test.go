@@ -0,0 +1,334 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func testOIDCPublicKeys(t *testing.T, is *IdentityStore) *jose.JSONWebKeySet {
+	t.Helper()
+
+	resp, err := is.HandleRequest(context.Background(), &logical.Request{
+		Path:      "oidc/.well-known/keys",
+		Operation: logical.ReadOperation,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	return &jwks
+}
+
+func TestIdentityStore_OIDC_Token(t *testing.T) {
+	ctx := context.Background()
+	is, ghAccessor, core := testIdentityStoreWithGithubAuth(t)
+
+	entity, err := is.CreateOrFetchEntity(context.Background(), &logical.Alias{
+		MountType:     "github",
+		MountAccessor: ghAccessor,
+		Name:          "githubuser",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Path:      "group",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name":              "engineering",
+			"member_entity_ids": entity.ID,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"allowed_client_ids": "*",
+			"algorithm":          "ES256",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Reserved claims can not be templated
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/role/test-role",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"key":      "test-key",
+			"template": `{"sub": "{{identity.entity.name}}"}`,
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for reserved claim: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/role/test-role",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"key":      "test-key",
+			"ttl":      "1h",
+			"template": `{"name": "{{identity.entity.name}}", "groups": "{{identity.entity.groups.names}}", "github": "user-{{identity.entity.aliases.` + ghAccessor + `.name}}", "missing": "{{identity.entity.metadata.missing}}"}`,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/role/test-role",
+		Operation: logical.ReadOperation,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	clientID := resp.Data["client_id"].(string)
+
+	// Tokens can only be generated for entities
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/token/test-role",
+		Operation: logical.ReadOperation,
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected error without an entity: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/token/test-role",
+		Operation: logical.ReadOperation,
+		EntityID:  entity.ID,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["client_id"] != clientID {
+		t.Fatalf("bad client_id: %v", resp.Data["client_id"])
+	}
+
+	sig, err := jose.ParseSigned(resp.Data["token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := testOIDCPublicKeys(t, is)
+	if len(jwks.Keys) != 1 {
+		t.Fatalf("expected 1 public key, got %d", len(jwks.Keys))
+	}
+	payload, err := sig.Verify(&jwks.Keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != entity.ID {
+		t.Fatalf("bad sub: %v", claims["sub"])
+	}
+	if claims["aud"] != clientID {
+		t.Fatalf("bad aud: %v", claims["aud"])
+	}
+	if claims["iss"] != core.redirectAddr+"/v1/identity/oidc" {
+		t.Fatalf("bad iss: %v", claims["iss"])
+	}
+	if int64(claims["exp"].(float64))-int64(claims["iat"].(float64)) != 3600 {
+		t.Fatalf("bad expiration: %v", claims)
+	}
+	if claims["name"] != entity.Name {
+		t.Fatalf("bad name: %v", claims["name"])
+	}
+	if !reflect.DeepEqual(claims["groups"], []interface{}{"engineering"}) {
+		t.Fatalf("bad groups: %#v", claims["groups"])
+	}
+	if claims["github"] != "user-githubuser" {
+		t.Fatalf("bad github: %v", claims["github"])
+	}
+	if _, ok := claims["missing"]; ok {
+		t.Fatal("expected claim without a value to be omitted")
+	}
+
+	// Keys in use by a role can't be deleted
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key",
+		Operation: logical.DeleteOperation,
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected error deleting key in use: resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestIdentityStore_OIDC_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	is, _, _ := testIdentityStoreWithGithubAuth(t)
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key",
+		Operation: logical.CreateOperation,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// The rotated key stays published until its verification TTL passes
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key/rotate",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"verification_ttl": 0,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if keys := testOIDCPublicKeys(t, is).Keys; len(keys) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(keys))
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := is.oidcPeriodicFunc(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	keys := testOIDCPublicKeys(t, is).Keys
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 public key, got %d", len(keys))
+	}
+
+	key, err := is.getOIDCKey(ctx, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys[0].KeyID != key.SigningKey.KeyID {
+		t.Fatalf("expected current signing key to be published, got %q", keys[0].KeyID)
+	}
+
+	// Keys due for rotation are rotated by the periodic func
+	key.NextRotation = time.Now().Add(-time.Second)
+	if err := is.putOIDCKey(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := is.oidcPeriodicFunc(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := is.getOIDCKey(ctx, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.SigningKey.KeyID == key.SigningKey.KeyID {
+		t.Fatal("expected key to be rotated")
+	}
+	if len(testOIDCPublicKeys(t, is).Keys) != 2 {
+		t.Fatal("expected previous key to remain published")
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key",
+		Operation: logical.DeleteOperation,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if len(testOIDCPublicKeys(t, is).Keys) != 0 {
+		t.Fatal("expected public keys to be removed with the key")
+	}
+}
+
+func TestIdentityStore_OIDC_Discovery(t *testing.T) {
+	ctx := context.Background()
+	is, _, core := testIdentityStoreWithGithubAuth(t)
+
+	if !core.router.LoginPath("identity/oidc/.well-known/openid-configuration") {
+		t.Fatal("expected discovery to be unauthenticated")
+	}
+	if !core.router.LoginPath("identity/oidc/.well-known/keys") {
+		t.Fatal("expected keys to be unauthenticated")
+	}
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/config",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"issuer": "https://vault.example.com/",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/.well-known/openid-configuration",
+		Operation: logical.ReadOperation,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	var disc oidcDiscovery
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &disc); err != nil {
+		t.Fatal(err)
+	}
+	if disc.Issuer != "https://vault.example.com/v1/identity/oidc" {
+		t.Fatalf("bad issuer: %q", disc.Issuer)
+	}
+	if disc.Keys != disc.Issuer+"/.well-known/keys" {
+		t.Fatalf("bad jwks_uri: %q", disc.Keys)
+	}
+}
+
+func TestIdentityStore_OIDC_PopulateTemplate(t *testing.T) {
+	entity := &identity.Entity{
+		ID:   "entity-id",
+		Name: "entity-name",
+		Metadata: map[string]string{
+			"color": "green",
+		},
+	}
+	groups := []*identity.Group{
+		{ID: "group-id", Name: "group-name"},
+	}
+
+	claims, err := populateOIDCTemplate(`{"nested": {"color": "{{identity.entity.metadata.color}}", "ids": ["{{identity.entity.id}}", "static"]}, "group_ids": "{{identity.entity.groups.ids}}", "count": 3}`, entity, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"nested": map[string]interface{}{
+			"color": "green",
+			"ids":   []interface{}{"entity-id", "static"},
+		},
+		"group_ids": []string{"group-id"},
+		"count":     float64(3),
+	}
+	if !reflect.DeepEqual(claims, expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", expected, claims)
+	}
+
+	for _, tmpl := range []string{
+		`not json`,
+		`{"foo": "{{identity.entity.unknown}}"}`,
+		`{"foo": "{{identity.entity.name"}`,
+		`{"foo": "prefix {{identity.entity.groups.names}}"}`,
+		`{"iat": 0}`,
+	} {
+		if _, err := populateOIDCTemplate(tmpl, entity, groups); err == nil {
+			t.Fatalf("expected error for template %q", tmpl)
+		}
+	}
+}
@@ -59,6 +59,9 @@ type IdentityStore struct {
 	// groupLock is used to protect modifications to group entries
 	groupLock sync.RWMutex
 
+	// oidcLock is used to protect modifications to OIDC keys and roles
+	oidcLock sync.RWMutex
+
 	// logger is the server logger copied over from core
 	logger log.Logger
 
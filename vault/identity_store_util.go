@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/storagepacker"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
@@ -827,6 +828,12 @@ func (i *IdentityStore) MemDBUpsertEntityInTxn(txn *memdb.Txn, entity *identity.
 		return fmt.Errorf("entity is nil")
 	}
 
+	// Entries persisted before namespaces existed belong to the root
+	// namespace
+	if entity.NamespaceID == "" {
+		entity.NamespaceID = namespace.RootNamespaceID
+	}
+
 	entityRaw, err := txn.First(entitiesTable, "id", entity.ID)
 	if err != nil {
 		return errwrap.Wrapf("failed to lookup entity from memdb using entity id: {{err}}", err)
@@ -904,7 +911,7 @@ func (i *IdentityStore) MemDBEntityByID(entityID string, clone bool) (*identity.
 	return i.MemDBEntityByIDInTxn(txn, entityID, clone)
 }
 
-func (i *IdentityStore) MemDBEntityByNameInTxn(txn *memdb.Txn, entityName string, clone bool) (*identity.Entity, error) {
+func (i *IdentityStore) MemDBEntityByNameInTxn(ctx context.Context, txn *memdb.Txn, entityName string, clone bool) (*identity.Entity, error) {
 	if entityName == "" {
 		return nil, fmt.Errorf("missing entity name")
 	}
@@ -913,7 +920,7 @@ func (i *IdentityStore) MemDBEntityByNameInTxn(txn *memdb.Txn, entityName string
 		return nil, fmt.Errorf("txn is nil")
 	}
 
-	entityRaw, err := txn.First(entitiesTable, "name", entityName)
+	entityRaw, err := txn.First(entitiesTable, "name", namespaceFromContext(ctx).ID, entityName)
 	if err != nil {
 		return nil, errwrap.Wrapf("failed to fetch entity from memdb using entity name: {{err}}", err)
 	}
@@ -934,14 +941,14 @@ func (i *IdentityStore) MemDBEntityByNameInTxn(txn *memdb.Txn, entityName string
 	return entity, nil
 }
 
-func (i *IdentityStore) MemDBEntityByName(entityName string, clone bool) (*identity.Entity, error) {
+func (i *IdentityStore) MemDBEntityByName(ctx context.Context, entityName string, clone bool) (*identity.Entity, error) {
 	if entityName == "" {
 		return nil, fmt.Errorf("missing entity name")
 	}
 
 	txn := i.db.Txn(false)
 
-	return i.MemDBEntityByNameInTxn(txn, entityName, clone)
+	return i.MemDBEntityByNameInTxn(ctx, txn, entityName, clone)
 }
 
 func (i *IdentityStore) MemDBEntitiesByMetadata(filters map[string]string, clone bool) ([]*identity.Entity, error) {
@@ -1182,7 +1189,7 @@ func (i *IdentityStore) sanitizeAlias(alias *identity.Alias) error {
 	return nil
 }
 
-func (i *IdentityStore) sanitizeEntity(entity *identity.Entity) error {
+func (i *IdentityStore) sanitizeEntity(ctx context.Context, entity *identity.Entity) error {
 	var err error
 
 	if entity == nil {
@@ -1200,9 +1207,14 @@ func (i *IdentityStore) sanitizeEntity(entity *identity.Entity) error {
 		entity.BucketKeyHash = i.entityPacker.BucketKeyHashByItemID(entity.ID)
 	}
 
+	// Bind the entity to the namespace of the request that created it
+	if entity.NamespaceID == "" {
+		entity.NamespaceID = namespaceFromContext(ctx).ID
+	}
+
 	// Create a name if there isn't one already
 	if entity.Name == "" {
-		entity.Name, err = i.generateName("entity")
+		entity.Name, err = i.generateName(ctx, "entity")
 		if err != nil {
 			return fmt.Errorf("failed to generate entity name")
 		}
@@ -1225,7 +1237,7 @@ func (i *IdentityStore) sanitizeEntity(entity *identity.Entity) error {
 	return nil
 }
 
-func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGroupIDs []string) error {
+func (i *IdentityStore) sanitizeAndUpsertGroup(ctx context.Context, group *identity.Group, memberGroupIDs []string) error {
 	var err error
 
 	if group == nil {
@@ -1243,9 +1255,14 @@ func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGrou
 		group.BucketKeyHash = i.groupPacker.BucketKeyHashByItemID(group.ID)
 	}
 
+	// Bind the group to the namespace of the request that created it
+	if group.NamespaceID == "" {
+		group.NamespaceID = namespaceFromContext(ctx).ID
+	}
+
 	// Create a name if there isn't one already
 	if group.Name == "" {
-		group.Name, err = i.generateName("group")
+		group.Name, err = i.generateName(ctx, "group")
 		if err != nil {
 			return fmt.Errorf("failed to generate group name")
 		}
@@ -1268,7 +1285,7 @@ func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGrou
 	// Remove duplicate entity IDs and check if all IDs are valid
 	group.MemberEntityIDs = strutil.RemoveDuplicates(group.MemberEntityIDs, false)
 	for _, entityID := range group.MemberEntityIDs {
-		err = i.validateEntityID(entityID)
+		err = i.validateEntityID(group.NamespaceID, entityID)
 		if err != nil {
 			return err
 		}
@@ -1285,7 +1302,7 @@ func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGrou
 		if err != nil {
 			return err
 		}
-		if memberGroup == nil {
+		if memberGroup == nil || memberGroup.NamespaceID != group.NamespaceID {
 			return fmt.Errorf("invalid member group ID %q", memberGroupID)
 		}
 
@@ -1373,12 +1390,12 @@ func (i *IdentityStore) validateMemberGroupID(groupID string, memberGroupID stri
 	return nil
 }
 
-func (i *IdentityStore) validateEntityID(entityID string) error {
+func (i *IdentityStore) validateEntityID(namespaceID, entityID string) error {
 	entity, err := i.MemDBEntityByID(entityID, false)
 	if err != nil {
 		return errwrap.Wrapf(fmt.Sprintf("failed to validate entity ID %q: {{err}}", entityID), err)
 	}
-	if entity == nil {
+	if entity == nil || entity.NamespaceID != namespaceID {
 		return fmt.Errorf("invalid entity ID %q", entityID)
 	}
 	return nil
@@ -1527,7 +1544,7 @@ func satisfiesMetadataFilters(meta map[string]string, filters map[string]string)
 	return true
 }
 
-func (i *IdentityStore) MemDBGroupByNameInTxn(txn *memdb.Txn, groupName string, clone bool) (*identity.Group, error) {
+func (i *IdentityStore) MemDBGroupByNameInTxn(ctx context.Context, txn *memdb.Txn, groupName string, clone bool) (*identity.Group, error) {
 	if groupName == "" {
 		return nil, fmt.Errorf("missing group name")
 	}
@@ -1536,7 +1553,7 @@ func (i *IdentityStore) MemDBGroupByNameInTxn(txn *memdb.Txn, groupName string,
 		return nil, fmt.Errorf("txn is nil")
 	}
 
-	groupRaw, err := txn.First(groupsTable, "name", groupName)
+	groupRaw, err := txn.First(groupsTable, "name", namespaceFromContext(ctx).ID, groupName)
 	if err != nil {
 		return nil, errwrap.Wrapf("failed to fetch group from memdb using group name: {{err}}", err)
 	}
@@ -1557,14 +1574,14 @@ func (i *IdentityStore) MemDBGroupByNameInTxn(txn *memdb.Txn, groupName string,
 	return group, nil
 }
 
-func (i *IdentityStore) MemDBGroupByName(groupName string, clone bool) (*identity.Group, error) {
+func (i *IdentityStore) MemDBGroupByName(ctx context.Context, groupName string, clone bool) (*identity.Group, error) {
 	if groupName == "" {
 		return nil, fmt.Errorf("missing group name")
 	}
 
 	txn := i.db.Txn(false)
 
-	return i.MemDBGroupByNameInTxn(txn, groupName, clone)
+	return i.MemDBGroupByNameInTxn(ctx, txn, groupName, clone)
 }
 
 func (i *IdentityStore) UpsertGroup(group *identity.Group, persist bool) error {
@@ -1643,6 +1660,12 @@ func (i *IdentityStore) MemDBUpsertGroupInTxn(txn *memdb.Txn, group *identity.Gr
 		return fmt.Errorf("group is nil")
 	}
 
+	// Entries persisted before namespaces existed belong to the root
+	// namespace
+	if group.NamespaceID == "" {
+		group.NamespaceID = namespace.RootNamespaceID
+	}
+
 	groupRaw, err := txn.First(groupsTable, "id", group.ID)
 	if err != nil {
 		return errwrap.Wrapf("failed to lookup group from memdb using group id: {{err}}", err)
@@ -1740,7 +1763,7 @@ func (i *IdentityStore) MemDBDeleteGroupByIDInTxn(txn *memdb.Txn, groupID string
 	return nil
 }
 
-func (i *IdentityStore) deleteGroupByName(groupName string) error {
+func (i *IdentityStore) deleteGroupByName(ctx context.Context, groupName string) error {
 	var err error
 	var group *identity.Group
 
@@ -1757,7 +1780,7 @@ func (i *IdentityStore) deleteGroupByName(groupName string) error {
 	defer txn.Abort()
 
 	// Fetch the group using its ID
-	group, err = i.MemDBGroupByNameInTxn(txn, groupName, false)
+	group, err = i.MemDBGroupByNameInTxn(ctx, txn, groupName, false)
 	if err != nil {
 		return err
 	}
@@ -1768,7 +1791,7 @@ func (i *IdentityStore) deleteGroupByName(groupName string) error {
 	}
 
 	// Delete the group using the same transaction
-	err = i.MemDBDeleteGroupByNameInTxn(txn, group.Name)
+	err = i.MemDBDeleteGroupByNameInTxn(ctx, txn, group.Name)
 	if err != nil {
 		return err
 	}
@@ -1785,7 +1808,7 @@ func (i *IdentityStore) deleteGroupByName(groupName string) error {
 	return nil
 }
 
-func (i *IdentityStore) MemDBDeleteGroupByNameInTxn(txn *memdb.Txn, groupName string) error {
+func (i *IdentityStore) MemDBDeleteGroupByNameInTxn(ctx context.Context, txn *memdb.Txn, groupName string) error {
 	if groupName == "" {
 		return nil
 	}
@@ -1794,7 +1817,7 @@ func (i *IdentityStore) MemDBDeleteGroupByNameInTxn(txn *memdb.Txn, groupName st
 		return fmt.Errorf("txn is nil")
 	}
 
-	group, err := i.MemDBGroupByNameInTxn(txn, groupName, false)
+	group, err := i.MemDBGroupByNameInTxn(ctx, txn, groupName, false)
 	if err != nil {
 		return err
 	}
@@ -2145,7 +2168,7 @@ func (i *IdentityStore) MemDBGroupIterator(ws memdb.WatchSet) (memdb.ResultItera
 	return iter, nil
 }
 
-func (i *IdentityStore) generateName(entryType string) (string, error) {
+func (i *IdentityStore) generateName(ctx context.Context, entryType string) (string, error) {
 	var name string
 OUTER:
 	for {
@@ -2157,7 +2180,7 @@ OUTER:
 
 		switch entryType {
 		case "entity":
-			entity, err := i.MemDBEntityByName(name, false)
+			entity, err := i.MemDBEntityByName(ctx, name, false)
 			if err != nil {
 				return "", err
 			}
@@ -2165,7 +2188,7 @@ OUTER:
 				break OUTER
 			}
 		case "group":
-			group, err := i.MemDBGroupByName(name, false)
+			group, err := i.MemDBGroupByName(ctx, name, false)
 			if err != nil {
 				return "", err
 			}
@@ -129,6 +129,15 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 		return nil, ErrAlreadyInit
 	}
 
+	// With raft storage this node becomes the first member of a new cluster
+	// so that the writes below can be committed
+	if raftBackend := c.raftBackend(); raftBackend != nil {
+		if err := raftBackend.Bootstrap(ctx); err != nil {
+			c.logger.Error("failed to bootstrap raft storage", "error", err)
+			return nil, errwrap.Wrapf("failed to bootstrap raft storage: {{err}}", err)
+		}
+	}
+
 	err = c.seal.Init(ctx)
 	if err != nil {
 		c.logger.Error("failed to initialize seal", "error", err)
@@ -268,6 +277,11 @@ func (c *Core) UnsealWithStoredKeys(ctx context.Context) error {
 		return nil
 	}
 
+	if c.SealMigrationPending() {
+		c.logger.Warn("seal migration pending, not unsealing with stored keys; unseal with the migrate flag to perform the migration")
+		return nil
+	}
+
 	c.logger.Info("stored unseal keys supported, attempting fetch")
 	keys, err := c.seal.GetStoredKeys(ctx)
 	if err != nil {
@@ -36,6 +36,7 @@ type Key struct {
 	Version     int
 	Value       []byte
 	InstallTime time.Time
+	Encryptions uint64
 }
 
 // Serialize is used to create a byte encoded key
@@ -22,7 +22,9 @@ import (
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/compressutil"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -76,7 +78,9 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"replication/primary/secondary-token",
 				"replication/reindex",
 				"rotate",
+				"rotate/*",
 				"config/cors",
+				"config/control-group",
 				"config/auditing/*",
 				"config/ui/headers/*",
 				"plugins/catalog/*",
@@ -85,6 +89,8 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
 				"leases/lookup/*",
+				"storage/raft/*",
+				"storage/verify",
 			},
 
 			Unauthenticated: []string{
@@ -830,6 +836,55 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "rotate/config$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"enabled": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "Whether the encryption key is rotated automatically.",
+					},
+					"max_operations": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The number of encryptions after which the encryption key is rotated.",
+					},
+					"interval": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: "The time after which the encryption key is rotated. Disabled if zero.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRotateConfigRead,
+					logical.UpdateOperation: b.handleRotateConfigUpdate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate/config"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate/config"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "rotate/reencrypt$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleRotateReencrypt,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate/reencrypt"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate/reencrypt"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "rotate/prune$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleRotatePrune,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate/prune"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate/prune"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "wrapping/wrap$",
 
@@ -1076,6 +1131,12 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	}
 
 	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, b.raftStoragePaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.storageVerifyPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.leaseCountQuotaPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.metricsPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.namespacePaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.controlGroupPaths()...)
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, &framework.Path{
@@ -1516,7 +1577,14 @@ func (b *SystemBackend) handleMountTable(ctx context.Context, req *logical.Reque
 		Data: make(map[string]interface{}),
 	}
 
+	ns := namespaceFromContext(ctx)
 	for _, entry := range b.Core.mounts.Entries {
+		// Only list the mounts of the namespace of the request, along with
+		// the singleton mounts which are routed beneath every namespace
+		if !entry.inNamespace(ns) && !strutil.StrListContains(singletonMounts, entry.Type) {
+			continue
+		}
+
 		// Populate mount info
 		info := map[string]interface{}{
 			"type":        entry.Type,
@@ -1728,17 +1796,18 @@ func handleErrorNoReadOnlyForward(
 func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
 	path = sanitizeMountPath(path)
+	fullPath := namespaceFromContext(ctx).Path + path
 
 	repState := b.Core.ReplicationState()
-	entry := b.Core.router.MatchingMountEntry(path)
+	entry := b.Core.router.MatchingMountEntry(fullPath)
 	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
 	}
 
 	// We return success when the mount does not exists to not expose if the
 	// mount existed or not
-	match := b.Core.router.MatchingMount(path)
-	if match == "" || path != match {
+	match := b.Core.router.MatchingMount(fullPath)
+	if match == "" || fullPath != match {
 		return nil, nil
 	}
 
@@ -1767,7 +1836,7 @@ func (b *SystemBackend) handleRemount(ctx context.Context, req *logical.Request,
 	fromPath = sanitizeMountPath(fromPath)
 	toPath = sanitizeMountPath(toPath)
 
-	entry := b.Core.router.MatchingMountEntry(fromPath)
+	entry := b.Core.router.MatchingMountEntry(namespaceFromContext(ctx).Path + fromPath)
 	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot remount a non-local mount on a replication secondary"), nil
 	}
@@ -1789,7 +1858,7 @@ func (b *SystemBackend) handleAuthTuneRead(ctx context.Context, req *logical.Req
 				"path must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
-	return b.handleTuneReadCommon("auth/" + path)
+	return b.handleTuneReadCommon(ctx, "auth/"+path)
 }
 
 // handleMountTuneRead is used to get config settings on a backend
@@ -1804,21 +1873,24 @@ func (b *SystemBackend) handleMountTuneRead(ctx context.Context, req *logical.Re
 	// This call will read both logical backend's configuration as well as auth methods'.
 	// Retaining this behavior for backward compatibility. If this behavior is not desired,
 	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneReadCommon(path)
+	return b.handleTuneReadCommon(ctx, path)
 }
 
 // handleTuneReadCommon returns the config settings of a path
-func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, error) {
+func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (*logical.Response, error) {
 	path = sanitizeMountPath(path)
+	ns := namespaceFromContext(ctx)
 
-	sysView := b.Core.router.MatchingSystemView(path)
+	sysView := b.Core.router.MatchingSystemView(ns.Path + path)
 	if sysView == nil {
 		b.Backend.Logger().Error("cannot fetch sysview", "path", path)
 		return handleError(fmt.Errorf("sys: cannot fetch sysview for path %q", path))
 	}
 
-	mountEntry := b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
+	// The singleton mounts routed beneath a namespace belong to the root
+	// namespace and cannot be inspected from within it
+	mountEntry := b.Core.router.MatchingMountEntry(ns.Path + path)
+	if mountEntry == nil || !mountEntry.inNamespace(ns) {
 		b.Backend.Logger().Error("cannot fetch mount entry", "path", path)
 		return handleError(fmt.Errorf("sys: cannot fetch mount entry for path %q", path))
 	}
@@ -1891,8 +1963,9 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
-	mountEntry := b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
+	ns := namespaceFromContext(ctx)
+	mountEntry := b.Core.router.MatchingMountEntry(ns.Path + path)
+	if mountEntry == nil || !mountEntry.inNamespace(ns) {
 		b.Backend.Logger().Error("tune failed: no mount entry found", "path", path)
 		return handleError(fmt.Errorf("tune of path %q failed: no mount entry found", path))
 	}
@@ -1912,8 +1985,8 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 	defer lock.Unlock()
 
 	// Check again after grabbing the lock
-	mountEntry = b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
+	mountEntry = b.Core.router.MatchingMountEntry(ns.Path + path)
+	if mountEntry == nil || !mountEntry.inNamespace(ns) {
 		b.Backend.Logger().Error("tune failed: no mount entry found", "path", path)
 		return handleError(fmt.Errorf("tune of path %q failed: no mount entry found", path))
 	}
@@ -2151,6 +2224,10 @@ func (b *SystemBackend) handleLeaseLookup(ctx context.Context, req *logical.Requ
 			logical.ErrInvalidRequest
 	}
 
+	if !leaseInNamespace(ctx, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
+
 	leaseTimes, err := b.Core.expiration.FetchLeaseTimes(leaseID)
 	if err != nil {
 		b.Backend.Logger().Error("error retrieving lease", "lease_id", leaseID, "error", err)
@@ -2187,6 +2264,7 @@ func (b *SystemBackend) handleLeaseLookupList(ctx context.Context, req *logical.
 	if prefix != "" && !strings.HasSuffix(prefix, "/") {
 		prefix = prefix + "/"
 	}
+	prefix = namespaceFromContext(ctx).Path + prefix
 
 	keys, err := b.Core.expiration.idView.List(ctx, prefix)
 	if err != nil {
@@ -2212,6 +2290,10 @@ func (b *SystemBackend) handleRenew(ctx context.Context, req *logical.Request, d
 	// Convert the increment
 	increment := time.Duration(incrementRaw) * time.Second
 
+	if !leaseInNamespace(ctx, leaseID) {
+		return logical.ErrorResponse("lease not found or lease is not renewable"), logical.ErrInvalidRequest
+	}
+
 	// Invoke the expiration manager directly
 	resp, err := b.Core.expiration.Renew(leaseID, increment)
 	if err != nil {
@@ -2233,6 +2315,10 @@ func (b *SystemBackend) handleRevoke(ctx context.Context, req *logical.Request,
 			logical.ErrInvalidRequest
 	}
 
+	if !leaseInNamespace(ctx, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
+
 	// Invoke the expiration manager directly
 	if err := b.Core.expiration.Revoke(leaseID); err != nil {
 		b.Backend.Logger().Error("lease revocation failed", "lease_id", leaseID, "error", err)
@@ -2243,19 +2329,19 @@ func (b *SystemBackend) handleRevoke(ctx context.Context, req *logical.Request,
 
 // handleRevokePrefix is used to revoke a prefix with many LeaseIDs
 func (b *SystemBackend) handleRevokePrefix(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRevokePrefixCommon(req, data, false)
+	return b.handleRevokePrefixCommon(ctx, req, data, false)
 }
 
 // handleRevokeForce is used to revoke a prefix with many LeaseIDs, ignoring errors
 func (b *SystemBackend) handleRevokeForce(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRevokePrefixCommon(req, data, true)
+	return b.handleRevokePrefixCommon(ctx, req, data, true)
 }
 
 // handleRevokePrefixCommon is used to revoke a prefix with many LeaseIDs
-func (b *SystemBackend) handleRevokePrefixCommon(
+func (b *SystemBackend) handleRevokePrefixCommon(ctx context.Context,
 	req *logical.Request, data *framework.FieldData, force bool) (*logical.Response, error) {
-	// Get all the options
-	prefix := data.Get("prefix").(string)
+	// Get all the options. The prefix is relative to the namespace.
+	prefix := namespaceFromContext(ctx).Path + data.Get("prefix").(string)
 
 	// Invoke the expiration manager directly
 	var err error
@@ -2279,7 +2365,12 @@ func (b *SystemBackend) handleAuthTable(ctx context.Context, req *logical.Reques
 	resp := &logical.Response{
 		Data: make(map[string]interface{}),
 	}
+	ns := namespaceFromContext(ctx)
 	for _, entry := range b.Core.auth.Entries {
+		if !entry.inNamespace(ns) && !strutil.StrListContains(singletonMounts, entry.Type) {
+			continue
+		}
+
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
@@ -2443,7 +2534,7 @@ func (b *SystemBackend) handleDisableAuth(ctx context.Context, req *logical.Requ
 	path := data.Get("path").(string)
 	path = sanitizeMountPath(path)
 
-	fullPath := credentialRoutePrefix + path
+	fullPath := namespaceFromContext(ctx).Path + credentialRoutePrefix + path
 
 	repState := b.Core.ReplicationState()
 	entry := b.Core.router.MatchingMountEntry(fullPath)
@@ -2471,8 +2562,10 @@ func (b *SystemBackend) handlePolicyList(ctx context.Context, req *logical.Reque
 	// Get all the configured policies
 	policies, err := b.Core.policyStore.ListPolicies(ctx, PolicyTypeACL)
 
-	// Add the special "root" policy
-	policies = append(policies, "root")
+	// Add the special "root" policy, which only exists in the root namespace
+	if namespaceFromContext(ctx).ID == namespace.RootNamespaceID {
+		policies = append(policies, "root")
+	}
 	resp := logical.ListResponse(policies)
 
 	// Backwords compatibility
@@ -2921,8 +3014,29 @@ func (b *SystemBackend) handleKeyStatus(ctx context.Context, req *logical.Reques
 		Data: map[string]interface{}{
 			"term":         info.Term,
 			"install_time": info.InstallTime.Format(time.RFC3339Nano),
+			"encryptions":  info.Encryptions,
 		},
 	}
+
+	if status := b.Core.ReencryptStatus(); status != nil {
+		reencryption := map[string]interface{}{
+			"term":        status.Term,
+			"start_time":  status.StartTime.Format(time.RFC3339Nano),
+			"running":     status.Running,
+			"complete":    status.Complete,
+			"scanned":     status.Scanned,
+			"reencrypted": status.Reencrypted,
+			"failed":      status.Failed,
+		}
+		if !status.EndTime.IsZero() {
+			reencryption["end_time"] = status.EndTime.Format(time.RFC3339Nano)
+		}
+		if status.Error != "" {
+			reencryption["error"] = status.Error
+		}
+		resp.Data["reencryption"] = reencryption
+	}
+
 	return resp, nil
 }
 
@@ -2933,42 +3047,109 @@ func (b *SystemBackend) handleRotate(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse("cannot rotate on a replication secondary"), nil
 	}
 
-	// Rotate to the new term
-	newTerm, err := b.Core.barrier.Rotate(ctx)
-	if err != nil {
-		b.Backend.Logger().Error("failed to create new encryption key", "error", err)
+	if _, err := b.Core.rotateBarrierKey(ctx); err != nil {
 		return handleError(err)
 	}
-	b.Backend.Logger().Info("installed new encryption key")
 
-	// In HA mode, we need to an upgrade path for the standby instances
-	if b.Core.ha != nil {
-		// Create the upgrade path to the new term
-		if err := b.Core.barrier.CreateUpgrade(ctx, newTerm); err != nil {
-			b.Backend.Logger().Error("failed to create new upgrade", "term", newTerm, "error", err)
-		}
+	return nil, nil
+}
 
-		// Schedule the destroy of the upgrade path
-		time.AfterFunc(keyRotateGracePeriod, func() {
-			if err := b.Core.barrier.DestroyUpgrade(ctx, newTerm); err != nil {
-				b.Backend.Logger().Error("failed to destroy upgrade", "term", newTerm, "error", err)
-			}
-		})
+// handleRotateConfigRead returns the automatic key rotation configuration
+func (b *SystemBackend) handleRotateConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.loadKeyRotationConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":        !config.Disabled,
+			"max_operations": config.MaxOperations,
+			"interval":       int64(config.Interval.Seconds()),
+		},
+	}, nil
+}
+
+// handleRotateConfigUpdate stores the automatic key rotation configuration
+func (b *SystemBackend) handleRotateConfigUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.loadKeyRotationConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Disabled = !enabledRaw.(bool)
+	}
+	if maxOpsRaw, ok := data.GetOk("max_operations"); ok {
+		config.MaxOperations = int64(maxOpsRaw.(int))
+	}
+	if intervalRaw, ok := data.GetOk("interval"); ok {
+		config.Interval = time.Duration(intervalRaw.(int)) * time.Second
+	}
+
+	switch {
+	case config.MaxOperations < 0:
+		return logical.ErrorResponse("max_operations cannot be negative"), logical.ErrInvalidRequest
+	case config.MaxOperations > defaultKeyRotationMaxOperations:
+		return logical.ErrorResponse(fmt.Sprintf("max_operations cannot be larger than %d", int64(defaultKeyRotationMaxOperations))), logical.ErrInvalidRequest
+	case config.Interval < 0:
+		return logical.ErrorResponse("interval cannot be negative"), logical.ErrInvalidRequest
+	case config.Interval > 0 && config.Interval < minimumKeyRotationInterval:
+		return logical.ErrorResponse(fmt.Sprintf("interval must be at least %s", minimumKeyRotationInterval)), logical.ErrInvalidRequest
 	}
 
-	// Write to the canary path, which will force a synchronous truing during
-	// replication
-	if err := b.Core.barrier.Put(ctx, &Entry{
-		Key:   coreKeyringCanaryPath,
-		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
-	}); err != nil {
-		b.Core.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, errwrap.Wrapf("failed to save keyring canary: {{err}}", err)
+	entry, err := logical.StorageEntryJSON(keyRotationConfigPath, config)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to create key rotation config entry: {{err}}", err)
+	}
+	if err := b.Core.systemBarrierView.SubView("config/").Put(ctx, entry); err != nil {
+		return nil, errwrap.Wrapf("failed to save key rotation config: {{err}}", err)
 	}
 
 	return nil, nil
 }
 
+// handleRotateReencrypt starts re-encrypting the data written under older
+// key terms with the active key
+func (b *SystemBackend) handleRotateReencrypt(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.ReplicationState()
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot re-encrypt on a replication secondary"), nil
+	}
+
+	if err := b.Core.startReencrypt(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+// handleRotatePrune removes the key terms no longer used to encrypt any data
+// from the keyring
+func (b *SystemBackend) handleRotatePrune(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.ReplicationState()
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot prune the keyring on a replication secondary"), nil
+	}
+
+	removed, err := b.Core.pruneKeyring(ctx)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if len(removed) > 0 {
+		b.Backend.Logger().Info("removed encryption keys", "terms", removed)
+	}
+
+	if removed == nil {
+		removed = []uint32{}
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"removed_terms": removed,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) handleWrappingPubkey(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	x, _ := b.Core.wrappingJWTKey.X.MarshalText()
 	y, _ := b.Core.wrappingJWTKey.Y.MarshalText()
@@ -3033,6 +3214,8 @@ func (b *SystemBackend) handleWrappingUnwrap(ctx context.Context, req *logical.R
 	switch te.Policies[0] {
 	case responseWrappingPolicyName:
 		response, err = b.responseWrappingUnwrap(ctx, token, thirdParty)
+	case controlGroupPolicyName:
+		response, err = b.controlGroupUnwrap(ctx, token)
 	}
 	if err != nil {
 		var respErr *logical.Response
@@ -3368,8 +3551,9 @@ func (b *SystemBackend) pathInternalUIMountsRead(ctx context.Context, req *logic
 	resp.Data["secret"] = secretMounts
 	resp.Data["auth"] = authMounts
 
+	ns := namespaceFromContext(ctx)
 	for _, entry := range b.Core.mounts.Entries {
-		if entry.Config.ListingVisibility == ListingVisibilityUnauth {
+		if entry.inNamespace(ns) && entry.Config.ListingVisibility == ListingVisibilityUnauth {
 			info := map[string]interface{}{
 				"type":        entry.Type,
 				"description": entry.Description,
@@ -3379,7 +3563,7 @@ func (b *SystemBackend) pathInternalUIMountsRead(ctx context.Context, req *logic
 	}
 
 	for _, entry := range b.Core.auth.Entries {
-		if entry.Config.ListingVisibility == ListingVisibilityUnauth {
+		if entry.inNamespace(ns) && entry.Config.ListingVisibility == ListingVisibilityUnauth {
 			info := map[string]interface{}{
 				"type":        entry.Type,
 				"description": entry.Description,
@@ -3845,6 +4029,157 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"rotate/config": {
+		"Configures the automatic rotation of the backend encryption key.",
+		`
+		The backend encryption key is rotated automatically by the active node
+		once it has been used for max_operations encryptions, or once it is
+		older than interval. The rotation is recorded in the audit log.
+		`,
+	},
+
+	"rotate/reencrypt": {
+		"Re-encrypts the data written under older backend encryption keys.",
+		`
+		Starts a background job that rewrites all data encrypted using an older
+		encryption key with the active key. The progress of the job is reported
+		by the key-status endpoint.
+		`,
+	},
+
+	"rotate/prune": {
+		"Removes backend encryption keys that are no longer in use.",
+		`
+		Removes the encryption keys older than the key that was active when the
+		last re-encryption was started. This requires the re-encryption to have
+		completed without failures since the last unseal.
+		`,
+	},
+
+	"raft-join": {
+		"Adds a node to the raft storage cluster.",
+		`
+		Adds the node with the given ID and raft address as a voting member of
+		the raft storage cluster. The node must be running Vault with raft
+		storage and must not have been initialized on its own. This must be
+		called on the active node.
+		`,
+	},
+
+	"raft-remove-peer": {
+		"Removes a node from the raft storage cluster.",
+		`
+		Removes the node with the given ID from the raft storage cluster. This
+		must be called on the active node.
+		`,
+	},
+
+	"raft-configuration": {
+		"Returns the members of the raft storage cluster.",
+		"",
+	},
+
+	"raft-snapshot": {
+		"Saves or restores a snapshot of the raft storage.",
+		`
+		Reading this path returns a snapshot of the raft storage as a binary
+		file. Writing a base64-encoded snapshot to this path replaces the
+		contents of the raft storage on every node in the cluster. The
+		snapshot must have been taken from a cluster with the same unseal
+		keys.
+		`,
+	},
+
+	"storage-verify": {
+		"Verifies that every storage entry can be decrypted.",
+		`
+		Reading this path reads every storage entry through the barrier and
+		checks that it decrypts, and for the entries of a known format, such
+		as the mount tables, tokens and leases, that it decodes. It returns the
+		entries that failed along with the key term they claim to be encrypted
+		under. Nothing is modified.
+		`,
+	},
+
+	"lease-count-quotas": {
+		"Create, read, update or delete a lease count quota.",
+		`
+		A lease count quota limits the number of leases, including the leases
+		of tokens, that can exist under a mount path or a namespace. Requests
+		that would create a lease beyond the limit fail with a 429 status code.
+		Reading a quota also returns the current number of leases under its
+		path. Root tokens are exempt from the quotas.
+		`,
+	},
+
+	"lease-count-quotas-list": {
+		"Lists the lease count quotas.",
+		"",
+	},
+
+	"metrics": {
+		"Export the metrics aggregated for telemetry purpose.",
+		`
+		Returns the metrics retained in memory by this server. By default the
+		most recently completed aggregation interval is returned as JSON.
+		With format=prometheus, running values are returned in the Prometheus
+		text exposition format; this requires prometheus_retention_time to be
+		set in the telemetry configuration.
+		`,
+	},
+
+	"namespaces": {
+		"Create, read or delete a child namespace.",
+		`
+		Namespaces are isolated environments within Vault, each having its own
+		secrets engines, auth methods, policies, tokens and identities. A
+		namespace is created as a child of the namespace of the request and
+		can only be deleted once it contains no namespaces, secrets engines
+		or auth methods.
+		`,
+	},
+
+	"namespaces-list": {
+		"Lists the child namespaces of the namespace of the request.",
+		"",
+	},
+
+	"config/control-group": {
+		"Configures control groups.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the control group configuration.
+
+    POST /
+        Sets the maximum TTL of control group wrapping tokens.
+
+    DELETE /
+        Clears the control group configuration.
+		`,
+	},
+
+	"control-group/authorize": {
+		"Authorizes a control group request.",
+		`
+The entity of the calling token is recorded as an authorizer of the request
+held back by the control group wrapping token with the given accessor. The
+entity must be a member of a group named by one of the factors of the control
+group. The request is processed when the wrapping token is unwrapped once all
+factors are satisfied.
+		`,
+	},
+
+	"control-group/request": {
+		"Returns the status of a control group request.",
+		`
+Returns the path and the requesting entity of the request held back by the
+control group wrapping token with the given accessor, along with the entities
+that have authorized it and whether it has been approved.
+		`,
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
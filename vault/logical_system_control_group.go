@@ -0,0 +1,298 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// controlGroupPaths returns the paths used to authorize control group
+// requests and to configure control groups
+func (b *SystemBackend) controlGroupPaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "config/control-group$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"max_ttl": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Description: "The maximum TTL of a control group wrapping token.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleControlGroupConfigRead,
+				logical.UpdateOperation: b.handleControlGroupConfigUpdate,
+				logical.DeleteOperation: b.handleControlGroupConfigDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["config/control-group"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["config/control-group"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "control-group/authorize$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Accessor of the control group wrapping token.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupAuthorize,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group/authorize"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group/authorize"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "control-group/request$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Accessor of the control group wrapping token.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupRequest,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group/request"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group/request"][1]),
+		},
+	}
+}
+
+// handleControlGroupConfigRead returns the control group configuration
+func (b *SystemBackend) handleControlGroupConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.loadControlGroupConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_ttl": int64(config.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+// handleControlGroupConfigUpdate stores the control group configuration
+func (b *SystemBackend) handleControlGroupConfigUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.loadControlGroupConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		config.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if config.MaxTTL < 0 {
+		return logical.ErrorResponse("max_ttl cannot be negative"), logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON(controlGroupConfigPath, config)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to create control group config entry: {{err}}", err)
+	}
+	if err := b.Core.systemBarrierView.SubView("config/").Put(ctx, entry); err != nil {
+		return nil, errwrap.Wrapf("failed to save control group config: {{err}}", err)
+	}
+
+	return nil, nil
+}
+
+// handleControlGroupConfigDelete removes the control group configuration
+func (b *SystemBackend) handleControlGroupConfigDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.systemBarrierView.SubView("config/").Delete(ctx, controlGroupConfigPath); err != nil {
+		return nil, errwrap.Wrapf("failed to delete control group config: {{err}}", err)
+	}
+
+	return nil, nil
+}
+
+// handleControlGroupAuthorize records the authorization of a control group
+// request by the entity of the calling token
+func (b *SystemBackend) handleControlGroupAuthorize(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	accessor := d.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+	}
+	if req.EntityID == "" {
+		return logical.ErrorResponse("authorizing a control group request requires a token associated with an entity"), logical.ErrInvalidRequest
+	}
+
+	b.Core.controlGroupLock.Lock()
+	defer b.Core.controlGroupLock.Unlock()
+
+	te, cgReq, err := b.Core.controlGroupRequestByAccessor(ctx, accessor)
+	switch {
+	case err == errControlGroupNotFound:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	case err != nil:
+		return nil, err
+	}
+
+	if req.EntityID == cgReq.EntityID {
+		return logical.ErrorResponse("requesters cannot authorize their own control group request"), logical.ErrPermissionDenied
+	}
+
+	factors, err := b.Core.controlGroupFactorsForEntity(cgReq, req.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if len(factors) == 0 {
+		return logical.ErrorResponse("entity is not a member of any group that can authorize this request"), logical.ErrPermissionDenied
+	}
+
+	authorized := false
+	for _, authz := range cgReq.Authorizations {
+		if authz.EntityID == req.EntityID {
+			authorized = true
+			break
+		}
+	}
+	if !authorized {
+		cgReq.Authorizations = append(cgReq.Authorizations, &controlGroupAuthorization{
+			EntityID: req.EntityID,
+			Time:     time.Now(),
+		})
+		if err := b.Core.storeControlGroupRequest(ctx, te.ID, cgReq); err != nil {
+			return nil, errwrap.Wrapf("failed to store control group authorization: {{err}}", err)
+		}
+	}
+
+	approved, err := b.Core.controlGroupApproved(cgReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"approved": approved,
+		},
+	}, nil
+}
+
+// handleControlGroupRequest returns the status of a control group request
+func (b *SystemBackend) handleControlGroupRequest(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	accessor := d.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+	}
+
+	_, cgReq, err := b.Core.controlGroupRequestByAccessor(ctx, accessor)
+	switch {
+	case err == errControlGroupNotFound:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	case err != nil:
+		return nil, err
+	}
+
+	approved, err := b.Core.controlGroupApproved(cgReq)
+	if err != nil {
+		return nil, err
+	}
+
+	requestEntity := map[string]interface{}{
+		"id":   cgReq.EntityID,
+		"name": "",
+	}
+	if cgReq.EntityID != "" {
+		entity, err := b.Core.identityStore.MemDBEntityByID(cgReq.EntityID, false)
+		if err != nil {
+			return nil, err
+		}
+		if entity != nil {
+			requestEntity["name"] = entity.Name
+		}
+	}
+
+	authorizations := make([]map[string]interface{}, 0, len(cgReq.Authorizations))
+	for _, authz := range cgReq.Authorizations {
+		var entityName string
+		entity, err := b.Core.identityStore.MemDBEntityByID(authz.EntityID, false)
+		if err != nil {
+			return nil, err
+		}
+		if entity != nil {
+			entityName = entity.Name
+		}
+		authorizations = append(authorizations, map[string]interface{}{
+			"entity_id":   authz.EntityID,
+			"entity_name": entityName,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"approved":       approved,
+			"request_path":   cgReq.Path,
+			"request_entity": requestEntity,
+			"authorizations": authorizations,
+		},
+	}, nil
+}
+
+// controlGroupUnwrap processes the request held back by a control group
+// wrapping token once the control group has been satisfied, returning the
+// raw HTTP response. The token is revoked afterwards.
+func (b *SystemBackend) controlGroupUnwrap(ctx context.Context, token string) (string, error) {
+	cgReq, err := b.Core.loadControlGroupRequest(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	approved, err := b.Core.controlGroupApproved(cgReq)
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		return "control group request needs further authorization", logical.ErrPermissionDenied
+	}
+
+	defer b.Core.tokenStore.Revoke(ctx, token)
+
+	req := &logical.Request{
+		ID:          cgReq.ID,
+		Operation:   cgReq.Operation,
+		Path:        cgReq.Path,
+		Data:        cgReq.Data,
+		ClientToken: cgReq.ClientToken,
+	}
+
+	replayCtx := namespace.ContextWithNamespace(ctx, b.Core.namespaceByPath(req.Path))
+	replayCtx = context.WithValue(replayCtx, contextControlGroupSatisfied, true)
+
+	resp, _, err := b.Core.handleRequest(replayCtx, req)
+	if err != nil {
+		if resp != nil && resp.IsError() {
+			return resp.Error().Error(), err
+		}
+		return "", err
+	}
+	if resp == nil {
+		return "", nil
+	}
+
+	httpResponse := logical.LogicalResponseToHTTPResponse(resp)
+	httpResponse.RequestID = req.ID
+
+	marshaledResponse, err := json.Marshal(httpResponse)
+	if err != nil {
+		return "", errwrap.Wrapf("failed to marshal control group response: {{err}}", err)
+	}
+
+	return string(marshaledResponse), nil
+}
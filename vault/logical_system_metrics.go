@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// metricsPath returns the path used to read the server's metrics
+func (b *SystemBackend) metricsPath() *framework.Path {
+	return &framework.Path{
+		Pattern: "metrics$",
+
+		Fields: map[string]*framework.FieldSchema{
+			"format": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Format to export metrics into. Currently accepts only \"prometheus\".",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.handleMetricsQuery,
+		},
+
+		HelpSynopsis:    strings.TrimSpace(sysHelp["metrics"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["metrics"][1]),
+	}
+}
+
+func (b *SystemBackend) handleMetricsQuery(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.Core.MetricsResponse(d.Get("format").(string))
+}
+
+// MetricsResponse returns the metrics retained in memory as a raw response in
+// the given format, which is JSON if empty
+func (c *Core) MetricsResponse(format string) (*logical.Response, error) {
+	if c.metricsHelper == nil {
+		return logical.ErrorResponse("metrics are not enabled on this server"), logical.ErrInvalidRequest
+	}
+
+	switch format {
+	case metricsutil.PrometheusMetricFormat:
+		if !c.metricsHelper.PrometheusEnabled() {
+			return logical.ErrorResponse(metricsutil.ErrPrometheusDisabled.Error()), logical.ErrInvalidRequest
+		}
+
+		var buf bytes.Buffer
+		if err := c.metricsHelper.WritePrometheus(&buf); err != nil {
+			return nil, errwrap.Wrapf("error formatting metrics: {{err}}", err)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPContentType: metricsutil.PrometheusContentType,
+				logical.HTTPRawBody:     buf.Bytes(),
+				logical.HTTPStatusCode:  http.StatusOK,
+			},
+		}, nil
+
+	case "":
+		summary, err := c.metricsHelper.Summary()
+		if err != nil {
+			return nil, errwrap.Wrapf("error reading metrics: {{err}}", err)
+		}
+		body, err := jsonutil.EncodeJSON(summary)
+		if err != nil {
+			return nil, errwrap.Wrapf("error encoding metrics: {{err}}", err)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPContentType: "application/json",
+				logical.HTTPRawBody:     body,
+				logical.HTTPStatusCode:  http.StatusOK,
+			},
+		}, nil
+
+	default:
+		return logical.ErrorResponse("unsupported metrics format"), logical.ErrInvalidRequest
+	}
+}
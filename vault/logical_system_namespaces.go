@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// namespacePaths returns the paths used to manage the child namespaces of
+// the namespace of the request
+func (b *SystemBackend) namespacePaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "namespaces/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleNamespacesList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "namespaces/(?P<path>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the namespace, relative to the namespace of the request.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleNamespacesRead,
+				logical.UpdateOperation: b.handleNamespacesCreate,
+				logical.DeleteOperation: b.handleNamespacesDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces"][1]),
+		},
+	}
+}
+
+// handleNamespacesList lists the direct children of the request namespace
+func (b *SystemBackend) handleNamespacesList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	parent := namespaceFromContext(ctx)
+
+	var keys []string
+	for _, ns := range b.Core.listNamespaces(parent) {
+		keys = append(keys, strings.TrimPrefix(ns.Path, parent.Path))
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+// handleNamespacesRead returns the details of a child namespace
+func (b *SystemBackend) handleNamespacesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	parent := namespaceFromContext(ctx)
+	name := strings.Trim(d.Get("path").(string), "/")
+
+	for _, ns := range b.Core.listNamespaces(parent) {
+		if ns.Path != parent.Path+name+"/" {
+			continue
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"id":   ns.ID,
+				"path": ns.Path,
+			},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// handleNamespacesCreate creates a child namespace
+func (b *SystemBackend) handleNamespacesCreate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := b.Core.createNamespace(ctx, d.Get("path").(string))
+	if err != nil {
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   ns.ID,
+			"path": ns.Path,
+		},
+	}, nil
+}
+
+// handleNamespacesDelete deletes an empty child namespace
+func (b *SystemBackend) handleNamespacesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	err := b.Core.deleteNamespace(ctx, d.Get("path").(string))
+	switch err {
+	case nil:
+		return nil, nil
+	case errNamespaceNotEmpty:
+		return logical.ErrorResponse("namespace must not contain any namespaces, secrets engines or auth methods"), logical.ErrInvalidRequest
+	default:
+		return handleError(err)
+	}
+}
@@ -0,0 +1,153 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// leaseCountQuotaPaths returns the paths used to manage the lease count
+// quotas
+func (b *SystemBackend) leaseCountQuotaPaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "quotas/lease-count/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleLeaseCountQuotasList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quotas-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lease-count-quotas-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "quotas/lease-count/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the quota rule.",
+				},
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Mount path or namespace path the quota applies to. If empty, the quota applies to every lease.",
+				},
+				"max_leases": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum number of leases, including the leases of tokens, that can exist under the path.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleLeaseCountQuotaRead,
+				logical.UpdateOperation: b.handleLeaseCountQuotaUpdate,
+				logical.DeleteOperation: b.handleLeaseCountQuotaDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quotas"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lease-count-quotas"][1]),
+		},
+	}
+}
+
+// leaseCountQuotaStore returns the lease count quotas. Performance standbys
+// do not count leases, so there it returns a read-only error to have the
+// request forwarded to the active node.
+func (b *SystemBackend) leaseCountQuotaStore() (*leaseCountQuotaStore, error) {
+	if b.Core.leaseCountQuotas == nil {
+		return nil, logical.ErrReadOnly
+	}
+	return b.Core.leaseCountQuotas, nil
+}
+
+// handleLeaseCountQuotasList lists the names of the lease count quotas
+func (b *SystemBackend) handleLeaseCountQuotasList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	quotas, err := b.leaseCountQuotaStore()
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(quotas.list()), nil
+}
+
+// handleLeaseCountQuotaRead returns a lease count quota along with the
+// current number of leases under its path
+func (b *SystemBackend) handleLeaseCountQuotaRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	quotas, err := b.leaseCountQuotaStore()
+	if err != nil {
+		return nil, err
+	}
+
+	rule := quotas.get(d.Get("name").(string))
+	if rule == nil {
+		return nil, nil
+	}
+
+	var count int64
+	if b.Core.expiration != nil {
+		count = b.Core.expiration.leaseCount(rule.Path)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":       rule.Name,
+			"path":       rule.Path,
+			"max_leases": rule.MaxLeases,
+			"count":      count,
+		},
+	}, nil
+}
+
+// handleLeaseCountQuotaUpdate creates or updates a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	quotas, err := b.leaseCountQuotaStore()
+	if err != nil {
+		return nil, err
+	}
+
+	name := d.Get("name").(string)
+	rule := quotas.get(name)
+	if rule == nil {
+		rule = &LeaseCountQuota{
+			Name: name,
+		}
+		if _, ok := d.GetOk("max_leases"); !ok {
+			return logical.ErrorResponse("missing max_leases"), logical.ErrInvalidRequest
+		}
+	}
+
+	if pathRaw, ok := d.GetOk("path"); ok {
+		path, err := b.Core.normalizeLeaseCountQuotaPath(pathRaw.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		rule.Path = path
+	}
+
+	if maxLeasesRaw, ok := d.GetOk("max_leases"); ok {
+		rule.MaxLeases = int64(maxLeasesRaw.(int))
+	}
+	if rule.MaxLeases <= 0 {
+		return logical.ErrorResponse("max_leases must be greater than zero"), logical.ErrInvalidRequest
+	}
+
+	if err := quotas.set(ctx, rule); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleLeaseCountQuotaDelete deletes a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	quotas, err := b.leaseCountQuotaStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := quotas.delete(ctx, d.Get("name").(string)); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,221 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/raft"
+)
+
+// raftStoragePaths returns the paths used to manage the raft storage backend
+func (b *SystemBackend) raftStoragePaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "storage/raft/join$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"node_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The ID of the node joining the cluster.",
+				},
+				"address": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft address of the node joining the cluster.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftJoin,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-join"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-join"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/remove-peer$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"node_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The ID of the node to remove from the cluster.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftRemovePeer,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-remove-peer"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-remove-peer"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/configuration$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleRaftConfigurationRead,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-configuration"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-configuration"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/snapshot$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"snapshot": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Base64-encoded snapshot to restore.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleRaftSnapshotRead,
+				logical.UpdateOperation: b.handleRaftSnapshotRestore,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-snapshot"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-snapshot"][1]),
+		},
+	}
+}
+
+// raftBackend returns the raft storage backend, or nil if Vault is not
+// using raft for storage
+func (c *Core) raftBackend() *raft.RaftBackend {
+	// The storage may be wrapped, for instance to emit metrics
+	backend := c.underlyingPhysical
+	if wrapper, ok := backend.(interface{ Underlying() physical.Backend }); ok {
+		backend = wrapper.Underlying()
+	}
+	raftBackend, _ := backend.(*raft.RaftBackend)
+	return raftBackend
+}
+
+func (b *SystemBackend) handleRaftJoin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	raftBackend := b.Core.raftBackend()
+	if raftBackend == nil {
+		return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+	}
+
+	nodeID := d.Get("node_id").(string)
+	if nodeID == "" {
+		return logical.ErrorResponse("missing node_id"), logical.ErrInvalidRequest
+	}
+	address := d.Get("address").(string)
+	if address == "" {
+		return logical.ErrorResponse("missing address"), logical.ErrInvalidRequest
+	}
+
+	if err := raftBackend.AddPeer(ctx, nodeID, address); err != nil {
+		return nil, errwrap.Wrapf("failed to add raft peer: {{err}}", err)
+	}
+
+	return nil, nil
+}
+
+func (b *SystemBackend) handleRaftRemovePeer(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	raftBackend := b.Core.raftBackend()
+	if raftBackend == nil {
+		return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+	}
+
+	nodeID := d.Get("node_id").(string)
+	if nodeID == "" {
+		return logical.ErrorResponse("missing node_id"), logical.ErrInvalidRequest
+	}
+
+	if err := raftBackend.RemovePeer(ctx, nodeID); err != nil {
+		return nil, errwrap.Wrapf("failed to remove raft peer: {{err}}", err)
+	}
+
+	return nil, nil
+}
+
+func (b *SystemBackend) handleRaftConfigurationRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	raftBackend := b.Core.raftBackend()
+	if raftBackend == nil {
+		return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+	}
+
+	peers, err := raftBackend.Peers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]map[string]interface{}, 0, len(peers))
+	for _, peer := range peers {
+		servers = append(servers, map[string]interface{}{
+			"node_id": peer.ID,
+			"address": peer.Address,
+			"leader":  peer.Leader,
+			"voter":   peer.Voter,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"servers": servers,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleRaftSnapshotRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	raftBackend := b.Core.raftBackend()
+	if raftBackend == nil {
+		return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+	}
+
+	var buf bytes.Buffer
+	if err := raftBackend.Snapshot(&buf); err != nil {
+		return nil, errwrap.Wrapf("failed to take raft snapshot: {{err}}", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/octet-stream",
+			logical.HTTPRawBody:     buf.Bytes(),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleRaftSnapshotRestore(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	raftBackend := b.Core.raftBackend()
+	if raftBackend == nil {
+		return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+	}
+
+	snapshot, err := base64.StdEncoding.DecodeString(d.Get("snapshot").(string))
+	if err != nil {
+		return logical.ErrorResponse("snapshot must be base64-encoded"), logical.ErrInvalidRequest
+	}
+	if len(snapshot) == 0 {
+		return logical.ErrorResponse("missing snapshot"), logical.ErrInvalidRequest
+	}
+
+	if err := raftBackend.RestoreSnapshot(ctx, bytes.NewReader(snapshot)); err != nil {
+		return nil, errwrap.Wrapf("failed to restore raft snapshot: {{err}}", err)
+	}
+
+	// Everything underneath the barrier has changed, so drop any cached
+	// entries and pick up the restored keyring
+	b.Core.physicalCache.Purge(ctx)
+	if err := b.Core.barrier.ReloadMasterKey(ctx); err != nil {
+		return nil, errwrap.Wrapf("error reloading master key: {{err}}", err)
+	}
+	if err := b.Core.barrier.ReloadKeyring(ctx); err != nil {
+		return nil, errwrap.Wrapf("error reloading keyring: {{err}}", err)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical/raft"
+)
+
+// testRaftWaitActive waits for the core to become active, allowing for a raft
+// election to take place first
+func testRaftWaitActive(t *testing.T, core *Core) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		standby, err := core.Standby()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !standby {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for core to become active")
+}
+
+func TestSystemBackend_RaftStorage(t *testing.T) {
+	raftDir, err := ioutil.TempDir("", "vault-raft-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(raftDir)
+
+	logger := logging.NewVaultLogger(log.Trace)
+	backend, err := raft.NewRaftBackend(map[string]string{
+		"path":    raftDir,
+		"address": "127.0.0.1:0",
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raftBackend := backend.(*raft.RaftBackend)
+	defer raftBackend.Close()
+
+	conf := testCoreConfig(t, raftBackend, logger)
+	conf.HAPhysical = raftBackend
+	conf.RedirectAddr = "http://127.0.0.1:8200"
+	core, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, root := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testRaftWaitActive(t, core)
+
+	ctx := context.Background()
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/storage/raft/configuration")
+	req.ClientToken = root
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := resp.Data["servers"].([]map[string]interface{})
+	if len(servers) != 1 || servers[0]["node_id"] != raftBackend.NodeID() || servers[0]["leader"] != true {
+		t.Fatalf("bad servers: %#v", servers)
+	}
+
+	// Take a snapshot, then write something that is not part of it
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/storage/raft/snapshot")
+	req.ClientToken = root
+	resp, err = core.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := resp.Data[logical.HTTPRawBody].([]byte)
+	if len(snapshot) == 0 {
+		t.Fatal("expected snapshot")
+	}
+
+	if err := core.barrier.Put(ctx, &Entry{Key: "raft-test", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/storage/raft/snapshot")
+	req.ClientToken = root
+	req.Data["snapshot"] = base64.StdEncoding.EncodeToString(snapshot)
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := core.barrier.Get(ctx, "raft-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("expected entry to be removed by restore: %#v", entry)
+	}
+
+	// Data survives sealing, which stops raft, and unsealing again
+	if err := core.barrier.Put(ctx, &Entry{Key: "raft-test", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testRaftWaitActive(t, core)
+
+	entry, err = core.barrier.Get(ctx, "raft-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "baz" {
+		t.Fatalf("bad entry: %#v", entry)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
@@ -34,7 +35,9 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"replication/primary/secondary-token",
 		"replication/reindex",
 		"rotate",
+		"rotate/*",
 		"config/cors",
+		"config/control-group",
 		"config/auditing/*",
 		"config/ui/headers/*",
 		"plugins/catalog/*",
@@ -43,6 +46,8 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
 		"leases/lookup/*",
+		"storage/raft/*",
+		"storage/verify",
 	}
 
 	b := testSystemBackend(t)
@@ -1897,6 +1902,7 @@ func TestSystemBackend_keyStatus(t *testing.T) {
 		"term": 1,
 	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -1920,8 +1926,10 @@ func TestSystemBackend_rotate(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
+	// The new key has been used once, to write the keyring canary
 	exp := map[string]interface{}{
-		"term": 2,
+		"term":        2,
+		"encryptions": uint64(1),
 	}
 	delete(resp.Data, "install_time")
 	if !reflect.DeepEqual(resp.Data, exp) {
@@ -1929,6 +1937,141 @@ func TestSystemBackend_rotate(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_rotateReencrypt(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := c.systemBackend
+
+	// Pruning requires a completed re-encryption
+	req := logical.TestRequest(t, logical.UpdateOperation, "rotate/prune")
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := c.barrier.Put(context.Background(), &Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate")
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/reencrypt")
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var reencryption map[string]interface{}
+	for i := 0; i < 100; i++ {
+		req = logical.TestRequest(t, logical.ReadOperation, "key-status")
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		reencryption = resp.Data["reencryption"].(map[string]interface{})
+		if !reencryption["running"].(bool) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if reencryption["complete"] != true || reencryption["failed"] != 0 || reencryption["term"] != uint32(2) {
+		t.Fatalf("bad: %#v", reencryption)
+	}
+	if reencryption["reencrypted"].(int) == 0 {
+		t.Fatalf("bad: %#v", reencryption)
+	}
+
+	pe, err := c.physical.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.BigEndian.Uint32(pe.Value[:4]) != 2 {
+		t.Fatalf("entry not re-encrypted")
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/prune")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["removed_terms"], []uint32{1}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	entry, err := c.barrier.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "bar" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestSystemBackend_rotateConfig(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := c.systemBackend
+
+	req := logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"enabled":        true,
+		"max_operations": int64(defaultKeyRotationMaxOperations),
+		"interval":       int64(0),
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Intervals shorter than the minimum are rejected
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data["interval"] = "1h"
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data["max_operations"] = 1
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The key is rotated once it has been used for max_operations
+	// encryptions
+	if err := c.barrier.Put(context.Background(), &Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.checkKeyRotation(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Term != 2 {
+		t.Fatalf("bad: %d", info.Term)
+	}
+
+	// Nothing happens while automatic rotation is disabled
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data["enabled"] = false
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.checkKeyRotation(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	info, err = c.barrier.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Term != 2 {
+		t.Fatalf("bad: %d", info.Term)
+	}
+}
+
 func testSystemBackend(t *testing.T) logical.Backend {
 	c, _, _ := TestCoreUnsealed(t)
 	return c.systemBackend
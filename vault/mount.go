@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/mitchellh/copystructure"
@@ -135,10 +136,10 @@ func (t *MountTable) shallowClone() *MountTable {
 }
 
 // setTaint is used to set the taint on given entry
-func (t *MountTable) setTaint(path string, value bool) *MountEntry {
+func (t *MountTable) setTaint(ns *namespace.Namespace, path string, value bool) *MountEntry {
 	n := len(t.Entries)
 	for i := 0; i < n; i++ {
-		if t.Entries[i].Path == path {
+		if t.Entries[i].inNamespace(ns) && t.Entries[i].Path == path {
 			t.Entries[i].Tainted = value
 			return t.Entries[i]
 		}
@@ -148,10 +149,10 @@ func (t *MountTable) setTaint(path string, value bool) *MountEntry {
 
 // remove is used to remove a given path entry; returns the entry that was
 // removed
-func (t *MountTable) remove(path string) *MountEntry {
+func (t *MountTable) remove(ns *namespace.Namespace, path string) *MountEntry {
 	n := len(t.Entries)
 	for i := 0; i < n; i++ {
-		if entry := t.Entries[i]; entry.Path == path {
+		if entry := t.Entries[i]; entry.inNamespace(ns) && entry.Path == path {
 			t.Entries[i], t.Entries[n-1] = t.Entries[n-1], nil
 			t.Entries = t.Entries[:n-1]
 			return entry
@@ -183,6 +184,11 @@ type MountEntry struct {
 	Local            bool              `json:"local"`              // Local mounts are not replicated or affected by replication
 	SealWrap         bool              `json:"seal_wrap"`          // Whether to wrap CSPs
 	Tainted          bool              `json:"tainted,omitempty"`  // Set as a Write-Ahead flag for unmount/remount
+	NamespaceID      string            `json:"namespace_id"`       // The namespace the mount belongs to
+
+	// namespace is the namespace the mount belongs to, resolved from
+	// NamespaceID when the table is loaded
+	namespace *namespace.Namespace
 
 	// synthesizedConfigCache is used to cache configuration values. These
 	// particular values are cached since we want to get them at a point-in-time
@@ -224,6 +230,33 @@ func (e *MountEntry) Clone() (*MountEntry, error) {
 	return cp.(*MountEntry), nil
 }
 
+// Namespace returns the namespace the mount belongs to
+func (e *MountEntry) Namespace() *namespace.Namespace {
+	if e.namespace == nil {
+		return namespace.RootNamespace
+	}
+	return e.namespace
+}
+
+// inNamespace returns whether the entry belongs to the given namespace.
+// Entries without a namespace, such as audit entries, belong to the root.
+func (e *MountEntry) inNamespace(ns *namespace.Namespace) bool {
+	if e.NamespaceID == "" {
+		return ns.ID == namespace.RootNamespaceID
+	}
+	return e.NamespaceID == ns.ID
+}
+
+// APIPath returns the full path used to route requests to the mount,
+// including the namespace and, for auth methods, the auth prefix
+func (e *MountEntry) APIPath() string {
+	path := e.Path
+	if e.Table == credentialTableType {
+		path = credentialRoutePrefix + path
+	}
+	return e.Namespace().Path + path
+}
+
 // SyncCache syncs tunable configuration values to the cache. In the case of
 // cached values, they should be retrieved via synthesizedConfigCache.Load()
 // instead of accessing them directly through MountConfig.
@@ -267,6 +300,17 @@ func (c *Core) mount(ctx context.Context, entry *MountEntry) error {
 			return logical.CodedError(403, fmt.Sprintf("Cannot mount more than one instance of '%s'", entry.Type))
 		}
 	}
+
+	ns := namespaceFromContext(ctx)
+	entry.NamespaceID = ns.ID
+	entry.namespace = ns
+
+	// Requests are assigned to the namespace holding their path, so a mount
+	// must not lie within a child namespace
+	if c.namespaceByPath(entry.APIPath()).ID != ns.ID {
+		return logical.CodedError(409, fmt.Sprintf("path '%s' is within a child namespace", entry.Path))
+	}
+
 	return c.mountInternal(ctx, entry)
 }
 
@@ -275,7 +319,7 @@ func (c *Core) mountInternal(ctx context.Context, entry *MountEntry) error {
 	defer c.mountsLock.Unlock()
 
 	// Verify there are no conflicting mounts
-	if match := c.router.MountConflict(entry.Path); match != "" {
+	if match := c.router.MountConflict(entry.APIPath()); match != "" {
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
 	}
 
@@ -342,12 +386,12 @@ func (c *Core) mountInternal(ctx context.Context, entry *MountEntry) error {
 	}
 	c.mounts = newTable
 
-	if err := c.router.Mount(backend, entry.Path, entry, view); err != nil {
+	if err := c.router.Mount(backend, entry.APIPath(), entry, view); err != nil {
 		return err
 	}
 
 	if c.logger.IsInfo() {
-		c.logger.Info("successful mount", "path", entry.Path, "type", entry.Type)
+		c.logger.Info("successful mount", "path", entry.APIPath(), "type", entry.Type)
 	}
 	return nil
 }
@@ -370,19 +414,22 @@ func (c *Core) unmount(ctx context.Context, path string) error {
 }
 
 func (c *Core) unmountInternal(ctx context.Context, path string) error {
+	ns := namespaceFromContext(ctx)
+	fullPath := ns.Path + path
+
 	// Verify exact match of the route
-	match := c.router.MatchingMount(path)
-	if match == "" || path != match {
+	match := c.router.MatchingMount(fullPath)
+	if match == "" || fullPath != match {
 		return fmt.Errorf("no matching mount")
 	}
 
 	// Get the view for this backend
-	view := c.router.MatchingStorageByAPIPath(path)
+	view := c.router.MatchingStorageByAPIPath(fullPath)
 
 	// Get the backend/mount entry for this path, used to remove ignored
 	// replication prefixes
-	backend := c.router.MatchingBackend(path)
-	entry := c.router.MatchingMountEntry(path)
+	backend := c.router.MatchingBackend(fullPath)
+	entry := c.router.MatchingMountEntry(fullPath)
 
 	// Mark the entry as tainted
 	if err := c.taintMountEntry(ctx, path); err != nil {
@@ -392,18 +439,18 @@ func (c *Core) unmountInternal(ctx context.Context, path string) error {
 
 	// Taint the router path to prevent routing. Note that in-flight requests
 	// are uncertain, right now.
-	if err := c.router.Taint(path); err != nil {
+	if err := c.router.Taint(fullPath); err != nil {
 		return err
 	}
 
 	if backend != nil {
 		// Invoke the rollback manager a final time
-		if err := c.rollback.Rollback(path); err != nil {
+		if err := c.rollback.Rollback(fullPath); err != nil {
 			return err
 		}
 
 		// Revoke all the dynamic keys
-		if err := c.expiration.RevokePrefix(path); err != nil {
+		if err := c.expiration.RevokePrefix(fullPath); err != nil {
 			return err
 		}
 
@@ -412,7 +459,7 @@ func (c *Core) unmountInternal(ctx context.Context, path string) error {
 	}
 
 	// Unmount the backend entirely
-	if err := c.router.Unmount(ctx, path); err != nil {
+	if err := c.router.Unmount(ctx, fullPath); err != nil {
 		return err
 	}
 
@@ -432,7 +479,7 @@ func (c *Core) unmountInternal(ctx context.Context, path string) error {
 	}
 
 	if c.logger.IsInfo() {
-		c.logger.Info("successfully unmounted", "path", path)
+		c.logger.Info("successfully unmounted", "path", fullPath)
 	}
 	return nil
 }
@@ -444,7 +491,7 @@ func (c *Core) removeMountEntry(ctx context.Context, path string) error {
 
 	// Remove the entry from the mount table
 	newTable := c.mounts.shallowClone()
-	entry := newTable.remove(path)
+	entry := newTable.remove(namespaceFromContext(ctx), path)
 	if entry == nil {
 		c.logger.Error("nil entry found removing entry in mounts table", "path", path)
 		return logical.CodedError(500, "failed to remove entry in mounts table")
@@ -473,7 +520,7 @@ func (c *Core) taintMountEntry(ctx context.Context, path string) error {
 
 	// As modifying the taint of an entry affects shallow clones,
 	// we simply use the original
-	entry := c.mounts.setTaint(path, true)
+	entry := c.mounts.setTaint(namespaceFromContext(ctx), path, true)
 	if entry == nil {
 		c.logger.Error("nil entry found tainting entry in mounts table", "path", path)
 		return logical.CodedError(500, "failed to taint entry in mounts table")
@@ -491,7 +538,7 @@ func (c *Core) taintMountEntry(ctx context.Context, path string) error {
 // remountForce takes a copy of the mount entry for the path and fully unmounts
 // and remounts the backend to pick up any changes, such as filtered paths
 func (c *Core) remountForce(ctx context.Context, path string) error {
-	me := c.router.MatchingMountEntry(path)
+	me := c.router.MatchingMountEntry(namespaceFromContext(ctx).Path + path)
 	if me == nil {
 		return fmt.Errorf("cannot find mount for path %q", path)
 	}
@@ -524,14 +571,21 @@ func (c *Core) remount(ctx context.Context, src, dst string) error {
 		}
 	}
 
+	ns := namespaceFromContext(ctx)
+	fullSrc := ns.Path + src
+	fullDst := ns.Path + dst
+
 	// Verify exact match of the route
-	match := c.router.MatchingMount(src)
-	if match == "" || src != match {
+	match := c.router.MatchingMount(fullSrc)
+	if match == "" || fullSrc != match {
 		return fmt.Errorf("no matching mount at %q", src)
 	}
 
-	if match := c.router.MatchingMount(dst); match != "" {
-		return fmt.Errorf("existing mount at %q", match)
+	if match := c.router.MatchingMount(fullDst); match != "" {
+		return fmt.Errorf("existing mount at %q", ns.TrimmedPath(match))
+	}
+	if c.namespaceByPath(fullDst).ID != ns.ID {
+		return fmt.Errorf("path %q is within a child namespace", dst)
 	}
 
 	// Mark the entry as tainted
@@ -540,24 +594,25 @@ func (c *Core) remount(ctx context.Context, src, dst string) error {
 	}
 
 	// Taint the router path to prevent routing
-	if err := c.router.Taint(src); err != nil {
+	if err := c.router.Taint(fullSrc); err != nil {
 		return err
 	}
 
 	// Invoke the rollback manager a final time
-	if err := c.rollback.Rollback(src); err != nil {
+	if err := c.rollback.Rollback(fullSrc); err != nil {
 		return err
 	}
 
 	// Revoke all the dynamic keys
-	if err := c.expiration.RevokePrefix(src); err != nil {
+	if err := c.expiration.RevokePrefix(fullSrc); err != nil {
 		return err
 	}
 
 	c.mountsLock.Lock()
 	var entry *MountEntry
-	for _, entry = range c.mounts.Entries {
-		if entry.Path == src {
+	for _, e := range c.mounts.Entries {
+		if e.inNamespace(ns) && e.Path == src {
+			entry = e
 			entry.Path = dst
 			entry.Tainted = false
 			break
@@ -581,17 +636,17 @@ func (c *Core) remount(ctx context.Context, src, dst string) error {
 	c.mountsLock.Unlock()
 
 	// Remount the backend
-	if err := c.router.Remount(src, dst); err != nil {
+	if err := c.router.Remount(fullSrc, fullDst); err != nil {
 		return err
 	}
 
 	// Un-taint the path
-	if err := c.router.Untaint(dst); err != nil {
+	if err := c.router.Untaint(fullDst); err != nil {
 		return err
 	}
 
 	if c.logger.IsInfo() {
-		c.logger.Info("successful remount", "old_path", src, "new_path", dst)
+		c.logger.Info("successful remount", "old_path", fullSrc, "new_path", fullDst)
 	}
 	return nil
 }
@@ -675,6 +730,11 @@ func (c *Core) loadMounts(ctx context.Context) error {
 
 	// Upgrade to table-scoped entries
 	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == "" {
+			entry.NamespaceID = namespace.RootNamespaceID
+			needPersist = true
+		}
+		entry.namespace = c.namespaceByID(entry.NamespaceID)
 		if entry.Type == "cubbyhole" && !entry.Local {
 			entry.Local = true
 			needPersist = true
@@ -797,6 +857,10 @@ func (c *Core) setupMounts(ctx context.Context) error {
 	var backendType logical.BackendType
 
 	for _, entry := range c.mounts.Entries {
+		if entry.namespace == nil {
+			c.logger.Error("skipping mount entry for missing namespace", "path", entry.Path, "namespace_id", entry.NamespaceID)
+			continue
+		}
 
 		// Initialize the backend, special casing for system
 		barrierPath := backendBarrierPrefix + entry.UUID + "/"
@@ -844,19 +908,19 @@ func (c *Core) setupMounts(ctx context.Context) error {
 
 	ROUTER_MOUNT:
 		// Mount the backend
-		err = c.router.Mount(backend, entry.Path, entry, view)
+		err = c.router.Mount(backend, entry.APIPath(), entry, view)
 		if err != nil {
-			c.logger.Error("failed to mount entry", "path", entry.Path, "error", err)
+			c.logger.Error("failed to mount entry", "path", entry.APIPath(), "error", err)
 			return errLoadMountsFailed
 		}
 
 		if c.logger.IsInfo() {
-			c.logger.Info("successfully mounted backend", "type", entry.Type, "path", entry.Path)
+			c.logger.Info("successfully mounted backend", "type", entry.Type, "path", entry.APIPath())
 		}
 
 		// Ensure the path is tainted if set in the mount table
 		if entry.Tainted {
-			c.router.Taint(entry.Path)
+			c.router.Taint(entry.APIPath())
 		}
 	}
 	return nil
@@ -871,7 +935,7 @@ func (c *Core) unloadMounts(ctx context.Context) error {
 	if c.mounts != nil {
 		mountTable := c.mounts.shallowClone()
 		for _, e := range mountTable.Entries {
-			backend := c.router.MatchingBackend(e.Path)
+			backend := c.router.MatchingBackend(e.APIPath())
 			if backend != nil {
 				backend.Cleanup(ctx)
 			}
@@ -952,6 +1016,7 @@ func (c *Core) defaultMountTable() *MountTable {
 
 	kvMount := &MountEntry{
 		Table:            mountTableType,
+		NamespaceID:      namespace.RootNamespaceID,
 		Path:             "secret/",
 		Type:             "kv",
 		Description:      "key/value secret storage",
@@ -987,6 +1052,7 @@ func (c *Core) requiredMountTable() *MountTable {
 	}
 	cubbyholeMount := &MountEntry{
 		Table:            mountTableType,
+		NamespaceID:      namespace.RootNamespaceID,
 		Path:             "cubbyhole/",
 		Type:             "cubbyhole",
 		Description:      "per-token private secret storage",
@@ -1010,6 +1076,7 @@ func (c *Core) requiredMountTable() *MountTable {
 	}
 	sysMount := &MountEntry{
 		Table:            mountTableType,
+		NamespaceID:      namespace.RootNamespaceID,
 		Path:             "sys/",
 		Type:             "system",
 		Description:      "system endpoints used for control, policy and debugging",
@@ -1032,6 +1099,7 @@ func (c *Core) requiredMountTable() *MountTable {
 	}
 	identityMount := &MountEntry{
 		Table:            mountTableType,
+		NamespaceID:      namespace.RootNamespaceID,
 		Path:             "identity/",
 		Type:             "identity",
 		Description:      "identity store",
@@ -0,0 +1,358 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/armon/go-radix"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// coreNamespacesPrefix is the storage prefix used to persist the
+	// namespace entries
+	coreNamespacesPrefix = "core/namespaces/"
+
+	// namespaceBarrierPrefix is the prefix of the storage holding the data
+	// that belongs to a namespace, such as its policies
+	namespaceBarrierPrefix = "namespaces/"
+)
+
+var (
+	// errNamespaceNotEmpty is returned when deleting a namespace that still
+	// has child namespaces or mounts
+	errNamespaceNotEmpty = errors.New("namespace is not empty")
+
+	// namespaceSysPaths are the prefixes of the system backend paths that
+	// are available within a non-root namespace. Everything else, such as
+	// sealing, audit devices or raw storage access, is reserved to the root
+	// namespace.
+	namespaceSysPaths = []string{
+		"auth",
+		"capabilities",
+		"control-group/",
+		"internal/ui/mounts",
+		"leases/",
+		"mounts",
+		"namespaces",
+		"policies/",
+		"policy",
+		"renew",
+		"revoke",
+		"tools/",
+		"wrapping/",
+	}
+)
+
+// namespaceRestrictedPath returns whether the given namespace-relative path
+// is a system backend path that is unavailable within a non-root namespace
+func namespaceRestrictedPath(path string) bool {
+	if !strings.HasPrefix(path, "sys/") {
+		return false
+	}
+
+	path = strings.TrimPrefix(path, "sys/")
+	for _, prefix := range namespaceSysPaths {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceFromContext returns the namespace stored in the context, falling
+// back to the root namespace if there is none
+func namespaceFromContext(ctx context.Context) *namespace.Namespace {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return namespace.RootNamespace
+	}
+	return ns
+}
+
+// leaseInNamespace returns whether the lease belongs to the namespace in the
+// context or to one of its descendants. Lease IDs always carry the full path
+// of the lease, including its namespace.
+func leaseInNamespace(ctx context.Context, leaseID string) bool {
+	return strings.HasPrefix(leaseID, namespaceFromContext(ctx).Path)
+}
+
+// namespaceView returns the barrier view holding the data of a non-root
+// namespace
+func (c *Core) namespaceView(ns *namespace.Namespace) *BarrierView {
+	return NewBarrierView(c.barrier, namespaceBarrierPrefix+ns.ID+"/")
+}
+
+// loadNamespaces is invoked as part of postUnseal to load the namespaces.
+// This must happen before the mount tables are loaded since mount entries
+// are bound to their namespace.
+func (c *Core) loadNamespaces(ctx context.Context) error {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+
+	c.namespacesByPath = radix.New()
+	c.namespacesByID = make(map[string]*namespace.Namespace)
+
+	keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, coreNamespacesPrefix))
+	if err != nil {
+		return errwrap.Wrapf("failed to list namespaces: {{err}}", err)
+	}
+
+	for _, key := range keys {
+		raw, err := c.barrier.Get(ctx, coreNamespacesPrefix+key)
+		if err != nil {
+			return errwrap.Wrapf("failed to read namespace: {{err}}", err)
+		}
+		if raw == nil {
+			continue
+		}
+
+		ns := new(namespace.Namespace)
+		if err := jsonutil.DecodeJSON(raw.Value, ns); err != nil {
+			return errwrap.Wrapf("failed to decode namespace: {{err}}", err)
+		}
+
+		c.namespacesByPath.Insert(ns.Path, ns)
+		c.namespacesByID[ns.ID] = ns
+	}
+
+	return nil
+}
+
+// setupNamespaces is invoked after the mounts and credentials have been set
+// up, routing the singleton mounts beneath every namespace and loading the
+// namespaced policies
+func (c *Core) setupNamespaces(ctx context.Context) error {
+	c.namespaceLock.RLock()
+	defer c.namespaceLock.RUnlock()
+
+	for _, ns := range c.namespacesByID {
+		if err := c.router.MountNamespace(ns); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to route namespace %q: {{err}}", ns.Path), err)
+		}
+		if err := c.policyStore.loadNamespacePolicies(ctx, ns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// teardownNamespaces is used to reverse loadNamespaces when the vault is
+// being sealed
+func (c *Core) teardownNamespaces() error {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+
+	c.namespacesByPath = nil
+	c.namespacesByID = nil
+	return nil
+}
+
+// namespaceByID returns the namespace with the given ID, or nil if it does
+// not exist
+func (c *Core) namespaceByID(id string) *namespace.Namespace {
+	if id == namespace.RootNamespaceID {
+		return namespace.RootNamespace
+	}
+
+	c.namespaceLock.RLock()
+	defer c.namespaceLock.RUnlock()
+
+	return c.namespacesByID[id]
+}
+
+// namespaceByPath returns the innermost namespace the given path belongs to
+func (c *Core) namespaceByPath(path string) *namespace.Namespace {
+	c.namespaceLock.RLock()
+	defer c.namespaceLock.RUnlock()
+
+	if c.namespacesByPath == nil {
+		return namespace.RootNamespace
+	}
+
+	_, raw, ok := c.namespacesByPath.LongestPrefix(path)
+	if !ok {
+		return namespace.RootNamespace
+	}
+	return raw.(*namespace.Namespace)
+}
+
+// listNamespaces returns the direct children of the given namespace, sorted
+// by path
+func (c *Core) listNamespaces(parent *namespace.Namespace) []*namespace.Namespace {
+	c.namespaceLock.RLock()
+	defer c.namespaceLock.RUnlock()
+
+	var ret []*namespace.Namespace
+	if c.namespacesByPath == nil {
+		return ret
+	}
+
+	c.namespacesByPath.WalkPrefix(parent.Path, func(path string, raw interface{}) bool {
+		rel := strings.TrimSuffix(strings.TrimPrefix(path, parent.Path), "/")
+		if rel != "" && !strings.Contains(rel, "/") {
+			ret = append(ret, raw.(*namespace.Namespace))
+		}
+		return false
+	})
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
+
+// createNamespace creates a namespace with the given name as a child of the
+// namespace in the context
+func (c *Core) createNamespace(ctx context.Context, name string) (*namespace.Namespace, error) {
+	name = strings.Trim(name, "/")
+	switch {
+	case name == "":
+		return nil, logical.CodedError(400, "missing namespace name")
+	case strings.Contains(name, "/"):
+		return nil, logical.CodedError(400, "namespace name must be a single path segment")
+	}
+
+	parent := namespaceFromContext(ctx)
+	path := parent.Path + name + "/"
+
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+
+	if _, ok := c.namespacesByPath.Get(path); ok {
+		return nil, logical.CodedError(400, fmt.Sprintf("namespace %q already exists", path))
+	}
+	if conflict := c.router.MountConflict(path); conflict != "" {
+		return nil, logical.CodedError(409, fmt.Sprintf("existing mount at %q", conflict))
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	ns := &namespace.Namespace{
+		ID:   id,
+		Path: path,
+	}
+
+	raw, err := jsonutil.EncodeJSON(ns)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to encode namespace: {{err}}", err)
+	}
+	if err := c.barrier.Put(ctx, &Entry{Key: coreNamespacesPrefix + ns.ID, Value: raw}); err != nil {
+		return nil, errwrap.Wrapf("failed to persist namespace: {{err}}", err)
+	}
+
+	if err := c.router.MountNamespace(ns); err != nil {
+		return nil, err
+	}
+	c.namespacesByPath.Insert(ns.Path, ns)
+	c.namespacesByID[ns.ID] = ns
+
+	// Set up the builtin policies of the namespace
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+	if err := c.policyStore.loadACLPolicy(nsCtx, defaultPolicyName, defaultPolicy); err != nil {
+		return nil, err
+	}
+	if err := c.policyStore.loadACLPolicy(nsCtx, responseWrappingPolicyName, responseWrappingPolicy); err != nil {
+		return nil, err
+	}
+	if err := c.policyStore.loadACLPolicy(nsCtx, controlGroupPolicyName, controlGroupPolicy); err != nil {
+		return nil, err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("created namespace", "path", ns.Path, "id", ns.ID)
+	}
+	return ns, nil
+}
+
+// deleteNamespace removes the child namespace with the given name from the
+// namespace in the context. The namespace must not contain any namespaces,
+// secrets engines or auth methods.
+func (c *Core) deleteNamespace(ctx context.Context, name string) error {
+	parent := namespaceFromContext(ctx)
+	path := parent.Path + namespace.Canonicalize(name)
+
+	c.namespaceLock.RLock()
+	raw, ok := c.namespacesByPath.Get(path)
+	if !ok {
+		c.namespaceLock.RUnlock()
+		return nil
+	}
+	ns := raw.(*namespace.Namespace)
+
+	hasChildren := false
+	c.namespacesByPath.WalkPrefix(ns.Path, func(childPath string, _ interface{}) bool {
+		hasChildren = childPath != ns.Path
+		return hasChildren
+	})
+	c.namespaceLock.RUnlock()
+
+	if hasChildren || c.namespaceHasMounts(ns) {
+		return errNamespaceNotEmpty
+	}
+
+	// Revoke the leases and tokens issued within the namespace. This is done
+	// without holding the lock as revocation may need to resolve namespaces.
+	if err := c.expiration.RevokePrefix(ns.Path); err != nil {
+		return errwrap.Wrapf("failed to revoke namespace leases: {{err}}", err)
+	}
+
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+
+	if err := c.tokenStore.deleteNamespaceRoles(ctx, ns); err != nil {
+		return err
+	}
+	if err := c.policyStore.removeNamespacePolicies(ctx, ns); err != nil {
+		return err
+	}
+	if err := logical.ClearView(ctx, c.namespaceView(ns)); err != nil {
+		return errwrap.Wrapf("failed to clear namespace storage: {{err}}", err)
+	}
+
+	if err := c.router.UnmountNamespace(ns); err != nil {
+		return err
+	}
+	if err := c.barrier.Delete(ctx, coreNamespacesPrefix+ns.ID); err != nil {
+		return errwrap.Wrapf("failed to delete namespace: {{err}}", err)
+	}
+	c.namespacesByPath.Delete(ns.Path)
+	delete(c.namespacesByID, ns.ID)
+
+	if c.logger.IsInfo() {
+		c.logger.Info("deleted namespace", "path", ns.Path, "id", ns.ID)
+	}
+	return nil
+}
+
+// namespaceHasMounts returns whether any secrets engine or auth method is
+// mounted within the namespace
+func (c *Core) namespaceHasMounts(ns *namespace.Namespace) bool {
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == ns.ID {
+			return true
+		}
+	}
+
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == ns.ID {
+			return true
+		}
+	}
+
+	return false
+}
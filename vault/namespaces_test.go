@@ -0,0 +1,284 @@
+package vault
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+func testNamespaceRequest(t *testing.T, c *Core, req *logical.Request) *logical.Response {
+	t.Helper()
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: err: %v resp: %#v", req.Operation, req.Path, err, resp)
+	}
+	return resp
+}
+
+func testCoreNamespace(t *testing.T, c *Core, token, path string) {
+	t.Helper()
+	req := logical.TestRequest(t, logical.UpdateOperation, path)
+	req.ClientToken = token
+	testNamespaceRequest(t, c, req)
+}
+
+func TestCore_Namespaces_CRUD(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	testCoreNamespace(t, c, root, "sys/namespaces/ns1")
+	testCoreNamespace(t, c, root, "sys/namespaces/ns2")
+	testCoreNamespace(t, c, root, "ns1/sys/namespaces/child")
+
+	// Creating an existing namespace should fail
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/ns1")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err == nil || !resp.IsError() {
+		t.Fatalf("expected error, got resp: %#v", resp)
+	}
+
+	// Namespaces can't shadow existing mounts
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/secret")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/namespaces")
+	req.ClientToken = root
+	resp := testNamespaceRequest(t, c, req)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"ns1/", "ns2/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "ns1/sys/namespaces")
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"child/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/sys/namespaces/child")
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	if resp.Data["path"] != "ns1/child/" || resp.Data["id"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A namespace with children can't be deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/ns1")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "ns1/sys/namespaces/child")
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/ns1")
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/namespaces")
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"ns2/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestCore_Namespaces_Isolation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	testCoreNamespace(t, c, root, "sys/namespaces/ns1")
+
+	// Mount a secrets engine within the namespace
+	req := logical.TestRequest(t, logical.UpdateOperation, "ns1/sys/mounts/kv")
+	req.Data["type"] = "kv"
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/kv/foo")
+	req.Data["value"] = "bar"
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	// The root namespace can't mount within the child namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/ns1/leak")
+	req.Data["type"] = "kv"
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err == nil || resp == nil || !strings.Contains(resp.Error().Error(), "child namespace") {
+		t.Fatalf("expected error, got: %v %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/remount")
+	req.Data["from"] = "secret"
+	req.Data["to"] = "ns1/leak"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The mount is not reachable from the root namespace
+	req = logical.TestRequest(t, logical.ReadOperation, "kv/foo")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The mount table of the namespace only holds its own mounts
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/sys/mounts")
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	if _, ok := resp.Data["kv/"]; !ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["secret/"]; ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A namespace with mounts can't be deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/ns1")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Restricted system paths are unavailable within the namespace
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/sys/audit")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrUnsupportedPath {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Policies are scoped to their namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/sys/policy/reader")
+	req.Data["policy"] = `path "kv/*" { capabilities = ["read"] }`
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/policy/reader")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	// Create a token within the namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/auth/token/create")
+	req.Data["policies"] = []string{"reader"}
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	nsToken := resp.Auth.ClientToken
+
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/kv/foo")
+	req.ClientToken = nsToken
+	resp = testNamespaceRequest(t, c, req)
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The namespace token can't reach outside of its namespace
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = nsToken
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Root tokens can only be created in the root namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/auth/token/create")
+	req.Data["policies"] = []string{"root"}
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCore_Namespaces_ParentPolicy(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	testCoreNamespace(t, c, root, "sys/namespaces/ns1")
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "ns1/sys/mounts/kv")
+	req.Data["type"] = "kv"
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/kv/foo")
+	req.Data["value"] = "bar"
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	// A policy of the root namespace reaches into the child namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/ns1-reader")
+	req.Data["policy"] = `path "ns1/kv/*" { capabilities = ["read"] }`
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.Data["policies"] = []string{"ns1-reader"}
+	req.ClientToken = root
+	resp := testNamespaceRequest(t, c, req)
+	token := resp.Auth.ClientToken
+
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/kv/foo")
+	req.ClientToken = token
+	resp = testNamespaceRequest(t, c, req)
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Policies of the child namespace with the same name don't apply
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/sys/policy/ns1-reader")
+	req.Data["policy"] = `path "kv/*" { capabilities = ["deny"] }`
+	req.ClientToken = root
+	testNamespaceRequest(t, c, req)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/kv/foo")
+	req.ClientToken = token
+	testNamespaceRequest(t, c, req)
+}
+
+func TestCore_Namespaces_Identity(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	testCoreNamespace(t, c, root, "sys/namespaces/ns1")
+
+	// Entity names are unique per namespace
+	req := logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.Data["name"] = "alice"
+	req.ClientToken = root
+	resp := testNamespaceRequest(t, c, req)
+	rootEntityID := resp.Data["id"].(string)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/identity/entity")
+	req.Data["name"] = "alice"
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	nsEntityID := resp.Data["id"].(string)
+
+	req = logical.TestRequest(t, logical.ListOperation, "ns1/identity/entity/id")
+	req.ClientToken = root
+	resp = testNamespaceRequest(t, c, req)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{nsEntityID}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "ns1/identity/entity/id/"+rootEntityID)
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	// Groups can't hold members of other namespaces
+	req = logical.TestRequest(t, logical.UpdateOperation, "ns1/identity/group")
+	req.Data["member_entity_ids"] = []string{rootEntityID}
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,523 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// perfStandbyInvalidationLogSize is the least number of written keys the
+	// active node remembers for the performance standbys. A standby that
+	// falls further behind invalidates its whole cache.
+	perfStandbyInvalidationLogSize = 16384
+)
+
+var (
+	// perfStandbyHeartbeatInterval is how often performance standbys fetch
+	// the keys written on the active node, which bounds how stale their reads
+	// can be
+	perfStandbyHeartbeatInterval = 1 * time.Second
+
+	// perfStandbyReloadPaths are the keys that hold the state loaded by
+	// setupPerfStandby; a write to any of them makes the standby reload it
+	perfStandbyReloadPaths = []string{
+		coreMountConfigPath,
+		coreLocalMountConfigPath,
+		coreAuthConfigPath,
+		coreLocalAuthConfigPath,
+		coreAuditConfigPath,
+		coreLocalAuditConfigPath,
+		coreWrappingJWTKeyPath,
+		systemBarrierPrefix + "config/cors",
+		systemBarrierPrefix + auditedHeadersSubPath + auditedHeadersEntry,
+	}
+)
+
+// perfStandbyStorage sits below the cache. It refuses writes while this node
+// is a performance standby, and records the keys written while it is the
+// active node so that the performance standbys can invalidate them.
+type perfStandbyStorage struct {
+	underlying physical.Backend
+	readOnly   *uint32
+	log        *invalidationLog
+}
+
+// transactionalPerfStandbyStorage is a perfStandbyStorage that wraps a
+// physical that is transactional
+type transactionalPerfStandbyStorage struct {
+	*perfStandbyStorage
+	physical.Transactional
+}
+
+var _ physical.Backend = (*perfStandbyStorage)(nil)
+var _ physical.Transactional = (*transactionalPerfStandbyStorage)(nil)
+
+func newPerfStandbyStorage(underlying physical.Backend) *perfStandbyStorage {
+	return &perfStandbyStorage{
+		underlying: underlying,
+		readOnly:   new(uint32),
+		log:        newInvalidationLog(perfStandbyInvalidationLogSize),
+	}
+}
+
+// backend returns the storage as a physical backend, which is transactional
+// if the underlying backend is
+func (s *perfStandbyStorage) backend() physical.Backend {
+	if txn, ok := s.underlying.(physical.Transactional); ok {
+		return &transactionalPerfStandbyStorage{
+			perfStandbyStorage: s,
+			Transactional:      txn,
+		}
+	}
+	return s
+}
+
+// setReadOnly toggles whether writes are refused
+func (s *perfStandbyStorage) setReadOnly(readOnly bool) {
+	if readOnly {
+		atomic.StoreUint32(s.readOnly, 1)
+		return
+	}
+	atomic.StoreUint32(s.readOnly, 0)
+}
+
+// refuse reports whether writes are refused, marking the request of the given
+// context as having attempted one if so
+func (s *perfStandbyStorage) refuse(ctx context.Context) bool {
+	if atomic.LoadUint32(s.readOnly) == 0 {
+		return false
+	}
+	if refused, ok := ctx.Value(refusedWriteKey{}).(*uint32); ok {
+		atomic.StoreUint32(refused, 1)
+	}
+	return true
+}
+
+func (s *perfStandbyStorage) Put(ctx context.Context, entry *physical.Entry) error {
+	if s.refuse(ctx) {
+		return logical.ErrReadOnly
+	}
+	if err := s.underlying.Put(ctx, entry); err != nil {
+		return err
+	}
+	s.log.record(entry.Key)
+	return nil
+}
+
+func (s *perfStandbyStorage) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	return s.underlying.Get(ctx, key)
+}
+
+func (s *perfStandbyStorage) Delete(ctx context.Context, key string) error {
+	if s.refuse(ctx) {
+		return logical.ErrReadOnly
+	}
+	if err := s.underlying.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.log.record(key)
+	return nil
+}
+
+func (s *perfStandbyStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.underlying.List(ctx, prefix)
+}
+
+func (s *transactionalPerfStandbyStorage) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	if s.refuse(ctx) {
+		return logical.ErrReadOnly
+	}
+	if err := s.Transactional.Transaction(ctx, txns); err != nil {
+		return err
+	}
+	for _, txn := range txns {
+		s.log.record(txn.Entry.Key)
+	}
+	return nil
+}
+
+// refusedWriteKey is the context key of the flag set when a request served
+// by a performance standby attempts to write to storage
+type refusedWriteKey struct{}
+
+// contextWithRefusedWrite returns a context that records whether a write was
+// refused while it was used
+func contextWithRefusedWrite(ctx context.Context) (context.Context, *uint32) {
+	refused := new(uint32)
+	return context.WithValue(ctx, refusedWriteKey{}, refused), refused
+}
+
+// invalidationLog holds the most recently written keys. Positions in the log
+// are given by an epoch, which changes whenever the log is reset, and by the
+// index of the next key to be recorded.
+type invalidationLog struct {
+	l       sync.Mutex
+	enabled bool
+	size    int
+	epoch   string
+	start   uint64
+	keys    []string
+}
+
+func newInvalidationLog(size int) *invalidationLog {
+	return &invalidationLog{
+		size: size,
+	}
+}
+
+// reset starts a new epoch, and enables or disables the recording of keys
+func (i *invalidationLog) reset(enabled bool) error {
+	epoch, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	i.enabled = enabled
+	i.epoch = epoch
+	i.start = 0
+	i.keys = nil
+	return nil
+}
+
+func (i *invalidationLog) record(key string) {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	if !i.enabled {
+		return
+	}
+
+	// Old keys are dropped in batches so that they are not copied on every
+	// write
+	i.keys = append(i.keys, key)
+	if len(i.keys) >= 2*i.size {
+		dropped := len(i.keys) - i.size
+		i.keys = append([]string(nil), i.keys[dropped:]...)
+		i.start += uint64(dropped)
+	}
+}
+
+// since returns the keys recorded after the given position along with the
+// current position. If the position is no longer in the log, ok is false and
+// everything has to be considered invalidated.
+func (i *invalidationLog) since(epoch string, index uint64) (keys []string, curEpoch string, curIndex uint64, ok bool) {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	curEpoch = i.epoch
+	curIndex = i.start + uint64(len(i.keys))
+
+	if !i.enabled || epoch != i.epoch || index < i.start || index > curIndex {
+		return nil, curEpoch, curIndex, false
+	}
+
+	keys = make([]string, curIndex-index)
+	copy(keys, i.keys[index-i.start:])
+	return keys, curEpoch, curIndex, true
+}
+
+// perfStandbyPosition is the position in the invalidation log of the active
+// node up to which a performance standby has invalidated its caches
+type perfStandbyPosition struct {
+	l     sync.Mutex
+	epoch string
+	index uint64
+}
+
+func (p *perfStandbyPosition) get() (string, uint64) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.epoch, p.index
+}
+
+func (p *perfStandbyPosition) set(epoch string, index uint64) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.epoch = epoch
+	p.index = index
+}
+
+// PerfStandby returns whether this node is a standby serving read-only
+// requests
+func (c *Core) PerfStandby() bool {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return c.standby && c.perfStandby
+}
+
+// mayGenerateLeases reports whether the backend serving the given path may
+// return secrets that get leases. Backends that cannot tell are assumed to.
+func (c *Core) mayGenerateLeases(path string) bool {
+	entry := c.router.MatchingMountEntry(path)
+	backend := c.router.MatchingBackend(path)
+	if entry == nil || backend == nil {
+		return false
+	}
+
+	// As in handleRequest, the kv secrets engine only gets leases if they
+	// are switched on
+	switch {
+	case entry.Type == "kv", entry.Type == "generic":
+		if ptbe, ok := backend.(*PassthroughBackend); ok {
+			return ptbe.GeneratesLeases()
+		}
+		return entry.Options["leased_passthrough"] == "true"
+	case entry.Type == "plugin" && entry.Config.PluginName == "kv":
+		return entry.Options["leased_passthrough"] == "true"
+	}
+
+	if lg, ok := backend.(logical.LeaseGenerator); ok {
+		return lg.GeneratesLeases()
+	}
+	return true
+}
+
+// setupPerfStandbyOrStop grabs the stateLock and sets up the performance
+// standby if it is not already. It returns false if runStandby has to exit
+// instead, because the stop channel was closed first or the node is sealed.
+func (c *Core) setupPerfStandbyOrStop(stopCh chan struct{}) bool {
+	lockGrabbedCh := make(chan struct{})
+	go func() {
+		c.stateLock.Lock()
+		// As in runStandby, if stopCh has been closed, which only happens
+		// while the stateLock is held, give up the lock right away
+		select {
+		case <-stopCh:
+			c.stateLock.Unlock()
+		default:
+			close(lockGrabbedCh)
+		}
+	}()
+
+	select {
+	case <-stopCh:
+		return false
+	case <-lockGrabbedCh:
+	}
+	defer c.stateLock.Unlock()
+
+	if c.sealed {
+		return false
+	}
+	if !c.perfStandby {
+		if err := c.setupPerfStandby(); err != nil {
+			c.logger.Error("performance standby setup failed, forwarding all requests", "error", err)
+		}
+	}
+	return true
+}
+
+// setupPerfStandby loads what is needed to serve read-only requests on a
+// standby. The stateLock must be held for writing. On failure the node
+// remains a standby that forwards every request.
+func (c *Core) setupPerfStandby() (retErr error) {
+	c.activeContext, c.activeContextCancelFunc = context.WithCancel(context.Background())
+	ctx := c.activeContext
+
+	defer func() {
+		if retErr != nil {
+			c.teardownPerfStandby()
+		}
+	}()
+	c.logger.Info("performance standby setup starting")
+
+	c.perfStandbyStorage.setReadOnly(true)
+
+	c.physicalCache.Purge(ctx)
+	if !c.cachingDisabled {
+		c.physicalCache.SetEnabled(true)
+	}
+
+	if err := c.ensureWrappingKey(ctx); err != nil {
+		return err
+	}
+	if err := c.setupPluginCatalog(); err != nil {
+		return err
+	}
+	if err := c.loadNamespaces(ctx); err != nil {
+		return err
+	}
+	if err := c.loadMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupPolicyStore(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCORSConfig(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupNamespaces(ctx); err != nil {
+		return err
+	}
+	c.setupPerfStandbyExpiration()
+	if err := c.loadAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.loadIdentityStoreArtifacts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAuditedHeadersConfig(ctx); err != nil {
+		return err
+	}
+
+	c.perfStandby = true
+	c.logger.Info("performance standby setup complete")
+	return nil
+}
+
+// setupPerfStandbyExpiration creates an expiration manager that can look up
+// leases but leaves their expiration to the active node
+func (c *Core) setupPerfStandbyExpiration() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	view := c.systemBarrierView.SubView(expirationSubPath)
+	mgr := NewExpirationManager(c, view, c.logger.ResetNamed("expiration"))
+	atomic.StoreInt32(&mgr.restoreMode, 0)
+	c.expiration = mgr
+
+	c.tokenStore.SetExpirationManager(mgr)
+}
+
+// teardownPerfStandby reverses setupPerfStandby. The stateLock must be held
+// for writing.
+func (c *Core) teardownPerfStandby() error {
+	c.logger.Info("performance standby teardown starting")
+	c.perfStandby = false
+
+	if c.activeContextCancelFunc != nil {
+		c.activeContextCancelFunc()
+	}
+
+	var result error
+	if err := c.teardownAudits(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down audits: {{err}}", err))
+	}
+	if err := c.stopExpiration(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
+	}
+	if err := c.teardownCredentials(c.activeContext); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
+	}
+	if err := c.teardownPolicyStore(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down policy store: {{err}}", err))
+	}
+	if err := c.unloadMounts(c.activeContext); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
+	}
+	if err := c.teardownNamespaces(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down namespaces: {{err}}", err))
+	}
+
+	c.physicalCache.SetEnabled(false)
+	c.physicalCache.Purge(c.activeContext)
+	c.perfStandbyStorage.setReadOnly(false)
+
+	c.logger.Info("performance standby teardown complete")
+	return result
+}
+
+// reloadPerfStandby reloads the state of a performance standby, when what it
+// loaded has changed on the active node
+func (c *Core) reloadPerfStandby() {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.sealed || !c.standby || !c.perfStandby {
+		return
+	}
+
+	c.logger.Info("reloading performance standby")
+	if err := c.teardownPerfStandby(); err != nil {
+		c.logger.Error("performance standby teardown failed", "error", err)
+	}
+	if err := c.setupPerfStandby(); err != nil {
+		c.logger.Error("performance standby setup failed, forwarding all requests", "error", err)
+	}
+}
+
+// perfStandbyInvalidations answers a performance standby asking for the keys
+// written since the given position of the invalidation log
+func (c *Core) perfStandbyInvalidations(in *EchoRequest, reply *EchoReply) {
+	keys, epoch, index, ok := c.perfStandbyStorage.log.since(in.InvalidationEpoch, in.InvalidationIndex)
+	reply.InvalidationEpoch = epoch
+	reply.InvalidationIndex = index
+	reply.InvalidatedKeys = keys
+	reply.InvalidationReset = !ok
+}
+
+// applyPerfStandbyInvalidations invalidates the keys that the active node has
+// reported as written since the last heartbeat
+func (c *Core) applyPerfStandbyInvalidations(reply *EchoReply) {
+	ctx := context.Background()
+
+	epoch, _ := c.perfStandbyPosition.get()
+	if reply.InvalidationReset || reply.InvalidationEpoch != epoch {
+		c.logger.Debug("invalidating all cached entries", "epoch", reply.InvalidationEpoch)
+		c.physicalCache.Purge(ctx)
+		c.perfStandbyPosition.set(reply.InvalidationEpoch, reply.InvalidationIndex)
+		c.reloadPerfStandby()
+		return
+	}
+
+	var reload bool
+	for _, key := range reply.InvalidatedKeys {
+		if c.invalidatePerfStandbyKey(ctx, key) {
+			reload = true
+		}
+	}
+	c.perfStandbyPosition.set(reply.InvalidationEpoch, reply.InvalidationIndex)
+
+	if reload {
+		c.reloadPerfStandby()
+	}
+}
+
+// invalidatePerfStandbyKey removes a key from the cache and lets the backend
+// holding it know that it has changed. It returns true if the key holds
+// state that requires reloading the performance standby.
+func (c *Core) invalidatePerfStandbyKey(ctx context.Context, key string) bool {
+	c.physicalCache.Invalidate(ctx, key)
+
+	for _, reloadPath := range perfStandbyReloadPaths {
+		if key == reloadPath {
+			return true
+		}
+	}
+	if strings.HasPrefix(key, coreNamespacesPrefix) {
+		return true
+	}
+
+	mountPath, prefix, ok := c.router.MatchingStoragePrefixByStoragePath(key)
+	if !ok {
+		return false
+	}
+	if backend := c.router.MatchingBackend(mountPath); backend != nil {
+		backend.InvalidateKey(ctx, strings.TrimPrefix(key, prefix))
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestInvalidationLog(t *testing.T) {
+	log := newInvalidationLog(2)
+
+	// Nothing is recorded until the log is enabled
+	log.record("foo")
+	if _, _, _, ok := log.since("", 0); ok {
+		t.Fatal("expected a disabled log to require a reset")
+	}
+
+	if err := log.reset(true); err != nil {
+		t.Fatal(err)
+	}
+	_, epoch, index, ok := log.since("", 0)
+	if ok {
+		t.Fatal("expected an unknown epoch to require a reset")
+	}
+	if index != 0 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	log.record("foo")
+	log.record("bar")
+	keys, curEpoch, index, ok := log.since(epoch, 0)
+	if !ok || curEpoch != epoch || index != 2 {
+		t.Fatalf("bad: %v %q %d", ok, curEpoch, index)
+	}
+	if !reflect.DeepEqual(keys, []string{"foo", "bar"}) {
+		t.Fatalf("bad: %v", keys)
+	}
+
+	keys, _, _, ok = log.since(epoch, 2)
+	if !ok || len(keys) != 0 {
+		t.Fatalf("bad: %v %v", ok, keys)
+	}
+
+	// Filling the log drops the oldest keys
+	log.record("baz")
+	log.record("qux")
+	if _, _, _, ok := log.since(epoch, 0); ok {
+		t.Fatal("expected a dropped position to require a reset")
+	}
+	keys, _, index, ok = log.since(epoch, 2)
+	if !ok || index != 4 {
+		t.Fatalf("bad: %v %d", ok, index)
+	}
+	if !reflect.DeepEqual(keys, []string{"baz", "qux"}) {
+		t.Fatalf("bad: %v", keys)
+	}
+
+	// A position ahead of the log is from another epoch
+	if _, _, _, ok := log.since(epoch, 5); ok {
+		t.Fatal("expected a position past the log to require a reset")
+	}
+}
+
+func TestCore_PerfStandby(t *testing.T) {
+	cluster := NewTestCluster(t, &CoreConfig{
+		PerformanceStandby: true,
+	}, nil)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	active := cluster.Cores[0].Core
+	standby := cluster.Cores[1].Core
+	TestWaitActive(t, active)
+
+	write := func(core *Core, path, value string) error {
+		_, err := core.HandleRequest(&logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        path,
+			ClientToken: cluster.RootToken,
+			Data: map[string]interface{}{
+				"value": value,
+			},
+		})
+		return err
+	}
+	read := func(core *Core, path string) interface{} {
+		resp, err := core.HandleRequest(&logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: cluster.RootToken,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			return nil
+		}
+		return resp.Data["value"]
+	}
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(15 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if err := write(active, "secret/foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("performance standby", standby.PerfStandby)
+
+	// Reads are served by the standby
+	waitFor("read on standby", func() bool {
+		return read(standby, "secret/foo") == "bar"
+	})
+
+	// Writes are refused
+	err := write(standby, "secret/foo", "baz")
+	if err != logical.ErrReadOnly {
+		t.Fatalf("expected read-only error, got: %v", err)
+	}
+
+	// Writes on the active node invalidate the cached entries
+	if err := write(active, "secret/foo", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("invalidation of the written key", func() bool {
+		return read(standby, "secret/foo") == "baz"
+	})
+
+	// New mounts are loaded
+	_, err = active.HandleRequest(&logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/mounts/other",
+		ClientToken: cluster.RootToken,
+		Data: map[string]interface{}{
+			"type": "kv",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := write(active, "other/foo", "qux"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("reload of the mount table", func() bool {
+		return read(standby, "other/foo") == "qux"
+	})
+}
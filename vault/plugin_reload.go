@@ -82,6 +82,7 @@ func (c *Core) reloadBackendCommon(ctx context.Context, entry *MountEntry, isAut
 	if isAuth {
 		path = credentialRoutePrefix + path
 	}
+	path = entry.Namespace().Path + path
 
 	// Fast-path out if the backend doesn't exist
 	raw, ok := c.router.root.Get(path)
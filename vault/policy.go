@@ -103,6 +103,27 @@ type PathRules struct {
 	RequiredParametersHCL []string                 `hcl:"required_parameters"`
 }
 
+// ControlGroup holds the authorizations that have to be given before a
+// request matching a path is processed
+type ControlGroup struct {
+	TTL     time.Duration
+	Factors []*ControlGroupFactor
+}
+
+// ControlGroupFactor is a single authorization requirement of a control group
+type ControlGroupFactor struct {
+	Name     string
+	Identity *IdentityFactor
+}
+
+// IdentityFactor requires a number of approvals from members of the given
+// identity groups
+type IdentityFactor struct {
+	GroupIDs   []string `hcl:"group_ids"`
+	GroupNames []string `hcl:"group_names"`
+	Approvals  int      `hcl:"approvals"`
+}
+
 type ACLPermissions struct {
 	CapabilitiesBitmap uint32
 	MinWrappingTTL     time.Duration
@@ -110,6 +131,7 @@ type ACLPermissions struct {
 	AllowedParameters  map[string][]interface{}
 	DeniedParameters   map[string][]interface{}
 	RequiredParameters []string
+	ControlGroup       *ControlGroup
 }
 
 func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
@@ -144,6 +166,14 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 		ret.DeniedParameters = clonedDenied.(map[string][]interface{})
 	}
 
+	if p.ControlGroup != nil {
+		clonedControlGroup, err := copystructure.Copy(p.ControlGroup)
+		if err != nil {
+			return nil, err
+		}
+		ret.ControlGroup = clonedControlGroup.(*ControlGroup)
+	}
+
 	return ret, nil
 }
 
@@ -204,6 +234,7 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			"required_parameters",
 			"min_wrapping_ttl",
 			"max_wrapping_ttl",
+			"control_group",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
@@ -297,6 +328,15 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 		if len(pc.RequiredParametersHCL) > 0 {
 			pc.Permissions.RequiredParameters = pc.RequiredParametersHCL[:]
 		}
+		if ot, ok := item.Val.(*ast.ObjectType); ok {
+			if o := ot.List.Filter("control_group"); len(o.Items) > 0 {
+				cg, err := parseControlGroup(o.Items[0].Val)
+				if err != nil {
+					return multierror.Prefix(err, fmt.Sprintf("path %q: control_group:", key))
+				}
+				pc.Permissions.ControlGroup = cg
+			}
+		}
 
 	PathFinished:
 		paths = append(paths, &pc)
@@ -306,6 +346,78 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseControlGroup(node ast.Node) (*ControlGroup, error) {
+	ot, ok := node.(*ast.ObjectType)
+	if !ok {
+		return nil, errors.New("must be an object")
+	}
+	if err := checkHCLKeys(ot, []string{"ttl", "factor"}); err != nil {
+		return nil, err
+	}
+
+	var cgHCL struct {
+		TTL interface{} `hcl:"ttl"`
+	}
+	if err := hcl.DecodeObject(&cgHCL, ot); err != nil {
+		return nil, err
+	}
+
+	cg := new(ControlGroup)
+	if cgHCL.TTL != nil {
+		dur, err := parseutil.ParseDurationSecond(cgHCL.TTL)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing ttl: {{err}}", err)
+		}
+		cg.TTL = dur
+	}
+
+	for _, item := range ot.List.Filter("factor").Items {
+		if len(item.Keys) == 0 {
+			return nil, errors.New("factor must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+		if err := checkHCLKeys(item.Val, []string{"identity"}); err != nil {
+			return nil, multierror.Prefix(err, fmt.Sprintf("factor %q:", name))
+		}
+
+		factor := &ControlGroupFactor{
+			Name: name,
+		}
+
+		if fot, ok := item.Val.(*ast.ObjectType); ok {
+			if o := fot.List.Filter("identity"); len(o.Items) > 0 {
+				if err := checkHCLKeys(o.Items[0].Val, []string{"group_ids", "group_names", "approvals"}); err != nil {
+					return nil, multierror.Prefix(err, fmt.Sprintf("factor %q:", name))
+				}
+				var identity IdentityFactor
+				if err := hcl.DecodeObject(&identity, o.Items[0].Val); err != nil {
+					return nil, multierror.Prefix(err, fmt.Sprintf("factor %q:", name))
+				}
+				factor.Identity = &identity
+			}
+		}
+
+		switch {
+		case factor.Identity == nil:
+			return nil, fmt.Errorf("factor %q: missing identity block", name)
+		case len(factor.Identity.GroupIDs) == 0 && len(factor.Identity.GroupNames) == 0:
+			return nil, fmt.Errorf("factor %q: group_ids or group_names must be set", name)
+		case factor.Identity.Approvals < 0:
+			return nil, fmt.Errorf("factor %q: approvals cannot be negative", name)
+		case factor.Identity.Approvals == 0:
+			factor.Identity.Approvals = 1
+		}
+
+		cg.Factors = append(cg.Factors, factor)
+	}
+
+	if len(cg.Factors) == 0 {
+		return nil, errors.New("at least one factor is required")
+	}
+
+	return cg, nil
+}
+
 func checkHCLKeys(node ast.Node, valid []string) error {
 	var list *ast.ObjectList
 	switch n := node.(type) {
@@ -12,6 +12,7 @@ import (
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
@@ -41,6 +42,18 @@ path "cubbyhole/response" {
     capabilities = ["create", "read"]
 }
 
+path "sys/wrapping/unwrap" {
+    capabilities = ["update"]
+}
+`
+
+	// controlGroupPolicy is the policy that allows control group tokens to
+	// be looked up and unwrapped.
+	controlGroupPolicy = `
+path "sys/wrapping/lookup" {
+    capabilities = ["update"]
+}
+
 path "sys/wrapping/unwrap" {
     capabilities = ["update"]
 }
@@ -204,6 +217,10 @@ func (c *Core) setupPolicyStore(ctx context.Context) error {
 	if err := c.policyStore.loadACLPolicy(ctx, responseWrappingPolicyName, responseWrappingPolicy); err != nil {
 		return err
 	}
+	// Ensure that the control group policy exists
+	if err := c.policyStore.loadACLPolicy(ctx, controlGroupPolicyName, controlGroupPolicy); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -215,6 +232,64 @@ func (c *Core) teardownPolicyStore() error {
 	return nil
 }
 
+// getACLView returns the view holding the ACL policies of the given
+// namespace
+func (ps *PolicyStore) getACLView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return ps.aclView
+	}
+	return ps.core.namespaceView(ns).SubView(systemBarrierPrefix + policyACLSubPath)
+}
+
+// cacheKey returns the key used for the given policy in the cache and the
+// type map. Policies of the root namespace are keyed by name alone.
+func (ps *PolicyStore) cacheKey(ns *namespace.Namespace, name string) string {
+	if ns.ID == namespace.RootNamespaceID {
+		return name
+	}
+	return ns.ID + "/" + name
+}
+
+// loadNamespacePolicies populates the type map with the policies stored
+// within the given namespace
+func (ps *PolicyStore) loadNamespacePolicies(ctx context.Context, ns *namespace.Namespace) error {
+	keys, err := logical.CollectKeys(ctx, ps.getACLView(ns))
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error collecting acl policy keys for namespace %q: {{err}}", ns.Path), err)
+	}
+	for _, key := range keys {
+		ps.policyTypeMap.Store(ps.cacheKey(ns, ps.sanitizeName(key)), PolicyTypeACL)
+	}
+	return nil
+}
+
+// removeNamespacePolicies deletes all of the policies of the given namespace
+func (ps *PolicyStore) removeNamespacePolicies(ctx context.Context, ns *namespace.Namespace) error {
+	ps.modifyLock.Lock()
+	defer ps.modifyLock.Unlock()
+
+	if err := logical.ClearView(ctx, ps.getACLView(ns)); err != nil {
+		return errwrap.Wrapf("failed to delete namespace policies: {{err}}", err)
+	}
+
+	prefix := ps.cacheKey(ns, "")
+	ps.policyTypeMap.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			ps.policyTypeMap.Delete(key)
+		}
+		return true
+	})
+	if ps.tokenPoliciesLRU != nil {
+		for _, key := range ps.tokenPoliciesLRU.Keys() {
+			if strings.HasPrefix(key.(string), prefix) {
+				ps.tokenPoliciesLRU.Remove(key)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (ps *PolicyStore) invalidate(ctx context.Context, name string, policyType PolicyType) {
 	// This may come with a prefixed "/" due to joining the file path
 	saneName := strings.TrimPrefix(name, "/")
@@ -224,7 +299,7 @@ func (ps *PolicyStore) invalidate(ctx context.Context, name string, policyType P
 	switch policyType {
 	case PolicyTypeACL:
 		if ps.tokenPoliciesLRU != nil {
-			ps.tokenPoliciesLRU.Remove(saneName)
+			ps.tokenPoliciesLRU.Remove(ps.cacheKey(namespaceFromContext(ctx), saneName))
 		}
 
 	default:
@@ -260,6 +335,10 @@ func (ps *PolicyStore) SetPolicy(ctx context.Context, p *Policy) error {
 func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
 	ps.modifyLock.Lock()
 	defer ps.modifyLock.Unlock()
+
+	ns := namespaceFromContext(ctx)
+	key := ps.cacheKey(ns, p.Name)
+
 	// Create the entry
 	entry, err := logical.StorageEntryJSON(p.Name, &PolicyEntry{
 		Version: 2,
@@ -271,14 +350,14 @@ func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
 	}
 	switch p.Type {
 	case PolicyTypeACL:
-		if err := ps.aclView.Put(ctx, entry); err != nil {
+		if err := ps.getACLView(ns).Put(ctx, entry); err != nil {
 			return errwrap.Wrapf("failed to persist policy: {{err}}", err)
 		}
-		ps.policyTypeMap.Store(p.Name, PolicyTypeACL)
+		ps.policyTypeMap.Store(key, PolicyTypeACL)
 
 		if ps.tokenPoliciesLRU != nil {
 			// Update the LRU cache
-			ps.tokenPoliciesLRU.Add(p.Name, p)
+			ps.tokenPoliciesLRU.Add(key, p)
 		}
 
 	default:
@@ -295,15 +374,18 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 	// Policies are normalized to lower-case
 	name = ps.sanitizeName(name)
 
+	ns := namespaceFromContext(ctx)
+	key := ps.cacheKey(ns, name)
+
 	var cache *lru.TwoQueueCache
 	var view *BarrierView
 	switch policyType {
 	case PolicyTypeACL:
 		cache = ps.tokenPoliciesLRU
-		view = ps.aclView
+		view = ps.getACLView(ns)
 	case PolicyTypeToken:
 		cache = ps.tokenPoliciesLRU
-		val, ok := ps.policyTypeMap.Load(name)
+		val, ok := ps.policyTypeMap.Load(key)
 		if !ok {
 			// Doesn't exist
 			return nil, nil
@@ -311,7 +393,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 		policyType = val.(PolicyType)
 		switch policyType {
 		case PolicyTypeACL:
-			view = ps.aclView
+			view = ps.getACLView(ns)
 		default:
 			return nil, fmt.Errorf("invalid type of policy in type map: %q", policyType)
 		}
@@ -319,16 +401,16 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 
 	if cache != nil {
 		// Check for cached policy
-		if raw, ok := cache.Get(name); ok {
+		if raw, ok := cache.Get(key); ok {
 			return raw.(*Policy), nil
 		}
 	}
 
-	// Special case the root policy
-	if policyType == PolicyTypeACL && name == "root" {
+	// Special case the root policy, which only exists in the root namespace
+	if policyType == PolicyTypeACL && name == "root" && ns.ID == namespace.RootNamespaceID {
 		p := &Policy{Name: "root"}
 		if cache != nil {
-			cache.Add(p.Name, p)
+			cache.Add(key, p)
 		}
 		return p, nil
 	}
@@ -338,7 +420,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 
 	// See if anything has added it since we got the lock
 	if cache != nil {
-		if raw, ok := cache.Get(name); ok {
+		if raw, ok := cache.Get(key); ok {
 			return raw.(*Policy), nil
 		}
 	}
@@ -375,7 +457,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 		// Reset this in case they set the name in the policy itself
 		policy.Name = name
 
-		ps.policyTypeMap.Store(name, PolicyTypeACL)
+		ps.policyTypeMap.Store(key, PolicyTypeACL)
 
 	default:
 		return nil, fmt.Errorf("unknown policy type %q", policyEntry.Type.String())
@@ -383,7 +465,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 
 	if cache != nil {
 		// Update the LRU cache
-		cache.Add(name, policy)
+		cache.Add(key, policy)
 	}
 
 	return policy, nil
@@ -398,7 +480,7 @@ func (ps *PolicyStore) ListPolicies(ctx context.Context, policyType PolicyType)
 	var err error
 	switch policyType {
 	case PolicyTypeACL:
-		keys, err = logical.CollectKeys(ctx, ps.aclView)
+		keys, err = logical.CollectKeys(ctx, ps.getACLView(namespaceFromContext(ctx)))
 	default:
 		return nil, fmt.Errorf("unknown policy type %q", policyType)
 	}
@@ -433,6 +515,9 @@ func (ps *PolicyStore) DeletePolicy(ctx context.Context, name string, policyType
 	// Policies are normalized to lower-case
 	name = ps.sanitizeName(name)
 
+	ns := namespaceFromContext(ctx)
+	key := ps.cacheKey(ns, name)
+
 	switch policyType {
 	case PolicyTypeACL:
 		if strutil.StrListContains(immutablePolicies, name) {
@@ -442,17 +527,17 @@ func (ps *PolicyStore) DeletePolicy(ctx context.Context, name string, policyType
 			return fmt.Errorf("cannot delete default policy")
 		}
 
-		err := ps.aclView.Delete(ctx, name)
+		err := ps.getACLView(ns).Delete(ctx, name)
 		if err != nil {
 			return errwrap.Wrapf("failed to delete policy: {{err}}", err)
 		}
 
 		if ps.tokenPoliciesLRU != nil {
 			// Clear the cache
-			ps.tokenPoliciesLRU.Remove(name)
+			ps.tokenPoliciesLRU.Remove(key)
 		}
 
-		ps.policyTypeMap.Delete(name)
+		ps.policyTypeMap.Delete(key)
 
 	}
 	return nil
@@ -476,6 +561,7 @@ func (ps *PolicyStore) ACL(ctx context.Context, names ...string) (*ACL, error) {
 	if err != nil {
 		return nil, errwrap.Wrapf("failed to construct ACL: {{err}}", err)
 	}
+	acl.namespace = namespaceFromContext(ctx)
 	return acl, nil
 }
 
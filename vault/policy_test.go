@@ -330,3 +330,87 @@ path "/" {
 		t.Errorf("bad error: %s", err)
 	}
 }
+
+func TestPolicy_ParseControlGroup(t *testing.T) {
+	p, err := ParseACLPolicy(strings.TrimSpace(`
+path "secret/foo" {
+	capabilities = ["create", "update"]
+	control_group = {
+		ttl = "4h"
+		factor "tech leads" {
+			identity {
+				group_names = ["managers", "leads"]
+				approvals = 2
+			}
+		}
+		factor "super users" {
+			identity {
+				group_ids = ["abcd"]
+			}
+		}
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &ControlGroup{
+		TTL: 4 * time.Hour,
+		Factors: []*ControlGroupFactor{
+			&ControlGroupFactor{
+				Name: "tech leads",
+				Identity: &IdentityFactor{
+					GroupNames: []string{"managers", "leads"},
+					Approvals:  2,
+				},
+			},
+			&ControlGroupFactor{
+				Name: "super users",
+				Identity: &IdentityFactor{
+					GroupIDs:  []string{"abcd"},
+					Approvals: 1,
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(p.Paths[0].Permissions.ControlGroup, expected) {
+		t.Fatalf("bad: %#v", p.Paths[0].Permissions.ControlGroup)
+	}
+}
+
+func TestPolicy_ParseBadControlGroup(t *testing.T) {
+	_, err := ParseACLPolicy(strings.TrimSpace(`
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "1h"
+	}
+}
+`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "at least one factor is required") {
+		t.Errorf("bad error: %s", err)
+	}
+
+	_, err = ParseACLPolicy(strings.TrimSpace(`
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		factor "ops" {
+			identity {
+				approvals = 1
+			}
+		}
+	}
+}
+`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "group_ids or group_names must be set") {
+		t.Errorf("bad error: %s", err)
+	}
+}
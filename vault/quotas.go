@@ -0,0 +1,180 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// leaseCountQuotaSubPath is the sub-path used for the lease count quota
+	// rules. This is nested under the system view.
+	leaseCountQuotaSubPath = "quotas/lease-count/"
+)
+
+var (
+	// errLeaseCountQuotaPathInMount is returned when a quota rule is scoped
+	// to a path within a mount, where leases are not counted separately
+	errLeaseCountQuotaPathInMount = errors.New("path must be a mount path or a namespace path")
+)
+
+// LeaseCountQuota limits the number of leases, including the leases of
+// tokens, that can exist under a mount path or a namespace
+type LeaseCountQuota struct {
+	Name string `json:"name"`
+
+	// Path is the mount path or the namespace path the quota applies to,
+	// always ending in a slash. An empty path applies to every lease.
+	Path string `json:"path"`
+
+	MaxLeases int64 `json:"max_leases"`
+}
+
+// leaseCountQuotaStore holds the lease count quota rules, which are read
+// from storage once and then served from memory
+type leaseCountQuotaStore struct {
+	view *BarrierView
+
+	l     sync.RWMutex
+	rules map[string]*LeaseCountQuota
+}
+
+// setupLeaseCountQuotas is invoked after the mounts and namespaces have been
+// set up, and before the expiration manager, which enforces the rules
+func (c *Core) setupLeaseCountQuotas(ctx context.Context) error {
+	store := &leaseCountQuotaStore{
+		view:  c.systemBarrierView.SubView(leaseCountQuotaSubPath),
+		rules: make(map[string]*LeaseCountQuota),
+	}
+
+	names, err := store.view.List(ctx, "")
+	if err != nil {
+		return errwrap.Wrapf("failed to list lease count quotas: {{err}}", err)
+	}
+	for _, name := range names {
+		raw, err := store.view.Get(ctx, name)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to read lease count quota %q: {{err}}", name), err)
+		}
+		if raw == nil {
+			continue
+		}
+
+		rule := new(LeaseCountQuota)
+		if err := jsonutil.DecodeJSON(raw.Value, rule); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to decode lease count quota %q: {{err}}", name), err)
+		}
+		store.rules[rule.Name] = rule
+	}
+
+	c.leaseCountQuotas = store
+	return nil
+}
+
+// teardownLeaseCountQuotas is used to reverse setupLeaseCountQuotas when the
+// vault is being sealed
+func (c *Core) teardownLeaseCountQuotas() error {
+	c.leaseCountQuotas = nil
+	return nil
+}
+
+// normalizeLeaseCountQuotaPath returns the path of a quota rule ending in a
+// slash, and verifies that it does not point within a mount, since leases
+// are only counted by mount
+func (c *Core) normalizeLeaseCountQuotaPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", nil
+	}
+	path += "/"
+
+	if strings.Contains(path, "..") {
+		return "", consts.ErrPathContainsParentReferences
+	}
+	if mount := c.router.MatchingMount(path); mount != "" && mount != path {
+		return "", errLeaseCountQuotaPathInMount
+	}
+	return path, nil
+}
+
+// get returns the rule of the given name, or nil if there is none
+func (s *leaseCountQuotaStore) get(name string) *LeaseCountQuota {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	rule, ok := s.rules[name]
+	if !ok {
+		return nil
+	}
+	ret := *rule
+	return &ret
+}
+
+// list returns the sorted names of the rules
+func (s *leaseCountQuotaStore) list() []string {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	names := make([]string, 0, len(s.rules))
+	for name := range s.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// set persists a rule, replacing any rule of the same name
+func (s *leaseCountQuotaStore) set(ctx context.Context, rule *LeaseCountQuota) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	entry, err := logical.StorageEntryJSON(rule.Name, rule)
+	if err != nil {
+		return errwrap.Wrapf("failed to encode lease count quota: {{err}}", err)
+	}
+	if err := s.view.Put(ctx, entry); err != nil {
+		return errwrap.Wrapf("failed to persist lease count quota: {{err}}", err)
+	}
+
+	s.rules[rule.Name] = rule
+	return nil
+}
+
+// delete removes the rule of the given name
+func (s *leaseCountQuotaStore) delete(ctx context.Context, name string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if err := s.view.Delete(ctx, name); err != nil {
+		return errwrap.Wrapf("failed to delete lease count quota: {{err}}", err)
+	}
+
+	delete(s.rules, name)
+	return nil
+}
+
+// matching returns the rules that apply to the given lease ID
+func (s *leaseCountQuotaStore) matching(leaseID string) []*LeaseCountQuota {
+	if s == nil {
+		return nil
+	}
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	var rules []*LeaseCountQuota
+	for _, rule := range s.rules {
+		if strings.HasPrefix(leaseID, rule.Path) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,249 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/inmem"
+)
+
+func TestCore_LeaseCountQuota(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return c.HandleRequest(&logical.Request{
+			Operation:   op,
+			Path:        path,
+			ClientToken: root,
+			Data:        data,
+		})
+	}
+	count := func(name string) int64 {
+		t.Helper()
+		resp, err := request(logical.ReadOperation, "sys/quotas/lease-count/"+name, nil)
+		if err != nil || resp == nil {
+			t.Fatalf("bad: %v %v", resp, err)
+		}
+		return resp.Data["count"].(int64)
+	}
+	register := func() (string, error) {
+		return c.expiration.Register(&logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        "secret/foo",
+			ClientToken: root,
+		}, &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+		})
+	}
+
+	// Rules cannot be scoped within a mount
+	resp, err := request(logical.UpdateOperation, "sys/quotas/lease-count/kv", map[string]interface{}{
+		"path":       "secret/foo",
+		"max_leases": 2,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got: %v %v", resp, err)
+	}
+
+	resp, err = request(logical.UpdateOperation, "sys/quotas/lease-count/kv", map[string]interface{}{
+		"path":       "secret",
+		"max_leases": 2,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+
+	resp, err = request(logical.ListOperation, "sys/quotas/lease-count/", nil)
+	if err != nil || resp == nil || len(resp.Data["keys"].([]string)) != 1 {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+
+	leaseID, err := register()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := register(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("kv"); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	_, err = register()
+	if err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected quota error, got: %v", err)
+	}
+	if n := count("kv"); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// Revoking a lease makes room for another. The passthrough backend does
+	// not support revoking, so the revocation is forced.
+	if err := c.expiration.revokeCommon(leaseID, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("kv"); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+	if _, err := register(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Token creation is limited as well
+	resp, err = request(logical.UpdateOperation, "sys/quotas/lease-count/tokens", map[string]interface{}{
+		"path":       "auth/token",
+		"max_leases": 100,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	tokens := count("tokens")
+	resp, err = request(logical.UpdateOperation, "sys/quotas/lease-count/tokens", map[string]interface{}{
+		"max_leases": tokens + 1,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+
+	createToken := func() (*logical.Response, error) {
+		return request(logical.UpdateOperation, "auth/token/create", map[string]interface{}{
+			"policies": "default",
+			"ttl":      "1h",
+		})
+	}
+	resp, err = createToken()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	resp, err = createToken()
+	if err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected quota error, got: %v %v", resp, err)
+	}
+	if resp == nil || !strings.Contains(resp.Error().Error(), `quota "tokens"`) {
+		t.Fatalf("bad: %v", resp)
+	}
+
+	// The counts are rebuilt as the leases are restored after unsealing
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for c.expiration.inRestoreMode() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the leases to be restored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := count("kv"); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+	if n := count("tokens"); n != tokens+1 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	resp, err = request(logical.DeleteOperation, "sys/quotas/lease-count/kv", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	if _, err := register(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// slowPutBackend delays writes so that concurrent requests interleave
+type slowPutBackend struct {
+	physical.Backend
+}
+
+func (b slowPutBackend) Put(ctx context.Context, entry *physical.Entry) error {
+	time.Sleep(time.Millisecond)
+	return b.Backend.Put(ctx, entry)
+}
+
+func TestCore_LeaseCountQuota_ConcurrentTokens(t *testing.T) {
+	inm, err := inmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, root := TestCoreUnsealedBackend(t, slowPutBackend{inm})
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return c.HandleRequest(&logical.Request{
+			Operation:   op,
+			Path:        path,
+			ClientToken: root,
+			Data:        data,
+		})
+	}
+
+	const max, extra = 20, 10
+	resp, err := request(logical.UpdateOperation, "sys/quotas/lease-count/tokens", map[string]interface{}{
+		"path":       "auth/token",
+		"max_leases": max,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	if n := c.expiration.leaseCount("auth/token/"); n != 0 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	var wg sync.WaitGroup
+	codes := make(chan int, max+extra)
+	for i := 0; i < max+extra; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := &logical.Request{
+				Operation:   logical.UpdateOperation,
+				Path:        "auth/token/create",
+				ClientToken: root,
+				Data: map[string]interface{}{
+					"policies": "default",
+					"ttl":      "1h",
+				},
+			}
+			resp, err := c.HandleRequest(req)
+			code, _ := logical.RespondErrorCommon(req, resp, err)
+			codes <- code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	var created, limited int
+	for code := range codes {
+		switch code {
+		case 0:
+			created++
+		case http.StatusTooManyRequests:
+			limited++
+		default:
+			t.Fatalf("unexpected status code %d", code)
+		}
+	}
+	if created != max || limited != extra {
+		t.Fatalf("bad: created %d, limited %d", created, limited)
+	}
+	if n := c.expiration.leaseCount("auth/token/"); n != max {
+		t.Fatalf("bad: %d", n)
+	}
+}
@@ -274,12 +274,17 @@ func (c *Core) refreshRequestForwardingConnection(ctx context.Context, clusterAd
 		c.logger.Error("err setting up forwarding rpc client", "error", err)
 		return err
 	}
+	heartbeatInterval := HeartbeatInterval
+	if c.perfStandbyEnabled {
+		heartbeatInterval = perfStandbyHeartbeatInterval
+	}
+
 	c.rpcClientConnContext = dctx
 	c.rpcClientConnCancelFunc = cancelFunc
 	c.rpcForwardingClient = &forwardingClient{
 		RequestForwardingClient: NewRequestForwardingClient(c.rpcClientConn),
 		core:        c,
-		echoTicker:  time.NewTicker(HeartbeatInterval),
+		echoTicker:  time.NewTicker(heartbeatInterval),
 		echoContext: dctx,
 	}
 	c.rpcForwardingClient.startHeartbeat()
@@ -423,10 +428,14 @@ func (s *forwardedRequestRPCServer) Echo(ctx context.Context, in *EchoRequest) (
 	if in.ClusterAddr != "" {
 		s.core.clusterPeerClusterAddrsCache.Set(in.ClusterAddr, nil, 0)
 	}
-	return &EchoReply{
+	reply := &EchoReply{
 		Message:          "pong",
 		ReplicationState: uint32(s.core.ReplicationState()),
-	}, nil
+	}
+	if in.PerfStandby {
+		s.core.perfStandbyInvalidations(in, reply)
+	}
+	return reply, nil
 }
 
 type forwardingClient struct {
@@ -447,11 +456,17 @@ func (c *forwardingClient) startHeartbeat() {
 			clusterAddr := c.core.clusterAddr
 			c.core.stateLock.RUnlock()
 
-			ctx, cancel := context.WithTimeout(c.echoContext, 2*time.Second)
-			resp, err := c.RequestForwardingClient.Echo(ctx, &EchoRequest{
+			req := &EchoRequest{
 				Message:     "ping",
 				ClusterAddr: clusterAddr,
-			})
+			}
+			if c.core.perfStandbyEnabled {
+				req.PerfStandby = true
+				req.InvalidationEpoch, req.InvalidationIndex = c.core.perfStandbyPosition.get()
+			}
+
+			ctx, cancel := context.WithTimeout(c.echoContext, 2*time.Second)
+			resp, err := c.RequestForwardingClient.Echo(ctx, req)
 			cancel()
 			if err != nil {
 				c.core.logger.Debug("forwarding: error sending echo request to active node", "error", err)
@@ -468,6 +483,11 @@ func (c *forwardingClient) startHeartbeat() {
 			// Store the active node's replication state to display in
 			// sys/health calls
 			atomic.StoreUint32(c.core.activeNodeReplicationState, resp.ReplicationState)
+
+			// Invalidate what has been written on the active node
+			if req.PerfStandby {
+				c.core.applyPerfStandbyInvalidations(resp)
+			}
 			//c.core.logger.Debug("forwarding: successful heartbeat")
 		}
 
@@ -42,6 +42,12 @@ type EchoRequest struct {
 	// ClusterAddrs is used to send up a list of cluster addresses to a dr
 	// primary from a dr secondary
 	ClusterAddrs []string `protobuf:"bytes,3,rep,name=cluster_addrs,json=clusterAddrs" json:"cluster_addrs,omitempty"`
+	// PerfStandby is set by performance standbys, which receive the keys
+	// written on the active node since the given point of its invalidation
+	// log
+	PerfStandby       bool   `protobuf:"varint,4,opt,name=perf_standby,json=perfStandby" json:"perf_standby,omitempty"`
+	InvalidationEpoch string `protobuf:"bytes,5,opt,name=invalidation_epoch,json=invalidationEpoch" json:"invalidation_epoch,omitempty"`
+	InvalidationIndex uint64 `protobuf:"varint,6,opt,name=invalidation_index,json=invalidationIndex" json:"invalidation_index,omitempty"`
 }
 
 func (m *EchoRequest) Reset()                    { *m = EchoRequest{} }
@@ -70,10 +76,40 @@ func (m *EchoRequest) GetClusterAddrs() []string {
 	return nil
 }
 
+func (m *EchoRequest) GetPerfStandby() bool {
+	if m != nil {
+		return m.PerfStandby
+	}
+	return false
+}
+
+func (m *EchoRequest) GetInvalidationEpoch() string {
+	if m != nil {
+		return m.InvalidationEpoch
+	}
+	return ""
+}
+
+func (m *EchoRequest) GetInvalidationIndex() uint64 {
+	if m != nil {
+		return m.InvalidationIndex
+	}
+	return 0
+}
+
 type EchoReply struct {
 	Message          string   `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
 	ClusterAddrs     []string `protobuf:"bytes,2,rep,name=cluster_addrs,json=clusterAddrs" json:"cluster_addrs,omitempty"`
 	ReplicationState uint32   `protobuf:"varint,3,opt,name=replication_state,json=replicationState" json:"replication_state,omitempty"`
+	// InvalidationEpoch and InvalidationIndex are the point of the
+	// invalidation log of the active node that the performance standby has
+	// reached once it has invalidated the InvalidatedKeys. InvalidationReset
+	// is set when the requested point is no longer in the log, in which case
+	// the whole cache has to be invalidated.
+	InvalidationEpoch string   `protobuf:"bytes,4,opt,name=invalidation_epoch,json=invalidationEpoch" json:"invalidation_epoch,omitempty"`
+	InvalidationIndex uint64   `protobuf:"varint,5,opt,name=invalidation_index,json=invalidationIndex" json:"invalidation_index,omitempty"`
+	InvalidatedKeys   []string `protobuf:"bytes,6,rep,name=invalidated_keys,json=invalidatedKeys" json:"invalidated_keys,omitempty"`
+	InvalidationReset bool     `protobuf:"varint,7,opt,name=invalidation_reset,json=invalidationReset" json:"invalidation_reset,omitempty"`
 }
 
 func (m *EchoReply) Reset()                    { *m = EchoReply{} }
@@ -102,6 +138,34 @@ func (m *EchoReply) GetReplicationState() uint32 {
 	return 0
 }
 
+func (m *EchoReply) GetInvalidationEpoch() string {
+	if m != nil {
+		return m.InvalidationEpoch
+	}
+	return ""
+}
+
+func (m *EchoReply) GetInvalidationIndex() uint64 {
+	if m != nil {
+		return m.InvalidationIndex
+	}
+	return 0
+}
+
+func (m *EchoReply) GetInvalidatedKeys() []string {
+	if m != nil {
+		return m.InvalidatedKeys
+	}
+	return nil
+}
+
+func (m *EchoReply) GetInvalidationReset() bool {
+	if m != nil {
+		return m.InvalidationReset
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*EchoRequest)(nil), "vault.EchoRequest")
 	proto.RegisterType((*EchoReply)(nil), "vault.EchoReply")
@@ -215,23 +279,30 @@ var _RequestForwarding_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("request_forwarding_service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 287 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x90, 0xbf, 0x4e, 0xc3, 0x30,
-	0x10, 0xc6, 0x9b, 0x96, 0x3f, 0xaa, 0xdb, 0xa2, 0xd6, 0x30, 0x44, 0x99, 0x42, 0x58, 0x22, 0x21,
-	0x39, 0x12, 0x2c, 0x2c, 0x0c, 0x0c, 0xf0, 0x00, 0xe1, 0x01, 0x22, 0xd7, 0x39, 0x12, 0x4b, 0x6e,
-	0x6c, 0x7c, 0x4e, 0xab, 0xac, 0x3c, 0x39, 0x6a, 0x92, 0xd2, 0x54, 0x95, 0x18, 0xef, 0x77, 0xa7,
-	0xef, 0xd3, 0xfd, 0x48, 0x68, 0xe1, 0xbb, 0x06, 0x74, 0xd9, 0x97, 0xb6, 0x3b, 0x6e, 0x73, 0x59,
-	0x15, 0x19, 0x82, 0xdd, 0x4a, 0x01, 0xcc, 0x58, 0xed, 0x34, 0xbd, 0xdc, 0xf2, 0x5a, 0xb9, 0xe0,
-	0xa5, 0x90, 0xae, 0xac, 0xd7, 0x4c, 0xe8, 0x4d, 0x52, 0x72, 0x2c, 0xa5, 0xd0, 0xd6, 0x24, 0xed,
-	0x2e, 0x29, 0x41, 0x19, 0xb0, 0xc9, 0x31, 0x22, 0x71, 0x8d, 0x01, 0xec, 0x02, 0x22, 0x4d, 0x66,
-	0xef, 0xa2, 0xd4, 0x69, 0x57, 0x44, 0x7d, 0x72, 0xbd, 0x01, 0x44, 0x5e, 0x80, 0xef, 0x85, 0x5e,
-	0x3c, 0x4d, 0x0f, 0x23, 0xbd, 0x27, 0x73, 0xa1, 0x6a, 0x74, 0x60, 0x33, 0x9e, 0xe7, 0xd6, 0x1f,
-	0xb7, 0xeb, 0x59, 0xcf, 0xde, 0xf2, 0xdc, 0xd2, 0x07, 0xb2, 0x18, 0x9e, 0xa0, 0x3f, 0x09, 0x27,
-	0xf1, 0x34, 0x9d, 0x0f, 0x6e, 0x30, 0xda, 0x91, 0x69, 0x57, 0x68, 0x54, 0xf3, 0x4f, 0xdd, 0x59,
-	0xd6, 0xf8, 0x3c, 0x8b, 0x3e, 0x92, 0x95, 0x05, 0xa3, 0xa4, 0xe0, 0x4e, 0xea, 0x2a, 0x43, 0xc7,
-	0x1d, 0xf8, 0x93, 0xd0, 0x8b, 0x17, 0xe9, 0x72, 0xb0, 0xf8, 0xdc, 0xf3, 0xa7, 0x1f, 0x8f, 0xac,
-	0xfa, 0x37, 0x3f, 0xfe, 0x5c, 0xd0, 0x57, 0x72, 0xd3, 0x4f, 0x07, 0x05, 0xb7, 0xec, 0xa8, 0x8a,
-	0xf5, 0x30, 0xb8, 0x3b, 0x85, 0x68, 0x74, 0x85, 0x10, 0x8d, 0x28, 0x23, 0x17, 0xfb, 0x6f, 0x28,
-	0x65, 0xad, 0x6c, 0x36, 0x70, 0x19, 0x2c, 0x4f, 0x98, 0x51, 0x4d, 0x34, 0x5a, 0x5f, 0xb5, 0xd6,
-	0x9f, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x94, 0x1d, 0xe9, 0x21, 0xda, 0x01, 0x00, 0x00,
+	// 394 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0xbf, 0xce, 0xd3, 0x30,
+	0x14, 0xc5, 0xbf, 0xb4, 0x69, 0x3f, 0xea, 0x7e, 0x85, 0xd6, 0x30, 0x58, 0x9d, 0x42, 0x58, 0x82,
+	0x10, 0x89, 0x04, 0x0b, 0x0b, 0x03, 0x43, 0x91, 0x10, 0x5b, 0xfa, 0x00, 0x91, 0x1b, 0xdf, 0x36,
+	0x16, 0x69, 0x6c, 0x7c, 0x9d, 0x42, 0x56, 0x5e, 0x88, 0x57, 0xe3, 0x11, 0x50, 0xfe, 0xd0, 0x26,
+	0x2a, 0x45, 0x62, 0xf4, 0xef, 0x5c, 0x9d, 0xeb, 0xa3, 0x7b, 0x88, 0x67, 0xe0, 0x6b, 0x09, 0x68,
+	0x93, 0xbd, 0x32, 0xdf, 0xb8, 0x11, 0xb2, 0x38, 0x24, 0x08, 0xe6, 0x24, 0x53, 0x08, 0xb5, 0x51,
+	0x56, 0xd1, 0xc9, 0x89, 0x97, 0xb9, 0x5d, 0xbf, 0x3b, 0x48, 0x9b, 0x95, 0xbb, 0x30, 0x55, 0xc7,
+	0x28, 0xe3, 0x98, 0xc9, 0x54, 0x19, 0x1d, 0x35, 0x5a, 0x94, 0x41, 0xae, 0xc1, 0x44, 0x17, 0x8b,
+	0xc8, 0x56, 0x1a, 0xb0, 0x35, 0xf0, 0x7f, 0x39, 0x64, 0xbe, 0x49, 0x33, 0x15, 0xb7, 0x9b, 0x28,
+	0x23, 0xf7, 0x47, 0x40, 0xe4, 0x07, 0x60, 0x8e, 0xe7, 0x04, 0xb3, 0xf8, 0xcf, 0x93, 0x3e, 0x27,
+	0x0f, 0x69, 0x5e, 0xa2, 0x05, 0x93, 0x70, 0x21, 0x0c, 0x1b, 0x35, 0xf2, 0xbc, 0x63, 0x1f, 0x84,
+	0x30, 0xf4, 0x05, 0x59, 0xf4, 0x47, 0x90, 0x8d, 0xbd, 0x71, 0x30, 0x8b, 0x1f, 0x7a, 0x33, 0x58,
+	0xfb, 0x68, 0x30, 0xfb, 0x04, 0x2d, 0x2f, 0xc4, 0xae, 0x62, 0xae, 0xe7, 0x04, 0x8f, 0xe2, 0x79,
+	0xcd, 0xb6, 0x2d, 0xa2, 0xaf, 0x09, 0x95, 0xc5, 0x89, 0xe7, 0x52, 0x70, 0x2b, 0x55, 0x91, 0x80,
+	0x56, 0x69, 0xc6, 0x26, 0xcd, 0xc2, 0x55, 0x5f, 0xd9, 0xd4, 0xc2, 0xd5, 0xb8, 0x2c, 0x04, 0x7c,
+	0x67, 0x53, 0xcf, 0x09, 0xdc, 0xe1, 0xf8, 0xa7, 0x5a, 0xf0, 0x7f, 0x8e, 0xc8, 0xac, 0x8d, 0xac,
+	0xf3, 0xea, 0x1f, 0x81, 0xaf, 0xd2, 0x8c, 0xfe, 0x92, 0xe6, 0x15, 0x59, 0x19, 0xd0, 0xb9, 0x4c,
+	0xdb, 0xd5, 0x68, 0xb9, 0x05, 0x36, 0xf6, 0x9c, 0x60, 0x11, 0x2f, 0x7b, 0xc2, 0xb6, 0xe6, 0x37,
+	0x72, 0xb9, 0xff, 0x97, 0x6b, 0x72, 0x23, 0x17, 0x7d, 0x49, 0x96, 0x67, 0x08, 0x22, 0xf9, 0x02,
+	0x15, 0xb2, 0x69, 0xf3, 0xe5, 0x27, 0x3d, 0xfe, 0x19, 0x2a, 0xbc, 0x72, 0x36, 0x80, 0x60, 0xd9,
+	0x7d, 0x73, 0x89, 0x81, 0x73, 0x5c, 0x0b, 0x6f, 0x7e, 0x38, 0x64, 0xd5, 0x15, 0xe4, 0xe3, 0xb9,
+	0x46, 0xf4, 0x3d, 0x79, 0xdc, 0xbd, 0x3a, 0x8d, 0x3e, 0x0d, 0x2f, 0x2d, 0x0b, 0x3b, 0xb8, 0x7e,
+	0x36, 0x84, 0xa8, 0x55, 0x81, 0xe0, 0xdf, 0xd1, 0x90, 0xb8, 0xf5, 0x15, 0x28, 0x0d, 0x9b, 0x9e,
+	0x86, 0xbd, 0x16, 0xae, 0x97, 0x03, 0xa6, 0xf3, 0xca, 0xbf, 0xdb, 0x4d, 0x9b, 0xc2, 0xbe, 0xfd,
+	0x3d, 0x00, 0x24, 0x7c, 0x3f, 0xa4, 0x15, 0x03, 0x00, 0x00,
 }
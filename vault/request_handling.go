@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/helper/wrapping"
@@ -30,13 +33,36 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	if c.sealed {
 		return nil, consts.ErrSealed
 	}
-	if c.standby {
+	if c.standby && !c.perfStandby {
 		return nil, consts.ErrStandby
 	}
 
 	ctx, cancel := context.WithCancel(c.activeContext)
 	defer cancel()
 
+	// Performance standbys cannot write to storage, so the requests that
+	// need to are reported as read-only to be forwarded to the active node
+	var refusedWrite *uint32
+	if c.standby {
+		ctx, refusedWrite = contextWithRefusedWrite(ctx)
+	}
+
+	// The namespace of the request is determined by its path, which already
+	// carries the namespace prefix
+	ns := c.namespaceByPath(req.Path)
+	ctx = namespace.ContextWithNamespace(ctx, ns)
+	if ns.ID != namespace.RootNamespaceID && namespaceRestrictedPath(ns.TrimmedPath(req.Path)) {
+		return logical.ErrorResponse(fmt.Sprintf("path %q is unavailable in a namespace", ns.TrimmedPath(req.Path))), logical.ErrUnsupportedPath
+	}
+
+	// Leases can only be registered by the active node. A backend that
+	// issued a secret here would issue another one once the request is
+	// forwarded, so those requests are left to the active node from the
+	// start.
+	if c.standby && c.mayGenerateLeases(req.Path) {
+		return nil, logical.ErrReadOnly
+	}
+
 	// Allowing writing to a path ending in / makes it extremely difficult to
 	// understand user intent for the filesystem-like backends (kv,
 	// cubbyhole) -- did they want a key named foo/ or did they want to write
@@ -95,11 +121,15 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		}
 	}
 
+	if refusedWrite != nil && atomic.LoadUint32(refusedWrite) == 1 {
+		resp, err = nil, logical.ErrReadOnly
+	}
+
 	auditResp := resp
 	// When unwrapping we want to log the actual response that will be written
 	// out. We still want to return the raw value to avoid automatic updating
 	// to any of it.
-	if req.Path == "sys/wrapping/unwrap" &&
+	if ns.TrimmedPath(req.Path) == "sys/wrapping/unwrap" &&
 		resp != nil &&
 		resp.Data != nil &&
 		resp.Data[logical.HTTPRawBody] != nil {
@@ -152,6 +182,9 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp *logical.Response, retAuth *logical.Auth, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
+	ns := namespaceFromContext(ctx)
+	relPath := ns.TrimmedPath(req.Path)
+
 	var nonHMACReqDataKeys []string
 	entry := c.router.MatchingMountEntry(req.Path)
 	if entry != nil {
@@ -162,7 +195,7 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	}
 
 	// Validate the token
-	auth, te, ctErr := c.checkToken(ctx, req, false)
+	auth, te, controlGroup, ctErr := c.checkToken(ctx, req, false)
 	// We run this logic first because we want to decrement the use count even in the case of an error
 	if te != nil {
 		// Attempt to use the token (decrement NumUses)
@@ -242,6 +275,17 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		return nil, auth, retErr
 	}
 
+	// If the request requires the authorizations of a control group, hold
+	// it back and return a wrapping token instead. The request is replayed
+	// once the control group is satisfied and the token is unwrapped.
+	if controlGroup != nil && !controlGroupSatisfied(ctx) {
+		resp, err := c.controlGroupWrap(ctx, req, te, controlGroup)
+		if err != nil {
+			retErr = multierror.Append(retErr, err)
+		}
+		return resp, auth, retErr
+	}
+
 	// Route the request
 	resp, routeErr := c.router.Route(ctx, req)
 	if resp != nil {
@@ -289,8 +333,8 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 	// If there is a secret, we must register it with the expiration manager.
 	// We exclude renewal of a lease, since it does not need to be re-registered
-	if resp != nil && resp.Secret != nil && !strings.HasPrefix(req.Path, "sys/renew") &&
-		!strings.HasPrefix(req.Path, "sys/leases/renew") {
+	if resp != nil && resp.Secret != nil && !strings.HasPrefix(relPath, "sys/renew") &&
+		!strings.HasPrefix(relPath, "sys/leases/renew") {
 		// KV mounts should return the TTL but not register
 		// for a lease as this provides a massive slowdown
 		registerLease := true
@@ -350,6 +394,9 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 			leaseID, err := c.expiration.Register(req, resp)
 			if err != nil {
+				if errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+					return logical.ErrorResponse(err.Error()), auth, err
+				}
 				c.logger.Error("failed to register lease", "request_path", req.Path, "error", err)
 				retErr = multierror.Append(retErr, ErrInternalError)
 				return nil, auth, retErr
@@ -360,7 +407,7 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 	// If the request was to renew a token, and if there are group aliases set
 	// in the auth object, then the group memberships should be refreshed
-	if strings.HasPrefix(req.Path, "auth/token/renew") &&
+	if strings.HasPrefix(relPath, "auth/token/renew") &&
 		resp != nil &&
 		resp.Auth != nil &&
 		resp.Auth.EntityID != "" &&
@@ -376,8 +423,8 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// Only the token store is allowed to return an auth block, for any
 	// other request this is an internal error. We exclude renewal of a token,
 	// since it does not need to be re-registered
-	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
-		if !strings.HasPrefix(req.Path, "auth/token/") {
+	if resp != nil && resp.Auth != nil && !strings.HasPrefix(relPath, "auth/token/renew") {
+		if !strings.HasPrefix(relPath, "auth/token/") {
 			c.logger.Error("unexpected Auth response for non-token backend", "request_path", req.Path)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
@@ -401,7 +448,7 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	}
 
 	if resp != nil &&
-		req.Path == "cubbyhole/response" &&
+		relPath == "cubbyhole/response" &&
 		len(te.Policies) == 1 &&
 		te.Policies[0] == responseWrappingPolicyName {
 		resp.AddWarning("Reading from 'cubbyhole/response' is deprecated. Please use sys/wrapping/unwrap to unwrap responses, as it provides additional security checks and other benefits.")
@@ -437,7 +484,7 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 
 	// The token store uses authentication even when creating a new token,
 	// so it's handled in handleRequest. It should not be reached here.
-	if strings.HasPrefix(req.Path, "auth/token/") {
+	if strings.HasPrefix(namespaceFromContext(ctx).TrimmedPath(req.Path), "auth/token/") {
 		c.logger.Error("unexpected login request for token backend", "request_path", req.Path)
 		return nil, nil, ErrInternalError
 	}
@@ -510,7 +557,7 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 
 			// Fetch the entity for the alias, or create an entity if one
 			// doesn't exist.
-			entity, err = c.identityStore.CreateOrFetchEntity(auth.Alias)
+			entity, err = c.identityStore.CreateOrFetchEntity(ctx, auth.Alias)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -533,7 +580,7 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		}
 
 		// Determine the source of the login
-		source := c.router.MatchingMount(req.Path)
+		source := namespaceFromContext(ctx).TrimmedPath(c.router.MatchingMount(req.Path))
 		source = strings.TrimPrefix(source, credentialRoutePrefix)
 		source = strings.Replace(source, "/", "-", -1)
 
@@ -576,6 +623,9 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		}
 
 		if err := c.tokenStore.create(ctx, &te); err != nil {
+			if errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+				return logical.ErrorResponse(err.Error()), auth, err
+			}
 			c.logger.Error("failed to create token", "error", err)
 			return nil, auth, ErrInternalError
 		}
@@ -114,10 +114,7 @@ func (m *RollbackManager) triggerRollbacks() {
 	backends := m.backends()
 
 	for _, e := range backends {
-		path := e.Path
-		if e.Table == credentialTableType {
-			path = credentialRoutePrefix + path
-		}
+		path := e.APIPath()
 
 		// When the mount is filtered, the backend will be nil
 		backend := m.router.MatchingBackend(path)
@@ -11,6 +11,7 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
 )
@@ -19,6 +20,15 @@ var (
 	whitelistedHeaders = []string{
 		consts.VaultKVCLIClientHeader,
 	}
+
+	// namespaceSingletonPaths are the routes of the singleton mounts, which
+	// are shared by every namespace
+	namespaceSingletonPaths = []string{
+		"sys/",
+		"cubbyhole/",
+		"identity/",
+		credentialRoutePrefix + "token/",
+	}
 )
 
 // Router is used to do prefix based routing of a request to a logical backend
@@ -61,6 +71,7 @@ type validateMountResponse struct {
 	MountType     string `json:"mount_type" structs:"mount_type" mapstructure:"mount_type"`
 	MountAccessor string `json:"mount_accessor" structs:"mount_accessor" mapstructure:"mount_accessor"`
 	MountPath     string `json:"mount_path" structs:"mount_path" mapstructure:"mount_path"`
+	NamespaceID   string `json:"namespace_id" structs:"namespace_id" mapstructure:"namespace_id"`
 }
 
 // validateMountByAccessor returns the mount type and ID for a given mount
@@ -75,15 +86,11 @@ func (r *Router) validateMountByAccessor(accessor string) *validateMountResponse
 		return nil
 	}
 
-	mountPath := mountEntry.Path
-	if mountEntry.Table == credentialTableType {
-		mountPath = credentialRoutePrefix + mountPath
-	}
-
 	return &validateMountResponse{
 		MountAccessor: mountEntry.Accessor,
 		MountType:     mountEntry.Type,
-		MountPath:     mountPath,
+		MountPath:     mountEntry.APIPath(),
+		NamespaceID:   mountEntry.Namespace().ID,
 	}
 }
 
@@ -168,6 +175,36 @@ func (r *Router) Unmount(ctx context.Context, prefix string) error {
 	return nil
 }
 
+// MountNamespace routes the singleton mounts beneath the path of the given
+// namespace. The route entries are shared with the root namespace, so only
+// the routing table is updated.
+func (r *Router) MountNamespace(ns *namespace.Namespace) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	for _, prefix := range namespaceSingletonPaths {
+		raw, ok := r.root.Get(prefix)
+		if !ok {
+			return fmt.Errorf("no singleton mount at %q", prefix)
+		}
+		r.root.Insert(ns.Path+prefix, raw)
+	}
+
+	return nil
+}
+
+// UnmountNamespace removes the singleton mount routes of the given namespace
+func (r *Router) UnmountNamespace(ns *namespace.Namespace) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	for _, prefix := range namespaceSingletonPaths {
+		r.root.Delete(ns.Path + prefix)
+	}
+
+	return nil
+}
+
 // Remount is used to change the mount location of a logical backend
 func (r *Router) Remount(src, dst string) error {
 	r.l.Lock()
@@ -444,9 +481,13 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 
 	// Allow EntityID to passthrough to the system backend. This is required to
 	// allow clients to generate MFA credentials in respective entity objects
-	// in identity store via the system backend.
-	switch {
-	case strings.HasPrefix(originalPath, "sys/"):
+	// in identity store via the system backend. The identity store needs it to
+	// issue identity tokens for the calling entity.
+	// The singleton mounts are matched by type since they are also routed
+	// beneath every namespace.
+	switch re.mountEntry.Type {
+	case "system":
+	case "identity":
 	default:
 		req.EntityID = ""
 	}
@@ -454,10 +495,10 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 	// Hash the request token unless the request is being routed to the token
 	// or system backend.
 	clientToken := req.ClientToken
-	switch {
-	case strings.HasPrefix(originalPath, "auth/token/"):
-	case strings.HasPrefix(originalPath, "sys/"):
-	case strings.HasPrefix(originalPath, "cubbyhole/"):
+	switch re.mountEntry.Type {
+	case "token":
+	case "system":
+	case "cubbyhole":
 		// In order for the token store to revoke later, we need to have the same
 		// salted ID, so we double-salt what's going to the cubbyhole backend
 		salt, err := r.tokenStoreSaltFunc(ctx)
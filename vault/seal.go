@@ -44,10 +44,11 @@ const (
 )
 
 const (
-	SealTypeShamir = "shamir"
-	SealTypePKCS11 = "pkcs11"
-	SealTypeAWSKMS = "awskms"
-	SealTypeTest   = "test-auto"
+	SealTypeShamir  = "shamir"
+	SealTypePKCS11  = "pkcs11"
+	SealTypeAWSKMS  = "awskms"
+	SealTypeTransit = "transit"
+	SealTypeTest    = "test-auto"
 
 	RecoveryTypeUnsupported = "unsupported"
 	RecoveryTypeShamir      = "shamir"
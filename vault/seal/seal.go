@@ -0,0 +1,38 @@
+package seal
+
+import (
+	"context"
+)
+
+const (
+	// Transit is the type of the seal backed by the transit secrets engine of
+	// another Vault cluster.
+	Transit = "transit"
+)
+
+// Access is the interface that an auto-unseal mechanism must implement. It
+// is responsible only for protecting small blobs of key material; the logic
+// for storing and retrieving those blobs lives in vault's autoSeal.
+type Access interface {
+	// SealType returns the type of the seal, e.g. "transit".
+	SealType() string
+
+	// KeyID returns the identifier of the key currently used to encrypt.
+	KeyID() string
+
+	Init(context.Context) error
+	Finalize(context.Context) error
+
+	Encrypt(context.Context, []byte) (*EncryptedBlobInfo, error)
+	Decrypt(context.Context, *EncryptedBlobInfo) ([]byte, error)
+}
+
+// EncryptedBlobInfo contains the information needed to decrypt a value that
+// was encrypted by an Access implementation.
+type EncryptedBlobInfo struct {
+	// Ciphertext is the encrypted value, in whatever form the seal produces.
+	Ciphertext []byte `json:"ciphertext"`
+
+	// KeyID is the identifier of the key used to encrypt Ciphertext.
+	KeyID string `json:"key_id"`
+}
@@ -0,0 +1,337 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// EnvTransitSealAddress, if set, overrides the address of the Vault
+	// cluster hosting the transit key.
+	EnvTransitSealAddress = "VAULT_TRANSIT_SEAL_ADDR"
+
+	// EnvTransitSealToken, if set, overrides the token used to talk to the
+	// Vault cluster hosting the transit key.
+	EnvTransitSealToken = "VAULT_TRANSIT_SEAL_TOKEN"
+
+	// EnvTransitSealMountPath, if set, overrides the mount path of the transit
+	// secrets engine.
+	EnvTransitSealMountPath = "VAULT_TRANSIT_SEAL_MOUNT_PATH"
+
+	// EnvTransitSealKeyName, if set, overrides the name of the transit key.
+	EnvTransitSealKeyName = "VAULT_TRANSIT_SEAL_KEY_NAME"
+)
+
+// Seal is a seal.Access implementation that protects key material using the
+// transit secrets engine of another Vault cluster.
+type Seal struct {
+	logger log.Logger
+
+	client    *api.Client
+	mountPath string
+	keyName   string
+
+	currentKeyID *atomic.Value
+
+	renewer  *api.Renewer
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// Ensure that we are implementing seal.Access
+var _ seal.Access = (*Seal)(nil)
+
+// NewSeal creates a new, unconfigured transit seal.
+func NewSeal(logger log.Logger) *Seal {
+	s := &Seal{
+		logger:       logger,
+		currentKeyID: new(atomic.Value),
+		stopCh:       make(chan struct{}),
+	}
+	s.currentKeyID.Store("")
+	return s
+}
+
+// SetConfig configures the seal from the given map of values, usually the
+// contents of a `seal "transit"` stanza. Environment variables take
+// precedence over values in the map. The returned map contains non-sensitive
+// information about the seal, suitable for display.
+func (s *Seal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	mountPath := config["mount_path"]
+	if v := os.Getenv(EnvTransitSealMountPath); v != "" {
+		mountPath = v
+	}
+	if mountPath == "" {
+		return nil, errors.New("mount_path is required")
+	}
+
+	keyName := config["key_name"]
+	if v := os.Getenv(EnvTransitSealKeyName); v != "" {
+		keyName = v
+	}
+	if keyName == "" {
+		return nil, errors.New("key_name is required")
+	}
+
+	disableRenewal := false
+	if v, ok := config["disable_renewal"]; ok && v != "" {
+		var err error
+		disableRenewal, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing disable_renewal: {{err}}", err)
+		}
+	}
+
+	apiConfig := api.DefaultConfig()
+	if apiConfig.Error != nil {
+		return nil, apiConfig.Error
+	}
+
+	address := config["address"]
+	if v := os.Getenv(EnvTransitSealAddress); v != "" {
+		address = v
+	}
+	if address != "" {
+		apiConfig.Address = address
+	}
+
+	tlsConfig := &api.TLSConfig{
+		CACert:        config["tls_ca_cert"],
+		ClientCert:    config["tls_client_cert"],
+		ClientKey:     config["tls_client_key"],
+		TLSServerName: config["tls_server_name"],
+	}
+	if v, ok := config["tls_skip_verify"]; ok && v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing tls_skip_verify: {{err}}", err)
+		}
+		tlsConfig.Insecure = skip
+	}
+	if (tlsConfig.ClientCert == "") != (tlsConfig.ClientKey == "") {
+		return nil, errors.New("both tls_client_cert and tls_client_key must be set for client TLS")
+	}
+	if tlsConfig.CACert != "" || tlsConfig.ClientCert != "" || tlsConfig.ClientKey != "" || tlsConfig.TLSServerName != "" || tlsConfig.Insecure {
+		if err := apiConfig.ConfigureTLS(tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := api.NewClient(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	token := config["token"]
+	if v := os.Getenv(EnvTransitSealToken); v != "" {
+		token = v
+	}
+	if token != "" {
+		client.SetToken(token)
+	}
+	if client.Token() == "" {
+		return nil, errors.New("missing token")
+	}
+
+	if err := s.setClient(client, mountPath, keyName); err != nil {
+		return nil, err
+	}
+
+	if !disableRenewal {
+		if err := s.startRenewal(); err != nil {
+			return nil, err
+		}
+	}
+
+	sealInfo := map[string]string{
+		"address":    client.Address(),
+		"mount_path": s.mountPath,
+		"key_name":   s.keyName,
+	}
+
+	return sealInfo, nil
+}
+
+// SetClient configures the seal to use an existing API client. It is mostly
+// useful for testing; token renewal is not performed for clients set this
+// way.
+func (s *Seal) SetClient(client *api.Client, mountPath, keyName string) error {
+	return s.setClient(client, mountPath, keyName)
+}
+
+func (s *Seal) setClient(client *api.Client, mountPath, keyName string) error {
+	if client == nil {
+		return errors.New("nil client")
+	}
+	if mountPath == "" {
+		return errors.New("mount_path is required")
+	}
+	if keyName == "" {
+		return errors.New("key_name is required")
+	}
+
+	s.client = client
+	s.mountPath = strings.Trim(mountPath, "/")
+	s.keyName = keyName
+	s.currentKeyID.Store(keyName)
+	return nil
+}
+
+// startRenewal looks up the configured token and, if it is renewable, keeps
+// it alive in the background until Finalize is called.
+func (s *Seal) startRenewal() error {
+	lookup, err := s.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return errwrap.Wrapf("error looking up transit seal token: {{err}}", err)
+	}
+	if lookup == nil || lookup.Data == nil {
+		return errors.New("empty response looking up transit seal token")
+	}
+
+	renewable, err := parseutil.ParseBool(lookup.Data["renewable"])
+	if err != nil {
+		return errwrap.Wrapf("error parsing renewable flag of transit seal token: {{err}}", err)
+	}
+	if !renewable {
+		return nil
+	}
+
+	secret, err := s.client.Auth().Token().RenewSelf(0)
+	if err != nil {
+		return errwrap.Wrapf("error renewing transit seal token: {{err}}", err)
+	}
+
+	renewer, err := s.client.NewRenewer(&api.RenewerInput{
+		Secret: secret,
+	})
+	if err != nil {
+		return errwrap.Wrapf("error creating renewer for transit seal token: {{err}}", err)
+	}
+	s.renewer = renewer
+
+	go renewer.Renew()
+	go func() {
+		for {
+			select {
+			case err := <-renewer.DoneCh():
+				if err != nil && s.logger != nil {
+					s.logger.Error("error renewing transit seal token", "error", err)
+				}
+				return
+			case <-renewer.RenewCh():
+				if s.logger != nil {
+					s.logger.Trace("successfully renewed transit seal token")
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Init is called during core.Initialize. This is a no-op.
+func (s *Seal) Init(context.Context) error {
+	return nil
+}
+
+// Finalize is called during shutdown. It stops token renewal.
+func (s *Seal) Finalize(context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+		if s.renewer != nil {
+			s.renewer.Stop()
+		}
+	})
+	return nil
+}
+
+// SealType returns the seal type for this particular seal implementation.
+func (s *Seal) SealType() string {
+	return seal.Transit
+}
+
+// KeyID returns the last known key id.
+func (s *Seal) KeyID() string {
+	return s.currentKeyID.Load().(string)
+}
+
+// Encrypt is used to encrypt the plaintext using the transit key.
+func (s *Seal) Encrypt(ctx context.Context, plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if s.client == nil {
+		return nil, errors.New("transit seal is not configured")
+	}
+
+	secret, err := s.client.Logical().Write(path.Join(s.mountPath, "encrypt", s.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error encrypting with transit seal: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty response encrypting with transit seal")
+	}
+
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok || ciphertext == "" {
+		return nil, errors.New("no ciphertext returned by transit seal")
+	}
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: []byte(ciphertext),
+		KeyID:      s.keyName,
+	}, nil
+}
+
+// Decrypt is used to decrypt the ciphertext using the transit key.
+func (s *Seal) Decrypt(ctx context.Context, in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if s.client == nil {
+		return nil, errors.New("transit seal is not configured")
+	}
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+	if in.KeyID != "" && in.KeyID != s.keyName {
+		return nil, fmt.Errorf("value was encrypted with transit key %q but seal is configured with key %q", in.KeyID, s.keyName)
+	}
+
+	secret, err := s.client.Logical().Write(path.Join(s.mountPath, "decrypt", s.keyName), map[string]interface{}{
+		"ciphertext": string(in.Ciphertext),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error decrypting with transit seal: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty response decrypting with transit seal")
+	}
+
+	plaintextB64, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("no plaintext returned by transit seal")
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(plaintextB64)
+	if err != nil {
+		return nil, errwrap.Wrapf("error decoding plaintext returned by transit seal: {{err}}", err)
+	}
+
+	return plaintext, nil
+}
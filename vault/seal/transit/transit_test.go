@@ -0,0 +1,170 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	transitbackend "github.com/hashicorp/vault/builtin/logical/transit"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+// testTransitCluster starts a Vault cluster with a transit key that can be
+// used as the upstream for a transit seal.
+func testTransitCluster(t *testing.T) (*vault.TestCluster, *api.Client) {
+	t.Helper()
+
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"transit": transitbackend.Factory,
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+
+	client := cluster.Cores[0].Client
+	if err := client.Sys().Mount("transit", &api.MountInput{
+		Type: "transit",
+	}); err != nil {
+		cluster.Cleanup()
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("transit/keys/autounseal", nil); err != nil {
+		cluster.Cleanup()
+		t.Fatal(err)
+	}
+
+	return cluster, client
+}
+
+func TestTransitSeal_EncryptDecrypt(t *testing.T) {
+	cluster, client := testTransitCluster(t)
+	defer cluster.Cleanup()
+
+	s := NewSeal(nil)
+	if err := s.SetClient(client, "/transit/", "autounseal"); err != nil {
+		t.Fatal(err)
+	}
+	if s.SealType() != seal.Transit {
+		t.Fatalf("bad seal type: %q", s.SealType())
+	}
+	if s.KeyID() != "autounseal" {
+		t.Fatalf("bad key id: %q", s.KeyID())
+	}
+
+	input := []byte("foo")
+	blob, err := s.Encrypt(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(blob.Ciphertext, input) {
+		t.Fatal("ciphertext contains plaintext")
+	}
+
+	pt, err := s.Decrypt(context.Background(), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, input) {
+		t.Fatalf("expected %q, got %q", input, pt)
+	}
+
+	blob.KeyID = "other"
+	if _, err := s.Decrypt(context.Background(), blob); err == nil {
+		t.Fatal("expected error decrypting with mismatched key id")
+	}
+}
+
+func TestTransitSeal_AutoUnseal(t *testing.T) {
+	transitCluster, transitClient := testTransitCluster(t)
+	defer transitCluster.Cleanup()
+
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		SealFunc: func() vault.Seal {
+			s := NewSeal(nil)
+			if err := s.SetClient(transitClient, "transit", "autounseal"); err != nil {
+				t.Fatal(err)
+			}
+			return vault.NewAutoSeal(s)
+		},
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+
+	if !core.SealAccess().StoredKeysSupported() {
+		t.Fatal("expected stored keys to be supported")
+	}
+	if !core.SealAccess().RecoveryKeySupported() {
+		t.Fatal("expected recovery keys to be supported")
+	}
+	if len(cluster.BarrierKeys) != 0 {
+		t.Fatalf("expected no barrier keys to be returned, got %d", len(cluster.BarrierKeys))
+	}
+	if len(cluster.RecoveryKeys) == 0 {
+		t.Fatal("expected recovery keys to be returned")
+	}
+
+	barrierConfig, err := core.SealAccess().BarrierConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if barrierConfig.Type != seal.Transit {
+		t.Fatalf("bad barrier seal type: %q", barrierConfig.Type)
+	}
+
+	// Seal everything and make sure the cores come back up on their own
+	cluster.EnsureCoresSealed(t)
+	if err := cluster.UnsealWithStoredKeys(t); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range cluster.Cores {
+		sealed, err := c.Sealed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealed {
+			t.Fatalf("core %d should be unsealed", i)
+		}
+	}
+
+	// Recovery keys can be used in place of stored keys
+	cluster.EnsureCoresSealed(t)
+	var unsealed bool
+	for _, key := range cluster.RecoveryKeys {
+		unsealed, err = core.UnsealWithRecoveryKeys(context.Background(), vault.TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !unsealed {
+		t.Fatal("expected core to be unsealed with recovery keys")
+	}
+}
+
+func TestTransitSeal_SetConfig_ClientTLS(t *testing.T) {
+	for _, tc := range []map[string]string{
+		{"tls_client_cert": "client.pem"},
+		{"tls_client_key": "client-key.pem"},
+	} {
+		tc["mount_path"] = "transit"
+		tc["key_name"] = "autounseal"
+		tc["token"] = "token"
+
+		s := NewSeal(nil)
+		if _, err := s.SetConfig(tc); err == nil || !strings.Contains(err.Error(), "tls_client_key") {
+			t.Fatalf("expected an error for config %#v, got %v", tc, err)
+		}
+	}
+}
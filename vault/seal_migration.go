@@ -0,0 +1,383 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// SetSealsForMigration checks whether the barrier is still protected by the
+// given existing seal rather than the seal the core was configured with. If
+// so, the core enters seal migration mode: it behaves as if configured with
+// the existing seal until it is unsealed with UnsealMigrate, at which point
+// the barrier is moved over to the configured seal.
+//
+// This must be called before the core is unsealed.
+func (c *Core) SetSealsForMigration(ctx context.Context, existingSeal Seal) error {
+	if existingSeal == nil {
+		return nil
+	}
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if !c.sealed {
+		return fmt.Errorf("cannot set up a seal migration while unsealed")
+	}
+
+	storedType, err := c.storedBarrierSealType(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch storedType {
+	case "":
+		// Not initialized, so there is nothing to migrate
+		return nil
+
+	case c.seal.BarrierType():
+		// Either there is nothing to migrate, or a migration has already
+		// been completed
+		return nil
+
+	case existingSeal.BarrierType():
+
+	default:
+		return fmt.Errorf("barrier seal type of %q matches neither the configured seal type %q nor the migration seal type %q", storedType, c.seal.BarrierType(), existingSeal.BarrierType())
+	}
+
+	existingSeal.SetCore(c)
+	c.sealMigrationTarget = c.seal
+	c.seal = existingSeal
+
+	c.logger.Warn("entering seal migration mode; Vault will not automatically unseal even if using an autoseal", "from_barrier_type", c.seal.BarrierType(), "to_barrier_type", c.sealMigrationTarget.BarrierType())
+
+	return nil
+}
+
+// SealMigrationPending returns whether the core is waiting to be unsealed
+// with UnsealMigrate in order to perform a seal migration.
+func (c *Core) SealMigrationPending() bool {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return c.sealMigrationTarget != nil
+}
+
+// storedBarrierSealType returns the seal type recorded in the barrier seal
+// configuration, or an empty string if Vault has not been initialized.
+func (c *Core) storedBarrierSealType(ctx context.Context) (string, error) {
+	pe, err := c.physical.Get(ctx, barrierSealConfigPath)
+	if err != nil {
+		return "", errwrap.Wrapf("failed to read seal configuration: {{err}}", err)
+	}
+	if pe == nil {
+		return "", nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		return "", errwrap.Wrapf("failed to decode seal configuration: {{err}}", err)
+	}
+
+	// Configurations written before the type was recorded are always Shamir
+	if conf.Type == "" {
+		conf.Type = SealTypeShamir
+	}
+
+	return conf.Type, nil
+}
+
+// UnsealMigrate is used to provide one of the key parts needed to unseal the
+// Vault while a seal migration is pending. When migrating away from Shamir
+// these are the existing unseal keys; when migrating to Shamir these are the
+// recovery keys. Once the threshold is met the Vault is unsealed and the
+// migration is performed.
+//
+// The key given as a parameter will automatically be zerod after this method
+// is done with it. If you want to keep the key around, a copy should be made.
+func (c *Core) UnsealMigrate(ctx context.Context, key []byte) (bool, error) {
+	defer metrics.MeasureSince([]string{"core", "unseal_migrate"}, time.Now())
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	// Explicitly check for init status
+	init, err := c.Initialized(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !init {
+		return false, ErrNotInit
+	}
+
+	// Verify the key length
+	min, max := c.BarrierKeyLength()
+	if len(key) < min {
+		return false, &ErrInvalidKey{fmt.Sprintf("key is shorter than minimum %d bytes", min)}
+	}
+	if len(key) > max {
+		return false, &ErrInvalidKey{fmt.Sprintf("key is longer than maximum %d bytes", max)}
+	}
+
+	// Check if already unsealed
+	if !c.sealed {
+		return true, nil
+	}
+
+	if c.sealMigrationTarget == nil {
+		return false, ErrNoSealMigration
+	}
+
+	// Keys for an existing autoseal are recovery keys, otherwise they are
+	// the Shamir unseal keys
+	var config *SealConfig
+	if c.seal.RecoveryKeySupported() {
+		config, err = c.seal.RecoveryConfig(ctx)
+	} else {
+		config, err = c.seal.BarrierConfig(ctx)
+	}
+	if err != nil {
+		return false, err
+	}
+	if config == nil {
+		return false, fmt.Errorf("unable to load seal configuration")
+	}
+
+	recoveredKey, err := c.recoverKeyPart(config, key)
+	if err != nil {
+		return false, err
+	}
+	if recoveredKey == nil {
+		return false, nil
+	}
+
+	masterKey, err := c.sealMigrationMasterKey(ctx, recoveredKey)
+	if err != nil {
+		memzero(recoveredKey)
+		return false, err
+	}
+
+	c.sealMigrationKey = recoveredKey
+	unsealed, err := c.unsealInternal(ctx, masterKey)
+	if err != nil {
+		memzero(c.sealMigrationKey)
+		c.sealMigrationKey = nil
+	}
+	return unsealed, err
+}
+
+// sealMigrationMasterKey determines the master key of the barrier from the
+// key recovered from the shares provided during a migration unseal. The
+// returned key has been verified to unseal the barrier.
+func (c *Core) sealMigrationMasterKey(ctx context.Context, recoveredKey []byte) ([]byte, error) {
+	existing, target := c.seal, c.sealMigrationTarget
+
+	var candidates [][]byte
+	switch {
+	case !existing.StoredKeysSupported():
+		// Migrating away from Shamir: the recovered key is the master key,
+		// unless a previous migration attempt already rekeyed the barrier, in
+		// which case the old master key has been set as the recovery key of
+		// the target seal and the new master key is stored by it.
+		candidates = append(candidates, recoveredKey)
+		if target.RecoveryKeySupported() {
+			if err := target.VerifyRecoveryKey(ctx, recoveredKey); err == nil {
+				storedKey, err := c.storedMasterKey(ctx, target)
+				if err != nil {
+					return nil, err
+				}
+				if storedKey != nil {
+					candidates = append(candidates, storedKey)
+				}
+			}
+		}
+
+	default:
+		// Migrating away from an autoseal: the recovered key is the recovery
+		// key. The master key is stored by the existing seal, unless a
+		// previous migration attempt already rekeyed the barrier to the
+		// recovery key.
+		if err := existing.VerifyRecoveryKey(ctx, recoveredKey); err != nil {
+			return nil, err
+		}
+		storedKey, err := c.storedMasterKey(ctx, existing)
+		if err != nil {
+			return nil, err
+		}
+		if storedKey != nil {
+			candidates = append(candidates, storedKey)
+		}
+		candidates = append(candidates, recoveredKey)
+	}
+
+	for _, candidate := range candidates {
+		err := c.barrier.Unseal(ctx, candidate)
+		switch {
+		case err == nil:
+			// Leave the barrier sealed; unsealInternal will open it
+			if err := c.barrier.Seal(); err != nil {
+				return nil, err
+			}
+			masterKey := make([]byte, len(candidate))
+			copy(masterKey, candidate)
+			return masterKey, nil
+		case err == ErrBarrierInvalidKey:
+			continue
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, ErrBarrierInvalidKey
+}
+
+// migrateSeal moves the barrier from the existing seal to the seal being
+// migrated to. Every step is safe to repeat, so a migration that was
+// interrupted is completed on the next migration unseal.
+//
+// N.B.: This must be called with the state write lock held and the barrier
+// unsealed.
+func (c *Core) migrateSeal(ctx context.Context) error {
+	if c.sealMigrationTarget == nil {
+		return nil
+	}
+
+	existing, target := c.seal, c.sealMigrationTarget
+
+	storedType, err := c.storedBarrierSealType(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Another node may have already performed the migration
+	if storedType != target.BarrierType() {
+		recoveredKey := c.sealMigrationKey
+		if recoveredKey == nil {
+			return fmt.Errorf("no key available to perform seal migration; unseal with the migrate flag")
+		}
+
+		c.logger.Info("performing seal migration", "from_barrier_type", existing.BarrierType(), "to_barrier_type", target.BarrierType())
+
+		switch {
+		case !existing.StoredKeysSupported() && target.StoredKeysSupported():
+			err = c.migrateFromShamir(ctx, existing, target, recoveredKey)
+		case existing.StoredKeysSupported() && !target.StoredKeysSupported():
+			err = c.migrateToShamir(ctx, existing, target, recoveredKey)
+		default:
+			err = fmt.Errorf("migration from seal type %q to %q is not supported", existing.BarrierType(), target.BarrierType())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	memzero(c.sealMigrationKey)
+	c.sealMigrationKey = nil
+	c.seal = target
+	c.sealMigrationTarget = nil
+
+	c.logger.Info("seal migration complete")
+
+	return nil
+}
+
+// migrateFromShamir moves the barrier to a seal that supports stored keys.
+// The old master key becomes the recovery key, so that the existing unseal
+// key shares can be used as recovery key shares, and the barrier is rekeyed
+// with a new master key that is stored by the new seal.
+func (c *Core) migrateFromShamir(ctx context.Context, existing, target Seal, oldMasterKey []byte) error {
+	barrierConfig, err := existing.BarrierConfig(ctx)
+	if err != nil {
+		return errwrap.Wrapf("failed to read existing barrier seal configuration: {{err}}", err)
+	}
+	if barrierConfig == nil {
+		return fmt.Errorf("existing barrier seal configuration not found")
+	}
+
+	if target.RecoveryKeySupported() {
+		recoveryConfig := &SealConfig{
+			Type:            target.RecoveryType(),
+			SecretShares:    barrierConfig.SecretShares,
+			SecretThreshold: barrierConfig.SecretThreshold,
+		}
+		if err := target.SetRecoveryConfig(ctx, recoveryConfig); err != nil {
+			return errwrap.Wrapf("failed to save recovery seal configuration: {{err}}", err)
+		}
+		if err := target.SetRecoveryKey(ctx, oldMasterKey); err != nil {
+			return errwrap.Wrapf("failed to save recovery key: {{err}}", err)
+		}
+	}
+
+	// If the barrier is still protected by the old master key, generate a
+	// new one. It is stored before the barrier is rekeyed so that an
+	// interruption can never leave the barrier keyed with an unknown key.
+	if err := c.barrier.VerifyMaster(oldMasterKey); err == nil {
+		newMasterKey, err := c.barrier.GenerateKey()
+		if err != nil {
+			return errwrap.Wrapf("failed to generate master key: {{err}}", err)
+		}
+		defer memzero(newMasterKey)
+
+		if err := target.SetStoredKeys(ctx, [][]byte{newMasterKey}); err != nil {
+			return errwrap.Wrapf("failed to store new master key: {{err}}", err)
+		}
+		if err := c.barrier.Rekey(ctx, newMasterKey); err != nil {
+			return errwrap.Wrapf("failed to rekey barrier: {{err}}", err)
+		}
+	}
+
+	newBarrierConfig := &SealConfig{
+		Type:            target.BarrierType(),
+		SecretShares:    1,
+		SecretThreshold: 1,
+		StoredShares:    1,
+	}
+	if err := target.SetBarrierConfig(ctx, newBarrierConfig); err != nil {
+		return errwrap.Wrapf("failed to save barrier seal configuration: {{err}}", err)
+	}
+
+	return nil
+}
+
+// migrateToShamir moves the barrier from a seal that supports stored keys to
+// Shamir. The barrier is rekeyed with the recovery key, so that the existing
+// recovery key shares become the unseal key shares, and the key material
+// managed by the old seal is removed.
+func (c *Core) migrateToShamir(ctx context.Context, existing, target Seal, recoveryKey []byte) error {
+	recoveryConfig, err := existing.RecoveryConfig(ctx)
+	if err != nil {
+		return errwrap.Wrapf("failed to read existing recovery seal configuration: {{err}}", err)
+	}
+	if recoveryConfig == nil {
+		return fmt.Errorf("existing recovery seal configuration not found")
+	}
+
+	if err := c.barrier.VerifyMaster(recoveryKey); err != nil {
+		if err := c.barrier.Rekey(ctx, recoveryKey); err != nil {
+			return errwrap.Wrapf("failed to rekey barrier: {{err}}", err)
+		}
+	}
+
+	newBarrierConfig := &SealConfig{
+		Type:            target.BarrierType(),
+		SecretShares:    recoveryConfig.SecretShares,
+		SecretThreshold: recoveryConfig.SecretThreshold,
+	}
+	if err := target.SetBarrierConfig(ctx, newBarrierConfig); err != nil {
+		return errwrap.Wrapf("failed to save barrier seal configuration: {{err}}", err)
+	}
+
+	// The old seal's key material is no longer needed. Failing to remove it
+	// is not fatal as the barrier no longer depends on it.
+	for _, path := range []string{storedBarrierKeysPath, recoveryKeyPath, recoverySealConfigPlaintextPath} {
+		if err := c.physical.Delete(ctx, path); err != nil {
+			c.logger.Warn("failed to remove key material of previous seal", "path", path, "error", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/inmem"
+	"github.com/hashicorp/vault/shamir"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+// testSealAccess is a seal.Access that "encrypts" by reversing bytes
+type testSealAccess struct{}
+
+func (testSealAccess) SealType() string               { return SealTypeTest }
+func (testSealAccess) KeyID() string                  { return "static-key" }
+func (testSealAccess) Init(context.Context) error     { return nil }
+func (testSealAccess) Finalize(context.Context) error { return nil }
+func (testSealAccess) reverse(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i := range in {
+		out[len(in)-1-i] = in[i]
+	}
+	return out
+}
+
+func (s testSealAccess) Encrypt(_ context.Context, plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: s.reverse(plaintext),
+		KeyID:      s.KeyID(),
+	}, nil
+}
+
+func (s testSealAccess) Decrypt(_ context.Context, in *seal.EncryptedBlobInfo) ([]byte, error) {
+	return s.reverse(in.Ciphertext), nil
+}
+
+func testSealMigrationCore(t *testing.T, phys physical.Backend, s Seal) *Core {
+	t.Helper()
+	logger := logging.NewVaultLogger(log.Trace)
+	conf := testCoreConfig(t, phys, logger)
+	conf.Seal = s
+	core, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core
+}
+
+func testSealMigrationCheckData(t *testing.T, core *Core) {
+	t.Helper()
+	entry, err := core.barrier.Get(context.Background(), "migration-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || !bytes.Equal(entry.Value, []byte("bar")) {
+		t.Fatalf("bad entry: %#v", entry)
+	}
+}
+
+func testSealMigrationUnseal(t *testing.T, core *Core, keys [][]byte) {
+	t.Helper()
+	var unsealed bool
+	var err error
+	for _, key := range keys {
+		unsealed, err = core.UnsealMigrate(context.Background(), TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !unsealed {
+		t.Fatal("expected core to be unsealed")
+	}
+}
+
+func TestCore_SealMigration_ShamirToAuto(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Trace)
+	phys, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	core, keys, root := TestCoreUnsealedBackend(t, phys)
+	if err := core.barrier.Put(ctx, &Entry{Key: "migration-test", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart with an autoseal configured
+	core = testSealMigrationCore(t, phys, NewAutoSeal(testSealAccess{}))
+	if err := core.SetSealsForMigration(ctx, NewDefaultSeal()); err != nil {
+		t.Fatal(err)
+	}
+	if !core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be pending")
+	}
+
+	// Regular unseals and auto-unseal must not happen during a migration
+	if _, err := core.Unseal(TestKeyCopy(keys[0])); err != ErrSealMigrationPending {
+		t.Fatalf("expected seal migration pending error, got %v", err)
+	}
+	if err := core.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("expected core to be sealed")
+	}
+
+	testSealMigrationUnseal(t, core, keys)
+	if core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be complete")
+	}
+	conf, err := core.SealAccess().BarrierConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Type != SealTypeTest || conf.StoredShares != 1 {
+		t.Fatalf("bad barrier config: %#v", conf)
+	}
+	testSealMigrationCheckData(t, core)
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old unseal keys no longer unseal the barrier
+	oldMasterKey, err := shamir.Combine(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.barrier.Unseal(ctx, oldMasterKey); err != ErrBarrierInvalidKey {
+		t.Fatalf("expected invalid key error, got %v", err)
+	}
+
+	// Restarting auto-unseals and does not migrate again
+	core = testSealMigrationCore(t, phys, NewAutoSeal(testSealAccess{}))
+	if err := core.SetSealsForMigration(ctx, NewDefaultSeal()); err != nil {
+		t.Fatal(err)
+	}
+	if core.SealMigrationPending() {
+		t.Fatal("expected no seal migration to be pending")
+	}
+	if err := core.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("expected core to be unsealed")
+	}
+	testSealMigrationCheckData(t, core)
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old unseal keys are now the recovery keys
+	var unsealed bool
+	for _, key := range keys {
+		unsealed, err = core.UnsealWithRecoveryKeys(ctx, TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !unsealed {
+		t.Fatal("expected core to be unsealed with recovery keys")
+	}
+}
+
+func TestCore_SealMigration_AutoToShamir(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Trace)
+	phys, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	core := testSealMigrationCore(t, phys, NewAutoSeal(testSealAccess{}))
+	_, recoveryKeys, root := TestCoreInitClusterWrapperSetup(t, core, nil, nil)
+	if err := core.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.barrier.Put(ctx, &Entry{Key: "migration-test", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart with the autoseal disabled
+	core = testSealMigrationCore(t, phys, NewDefaultSeal())
+	if err := core.SetSealsForMigration(ctx, NewAutoSeal(testSealAccess{})); err != nil {
+		t.Fatal(err)
+	}
+	if !core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be pending")
+	}
+	if err := core.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("expected core to be sealed")
+	}
+
+	testSealMigrationUnseal(t, core, recoveryKeys)
+	if core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be complete")
+	}
+	testSealMigrationCheckData(t, core)
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{storedBarrierKeysPath, recoveryKeyPath, recoverySealConfigPlaintextPath} {
+		entry, err := phys.Get(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("expected %q to be removed", path)
+		}
+	}
+
+	// The recovery keys are now the unseal keys
+	core = testSealMigrationCore(t, phys, NewDefaultSeal())
+	conf, err := core.SealAccess().BarrierConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Type != SealTypeShamir || conf.SecretShares != len(recoveryKeys) {
+		t.Fatalf("bad barrier config: %#v", conf)
+	}
+	for _, key := range recoveryKeys {
+		if _, err := core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("expected core to be unsealed")
+	}
+	testSealMigrationCheckData(t, core)
+}
+
+func TestCore_SealMigration_Interrupted(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Trace)
+	phys, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	core, keys, root := TestCoreUnsealedBackend(t, phys)
+	if err := core.barrier.Put(ctx, &Entry{Key: "migration-test", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	oldConfig, err := phys.Get(ctx, barrierSealConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	core = testSealMigrationCore(t, phys, NewAutoSeal(testSealAccess{}))
+	if err := core.SetSealsForMigration(ctx, NewDefaultSeal()); err != nil {
+		t.Fatal(err)
+	}
+	testSealMigrationUnseal(t, core, keys)
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an interruption after the barrier was rekeyed but before the
+	// new seal configuration was written
+	if err := phys.Put(ctx, oldConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	core = testSealMigrationCore(t, phys, NewAutoSeal(testSealAccess{}))
+	if err := core.SetSealsForMigration(ctx, NewDefaultSeal()); err != nil {
+		t.Fatal(err)
+	}
+	if !core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be pending")
+	}
+	testSealMigrationUnseal(t, core, keys)
+	if core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be complete")
+	}
+	testSealMigrationCheckData(t, core)
+
+	entry, err := phys.Get(ctx, barrierSealConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf SealConfig
+	if err := json.Unmarshal(entry.Value, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Type != SealTypeTest {
+		t.Fatalf("bad seal type: %q", conf.Type)
+	}
+}
@@ -0,0 +1,233 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+)
+
+// snapshotExcludedPaths are the storage entries that belong to the running
+// servers rather than to the data, and are neither saved nor restored
+var snapshotExcludedPaths = []string{CoreLockPath}
+
+// SaveSnapshot writes a snapshot of every storage entry to w. The entries
+// are written as stored, so they remain encrypted by the barrier.
+func (c *Core) SaveSnapshot(req *logical.Request, w io.Writer) error {
+	defer metrics.MeasureSince([]string{"core", "snapshot", "save"}, time.Now())
+
+	file, err := c.saveSnapshotFile(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	// The client may be slow to read the snapshot, so it is streamed from
+	// the file without holding any lock
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		return errwrap.Wrapf("failed to send snapshot: {{err}}", err)
+	}
+	return nil
+}
+
+// saveSnapshotFile writes the snapshot to a temporary file. Requests are
+// served meanwhile; the snapshot is taken at a single point in time only if
+// the storage backend supports consistent reads.
+func (c *Core) saveSnapshotFile(req *logical.Request) (*os.File, error) {
+	c.stateLock.RLock()
+	if c.sealed {
+		c.stateLock.RUnlock()
+		return nil, consts.ErrSealed
+	}
+	if c.standby {
+		c.stateLock.RUnlock()
+		return nil, consts.ErrStandby
+	}
+	ctx := c.activeContext
+	err := c.checkSnapshotRequest(ctx, req)
+	c.stateLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// The storage may be wrapped, for instance to emit metrics
+	backend := c.underlyingPhysical
+	if wrapper, ok := backend.(interface{ Underlying() physical.Backend }); ok {
+		backend = wrapper.Underlying()
+	}
+
+	file, err := ioutil.TempFile("", "vault-snapshot-")
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to create temporary snapshot file: {{err}}", err)
+	}
+
+	// The snapshot is cut short if Vault is sealed or steps down meanwhile,
+	// as the active context is canceled
+	count, err := physical.WriteSnapshot(ctx, backend, file, snapshotExcludedPaths)
+	if err != nil {
+		c.logger.Error("failed to save snapshot", "error", err)
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	c.logger.Info("saved snapshot", "entries", count)
+	return file, nil
+}
+
+// RestoreSnapshot replaces every storage entry with those of the snapshot
+// read from r, then seals Vault. It must be unsealed again with the unseal
+// keys of the snapshot.
+func (c *Core) RestoreSnapshot(req *logical.Request, r io.Reader) (retErr error) {
+	defer metrics.MeasureSince([]string{"core", "snapshot", "restore"}, time.Now())
+
+	c.stateLock.RLock()
+	if c.sealed {
+		c.stateLock.RUnlock()
+		return consts.ErrSealed
+	}
+	if c.standby {
+		c.stateLock.RUnlock()
+		return consts.ErrStandby
+	}
+	err := c.checkSnapshotRequest(c.activeContext, req)
+	c.stateLock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// The snapshot has to be verified before anything is replaced, so it is
+	// read in full before restoring it
+	file, err := ioutil.TempFile("", "vault-snapshot-")
+	if err != nil {
+		return errwrap.Wrapf("failed to create temporary snapshot file: {{err}}", err)
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return errwrap.Wrapf("failed to read snapshot: {{err}}", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := physical.VerifySnapshot(file); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Stop serving requests while the storage is replaced
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+
+	// Tell any requests that know about this to stop
+	if c.activeContextCancelFunc != nil {
+		c.activeContextCancelFunc()
+	}
+
+	count, err := physical.RestoreSnapshot(context.Background(), c.underlyingPhysical, file, snapshotExcludedPaths)
+	if err != nil {
+		c.logger.Error("failed to restore snapshot", "entries", count, "error", err)
+		retErr = multierror.Append(retErr, err)
+	} else {
+		c.logger.Info("restored snapshot", "entries", count)
+	}
+
+	// Whatever was read before the restore is stale now, including the
+	// keyring, so Vault has to be unsealed again
+	c.storageRestored = true
+	c.physicalCache.Purge(context.Background())
+	c.seal.SetBarrierConfig(context.Background(), nil)
+	if c.seal.RecoveryKeySupported() {
+		c.seal.SetRecoveryConfig(context.Background(), nil)
+	}
+
+	if err := c.sealInternal(false); err != nil {
+		retErr = multierror.Append(retErr, err)
+	}
+
+	return retErr
+}
+
+// checkSnapshotRequest audits the snapshot request and verifies that it is
+// made with root privileges
+func (c *Core) checkSnapshotRequest(ctx context.Context, req *logical.Request) error {
+	if req == nil {
+		return errors.New("nil request to snapshot")
+	}
+
+	acl, te, entity, err := c.fetchACLTokenEntryAndEntity(req.ClientToken)
+	if err != nil {
+		return err
+	}
+
+	// Audit-log the request before going any further
+	auth := &logical.Auth{
+		ClientToken: req.ClientToken,
+		Policies:    te.Policies,
+		Metadata:    te.Meta,
+		DisplayName: te.DisplayName,
+		EntityID:    te.EntityID,
+	}
+
+	logInput := &audit.LogInput{
+		Auth:    auth,
+		Request: req,
+	}
+	if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit request", "request_path", req.Path, "error", err)
+		return errors.New("failed to audit request, cannot continue")
+	}
+
+	// Attempt to use the token (decrement num_uses)
+	if te != nil {
+		te, err = c.tokenStore.UseToken(ctx, te)
+		if err != nil {
+			c.logger.Error("failed to use token", "error", err)
+			return ErrInternalError
+		}
+		if te == nil {
+			// Token has been revoked
+			return logical.ErrPermissionDenied
+		}
+	}
+
+	// Verify that this operation is allowed
+	authResults := c.performPolicyChecks(ctx, acl, te, req, entity, &PolicyCheckOpts{
+		RootPrivsRequired: true,
+	})
+	if authResults.Error.ErrorOrNil() != nil {
+		return authResults.Error
+	}
+	if !authResults.Allowed {
+		return logical.ErrPermissionDenied
+	}
+
+	return nil
+}
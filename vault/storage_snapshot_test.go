@@ -0,0 +1,213 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/logging"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/file"
+	"github.com/hashicorp/vault/physical/inmem"
+)
+
+// writerFunc is an io.Writer calling the function for every write
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestCore_StorageSnapshot(t *testing.T) {
+	// Take the snapshot from a core using the in-memory backend
+	c1, keys, root1 := TestCoreUnsealed(t)
+
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"value": "bar"},
+		ClientToken: root1,
+	}
+	if _, err := c1.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Root privileges are required
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/snapshot",
+		ClientToken: "invalid",
+	}
+	if err := c1.SaveSnapshot(req, ioutil.Discard); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var snapshot bytes.Buffer
+	req.ClientToken = root1
+	if err := c1.SaveSnapshot(req, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// And restore it into a core using the file backend
+	dir, err := ioutil.TempDir("", "vault-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := file.NewFileBackend(map[string]string{"path": dir}, logging.NewVaultLogger(log.Debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _, root2 := TestCoreUnsealedBackend(t, backend)
+
+	req = &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/baz",
+		Data:        map[string]interface{}{"value": "qux"},
+		ClientToken: root2,
+	}
+	if _, err := c2.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A corrupted snapshot is rejected without touching the storage
+	corrupted := append([]byte{}, snapshot.Bytes()...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	req = &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/storage/snapshot-restore",
+		ClientToken: root2,
+	}
+	err = c2.RestoreSnapshot(req, bytes.NewReader(corrupted))
+	if err == nil || !errwrap.Contains(err, physical.ErrInvalidSnapshot.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := c2.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	if err := c2.RestoreSnapshot(req, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := c2.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+
+	// The restored storage is unsealed with the keys of the snapshot
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c2, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	if sealed, _ := c2.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root1,
+	}
+	resp, err := c2.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	req.Path = "secret/baz"
+	resp, err = c2.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	if _, err := c2.HandleRequest(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root2,
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCore_StorageSnapshot_Streaming(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	write := func(path string) error {
+		_, err := c.HandleRequest(&logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        path,
+			Data:        map[string]interface{}{"value": "bar"},
+			ClientToken: root,
+		})
+		return err
+	}
+	if err := write("secret/before"); err != nil {
+		t.Fatal(err)
+	}
+
+	// While the snapshot is sent, neither the state lock is held nor are
+	// writes held back, and the writes are not part of the snapshot
+	var snapshot bytes.Buffer
+	var once sync.Once
+	w := writerFunc(func(p []byte) (int, error) {
+		once.Do(func() {
+			doneCh := make(chan error)
+			go func() {
+				c.stateLock.Lock()
+				c.stateLock.Unlock()
+				doneCh <- write("secret/during")
+			}()
+			select {
+			case err := <-doneCh:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("blocked while sending the snapshot")
+			}
+		})
+		return snapshot.Write(p)
+	})
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/snapshot",
+		ClientToken: root,
+	}
+	if err := c.SaveSnapshot(req, w); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := inmem.NewInmem(nil, logging.NewVaultLogger(log.Debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := physical.RestoreSnapshot(context.Background(), restored, bytes.NewReader(snapshot.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+	_, prefix, ok := c.router.MatchingStoragePrefixByAPIPath("secret/")
+	if !ok {
+		t.Fatal("missing secret mount")
+	}
+	for key, exists := range map[string]bool{"before": true, "during": false} {
+		entry, err := restored.Get(context.Background(), prefix+key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (entry != nil) != exists {
+			t.Fatalf("expected %q to exist in the snapshot: %t", key, exists)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+var (
+	// storageVerifyUnencryptedPaths are the entries written to the storage
+	// directly rather than through the barrier, which cannot be verified
+	storageVerifyUnencryptedPaths = []string{
+		CoreLockPath,
+		barrierSealConfigPath,
+		recoverySealConfigPlaintextPath,
+		recoveryKeyPath,
+		storedBarrierKeysPath,
+		hsmStoredIVPath,
+		coreBarrierUnsealKeysBackupPath,
+		coreRecoveryUnsealKeysBackupPath,
+	}
+
+	// storageVerifyMountTablePaths are the entries holding a mount table
+	storageVerifyMountTablePaths = []string{
+		coreMountConfigPath,
+		coreLocalMountConfigPath,
+		coreAuthConfigPath,
+		coreLocalAuthConfigPath,
+		coreAuditConfigPath,
+		coreLocalAuditConfigPath,
+	}
+)
+
+// StorageVerifyReport is the result of verifying every storage entry
+type StorageVerifyReport struct {
+	// Scanned is the number of entries verified, and Skipped the number of
+	// those that are not encrypted by the barrier
+	Scanned int
+	Skipped int
+
+	// Failures lists the entries that failed to decrypt or to decode
+	Failures []*StorageVerifyFailure
+}
+
+// StorageVerifyFailure describes an entry that failed verification
+type StorageVerifyFailure struct {
+	Key string
+
+	// Term is the key term the entry claims to be encrypted under, or zero
+	// if it is too damaged to tell
+	Term uint32
+
+	Error string
+}
+
+// VerifyStorage checks that every storage entry decrypts and that the
+// entries of a known format decode, without modifying anything
+func (c *Core) VerifyStorage(ctx context.Context) (*StorageVerifyReport, error) {
+	defer metrics.MeasureSince([]string{"core", "storage", "verify"}, time.Now())
+
+	report := &StorageVerifyReport{}
+	err := logical.ScanView(ctx, c.barrier, func(path string) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		report.Scanned++
+		if strutil.StrListContains(storageVerifyUnencryptedPaths, path) {
+			report.Skipped++
+			return
+		}
+
+		term, plain, err := c.barrier.Verify(ctx, path)
+		if err == nil && plain != nil {
+			err = verifyStorageValue(path, plain)
+		}
+		if err != nil {
+			report.Failures = append(report.Failures, &StorageVerifyFailure{
+				Key:   path,
+				Term:  term,
+				Error: err.Error(),
+			})
+		}
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(report.Failures) > 0 {
+		c.logger.Warn("storage verification found bad entries", "scanned", report.Scanned, "bad", len(report.Failures))
+	} else {
+		c.logger.Info("storage verification complete", "scanned", report.Scanned)
+	}
+	return report, nil
+}
+
+// verifyStorageValue checks that a decrypted entry decodes, for the entries
+// of a known format
+func verifyStorageValue(key string, value []byte) error {
+	switch {
+	case strutil.StrListContains(storageVerifyMountTablePaths, key):
+		return jsonutil.DecodeJSON(value, new(MountTable))
+	case strings.HasPrefix(key, systemBarrierPrefix+tokenSubPath+lookupPrefix):
+		return jsonutil.DecodeJSON(value, new(TokenEntry))
+	case strings.HasPrefix(key, systemBarrierPrefix+expirationSubPath+leaseViewPrefix):
+		_, err := decodeLeaseEntry(value)
+		return err
+	}
+	return nil
+}
+
+// storageVerifyPaths returns the path used to verify the storage
+func (b *SystemBackend) storageVerifyPaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "storage/verify$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleStorageVerify,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["storage-verify"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["storage-verify"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleStorageVerify(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	report, err := b.Core.VerifyStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	badKeys := make([]map[string]interface{}, 0, len(report.Failures))
+	for _, failure := range report.Failures {
+		badKeys = append(badKeys, map[string]interface{}{
+			"key":   failure.Key,
+			"term":  failure.Term,
+			"error": failure.Error,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"scanned":  report.Scanned,
+			"skipped":  report.Skipped,
+			"bad_keys": badKeys,
+		},
+	}, nil
+}
@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCore_VerifyStorage(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := context.Background()
+
+	report, err := c.VerifyStorage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned == 0 {
+		t.Fatal("expected entries to be scanned")
+	}
+	// The seal configuration is not encrypted by the barrier
+	if report.Skipped == 0 {
+		t.Fatal("expected unencrypted entries to be skipped")
+	}
+	if len(report.Failures) != 0 {
+		t.Fatalf("bad: %#v", report.Failures)
+	}
+
+	// Corrupt the ciphertext of the mount table
+	entry, err := c.underlyingPhysical.Get(ctx, coreMountConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Value[len(entry.Value)-1] ^= 0xff
+	if err := c.underlyingPhysical.Put(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	c.physicalCache.Purge(ctx)
+
+	// Write a token entry that decrypts but does not decode
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   "sys/token/id/bogus",
+		Value: []byte("bogus"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = c.VerifyStorage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failures) != 2 {
+		t.Fatalf("bad: %#v", report.Failures)
+	}
+	for _, failure := range report.Failures {
+		switch failure.Key {
+		case coreMountConfigPath:
+			if failure.Term != 1 || !strings.Contains(failure.Error, "decryption failed") {
+				t.Fatalf("bad: %#v", failure)
+			}
+		case "sys/token/id/bogus":
+			if failure.Term != 1 {
+				t.Fatalf("bad: %#v", failure)
+			}
+		default:
+			t.Fatalf("unexpected failure: %#v", failure)
+		}
+	}
+
+	// Nothing was modified
+	after, err := c.underlyingPhysical.Get(ctx, coreMountConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after.Value) != string(entry.Value) {
+		t.Fatal("expected the corrupted entry to be left as is")
+	}
+}
@@ -1197,7 +1197,9 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.CacheSize = base.CacheSize
 		coreConfig.PluginDirectory = base.PluginDirectory
 		coreConfig.Seal = base.Seal
+		coreConfig.MetricsHelper = base.MetricsHelper
 		coreConfig.DevToken = base.DevToken
+		coreConfig.PerformanceStandby = base.PerformanceStandby
 
 		if !coreConfig.DisableMlock {
 			base.DisableMlock = false
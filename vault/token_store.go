@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"sync"
 	"sync/atomic"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/helper/salt"
@@ -49,6 +51,10 @@ const (
 	// rolesPrefix is the prefix used to store role information
 	rolesPrefix = "roles/"
 
+	// namespaceRolesPrefix is the prefix used to store the role information
+	// of non-root namespaces
+	namespaceRolesPrefix = "namespace-roles/"
+
 	// tokenRevocationDeferred indicates that the token should not be used
 	// again but is currently fulfilling its final use
 	tokenRevocationDeferred = -1
@@ -92,7 +98,9 @@ type TokenStore struct {
 
 	cubbyholeBackend *CubbyholeBackend
 
-	policyLookupFunc func(string) (*Policy, error)
+	policyLookupFunc func(context.Context, string) (*Policy, error)
+
+	namespaceLookupFunc func(string) *namespace.Namespace
 
 	tokenLocks []*locksutil.LockEntry
 
@@ -114,15 +122,16 @@ func NewTokenStore(ctx context.Context, logger log.Logger, c *Core, config *logi
 
 	// Initialize the store
 	t := &TokenStore{
-		view:               view,
-		cubbyholeDestroyer: destroyCubbyhole,
-		logger:             logger,
-		tokenLocks:         locksutil.CreateLocks(),
-		saltLock:           sync.RWMutex{},
+		view:                view,
+		cubbyholeDestroyer:  destroyCubbyhole,
+		logger:              logger,
+		tokenLocks:          locksutil.CreateLocks(),
+		saltLock:            sync.RWMutex{},
+		namespaceLookupFunc: c.namespaceByID,
 	}
 
 	if c.policyStore != nil {
-		t.policyLookupFunc = func(name string) (*Policy, error) {
+		t.policyLookupFunc = func(ctx context.Context, name string) (*Policy, error) {
 			return c.policyStore.GetPolicy(ctx, name, PolicyTypeToken)
 		}
 	}
@@ -571,6 +580,18 @@ type TokenEntry struct {
 	ExplicitMaxTTLDeprecated time.Duration `json:"ExplicitMaxTTL" mapstructure:"ExplicitMaxTTL" structs:"ExplicitMaxTTL" sentinel:""`
 
 	EntityID string `json:"entity_id" mapstructure:"entity_id" structs:"entity_id"`
+
+	// The namespace the token was created in; empty for tokens created
+	// before namespaces existed, which belong to the root namespace
+	NamespaceID string `json:"namespace_id" mapstructure:"namespace_id" structs:"namespace_id"`
+}
+
+// namespaceID returns the ID of the namespace the token belongs to
+func (te *TokenEntry) namespaceID() string {
+	if te.NamespaceID == "" {
+		return namespace.RootNamespaceID
+	}
+	return te.NamespaceID
 }
 
 func (te *TokenEntry) SentinelGet(key string) (interface{}, error) {
@@ -688,6 +709,15 @@ func (ts *TokenStore) rootToken(ctx context.Context) (*TokenEntry, error) {
 	if err := ts.create(ctx, te); err != nil {
 		return nil, err
 	}
+
+	// Root tokens generated here do not get a lease
+	if ts.expiration != nil {
+		saltedID, err := ts.SaltID(ctx, te.ID)
+		if err != nil {
+			return nil, err
+		}
+		ts.expiration.releaseLeaseCount(path.Join(te.Path, saltedID))
+	}
 	return te, nil
 }
 
@@ -708,9 +738,17 @@ func (ts *TokenStore) tokenStoreAccessorList(ctx context.Context, req *logical.R
 		}
 		if aEntry.TokenID == "" {
 			resp.AddWarning(fmt.Sprintf("Found an accessor entry missing a token: %v", aEntry.AccessorID))
-		} else {
-			ret = append(ret, aEntry.AccessorID)
+			continue
+		}
+
+		// Only list the accessors of tokens visible from the namespace
+		if ns := namespaceFromContext(ctx); ns.ID != namespace.RootNamespaceID {
+			te, err := ts.Lookup(ctx, aEntry.TokenID)
+			if err != nil || te == nil || !ts.tokenVisible(ctx, te) {
+				continue
+			}
 		}
+		ret = append(ret, aEntry.AccessorID)
 	}
 
 	resp.Data = map[string]interface{}{
@@ -756,7 +794,7 @@ func (ts *TokenStore) createAccessor(ctx context.Context, entry *TokenEntry) err
 
 // Create is used to create a new token entry. The entry is assigned
 // a newly generated ID if not provided.
-func (ts *TokenStore) create(ctx context.Context, entry *TokenEntry) error {
+func (ts *TokenStore) create(ctx context.Context, entry *TokenEntry) (retErr error) {
 	defer metrics.MeasureSince([]string{"token", "create"}, time.Now())
 	// Generate an ID if necessary
 	if entry.ID == "" {
@@ -767,6 +805,12 @@ func (ts *TokenStore) create(ctx context.Context, entry *TokenEntry) error {
 		entry.ID = entryUUID
 	}
 
+	// Bind the token to the namespace it is created in. Tokens of the root
+	// namespace leave the ID empty, as tokens predating namespaces do.
+	if ns := namespaceFromContext(ctx); entry.NamespaceID == "" && ns.ID != namespace.RootNamespaceID {
+		entry.NamespaceID = ns.ID
+	}
+
 	saltedID, err := ts.SaltID(ctx, entry.ID)
 	if err != nil {
 		return err
@@ -778,6 +822,22 @@ func (ts *TokenStore) create(ctx context.Context, entry *TokenEntry) error {
 
 	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
 
+	// Reserve the lease count of the lease the token will get, enforcing the
+	// lease count quotas. Root tokens are exempt so that an operator can
+	// always regain access. The reservation is released if the token is
+	// revoked before its lease is registered.
+	if ts.expiration != nil {
+		leaseID := path.Join(entry.Path, saltedID)
+		if err := ts.expiration.acquireLeaseCount(leaseID, !strutil.StrListContains(entry.Policies, "root")); err != nil {
+			return err
+		}
+		defer func() {
+			if retErr != nil {
+				ts.expiration.releaseLeaseCount(leaseID)
+			}
+		}()
+	}
+
 	err = ts.createAccessor(ctx, entry)
 	if err != nil {
 		return err
@@ -1601,6 +1661,10 @@ func (ts *TokenStore) handleUpdateRevokeAccessor(ctx context.Context, req *logic
 		return nil, err
 	}
 
+	if resp, err := ts.checkTokenVisible(ctx, aEntry.TokenID); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke the token and its children
 	if err := ts.RevokeTree(ctx, aEntry.TokenID); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -1642,6 +1706,11 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 			logical.ErrInvalidRequest
 	}
 
+	// Tokens are created in the namespace of the request, which may be a
+	// descendant of the namespace of the parent token
+	ns := namespaceFromContext(ctx)
+	crossNamespace := parent.namespaceID() != ns.ID
+
 	// Check if the client token has sudo/root privileges for the requested path
 	isSudo := ts.System().SudoPrivilege(ctx, req.MountPoint+req.Path, req.ClientToken)
 
@@ -1678,7 +1747,7 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		// The mount point is always the same since we have only one token
 		// store; using req.MountPoint causes trouble in tests since they don't
 		// have an official mount
-		Path: fmt.Sprintf("auth/token/%s", req.Path),
+		Path: fmt.Sprintf("%sauth/token/%s", ns.Path, req.Path),
 
 		Meta:         data.Metadata,
 		DisplayName:  "token",
@@ -1729,6 +1798,12 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 
 	resp := &logical.Response{}
 
+	// The parent's policies have no meaning in another namespace, so they
+	// can neither be inherited nor used to bound the requested policies
+	if crossNamespace && len(data.Policies) == 0 && (role == nil || len(role.AllowedPolicies) == 0) {
+		return logical.ErrorResponse("policies must be specified when creating a token in a namespace other than the parent token's"), logical.ErrInvalidRequest
+	}
+
 	var addDefault bool
 
 	// N.B.: The logic here uses various calculations as to whether default
@@ -1802,6 +1877,10 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		// Only inherit "default" if the parent already has it, so don't touch addDefault here
 		data.Policies = policyutil.SanitizePolicies(parent.Policies, policyutil.DoNotAddDefaultPolicy)
 
+	// Policies in another namespace are not compared against the parent's
+	case crossNamespace:
+		addDefault = !data.NoDefaultPolicy
+
 	// When a role is not in use or does not specify allowed/disallowed, only
 	// permit policies to be a subset unless the client has root or sudo
 	// privileges. Default is added in this case if the parent has it, unless
@@ -1847,6 +1926,9 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 	if strutil.StrListContains(data.Policies, "root") && !strutil.StrListContains(parent.Policies, "root") {
 		return logical.ErrorResponse("root tokens may not be created without parent token being root"), logical.ErrInvalidRequest
 	}
+	if strutil.StrListContains(te.Policies, "root") && ns.ID != namespace.RootNamespaceID {
+		return logical.ErrorResponse("root tokens may only be created in the root namespace"), logical.ErrInvalidRequest
+	}
 
 	//
 	// NOTE: Do not modify policies below this line. We need the checks above
@@ -1878,7 +1960,7 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 	// At this point, it is clear whether the token is going to be an orphan or
 	// not. If the token is not going to be an orphan, inherit the parent's
 	// entity identifier into the child token.
-	if te.Parent != "" {
+	if te.Parent != "" && !crossNamespace {
 		te.EntityID = parent.EntityID
 	}
 
@@ -1993,6 +2075,9 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 
 	// Create the token
 	if err := ts.create(ctx, &te); err != nil {
+		if errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+			return logical.ErrorResponse(err.Error()), err
+		}
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
@@ -2015,7 +2100,7 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 
 	if ts.policyLookupFunc != nil {
 		for _, p := range te.Policies {
-			policy, err := ts.policyLookupFunc(p)
+			policy, err := ts.policyLookupFunc(ctx, p)
 			if err != nil {
 				return logical.ErrorResponse(fmt.Sprintf("could not look up policy %s", p)), nil
 			}
@@ -2053,6 +2138,10 @@ func (ts *TokenStore) handleRevokeTree(ctx context.Context, req *logical.Request
 		urltoken = true
 	}
 
+	if resp, err := ts.checkTokenVisible(ctx, id); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke the token and its children
 	if err := ts.RevokeTree(ctx, id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2098,6 +2187,10 @@ func (ts *TokenStore) handleRevokeOrphan(ctx context.Context, req *logical.Reque
 			logical.ErrInvalidRequest
 	}
 
+	if resp, err := ts.checkTokenVisible(ctx, id); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke and orphan
 	if err := ts.Revoke(ctx, id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2149,7 +2242,7 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	if out == nil {
+	if out == nil || !ts.tokenVisible(ctx, out) {
 		return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
 	}
 
@@ -2171,6 +2264,7 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 			"ttl":              int64(0),
 			"explicit_max_ttl": int64(out.ExplicitMaxTTL.Seconds()),
 			"entity_id":        out.EntityID,
+			"namespace_path":   ts.tokenNamespacePath(out),
 		},
 	}
 
@@ -2240,7 +2334,7 @@ func (ts *TokenStore) handleRenew(ctx context.Context, req *logical.Request, dat
 	}
 
 	// Verify the token exists
-	if te == nil {
+	if te == nil || !ts.tokenVisible(ctx, te) {
 		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
 	}
 
@@ -2294,8 +2388,72 @@ func (ts *TokenStore) authRenew(ctx context.Context, req *logical.Request, d *fr
 	return &logical.Response{Auth: req.Auth}, nil
 }
 
+// rolesPrefix returns the storage prefix of the roles of the namespace in
+// the context
+func (ts *TokenStore) rolesPrefix(ctx context.Context) string {
+	ns := namespaceFromContext(ctx)
+	if ns.ID == namespace.RootNamespaceID {
+		return rolesPrefix
+	}
+	return namespaceRolesPrefix + ns.ID + "/"
+}
+
+// deleteNamespaceRoles removes the roles of the given namespace
+func (ts *TokenStore) deleteNamespaceRoles(ctx context.Context, ns *namespace.Namespace) error {
+	if ns.ID == namespace.RootNamespaceID {
+		return nil
+	}
+	if err := logical.ClearView(ctx, ts.view.SubView(namespaceRolesPrefix+ns.ID+"/")); err != nil {
+		return errwrap.Wrapf("failed to delete namespace token roles: {{err}}", err)
+	}
+	return nil
+}
+
+// tokenVisible returns whether the token belongs to the namespace in the
+// context or to one of its descendants. Tokens whose namespace no longer
+// exists are only visible from the root namespace.
+func (ts *TokenStore) tokenVisible(ctx context.Context, te *TokenEntry) bool {
+	ns := namespaceFromContext(ctx)
+	if ns.ID == namespace.RootNamespaceID {
+		return true
+	}
+
+	var tokenNS *namespace.Namespace
+	if ts.namespaceLookupFunc != nil {
+		tokenNS = ts.namespaceLookupFunc(te.namespaceID())
+	}
+	if tokenNS == nil {
+		return false
+	}
+	return ns.Contains(tokenNS)
+}
+
+// checkTokenVisible returns an error response if the token with the given ID
+// exists but is not visible from the namespace in the context
+func (ts *TokenStore) checkTokenVisible(ctx context.Context, id string) (*logical.Response, error) {
+	te, err := ts.lookupTainted(ctx, id)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if te != nil && !ts.tokenVisible(ctx, te) {
+		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// tokenNamespacePath returns the path of the namespace the token belongs to
+func (ts *TokenStore) tokenNamespacePath(te *TokenEntry) string {
+	if ts.namespaceLookupFunc == nil {
+		return ""
+	}
+	if ns := ts.namespaceLookupFunc(te.namespaceID()); ns != nil {
+		return ns.Path
+	}
+	return ""
+}
+
 func (ts *TokenStore) tokenStoreRole(ctx context.Context, name string) (*tsRoleEntry, error) {
-	entry, err := ts.view.Get(ctx, fmt.Sprintf("%s%s", rolesPrefix, name))
+	entry, err := ts.view.Get(ctx, fmt.Sprintf("%s%s", ts.rolesPrefix(ctx), name))
 	if err != nil {
 		return nil, err
 	}
@@ -2312,21 +2470,22 @@ func (ts *TokenStore) tokenStoreRole(ctx context.Context, name string) (*tsRoleE
 }
 
 func (ts *TokenStore) tokenStoreRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entries, err := ts.view.List(ctx, rolesPrefix)
+	prefix := ts.rolesPrefix(ctx)
+	entries, err := ts.view.List(ctx, prefix)
 	if err != nil {
 		return nil, err
 	}
 
 	ret := make([]string, len(entries))
 	for i, entry := range entries {
-		ret[i] = strings.TrimPrefix(entry, rolesPrefix)
+		ret[i] = strings.TrimPrefix(entry, prefix)
 	}
 
 	return logical.ListResponse(ret), nil
 }
 
 func (ts *TokenStore) tokenStoreRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := ts.view.Delete(ctx, fmt.Sprintf("%s%s", rolesPrefix, data.Get("role_name").(string)))
+	err := ts.view.Delete(ctx, fmt.Sprintf("%s%s", ts.rolesPrefix(ctx), data.Get("role_name").(string)))
 	if err != nil {
 		return nil, err
 	}
@@ -2471,7 +2630,7 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(ctx context.Context, req *logic
 	}
 
 	// Store it
-	jsonEntry, err := logical.StorageEntryJSON(fmt.Sprintf("%s%s", rolesPrefix, name), entry)
+	jsonEntry, err := logical.StorageEntryJSON(fmt.Sprintf("%s%s", ts.rolesPrefix(ctx), name), entry)
 	if err != nil {
 		return nil, err
 	}
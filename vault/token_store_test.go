@@ -1505,6 +1505,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"namespace_path":   "",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1545,6 +1546,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"renewable":        true,
 		"entity_id":        "",
+		"namespace_path":   "",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1596,6 +1598,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"renewable":        true,
 		"entity_id":        "",
+		"namespace_path":   "",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1678,6 +1681,7 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
 		"entity_id":        "",
+		"namespace_path":   "",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
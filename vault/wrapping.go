@@ -127,7 +127,7 @@ DONELISTHANDLING:
 	resp.WrapInfo.Accessor = te.Accessor
 	resp.WrapInfo.CreationTime = creationTime
 	// If this is not a rewrap, store the request path as creation_path
-	if req.Path != "sys/wrapping/rewrap" {
+	if namespaceFromContext(ctx).TrimmedPath(req.Path) != "sys/wrapping/rewrap" {
 		resp.WrapInfo.CreationPath = req.Path
 	}
 
@@ -181,7 +181,7 @@ DONELISTHANDLING:
 	}
 
 	// During a rewrap, store the original response, don't wrap it again.
-	if req.Path == "sys/wrapping/rewrap" {
+	if namespaceFromContext(ctx).TrimmedPath(req.Path) == "sys/wrapping/rewrap" {
 		cubbyReq.Data = map[string]interface{}{
 			"response": resp.Data["response"],
 		}
@@ -231,7 +231,7 @@ DONELISTHANDLING:
 		"creation_time": creationTime,
 	}
 	// Store creation_path if not a rewrap
-	if req.Path != "sys/wrapping/rewrap" {
+	if namespaceFromContext(ctx).TrimmedPath(req.Path) != "sys/wrapping/rewrap" {
 		cubbyReq.Data["creation_path"] = req.Path
 	} else {
 		cubbyReq.Data["creation_path"] = resp.WrapInfo.CreationPath